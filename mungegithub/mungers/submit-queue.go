@@ -57,6 +57,7 @@ const (
 	retestNotRequiredLabel         = "retest-not-required"
 	retestNotRequiredDocsOnlyLabel = "retest-not-required-docs-only"
 	doNotMergeLabel                = "do-not-merge"
+	doNotMergePrefix               = doNotMergeLabel + "/"
 	claYesLabel                    = "cla: yes"
 	claNoLabel                     = "cla: no"
 	cncfClaYesLabel                = "cncf-cla: yes"
@@ -973,6 +974,7 @@ const (
 	unmergeable             = "PR is unable to be automatically merged. Needs rebase."
 	undeterminedMergability = "Unable to determine is PR is mergeable. Will try again later."
 	noMerge                 = "Will not auto merge because " + doNotMergeLabel + " is present"
+	noMergeFmt              = "Will not auto merge because %s is present"
 	ciFailure               = "Required Github CI test is not green"
 	ciFailureFmt            = ciFailure + ": %s"
 	e2eFailure              = "The e2e tests are failing. The entire submit queue is blocked."
@@ -1096,6 +1098,12 @@ func (sq *SubmitQueue) validForMergeExt(obj *github.MungeObject, checkStatus boo
 		sq.SetMergeStatus(obj, noMerge)
 		return false
 	}
+	// Nor any of the more specific do-not-merge/* labels, such as those that
+	// prow's hold plugin applies.
+	if labels := github.GetLabelsWithPrefix(obj.Issue.Labels, doNotMergePrefix); len(labels) > 0 {
+		sq.SetMergeStatus(obj, fmt.Sprintf(noMergeFmt, labels[0]))
+		return false
+	}
 
 	return true
 }
@@ -1596,7 +1604,7 @@ func (sq *SubmitQueue) serveMergeInfo(res http.ResponseWriter, req *http.Request
 	if gateApproved {
 		out.WriteString(fmt.Sprintf(`<li>The PR must have the %q label</li>`, approvedLabel))
 	}
-	out.WriteString(fmt.Sprintf("<li>The PR must not have the %q label</li>", doNotMergeLabel))
+	out.WriteString(fmt.Sprintf("<li>The PR must not have the %q label or any label starting with %q</li>", doNotMergeLabel, doNotMergePrefix))
 	out.WriteString(`</ol><br>`)
 	out.WriteString("The PR can then be queued to re-test before merge. Once it reaches the top of the queue all of the above conditions must be true but so must the following:")
 	out.WriteString("<ol>")
@@ -105,6 +105,10 @@ func DoNotMergeIssue() *github.Issue {
 	return github_test.Issue(someUserName, 1, []string{claYesLabel, lgtmLabel, approvedLabel, doNotMergeLabel}, true)
 }
 
+func HoldIssue() *github.Issue {
+	return github_test.Issue(someUserName, 1, []string{claYesLabel, lgtmLabel, approvedLabel, doNotMergePrefix + "hold"}, true)
+}
+
 func DoNotMergeMilestoneIssue() *github.Issue {
 	issue := github_test.Issue(someUserName, 1, []string{claYesLabel, lgtmLabel, doNotMergeLabel}, true)
 	milestone := &github.Milestone{
@@ -781,6 +785,20 @@ func TestSubmitQueue(t *testing.T) {
 			reason:          noMerge,
 			state:           "pending",
 		},
+		{
+			name:            "Fail because do-not-merge/hold label is present",
+			pr:              ValidPR(),
+			issue:           HoldIssue(),
+			events:          NewLGTMEvents(),
+			commits:         Commits(), // Modified at time.Unix(7), 8, and 9
+			ciStatus:        SuccessStatus(),
+			lastBuildNumber: LastBuildNumber(),
+			gcsResult:       SuccessGCS(),
+			retest1Pass:     true,
+			retest2Pass:     true,
+			reason:          fmt.Sprintf(noMergeFmt, doNotMergePrefix+"hold"),
+			state:           "pending",
+		},
 		// Should fail because the 'do-not-merge-milestone' is set.
 		{
 			name:            "Do Not Merge Milestone Set",
@@ -0,0 +1,263 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Branchprotector keeps the required status contexts in GitHub branch
+// protection in sync with the presubmits in config.yaml. Each branch requires
+// the contexts of the presubmits that always run against it, report to
+// GitHub, and are not optional. Only branches that are already protected are
+// updated, unless --protect-new-branches is set, in which case the branches
+// listed in branch_protection are protected too. The branch_protection section
+// of config.yaml can add or exclude contexts per org or repo, restrict which
+// branches are reconciled, or skip a repo entirely. Run it once with
+// --dry-run=false to apply the changes.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+)
+
+var (
+	configPath      = flag.String("config-path", "/etc/config/config", "Path to config.yaml.")
+	githubBotName   = flag.String("github-bot-name", "", "Name of the GitHub bot.")
+	githubTokenFile = flag.String("github-token-file", "/etc/github/oauth", "Path to the file containing the GitHub OAuth secret.")
+	githubEndpoint  = flag.String("github-endpoint", "https://api.github.com", "GitHub's API endpoint, or that of a ghproxy in front of it.")
+	dryRun          = flag.Bool("dry-run", true, "Dry run for testing. Uses API tokens but does not mutate.")
+
+	protectNew = flag.Bool("protect-new-branches", false, "Also protect unprotected branches listed in branch_protection. Protected branches can't be force-pushed or deleted.")
+
+	githubAppID      = flag.Int("github-app-id", 0, "ID of the GitHub App to authenticate as instead of using --github-token-file. The bot name is then <app-name>[bot].")
+	githubAppKeyFile = flag.String("github-app-private-key-file", "/etc/github-app/key", "Path to the file containing the GitHub App's private key.")
+)
+
+func main() {
+	flag.Parse()
+	logrus.SetFormatter(&logrus.JSONFormatter{})
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error loading config.")
+	}
+
+	var oauthSecret string
+	if *githubAppID == 0 {
+		oauthSecretRaw, err := ioutil.ReadFile(*githubTokenFile)
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not read oauth secret file.")
+		}
+		oauthSecret = string(bytes.TrimSpace(oauthSecretRaw))
+	}
+	if *githubBotName == "" {
+		logrus.Fatal("Must specify --github-bot-name.")
+	}
+	var gc *github.Client
+	if *dryRun {
+		gc = github.NewDryRunClient(*githubBotName, oauthSecret)
+	} else {
+		gc = github.NewClient(*githubBotName, oauthSecret)
+	}
+	gc.SetEndpoint(*githubEndpoint)
+	if *githubAppID != 0 {
+		key, err := ioutil.ReadFile(*githubAppKeyFile)
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not read GitHub App private key file.")
+		}
+		if err := gc.UseApp(*githubAppID, key); err != nil {
+			logrus.WithError(err).Fatal("Error using GitHub App.")
+		}
+	}
+	gc.Logger = logrus.WithField("client", "github")
+
+	if err := protect(gc, cfg, *protectNew); err != nil {
+		logrus.WithError(err).Fatal("Error protecting branches.")
+	}
+}
+
+type githubClient interface {
+	GetBranches(org, repo string) ([]github.Branch, error)
+	GetRequiredStatusChecks(org, repo, branch string) (*github.RequiredStatusChecks, error)
+	UpdateRequiredStatusChecks(org, repo, branch string, checks github.RequiredStatusChecks) error
+	ProtectBranch(org, repo, branch string, checks github.RequiredStatusChecks) error
+}
+
+// protect reconciles every repo that has presubmits or is named by an
+// override. If protectNew is set, it also protects the unprotected branches
+// that an override lists.
+func protect(gc githubClient, cfg *config.Config, protectNew bool) error {
+	repos := map[string]bool{}
+	for r := range cfg.Presubmits {
+		repos[r] = true
+	}
+	for _, bp := range cfg.BranchProtection {
+		for _, r := range bp.Repos {
+			if strings.Contains(r, "/") {
+				repos[r] = true
+			}
+		}
+	}
+	var names []string
+	for r := range repos {
+		names = append(names, r)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, r := range names {
+		parts := strings.SplitN(r, "/", 2)
+		if len(parts) != 2 {
+			errs = append(errs, fmt.Errorf("bad repo name %q", r))
+			continue
+		}
+		if err := protectRepo(gc, parts[0], parts[1], cfg.Presubmits[r], override(cfg, parts[0], parts[1]), protectNew); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", r, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("errors protecting branches: %v", errs)
+	}
+	return nil
+}
+
+// override returns the branch protection override for the repo. An org/repo
+// entry takes precedence over an org entry.
+func override(cfg *config.Config, org, repo string) config.BranchProtection {
+	fullName := org + "/" + repo
+	var bp config.BranchProtection
+	for _, c := range cfg.BranchProtection {
+		for _, r := range c.Repos {
+			if r == fullName {
+				return c
+			}
+			if r == org {
+				bp = c
+			}
+		}
+	}
+	return bp
+}
+
+// requiredContexts returns the sorted contexts that must pass before merging
+// into branch.
+func requiredContexts(presubmits []config.Presubmit, branch string, bp config.BranchProtection) []string {
+	excluded := map[string]bool{}
+	for _, c := range bp.ExcludeContexts {
+		excluded[c] = true
+	}
+	contexts := map[string]bool{}
+	for _, c := range bp.Contexts {
+		contexts[c] = true
+	}
+	for _, p := range presubmits {
+		if !p.AlwaysRun || p.SkipReport || p.Optional || !p.RunsAgainstBranch(branch) {
+			continue
+		}
+		contexts[p.Context] = true
+	}
+	required := []string{}
+	for c := range contexts {
+		if !excluded[c] {
+			required = append(required, c)
+		}
+	}
+	sort.Strings(required)
+	return required
+}
+
+// protectRepo updates the required contexts of the protected branches of the
+// repo. Unprotected branches are only protected if protectNew is set and the
+// override lists them, since protection stops force-pushes and deletion.
+func protectRepo(gc githubClient, org, repo string, presubmits []config.Presubmit, bp config.BranchProtection, protectNew bool) error {
+	if bp.Skip {
+		return nil
+	}
+	branches, err := gc.GetBranches(org, repo)
+	if err != nil {
+		return fmt.Errorf("error listing branches: %v", err)
+	}
+	var errs []error
+	for _, b := range branches {
+		if len(bp.Branches) > 0 && !contains(bp.Branches, b.Name) {
+			continue
+		}
+		required := requiredContexts(presubmits, b.Name, bp)
+		log := logrus.WithFields(logrus.Fields{"org": org, "repo": repo, "branch": b.Name, "contexts": required})
+		if !b.Protected {
+			if !protectNew || !contains(bp.Branches, b.Name) || len(required) == 0 {
+				continue
+			}
+			log.Info("Protecting branch.")
+			if err := gc.ProtectBranch(org, repo, b.Name, github.RequiredStatusChecks{Contexts: required}); err != nil {
+				errs = append(errs, fmt.Errorf("error protecting %s: %v", b.Name, err))
+			}
+			continue
+		}
+		checks, err := gc.GetRequiredStatusChecks(org, repo, b.Name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error getting status checks of %s: %v", b.Name, err))
+			continue
+		}
+		if checks == nil {
+			// Turning on status checks means replacing the whole protection,
+			// which would drop whatever else someone set up by hand.
+			log.Warning("Branch is protected but doesn't require status checks, skipping.")
+			continue
+		}
+		if sameContexts(checks.Contexts, required) {
+			continue
+		}
+		log.WithField("previous", checks.Contexts).Info("Updating required contexts.")
+		if err := gc.UpdateRequiredStatusChecks(org, repo, b.Name, github.RequiredStatusChecks{Strict: checks.Strict, Contexts: required}); err != nil {
+			errs = append(errs, fmt.Errorf("error updating status checks of %s: %v", b.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// sameContexts compares have with the sorted want, ignoring order.
+func sameContexts(have, want []string) bool {
+	if len(have) != len(want) {
+		return false
+	}
+	sorted := append([]string(nil), have...)
+	sort.Strings(sorted)
+	for i := range sorted {
+		if sorted[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+)
+
+type fakeGitHub struct {
+	branches map[string][]github.Branch
+	checks   map[string]*github.RequiredStatusChecks
+
+	updated   []string
+	protected []string
+}
+
+func (f *fakeGitHub) GetBranches(org, repo string) ([]github.Branch, error) {
+	return f.branches[org+"/"+repo], nil
+}
+
+func (f *fakeGitHub) GetRequiredStatusChecks(org, repo, branch string) (*github.RequiredStatusChecks, error) {
+	return f.checks[fmt.Sprintf("%s/%s=%s", org, repo, branch)], nil
+}
+
+func (f *fakeGitHub) UpdateRequiredStatusChecks(org, repo, branch string, checks github.RequiredStatusChecks) error {
+	f.updated = append(f.updated, fmt.Sprintf("%s/%s=%s:%v:%v", org, repo, branch, checks.Strict, checks.Contexts))
+	return nil
+}
+
+func (f *fakeGitHub) ProtectBranch(org, repo, branch string, checks github.RequiredStatusChecks) error {
+	f.protected = append(f.protected, fmt.Sprintf("%s/%s=%s:%v", org, repo, branch, checks.Contexts))
+	return nil
+}
+
+func TestRequiredContexts(t *testing.T) {
+	presubmits := []config.Presubmit{
+		{Context: "unit", AlwaysRun: true},
+		{Context: "e2e", AlwaysRun: true, Brancher: config.Brancher{Branches: []string{"master"}}},
+		{Context: "manual"},
+		{Context: "silent", AlwaysRun: true, SkipReport: true},
+		{Context: "optional", AlwaysRun: true, Optional: true},
+		{Context: "lint", AlwaysRun: true},
+	}
+	var testcases = []struct {
+		name     string
+		branch   string
+		bp       config.BranchProtection
+		expected []string
+	}{
+		{
+			name:     "master",
+			branch:   "master",
+			expected: []string{"e2e", "lint", "unit"},
+		},
+		{
+			name:     "release branch",
+			branch:   "release-1.7",
+			expected: []string{"lint", "unit"},
+		},
+		{
+			name:     "extra and excluded contexts",
+			branch:   "master",
+			bp:       config.BranchProtection{Contexts: []string{"cla/linuxfoundation"}, ExcludeContexts: []string{"lint"}},
+			expected: []string{"cla/linuxfoundation", "e2e", "unit"},
+		},
+	}
+	for _, tc := range testcases {
+		if actual := requiredContexts(presubmits, tc.branch, tc.bp); !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("For case %s, expected contexts %v, got %v", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestOverride(t *testing.T) {
+	cfg := &config.Config{
+		BranchProtection: []config.BranchProtection{
+			{Repos: []string{"org/repo"}, Skip: true},
+			{Repos: []string{"org", "other"}, Contexts: []string{"cla"}},
+		},
+	}
+	if bp := override(cfg, "org", "repo"); !bp.Skip {
+		t.Errorf("Expected the org/repo override to win, got %+v", bp)
+	}
+	if bp := override(cfg, "org", "another"); bp.Skip || len(bp.Contexts) != 1 {
+		t.Errorf("Expected the org override, got %+v", bp)
+	}
+	if bp := override(cfg, "third", "repo"); !reflect.DeepEqual(bp, config.BranchProtection{}) {
+		t.Errorf("Expected no override, got %+v", bp)
+	}
+}
+
+func TestProtect(t *testing.T) {
+	cfg := &config.Config{
+		Presubmits: map[string][]config.Presubmit{
+			"org/repo": {
+				{Context: "unit", AlwaysRun: true},
+				{Context: "e2e", AlwaysRun: true, Brancher: config.Brancher{Branches: []string{"master"}}},
+			},
+			"org/skipped": {
+				{Context: "unit", AlwaysRun: true},
+			},
+			"org/manual": {
+				{Context: "unit"},
+			},
+		},
+		BranchProtection: []config.BranchProtection{
+			{Repos: []string{"org/skipped"}, Skip: true},
+			{Repos: []string{"org/nojobs"}, Contexts: []string{"cla"}, Branches: []string{"master"}},
+		},
+	}
+	var testcases = []struct {
+		name       string
+		protectNew bool
+		protected  []string
+	}{
+		{
+			name: "only protected branches",
+		},
+		{
+			name:       "protect listed branches",
+			protectNew: true,
+			protected:  []string{"org/nojobs=master:[cla]"},
+		},
+	}
+	for _, tc := range testcases {
+		fgc := &fakeGitHub{
+			branches: map[string][]github.Branch{
+				"org/repo": {
+					{Name: "master", Protected: true},
+					{Name: "release-1.6", Protected: true},
+					{Name: "release-1.7", Protected: true},
+					{Name: "feature"},
+				},
+				"org/skipped": {{Name: "master"}},
+				"org/manual":  {{Name: "master"}, {Name: "stale", Protected: true}},
+				"org/nojobs":  {{Name: "master"}, {Name: "dev"}},
+			},
+			checks: map[string]*github.RequiredStatusChecks{
+				"org/repo=master":      {Strict: true, Contexts: []string{"unit"}},
+				"org/repo=release-1.6": {Contexts: []string{"unit"}},
+				"org/manual=stale":     {Contexts: []string{"unit"}},
+			},
+		}
+		if err := protect(fgc, cfg, tc.protectNew); err != nil {
+			t.Fatalf("For case %s, didn't expect error: %v", tc.name, err)
+		}
+		expectedUpdated := []string{"org/manual=stale:false:[]", "org/repo=master:true:[e2e unit]"}
+		sort.Strings(fgc.updated)
+		if !reflect.DeepEqual(fgc.updated, expectedUpdated) {
+			t.Errorf("For case %s, expected updates %v, got %v", tc.name, expectedUpdated, fgc.updated)
+		}
+		sort.Strings(fgc.protected)
+		if !reflect.DeepEqual(fgc.protected, tc.protected) {
+			t.Errorf("For case %s, expected protected branches %v, got %v", tc.name, tc.protected, fgc.protected)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/ghodss/yaml"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/kube"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+const (
+	sessionCookie = "deck-session"
+	stateCookie   = "deck-oauth-state"
+	csrfHeader    = "X-CSRF-Token"
+
+	sessionLength = 24 * time.Hour
+)
+
+// oauthConfig is the GitHub OAuth app that deck logs users in with.
+type oauthConfig struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	// RedirectURL is deck's /github-login/redirect path, as registered
+	// with the OAuth app.
+	RedirectURL string `json:"redirect_url"`
+	// CookieSecret signs session cookies. Every deck replica needs the same
+	// one.
+	CookieSecret string `json:"cookie_secret"`
+}
+
+// permissionClient is what authAgent asks GitHub about users with.
+type permissionClient interface {
+	IsMember(org, user string) (bool, error)
+	IsCollaborator(org, repo, user string) (bool, error)
+	GetFile(org, repo, filepath, commit string) ([]byte, error)
+}
+
+type userGetter interface {
+	GetUser() (*github.User, error)
+}
+
+// authAgent logs users in with GitHub and decides what they may do.
+type authAgent struct {
+	oauth oauthConfig
+	// githubURL is where the OAuth app lives, normally https://github.com.
+	githubURL string
+	ghc       permissionClient
+	// userClient returns a client that acts as the user with the token.
+	userClient func(token string) userGetter
+	deck       func() config.Deck
+}
+
+// newAuthAgent reads the OAuth app config and the token that deck checks
+// user permissions with.
+func newAuthAgent(oauthConfigFile, githubTokenFile, githubEndpoint string, ca *config.Agent) (*authAgent, error) {
+	b, err := ioutil.ReadFile(oauthConfigFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read OAuth config file: %v", err)
+	}
+	var oc oauthConfig
+	if err := yaml.Unmarshal(b, &oc); err != nil {
+		return nil, fmt.Errorf("could not parse OAuth config file: %v", err)
+	}
+	if oc.ClientID == "" || oc.ClientSecret == "" || oc.RedirectURL == "" || oc.CookieSecret == "" {
+		return nil, errors.New("OAuth config needs client_id, client_secret, redirect_url and cookie_secret")
+	}
+	tokenRaw, err := ioutil.ReadFile(githubTokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read GitHub token file: %v", err)
+	}
+	ghc := github.NewClient("", string(bytes.TrimSpace(tokenRaw)))
+	ghc.SetEndpoint(githubEndpoint)
+	return &authAgent{
+		oauth:     oc,
+		githubURL: "https://github.com",
+		ghc:       ghc,
+		// User tokens go straight to GitHub rather than through a ghproxy.
+		userClient: func(token string) userGetter { return github.NewClient("", token) },
+		deck:       func() config.Deck { return ca.Config().Deck },
+	}, nil
+}
+
+// handleLogin sends the user to GitHub to log in.
+func (aa *authAgent) handleLogin(w http.ResponseWriter, r *http.Request) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		http.Error(w, "Error generating state", http.StatusInternalServerError)
+		logrus.WithError(err).Error("Error generating OAuth state.")
+		return
+	}
+	state := hex.EncodeToString(b)
+	http.SetCookie(w, aa.cookie(stateCookie, state, int((10*time.Minute).Seconds())))
+	q := url.Values{
+		"client_id":    {aa.oauth.ClientID},
+		"redirect_uri": {aa.oauth.RedirectURL},
+		"state":        {state},
+	}
+	http.Redirect(w, r, aa.githubURL+"/login/oauth/authorize?"+q.Encode(), http.StatusFound)
+}
+
+// handleRedirect is where GitHub sends the user back to after they log in.
+// It swaps the code in the query for a token, looks up who the token belongs
+// to and starts a session for them.
+func (aa *authAgent) handleRedirect(w http.ResponseWriter, r *http.Request) {
+	state, err := r.Cookie(stateCookie)
+	if err != nil || state.Value == "" || !hmac.Equal([]byte(state.Value), []byte(r.URL.Query().Get("state"))) {
+		http.Error(w, "Invalid OAuth state, try logging in again", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, aa.cookie(stateCookie, "", -1))
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		http.Error(w, "Missing code query", http.StatusBadRequest)
+		return
+	}
+	token, err := aa.exchange(code, state.Value)
+	if err != nil {
+		http.Error(w, "Error logging in with GitHub", http.StatusBadGateway)
+		logrus.WithError(err).Warning("Error exchanging OAuth code.")
+		return
+	}
+	user, err := aa.userClient(token).GetUser()
+	if err != nil {
+		http.Error(w, "Error logging in with GitHub", http.StatusBadGateway)
+		logrus.WithError(err).Warning("Error getting GitHub user.")
+		return
+	}
+	http.SetCookie(w, aa.cookie(sessionCookie, aa.newSession(user.Login, time.Now().Add(sessionLength)), int(sessionLength.Seconds())))
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// cookie returns a cookie that scripts can't read and that other sites can't
+// send along with their requests to deck, except for following a link. It
+// is only sent over HTTPS unless deck itself is served over plain HTTP, such
+// as when testing locally. A negative maxAge deletes the cookie.
+func (aa *authAgent) cookie(name, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   !strings.HasPrefix(aa.oauth.RedirectURL, "http://"),
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// exchange swaps an OAuth code for a token.
+func (aa *authAgent) exchange(code, state string) (string, error) {
+	form := url.Values{
+		"client_id":     {aa.oauth.ClientID},
+		"client_secret": {aa.oauth.ClientSecret},
+		"code":          {code},
+		"redirect_uri":  {aa.oauth.RedirectURL},
+		"state":         {state},
+	}
+	req, err := http.NewRequest(http.MethodPost, aa.githubURL+"/login/oauth/access_token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("response has status %q", resp.Status)
+	}
+	var tr struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return "", err
+	}
+	if tr.Error != "" {
+		return "", fmt.Errorf("GitHub says %q", tr.Error)
+	}
+	if tr.AccessToken == "" {
+		return "", errors.New("response has no access token")
+	}
+	return tr.AccessToken, nil
+}
+
+// handleLogout ends the session. It only accepts POSTs, so that a link or an
+// image on another page can't log the user out.
+func (aa *authAgent) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Log out with a POST", http.StatusMethodNotAllowed)
+		return
+	}
+	http.SetCookie(w, aa.cookie(sessionCookie, "", -1))
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// session is who the user is, as far as deck can tell.
+type session struct {
+	// Login is empty if the user isn't logged in.
+	Login string `json:"login,omitempty"`
+	// CSRFToken must be sent in the X-CSRF-Token header of requests that
+	// act as the user.
+	CSRFToken string `json:"csrf_token,omitempty"`
+	// LoginEnabled is false if deck has no OAuth app to log users in with.
+	LoginEnabled bool `json:"login_enabled"`
+}
+
+// handleUser serves the session of the user, so that pages know whether to
+// offer to log in or to rerun jobs. It is only served as JSON, never as a
+// script, because other sites could include a script and read the CSRF
+// token, while they can't read JSON from deck.
+func handleUser(aa *authAgent) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Content-Type", "application/json")
+		s := session{LoginEnabled: aa != nil}
+		if aa != nil {
+			if c, err := r.Cookie(sessionCookie); err == nil {
+				if login, ok := aa.checkSession(c.Value); ok {
+					s.Login = login
+					s.CSRFToken = aa.csrfToken(c.Value)
+				}
+			}
+		}
+		b, err := json.Marshal(s)
+		if err != nil {
+			http.Error(w, "Error marshaling session", http.StatusInternalServerError)
+			logrus.WithError(err).Error("Error marshaling session.")
+			return
+		}
+		w.Write(b)
+	}
+}
+
+// newSession returns a session cookie value for the user that expires at
+// expiry. GitHub logins can't contain "|".
+func (aa *authAgent) newSession(login string, expiry time.Time) string {
+	v := login + "|" + strconv.FormatInt(expiry.Unix(), 10)
+	return v + "|" + aa.sign("session|"+v)
+}
+
+// checkSession returns the login of a session cookie value if it's signed by
+// us and hasn't expired.
+func (aa *authAgent) checkSession(value string) (string, bool) {
+	parts := strings.Split(value, "|")
+	if len(parts) != 3 {
+		return "", false
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(aa.sign("session|"+parts[0]+"|"+parts[1]))) {
+		return "", false
+	}
+	expiry, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() > expiry {
+		return "", false
+	}
+	return parts[0], true
+}
+
+func (aa *authAgent) csrfToken(session string) string {
+	return aa.sign("csrf|" + session)
+}
+
+func (aa *authAgent) sign(v string) string {
+	mac := hmac.New(sha256.New, []byte(aa.oauth.CookieSecret))
+	mac.Write([]byte(v))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+var errNotLoggedIn = errors.New("not logged in")
+
+// authenticate returns the login of the user that made the request. It
+// requires the CSRF token of the session so that other sites can't act as
+// the user.
+func (aa *authAgent) authenticate(r *http.Request) (string, error) {
+	if aa == nil {
+		return "", errors.New("login is not enabled")
+	}
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return "", errNotLoggedIn
+	}
+	login, ok := aa.checkSession(c.Value)
+	if !ok {
+		return "", errNotLoggedIn
+	}
+	if !hmac.Equal([]byte(r.Header.Get(csrfHeader)), []byte(aa.csrfToken(c.Value))) {
+		return "", errors.New("invalid CSRF token")
+	}
+	return login, nil
+}
+
+// authorize returns whether the user may rerun and abort the job. Admins may
+// act on every job. Jobs for repos need the permission that the deck config
+// asks for, and jobs without a repo need an admin.
+func (aa *authAgent) authorize(login string, pj kube.ProwJob) (bool, error) {
+	d := aa.deck()
+	for _, admin := range d.Admins {
+		if strings.EqualFold(admin, login) {
+			return true, nil
+		}
+	}
+	org, repo := pj.Spec.Refs.Org, pj.Spec.Refs.Repo
+	if org == "" || repo == "" {
+		return false, nil
+	}
+	switch p := d.RerunPermission(org, repo); p {
+	case config.OrgMemberPermission:
+		return aa.ghc.IsMember(org, login)
+	case config.CollaboratorPermission:
+		return aa.ghc.IsCollaborator(org, repo, login)
+	case config.ApproverPermission:
+		o, err := plugins.RootOwners(aa.ghc, org, repo)
+		if err != nil {
+			return false, fmt.Errorf("error getting OWNERS of %s/%s: %v", org, repo, err)
+		}
+		return o.IsApprover(login), nil
+	default:
+		return false, fmt.Errorf("unknown permission %q", p)
+	}
+}
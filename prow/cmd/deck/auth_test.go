@@ -0,0 +1,215 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/kube"
+)
+
+type fakePermissionClient struct{}
+
+func (f fakePermissionClient) IsMember(org, user string) (bool, error) {
+	return user == "alice" || user == "carol", nil
+}
+
+func (f fakePermissionClient) IsCollaborator(org, repo, user string) (bool, error) {
+	return user == "alice", nil
+}
+
+func (f fakePermissionClient) GetFile(org, repo, filepath, commit string) ([]byte, error) {
+	if filepath != "OWNERS" {
+		return nil, errors.New("no such file")
+	}
+	return []byte("approvers:\n- Carol\n"), nil
+}
+
+type fakeUserGetter string
+
+func (f fakeUserGetter) GetUser() (*github.User, error) {
+	if f != "good-token" {
+		return nil, errors.New("bad token")
+	}
+	return &github.User{Login: "alice"}, nil
+}
+
+func testAuthAgent(githubURL string) *authAgent {
+	return &authAgent{
+		oauth: oauthConfig{
+			ClientID:     "id",
+			ClientSecret: "secret",
+			RedirectURL:  "https://deck/github-login/redirect",
+			CookieSecret: "cookie",
+		},
+		githubURL:  githubURL,
+		ghc:        fakePermissionClient{},
+		userClient: func(token string) userGetter { return fakeUserGetter(token) },
+		deck: func() config.Deck {
+			return config.Deck{
+				Admins: []string{"Dave"},
+				RerunAuth: []config.RerunAuth{
+					{Repos: []string{"org/collab"}, Permission: config.CollaboratorPermission},
+					{Repos: []string{"org/approve"}, Permission: config.ApproverPermission},
+				},
+			}
+		},
+	}
+}
+
+func TestSession(t *testing.T) {
+	aa := testAuthAgent("")
+	s := aa.newSession("alice", time.Now().Add(time.Hour))
+	if login, ok := aa.checkSession(s); !ok || login != "alice" {
+		t.Errorf("Expected a session for alice, got %q, %t", login, ok)
+	}
+	if _, ok := aa.checkSession("bob" + s[len("alice"):]); ok {
+		t.Error("Expected a tampered session to be invalid.")
+	}
+	if _, ok := aa.checkSession(aa.newSession("alice", time.Now().Add(-time.Minute))); ok {
+		t.Error("Expected an expired session to be invalid.")
+	}
+	other := testAuthAgent("")
+	other.oauth.CookieSecret = "other"
+	if _, ok := other.checkSession(s); ok {
+		t.Error("Expected a session signed with another secret to be invalid.")
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	var testcases = []struct {
+		name     string
+		login    string
+		repo     string
+		expected bool
+	}{
+		{"admin on periodic", "dave", "", true},
+		{"member on periodic", "alice", "", false},
+		{"member", "carol", "other", true},
+		{"non-member", "bob", "other", false},
+		{"collaborator", "alice", "collab", true},
+		{"member but not collaborator", "carol", "collab", false},
+		{"approver", "carol", "approve", true},
+		{"collaborator but not approver", "alice", "approve", false},
+	}
+	aa := testAuthAgent("")
+	for _, tc := range testcases {
+		pj := kube.ProwJob{Spec: kube.ProwJobSpec{Job: "job"}}
+		if tc.repo != "" {
+			pj.Spec.Refs = kube.Refs{Org: "org", Repo: tc.repo}
+		}
+		allowed, err := aa.authorize(tc.login, pj)
+		if err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+		} else if allowed != tc.expected {
+			t.Errorf("For case %s, expected %t, got %t", tc.name, tc.expected, allowed)
+		}
+	}
+}
+
+func TestLogin(t *testing.T) {
+	gh := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/login/oauth/access_token" || r.Method != http.MethodPost {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if r.FormValue("code") != "code" || r.FormValue("client_secret") != "secret" {
+			t.Errorf("Bad form: %v", r.Form)
+		}
+		fmt.Fprint(w, `{"access_token": "good-token"}`)
+	}))
+	defer gh.Close()
+	aa := testAuthAgent(gh.URL)
+
+	rr := httptest.NewRecorder()
+	aa.handleLogin(rr, httptest.NewRequest(http.MethodGet, "/github-login", nil))
+	if rr.Code != http.StatusFound {
+		t.Fatalf("Expected a redirect, got %d", rr.Code)
+	}
+	loc, err := url.Parse(rr.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("Bad redirect: %v", err)
+	}
+	state := loc.Query().Get("state")
+	if loc.Path != "/login/oauth/authorize" || loc.Query().Get("client_id") != "id" || state == "" {
+		t.Fatalf("Bad redirect: %s", loc)
+	}
+	stateCookies := rr.Result().Cookies()
+
+	// A mismatched state must not log anyone in.
+	req := httptest.NewRequest(http.MethodGet, "/github-login/redirect?code=code&state=other", nil)
+	for _, c := range stateCookies {
+		req.AddCookie(c)
+	}
+	rr = httptest.NewRecorder()
+	aa.handleRedirect(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected %d for a mismatched state, got %d", http.StatusBadRequest, rr.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/github-login/redirect?code=code&state="+state, nil)
+	for _, c := range stateCookies {
+		req.AddCookie(c)
+	}
+	rr = httptest.NewRecorder()
+	aa.handleRedirect(rr, req)
+	if rr.Code != http.StatusFound {
+		t.Fatalf("Expected a redirect, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var session *http.Cookie
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == sessionCookie {
+			session = c
+		}
+	}
+	if session == nil {
+		t.Fatal("Expected a session cookie.")
+	}
+	for _, c := range append(stateCookies, session) {
+		if !c.Secure || !c.HttpOnly || c.SameSite != http.SameSiteLaxMode {
+			t.Errorf("Expected a secure, HTTP-only, same-site cookie, got %s", c)
+		}
+	}
+
+	// The session is only served as JSON, even if a page asks for a script.
+	req = httptest.NewRequest(http.MethodGet, "/user?var=user", nil)
+	req.AddCookie(session)
+	rr = httptest.NewRecorder()
+	handleUser(aa).ServeHTTP(rr, req)
+	expected := fmt.Sprintf(`{"login":"alice","csrf_token":"%s","login_enabled":true}`, aa.csrfToken(session.Value))
+	if rr.Body.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	aa.handleLogout(rr, httptest.NewRequest(http.MethodGet, "/logout", nil))
+	if rr.Code != http.StatusMethodNotAllowed || len(rr.Result().Cookies()) != 0 {
+		t.Errorf("Expected GET /logout to be refused, got %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	aa.handleLogout(rr, httptest.NewRequest(http.MethodPost, "/logout", nil))
+	if cs := rr.Result().Cookies(); rr.Code != http.StatusSeeOther || len(cs) != 1 || cs[0].Name != sessionCookie || cs[0].MaxAge >= 0 {
+		t.Errorf("Expected POST /logout to clear the session, got %d with cookies %v", rr.Code, cs)
+	}
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"time"
+
+	"k8s.io/test-infra/prow/kube"
+)
+
+// JobFilter selects jobs. Zero fields match every job.
+type JobFilter struct {
+	Type   string
+	Repo   string
+	Job    string
+	State  string
+	Author string
+	// Number matches presubmits of the PR and batches that include it.
+	Number int
+	// Since and Until bound the jobs' start times.
+	Since time.Time
+	Until time.Time
+}
+
+func (f JobFilter) matches(j Job) bool {
+	if f.Type != "" && f.Type != j.Type {
+		return false
+	}
+	if f.Repo != "" && f.Repo != j.Repo {
+		return false
+	}
+	if f.Job != "" && f.Job != j.Job {
+		return false
+	}
+	if f.State != "" && f.State != j.State {
+		return false
+	}
+	if f.Author != "" || f.Number != 0 {
+		if _, ok := j.pull(f.Author, f.Number); !ok {
+			return false
+		}
+	}
+	if !f.Since.IsZero() && j.st.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && j.st.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// pull returns the job's pull by author with number. Zero values match any.
+func (j Job) pull(author string, number int) (kube.Pull, bool) {
+	for _, p := range j.pulls {
+		if (author == "" || author == p.Author) && (number == 0 || number == p.Number) {
+			return p, true
+		}
+	}
+	return kube.Pull{}, false
+}
+
+// FilteredJobs returns the jobs that match the filter, newest first.
+func (ja *JobAgent) FilteredJobs(f JobFilter) []Job {
+	ja.mut.Lock()
+	defer ja.mut.Unlock()
+	res := []Job{}
+	for _, j := range ja.jobs {
+		if f.matches(j) {
+			res = append(res, j)
+		}
+	}
+	return res
+}
+
+// PullHistory is every run of every job for a PR.
+type PullHistory struct {
+	Repo   string `json:"repo"`
+	Number int    `json:"number"`
+	Author string `json:"author"`
+	// Commits are the PR's SHAs that jobs ran against, newest first.
+	Commits []CommitRuns `json:"commits"`
+}
+
+// CommitRuns are the runs against one SHA of a PR, newest first. They
+// include batches that the PR was part of.
+type CommitRuns struct {
+	SHA  string `json:"sha"`
+	Runs []Job  `json:"runs"`
+}
+
+// PullHistory returns the runs for repo#number from the cache.
+func (ja *JobAgent) PullHistory(repo string, number int) PullHistory {
+	h := PullHistory{
+		Repo:    repo,
+		Number:  number,
+		Commits: []CommitRuns{},
+	}
+	index := make(map[string]int)
+	// Jobs are newest first, so commits come out newest first too.
+	for _, j := range ja.FilteredJobs(JobFilter{Repo: repo, Number: number}) {
+		p, _ := j.pull("", number)
+		if h.Author == "" {
+			h.Author = p.Author
+		}
+		n, ok := index[p.SHA]
+		if !ok {
+			n = len(h.Commits)
+			index[p.SHA] = n
+			h.Commits = append(h.Commits, CommitRuns{SHA: p.SHA})
+		}
+		h.Commits[n].Runs = append(h.Commits[n].Runs, j)
+	}
+	return h
+}
+
+// JobHistory is the recent runs of a job along with how they went.
+type JobHistory struct {
+	Job string `json:"job"`
+	// Runs are newest first.
+	Runs []Job `json:"runs"`
+	// Passed and Failed count the runs that succeeded and that failed or
+	// errored. Runs that are unfinished or aborted count toward neither.
+	Passed int `json:"passed"`
+	Failed int `json:"failed"`
+	// AverageDuration is over the runs that finished, and empty if there are
+	// none.
+	AverageDuration string `json:"average_duration"`
+	// MaxDuration is the longest of the runs that finished.
+	MaxDuration string `json:"max_duration"`
+}
+
+// JobHistory returns the runs of job from the cache.
+func (ja *JobAgent) JobHistory(job string) JobHistory {
+	h := JobHistory{
+		Job:  job,
+		Runs: ja.FilteredJobs(JobFilter{Job: job}),
+	}
+	var total, longest time.Duration
+	var finished int
+	for _, j := range h.Runs {
+		switch kube.ProwJobState(j.State) {
+		case kube.SuccessState:
+			h.Passed++
+		case kube.FailureState, kube.ErrorState:
+			h.Failed++
+		}
+		if j.ft.IsZero() {
+			continue
+		}
+		d := j.ft.Sub(j.st)
+		total += d
+		finished++
+		if d > longest {
+			longest = d
+		}
+	}
+	if finished > 0 {
+		average := total / time.Duration(finished)
+		h.AverageDuration = (average - average%time.Second).String()
+		h.MaxDuration = (longest - longest%time.Second).String()
+	}
+	return h
+}
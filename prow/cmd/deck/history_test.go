@@ -0,0 +1,171 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/test-infra/prow/kube"
+)
+
+func pj(job string, t kube.ProwJobType, state kube.ProwJobState, start, duration time.Duration, pulls ...kube.Pull) kube.ProwJob {
+	base := time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)
+	p := kube.ProwJob{
+		Spec: kube.ProwJobSpec{
+			Type: t,
+			Job:  job,
+			Refs: kube.Refs{
+				Org:   "kubernetes",
+				Repo:  "test-infra",
+				Pulls: pulls,
+			},
+		},
+		Status: kube.ProwJobStatus{
+			State:     state,
+			StartTime: base.Add(start),
+		},
+	}
+	if duration != 0 {
+		p.Status.CompletionTime = base.Add(start + duration)
+	}
+	return p
+}
+
+func historyAgent(t *testing.T) *JobAgent {
+	ja := &JobAgent{
+		kc: fkc{
+			pj("unit", kube.PresubmitJob, kube.SuccessState, 0, 10*time.Minute, kube.Pull{Number: 1, Author: "alice", SHA: "a1"}),
+			pj("unit", kube.PresubmitJob, kube.FailureState, time.Hour, 20*time.Minute, kube.Pull{Number: 1, Author: "alice", SHA: "a2"}),
+			pj("e2e", kube.PresubmitJob, kube.PendingState, 2*time.Hour, 0, kube.Pull{Number: 1, Author: "alice", SHA: "a2"}),
+			pj("unit", kube.PresubmitJob, kube.ErrorState, 3*time.Hour, 30*time.Second, kube.Pull{Number: 2, Author: "bob", SHA: "b1"}),
+			pj("unit", kube.BatchJob, kube.AbortedState, 4*time.Hour, time.Minute, kube.Pull{Number: 1, Author: "alice", SHA: "a2"}, kube.Pull{Number: 2, Author: "bob", SHA: "b1"}),
+			pj("ci", kube.PeriodicJob, kube.SuccessState, 5*time.Hour, time.Minute),
+		},
+	}
+	if err := ja.update(); err != nil {
+		t.Fatalf("Updating: %v", err)
+	}
+	return ja
+}
+
+func jobNames(jobs []Job) []string {
+	var names []string
+	for _, j := range jobs {
+		names = append(names, j.Type+"/"+j.Job+"/"+j.State)
+	}
+	return names
+}
+
+func TestFilteredJobs(t *testing.T) {
+	ja := historyAgent(t)
+	base := time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)
+	var testcases = []struct {
+		name     string
+		filter   JobFilter
+		expected []string
+	}{
+		{
+			name:   "everything",
+			filter: JobFilter{},
+			expected: []string{
+				"periodic/ci/success",
+				"batch/unit/aborted",
+				"presubmit/unit/error",
+				"presubmit/e2e/pending",
+				"presubmit/unit/failure",
+				"presubmit/unit/success",
+			},
+		},
+		{
+			name:     "type and job",
+			filter:   JobFilter{Type: "presubmit", Job: "unit"},
+			expected: []string{"presubmit/unit/error", "presubmit/unit/failure", "presubmit/unit/success"},
+		},
+		{
+			name:     "state",
+			filter:   JobFilter{State: "success"},
+			expected: []string{"periodic/ci/success", "presubmit/unit/success"},
+		},
+		{
+			name:     "author includes batches",
+			filter:   JobFilter{Author: "bob"},
+			expected: []string{"batch/unit/aborted", "presubmit/unit/error"},
+		},
+		{
+			name:     "number",
+			filter:   JobFilter{Number: 1, Job: "unit"},
+			expected: []string{"batch/unit/aborted", "presubmit/unit/failure", "presubmit/unit/success"},
+		},
+		{
+			name:     "other repo",
+			filter:   JobFilter{Repo: "kubernetes/kubernetes"},
+			expected: nil,
+		},
+		{
+			name:     "time range",
+			filter:   JobFilter{Since: base.Add(time.Hour), Until: base.Add(2 * time.Hour)},
+			expected: []string{"presubmit/e2e/pending", "presubmit/unit/failure"},
+		},
+	}
+	for _, tc := range testcases {
+		if actual := jobNames(ja.FilteredJobs(tc.filter)); !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("For case %s, expected %v, got %v", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestPullHistory(t *testing.T) {
+	h := historyAgent(t).PullHistory("kubernetes/test-infra", 1)
+	if h.Author != "alice" {
+		t.Errorf("Expected author alice, got %s", h.Author)
+	}
+	var shas []string
+	var runs [][]string
+	for _, c := range h.Commits {
+		shas = append(shas, c.SHA)
+		runs = append(runs, jobNames(c.Runs))
+	}
+	if expected := []string{"a2", "a1"}; !reflect.DeepEqual(shas, expected) {
+		t.Errorf("Expected SHAs %v, got %v", expected, shas)
+	}
+	expected := [][]string{
+		{"batch/unit/aborted", "presubmit/e2e/pending", "presubmit/unit/failure"},
+		{"presubmit/unit/success"},
+	}
+	if !reflect.DeepEqual(runs, expected) {
+		t.Errorf("Expected runs %v, got %v", expected, runs)
+	}
+}
+
+func TestJobHistory(t *testing.T) {
+	h := historyAgent(t).JobHistory("unit")
+	if len(h.Runs) != 4 {
+		t.Errorf("Expected 4 runs, got %d", len(h.Runs))
+	}
+	if h.Passed != 1 || h.Failed != 2 {
+		t.Errorf("Expected 1 passed and 2 failed, got %d and %d", h.Passed, h.Failed)
+	}
+	// (10m + 20m + 30s + 1m) / 4
+	if h.AverageDuration != "7m52s" {
+		t.Errorf("Expected average duration 7m52s, got %s", h.AverageDuration)
+	}
+	if h.MaxDuration != "20m0s" {
+		t.Errorf("Expected max duration 20m0s, got %s", h.MaxDuration)
+	}
+}
@@ -18,6 +18,8 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
 	"regexp"
 	"sort"
 	"strconv"
@@ -34,6 +36,10 @@ const (
 	period = 30 * time.Second
 )
 
+// jenkinsLogPoll is how often to ask Jenkins for more of a running build's
+// log while streaming it.
+var jenkinsLogPoll = 5 * time.Second
+
 type Job struct {
 	Type        string            `json:"type"`
 	Repo        string            `json:"repo"`
@@ -56,8 +62,9 @@ type Job struct {
 	Agent       kube.ProwJobAgent `json:"agent"`
 	ProwJob     string            `json:"prow_job"`
 
-	st time.Time
-	ft time.Time
+	st    time.Time
+	ft    time.Time
+	pulls []kube.Pull
 }
 
 type listPJClient interface {
@@ -66,6 +73,7 @@ type listPJClient interface {
 
 type podLogClient interface {
 	GetLog(pod string) ([]byte, error)
+	StreamLog(pod string) (io.ReadCloser, error)
 }
 
 type JobAgent struct {
@@ -88,14 +96,6 @@ func (ja *JobAgent) Start() {
 	}()
 }
 
-func (ja *JobAgent) Jobs() []Job {
-	ja.mut.Lock()
-	defer ja.mut.Unlock()
-	res := make([]Job, len(ja.jobs))
-	copy(res, ja.jobs)
-	return res
-}
-
 var jobNameRE = regexp.MustCompile(`^([\w-]+)-(\d+)$`)
 
 // TODO(#3402): Remove this.
@@ -145,6 +145,84 @@ func (ja *JobAgent) GetJobLog(job, id string) ([]byte, error) {
 	return ja.jc.GetLog(job, num)
 }
 
+// StreamJobLog follows the log of a run from offset bytes in until the run
+// finishes or the stream is closed. The caller must close it.
+func (ja *JobAgent) StreamJobLog(job, id string, offset int64) (io.ReadCloser, error) {
+	var j kube.ProwJob
+	ja.mut.Lock()
+	idMap, ok := ja.jobsIDMap[job]
+	if ok {
+		j, ok = idMap[id]
+	}
+	ja.mut.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no such job %s %s", job, id)
+	}
+	if j.Spec.Agent == kube.KubernetesAgent {
+		// Pod logs can only be followed from the start.
+		stream, err := ja.pkc.StreamLog(j.Status.PodName)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.CopyN(ioutil.Discard, stream, offset); err != nil && err != io.EOF {
+			stream.Close()
+			return nil, err
+		}
+		return stream, nil
+	}
+	if ja.jc == nil {
+		return nil, fmt.Errorf("cannot get log for %s %s", job, id)
+	}
+	num, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, err
+	}
+	return streamJenkinsLog(ja.jc, job, num, offset), nil
+}
+
+// jenkinsLogStream reads a build's progressive console output.
+type jenkinsLogStream struct {
+	*io.PipeReader
+	done chan struct{}
+	once sync.Once
+}
+
+func (s *jenkinsLogStream) Close() error {
+	s.once.Do(func() { close(s.done) })
+	return s.PipeReader.Close()
+}
+
+func streamJenkinsLog(jc *jenkins.Client, job string, build int, offset int64) io.ReadCloser {
+	pr, pw := io.Pipe()
+	s := &jenkinsLogStream{PipeReader: pr, done: make(chan struct{})}
+	go func() {
+		for {
+			log, next, more, err := jc.GetProgressiveLog(job, build, offset)
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			if len(log) > 0 {
+				if _, err := pw.Write(log); err != nil {
+					// The reader was closed.
+					return
+				}
+			}
+			if !more {
+				pw.Close()
+				return
+			}
+			offset = next
+			select {
+			case <-s.done:
+				return
+			case <-time.After(jenkinsLogPoll):
+			}
+		}
+	}()
+	return s
+}
+
 func (ja *JobAgent) tryUpdate() {
 	if err := ja.update(); err != nil {
 		logrus.WithError(err).Warning("Error updating job list.")
@@ -185,8 +263,9 @@ func (ja *JobAgent) update() error {
 			PodName:     j.Status.PodName,
 			URL:         j.Status.URL,
 
-			st: j.Status.StartTime,
-			ft: j.Status.CompletionTime,
+			st:    j.Status.StartTime,
+			ft:    j.Status.CompletionTime,
+			pulls: j.Spec.Refs.Pulls,
 		}
 		if !nj.ft.IsZero() {
 			nj.Finished = nj.ft.Format(time.RFC3339Nano)
@@ -17,9 +17,17 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
+	"k8s.io/test-infra/prow/jenkins"
 	"k8s.io/test-infra/prow/kube"
 )
 
@@ -38,6 +46,14 @@ func (f fpkc) GetLog(pod string) ([]byte, error) {
 	return nil, fmt.Errorf("pod not found: %s", pod)
 }
 
+func (f fpkc) StreamLog(pod string) (io.ReadCloser, error) {
+	log, err := f.GetLog(pod)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(log)), nil
+}
+
 func TestGetLog(t *testing.T) {
 	kc := fkc{
 		kube.ProwJob{
@@ -62,3 +78,115 @@ func TestGetLog(t *testing.T) {
 		t.Fatalf("Failed to get log: %v", err)
 	}
 }
+
+func TestStreamJobLog(t *testing.T) {
+	// Jenkins hands out the log in three pieces, the last once the build is
+	// done.
+	pieces := []string{"one\n", "two\n", "three\n"}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/job/jenkins-job/42/logText/progressiveText" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		start, err := strconv.Atoi(r.URL.Query().Get("start"))
+		if err != nil {
+			t.Errorf("Bad start: %v", err)
+		}
+		var offset int
+		for n, p := range pieces {
+			if offset+len(p) > start {
+				w.Header().Set("X-Text-Size", strconv.Itoa(offset+len(p)))
+				if n < len(pieces)-1 {
+					w.Header().Set("X-More-Data", "true")
+				}
+				fmt.Fprint(w, p[start-offset:])
+				return
+			}
+			offset += len(p)
+		}
+		w.Header().Set("X-Text-Size", strconv.Itoa(offset))
+	}))
+	defer ts.Close()
+	defer func(poll time.Duration) { jenkinsLogPoll = poll }(jenkinsLogPoll)
+	jenkinsLogPoll = time.Millisecond
+
+	kc := fkc{
+		kube.ProwJob{
+			Spec: kube.ProwJobSpec{
+				Agent: kube.KubernetesAgent,
+				Job:   "job",
+			},
+			Status: kube.ProwJobStatus{
+				PodName: "wowowow",
+				BuildID: "123",
+			},
+		},
+		kube.ProwJob{
+			Spec: kube.ProwJobSpec{
+				Agent: kube.JenkinsAgent,
+				Job:   "jenkins-job",
+			},
+			Status: kube.ProwJobStatus{
+				BuildID: "42",
+			},
+		},
+	}
+	ja := &JobAgent{
+		kc:  kc,
+		pkc: &fpkc{},
+		jc:  jenkins.NewClient(ts.URL, "user", "token"),
+	}
+	if err := ja.update(); err != nil {
+		t.Fatalf("Updating: %v", err)
+	}
+	var testcases = []struct {
+		name     string
+		job      string
+		id       string
+		offset   int64
+		expected string
+	}{
+		{
+			name:     "pod from the start",
+			job:      "job",
+			id:       "123",
+			expected: "wow",
+		},
+		{
+			name:     "pod from an offset",
+			job:      "job",
+			id:       "123",
+			offset:   1,
+			expected: "ow",
+		},
+		{
+			name:     "jenkins from the start",
+			job:      "jenkins-job",
+			id:       "42",
+			expected: "one\ntwo\nthree\n",
+		},
+		{
+			name:     "jenkins from an offset",
+			job:      "jenkins-job",
+			id:       "42",
+			offset:   6,
+			expected: "o\nthree\n",
+		},
+	}
+	for _, tc := range testcases {
+		stream, err := ja.StreamJobLog(tc.job, tc.id, tc.offset)
+		if err != nil {
+			t.Errorf("For case %s, error streaming log: %v", tc.name, err)
+			continue
+		}
+		b, err := ioutil.ReadAll(stream)
+		stream.Close()
+		if err != nil {
+			t.Errorf("For case %s, error reading log: %v", tc.name, err)
+		} else if string(b) != tc.expected {
+			t.Errorf("For case %s, expected %q, got %q", tc.name, tc.expected, string(b))
+		}
+	}
+	if _, err := ja.StreamJobLog("ohno", "123", 0); err == nil {
+		t.Error("Expected an error streaming a job that doesn't exist.")
+	}
+}
@@ -21,13 +21,19 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"regexp"
+	"strconv"
+	"time"
 
 	"github.com/NYTimes/gziphandler"
 	"github.com/Sirupsen/logrus"
 	"github.com/ghodss/yaml"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/jenkins"
@@ -42,8 +48,31 @@ var (
 	jenkinsURL       = flag.String("jenkins-url", "", "Jenkins URL")
 	jenkinsUserName  = flag.String("jenkins-user", "jenkins-trigger", "Jenkins username")
 	jenkinsTokenFile = flag.String("jenkins-token-file", "/etc/jenkins/jenkins", "Path to the file containing the Jenkins API token.")
+
+	maxLogStreams = flag.Int("max-log-streams", 50, "Most logs to stream to browsers at once.")
+	spliceURL     = flag.String("splice-url", "", "URL of splice's merge pools, such as http://splice/pool. If empty, the merge queue page is empty.")
+
+	oauthConfigFile = flag.String("oauth-config-file", "", "Path to a YAML file with the GitHub OAuth app that users log in with to rerun and abort jobs. If empty, nobody can.")
+	githubTokenFile = flag.String("github-token-file", "/etc/github/oauth", "Path to the file containing the GitHub OAuth token used to check user permissions.")
+	githubEndpoint  = flag.String("github-endpoint", "https://api.github.com", "GitHub's API endpoint, or that of a ghproxy in front of it.")
 )
 
+var (
+	logStreams = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "prow_deck_log_streams",
+		Help: "Logs being streamed to browsers.",
+	})
+	userActions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "prow_deck_user_actions_total",
+		Help: "Jobs rerun or aborted by logged-in users, by action.",
+	}, []string{"action"})
+)
+
+func init() {
+	prometheus.MustRegister(logStreams)
+	prometheus.MustRegister(userActions)
+}
+
 // Matches letters, numbers, hyphens, and underscores.
 var objReg = regexp.MustCompile(`^[\w-]+$`)
 
@@ -87,47 +116,175 @@ func main() {
 	}
 	ja.Start()
 
+	pa := &PoolAgent{url: *spliceURL}
+	if *spliceURL != "" {
+		pa.Start()
+	}
+
+	var aa *authAgent
+	if *oauthConfigFile != "" {
+		aa, err = newAuthAgent(*oauthConfigFile, *githubTokenFile, *githubEndpoint, configAgent)
+		if err != nil {
+			logrus.WithError(err).Fatal("Error setting up GitHub login.")
+		}
+		http.HandleFunc("/github-login", aa.handleLogin)
+		http.HandleFunc("/github-login/redirect", aa.handleRedirect)
+		http.HandleFunc("/logout", aa.handleLogout)
+	}
+
 	http.Handle("/", gziphandler.GzipHandler(http.FileServer(http.Dir("/static"))))
 	http.Handle("/data.js", gziphandler.GzipHandler(handleData(ja)))
-	http.Handle("/log", gziphandler.GzipHandler(handleLog(ja)))
-	http.Handle("/rerun", gziphandler.GzipHandler(handleRerun(kc)))
+	http.Handle("/pr-data.js", gziphandler.GzipHandler(handlePullHistory(ja)))
+	http.Handle("/job-data.js", gziphandler.GzipHandler(handleJobHistory(ja)))
+	http.Handle("/tide-data.js", gziphandler.GzipHandler(handleTide(pa, ja)))
+	http.Handle("/log", gziphandler.GzipHandler(handleLog(ja, *maxLogStreams)))
+	http.Handle("/user", gziphandler.GzipHandler(handleUser(aa)))
+	http.Handle("/rerun", gziphandler.GzipHandler(handleRerun(kc, aa)))
+	http.Handle("/abort", gziphandler.GzipHandler(handleAbort(kc, aa)))
+	http.Handle("/metrics", promhttp.Handler())
 
 	logrus.WithError(http.ListenAndServe(":8080", nil)).Fatal("ListenAndServe returned.")
 }
 
-func handleData(ja *JobAgent) http.HandlerFunc {
+type jobLister interface {
+	FilteredJobs(JobFilter) []Job
+	PullHistory(repo string, number int) PullHistory
+	JobHistory(job string) JobHistory
+}
+
+// handleData serves the jobs that match the query's type, repo, job, state,
+// author and pull parameters, and that started between its since and until
+// parameters, which are RFC 3339 times. Missing parameters match every job.
+func handleData(ja jobLister) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Cache-Control", "no-cache")
-		jobs := ja.Jobs()
-		jd, err := json.Marshal(jobs)
+		f, err := filterFromQuery(r.URL.Query())
 		if err != nil {
-			logrus.WithError(err).Error("Error marshaling jobs.")
-			jd = []byte("[]")
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
-		// If we have a "var" query, then write out "var value = {...};".
-		// Otherwise, just write out the JSON.
-		if v := r.URL.Query().Get("var"); v != "" {
-			fmt.Fprintf(w, "var %s = %s;", v, string(jd))
-		} else {
-			fmt.Fprintf(w, string(jd))
+		writeData(w, r, ja.FilteredJobs(f), "[]")
+	}
+}
+
+func filterFromQuery(q url.Values) (JobFilter, error) {
+	f := JobFilter{
+		Type:   q.Get("type"),
+		Repo:   q.Get("repo"),
+		Job:    q.Get("job"),
+		State:  q.Get("state"),
+		Author: q.Get("author"),
+	}
+	if pull := q.Get("pull"); pull != "" {
+		n, err := strconv.Atoi(pull)
+		if err != nil {
+			return f, fmt.Errorf("invalid pull query: %v", err)
+		}
+		f.Number = n
+	}
+	for _, t := range []struct {
+		param string
+		dest  *time.Time
+	}{{"since", &f.Since}, {"until", &f.Until}} {
+		if v := q.Get(t.param); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, fmt.Errorf("invalid %s query: %v", t.param, err)
+			}
+			*t.dest = parsed
+		}
+	}
+	return f, nil
+}
+
+// handlePullHistory serves every run for the PR in the repo and pull query.
+func handlePullHistory(ja jobLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		repo := r.URL.Query().Get("repo")
+		number, err := strconv.Atoi(r.URL.Query().Get("pull"))
+		if repo == "" || err != nil {
+			http.Error(w, "Missing repo and pull query", http.StatusBadRequest)
+			return
+		}
+		writeData(w, r, ja.PullHistory(repo, number), "{}")
+	}
+}
+
+// handleJobHistory serves the recent runs of the job in the job query.
+func handleJobHistory(ja jobLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		job := r.URL.Query().Get("job")
+		if !objReg.MatchString(job) {
+			http.Error(w, "Invalid job query", http.StatusBadRequest)
+			return
 		}
+		writeData(w, r, ja.JobHistory(job), "{}")
+	}
+}
+
+// writeData writes data as JSON, or as "var value = {...};" if the request
+// has a "var" query so that pages can load it with a script tag. If data
+// can't be marshalled, it writes empty instead.
+func writeData(w http.ResponseWriter, r *http.Request, data interface{}, empty string) {
+	jd, err := json.Marshal(data)
+	if err != nil {
+		logrus.WithError(err).Error("Error marshaling data.")
+		jd = []byte(empty)
+	}
+	if v := r.URL.Query().Get("var"); v != "" {
+		fmt.Fprintf(w, "var %s = %s;", v, string(jd))
+	} else {
+		w.Write(jd)
 	}
 }
 
 type logClient interface {
 	GetLog(name string) ([]byte, error)
 	GetJobLog(job, id string) ([]byte, error)
+	StreamJobLog(job, id string, offset int64) (io.ReadCloser, error)
 }
 
+// handleLog serves build logs. With follow=true, it streams a run's log as it
+// is written, starting offset bytes in so that clients can resume. At most
+// maxStreams logs are streamed at once.
 // TODO(spxtr): Cache, rate limit.
-func handleLog(lc logClient) http.HandlerFunc {
+func handleLog(lc logClient, maxStreams int) http.HandlerFunc {
+	streams := make(chan struct{}, maxStreams)
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Cache-Control", "no-cache")
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		pod := r.URL.Query().Get("pod")
 		job := r.URL.Query().Get("job")
 		id := r.URL.Query().Get("id")
-		if pod != "" {
+		if r.URL.Query().Get("follow") == "true" {
+			if !objReg.MatchString(job) || !objReg.MatchString(id) {
+				http.Error(w, "Invalid job and ID query", http.StatusBadRequest)
+				return
+			}
+			var offset int64
+			if o := r.URL.Query().Get("offset"); o != "" {
+				var err error
+				if offset, err = strconv.ParseInt(o, 10, 64); err != nil || offset < 0 {
+					http.Error(w, "Invalid offset query", http.StatusBadRequest)
+					return
+				}
+			}
+			select {
+			case streams <- struct{}{}:
+				logStreams.Inc()
+				defer func() {
+					logStreams.Dec()
+					<-streams
+				}()
+			default:
+				w.Header().Set("Retry-After", "30")
+				http.Error(w, "Too many logs are being streamed, try again later", http.StatusServiceUnavailable)
+				return
+			}
+			streamLog(w, r, lc, job, id, offset)
+		} else if pod != "" {
 			// TODO(#3402): Remove this branch.
 			if !objReg.MatchString(pod) {
 				http.Error(w, "Invalid pod query", http.StatusBadRequest)
@@ -167,24 +324,125 @@ func handleLog(lc logClient) http.HandlerFunc {
 	}
 }
 
+// streamLog copies the log to the response as it arrives, flushing after
+// every read so that the browser sees each line as soon as we do.
+func streamLog(w http.ResponseWriter, r *http.Request, lc logClient, job, id string, offset int64) {
+	stream, err := lc.StreamJobLog(job, id, offset)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Log not found: %v", err), http.StatusNotFound)
+		logrus.WithError(err).Warning("Error returned.")
+		return
+	}
+	defer stream.Close()
+	// Stop waiting on the log once the client goes away.
+	ctx := r.Context()
+	go func() {
+		<-ctx.Done()
+		stream.Close()
+	}()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := stream.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err == io.EOF {
+			return
+		} else if err != nil {
+			if ctx.Err() == nil {
+				logrus.WithError(err).Warning("Error streaming log.")
+			}
+			return
+		}
+	}
+}
+
 type pjClient interface {
 	GetProwJob(string) (kube.ProwJob, error)
+	CreateProwJob(kube.ProwJob) (kube.ProwJob, error)
+	ReplaceProwJob(string, kube.ProwJob) (kube.ProwJob, error)
 }
 
-func handleRerun(kc pjClient) http.HandlerFunc {
+// Annotations that record who acted on a ProwJob from deck.
+const (
+	triggeredByAnnotation = "prow.k8s.io/triggered-by"
+	abortedByAnnotation   = "prow.k8s.io/aborted-by"
+)
+
+// handleRerun serves the ProwJob that would rerun the one in the prowjob
+// query. A POST creates it as the logged-in user, who must be allowed to
+// rerun the job.
+func handleRerun(kc pjClient, aa *authAgent) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		name := r.URL.Query().Get("prowjob")
-		if !objReg.MatchString(name) {
-			http.Error(w, "Invalid ProwJob query", http.StatusBadRequest)
+		pj, login, ok := authorizedProwJob(w, r, kc, aa)
+		if !ok {
 			return
 		}
-		pj, err := kc.GetProwJob(name)
+		npj := plank.NewProwJob(pj.Spec)
+		if r.Method == http.MethodPost {
+			npj.Metadata.Annotations = map[string]string{triggeredByAnnotation: login}
+			var err error
+			if npj, err = kc.CreateProwJob(npj); err != nil {
+				http.Error(w, fmt.Sprintf("Error creating ProwJob: %v", err), http.StatusInternalServerError)
+				logrus.WithError(err).Error("Error creating ProwJob.")
+				return
+			}
+			logrus.WithFields(logrus.Fields{"job": pj.Spec.Job, "prowjob": npj.Metadata.Name, "user": login}).Info("Rerun job.")
+			userActions.WithLabelValues("rerun").Inc()
+		}
+		b, err := yaml.Marshal(&npj)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("ProwJob not found: %v", err), http.StatusNotFound)
-			logrus.WithError(err).Warning("Error returned.")
+			http.Error(w, fmt.Sprintf("Error marshaling: %v", err), http.StatusInternalServerError)
+			logrus.WithError(err).Error("Error marshaling jobs.")
 			return
 		}
-		npj := plank.NewProwJob(pj.Spec)
+		if _, err := w.Write(b); err != nil {
+			logrus.WithError(err).Error("Error writing log.")
+		}
+	}
+}
+
+// handleAbort aborts the unfinished ProwJob in the prowjob query as the
+// logged-in user, who must be allowed to rerun the job. Like plank does for
+// superseded presubmits, it only marks the ProwJob as aborted.
+func handleAbort(kc pjClient, aa *authAgent) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Abort needs a POST", http.StatusMethodNotAllowed)
+			return
+		}
+		pj, login, ok := authorizedProwJob(w, r, kc, aa)
+		if !ok {
+			return
+		}
+		if pj.Complete() {
+			http.Error(w, "ProwJob is already complete", http.StatusConflict)
+			return
+		}
+		pj.Status.CompletionTime = time.Now()
+		pj.Status.State = kube.AbortedState
+		pj.Status.Description = fmt.Sprintf("Aborted by %s.", login)
+		if pj.Metadata.Annotations == nil {
+			pj.Metadata.Annotations = map[string]string{}
+		}
+		pj.Metadata.Annotations[abortedByAnnotation] = login
+		npj, err := kc.ReplaceProwJob(pj.Metadata.Name, pj)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error aborting ProwJob: %v", err), http.StatusInternalServerError)
+			logrus.WithError(err).Error("Error replacing ProwJob.")
+			return
+		}
+		logrus.WithFields(logrus.Fields{"job": pj.Spec.Job, "prowjob": pj.Metadata.Name, "user": login}).Info("Aborted job.")
+		userActions.WithLabelValues("abort").Inc()
 		b, err := yaml.Marshal(&npj)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Error marshaling: %v", err), http.StatusInternalServerError)
@@ -192,7 +450,47 @@ func handleRerun(kc pjClient) http.HandlerFunc {
 			return
 		}
 		if _, err := w.Write(b); err != nil {
-			logrus.WithError(err).Error("Error writing log.")
+			logrus.WithError(err).Error("Error writing ProwJob.")
 		}
 	}
 }
+
+// authorizedProwJob gets the ProwJob in the prowjob query. For a POST, it
+// also returns the logged-in user, after checking that they may act on the
+// ProwJob. If it returns false, it has already written an error.
+func authorizedProwJob(w http.ResponseWriter, r *http.Request, kc pjClient, aa *authAgent) (kube.ProwJob, string, bool) {
+	var login string
+	if r.Method == http.MethodPost {
+		var err error
+		if login, err = aa.authenticate(r); err != nil {
+			http.Error(w, fmt.Sprintf("Unauthorized: %v", err), http.StatusUnauthorized)
+			return kube.ProwJob{}, "", false
+		}
+	} else if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return kube.ProwJob{}, "", false
+	}
+	name := r.URL.Query().Get("prowjob")
+	if !objReg.MatchString(name) {
+		http.Error(w, "Invalid ProwJob query", http.StatusBadRequest)
+		return kube.ProwJob{}, "", false
+	}
+	pj, err := kc.GetProwJob(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ProwJob not found: %v", err), http.StatusNotFound)
+		logrus.WithError(err).Warning("Error returned.")
+		return kube.ProwJob{}, "", false
+	}
+	if login == "" {
+		return pj, "", true
+	}
+	if allowed, err := aa.authorize(login, pj); err != nil {
+		http.Error(w, "Error checking permissions", http.StatusInternalServerError)
+		logrus.WithError(err).Error("Error checking permissions.")
+		return kube.ProwJob{}, "", false
+	} else if !allowed {
+		http.Error(w, fmt.Sprintf("%s may not act on %s", login, pj.Spec.Job), http.StatusForbidden)
+		return kube.ProwJob{}, "", false
+	}
+	return pj, login, true
+}
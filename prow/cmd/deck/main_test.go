@@ -17,12 +17,18 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ghodss/yaml"
 
@@ -45,11 +51,21 @@ func (f flc) GetJobLog(job, id string) ([]byte, error) {
 	return nil, errors.New("muahaha")
 }
 
+func (f flc) StreamJobLog(job, id string, offset int64) (io.ReadCloser, error) {
+	log, err := f.GetJobLog(job, id)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(log[offset:])), nil
+}
+
 func TestHandleLog(t *testing.T) {
 	var testcases = []struct {
 		name string
 		path string
 		code int
+		// body defaults to "hello".
+		body string
 	}{
 		{
 			name: "no pod name",
@@ -101,8 +117,34 @@ func TestHandleLog(t *testing.T) {
 			path: "?job=ohno&id=123",
 			code: http.StatusNotFound,
 		},
+		{
+			name: "follow",
+			path: "?job=job&id=123&follow=true",
+			code: http.StatusOK,
+		},
+		{
+			name: "follow from an offset",
+			path: "?job=job&id=123&follow=true&offset=2",
+			code: http.StatusOK,
+			body: "llo",
+		},
+		{
+			name: "follow with a bad offset",
+			path: "?job=job&id=123&follow=true&offset=-1",
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "follow a pod",
+			path: "?pod=pn&follow=true",
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "follow, not found",
+			path: "?job=ohno&id=123&follow=true",
+			code: http.StatusNotFound,
+		},
 	}
-	handler := handleLog(flc(0))
+	handler := handleLog(flc(0), 1)
 	for _, tc := range testcases {
 		req, err := http.NewRequest(http.MethodGet, "", nil)
 		if err != nil {
@@ -115,6 +157,10 @@ func TestHandleLog(t *testing.T) {
 		req.URL = u
 		rr := httptest.NewRecorder()
 		handler.ServeHTTP(rr, req)
+		expected := tc.body
+		if expected == "" {
+			expected = "hello"
+		}
 		if rr.Code != tc.code {
 			t.Errorf("Wrong error code. Got %v, want %v", rr.Code, tc.code)
 		} else if rr.Code == http.StatusOK {
@@ -122,31 +168,120 @@ func TestHandleLog(t *testing.T) {
 			defer resp.Body.Close()
 			if body, err := ioutil.ReadAll(resp.Body); err != nil {
 				t.Errorf("Error reading response body: %v", err)
-			} else if string(body) != "hello" {
+			} else if string(body) != expected {
 				t.Errorf("Unexpected body: got %s.", string(body))
 			}
 		}
 	}
 }
 
-type fpjc kube.ProwJob
+// blockingLogClient streams logs that never end until closed.
+type blockingLogClient struct {
+	flc
+	started chan struct{}
+}
+
+func (b blockingLogClient) StreamJobLog(job, id string, offset int64) (io.ReadCloser, error) {
+	pr, _ := io.Pipe()
+	b.started <- struct{}{}
+	return pr, nil
+}
+
+// TestHandleLogStreamLimit checks that only one log is streamed at once, and
+// that the slot frees up when the client goes away.
+func TestHandleLogStreamLimit(t *testing.T) {
+	lc := blockingLogClient{started: make(chan struct{}, 1)}
+	s := httptest.NewServer(handleLog(lc, 1))
+	defer s.Close()
+	path := s.URL + "?job=job&id=123&follow=true"
+
+	// follow starts streaming and returns a func that hangs up and returns
+	// the response code.
+	follow := func() func() int {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		code := make(chan int, 1)
+		go func() {
+			resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+			if err != nil {
+				code <- 0
+				return
+			}
+			code <- resp.StatusCode
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+		}()
+		return func() int {
+			cancel()
+			return <-code
+		}
+	}
+
+	hangUp := follow()
+	<-lc.started
+	resp, err := http.Get(path)
+	if err != nil {
+		t.Fatalf("Error making second request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected %d for the second stream, got %d", http.StatusServiceUnavailable, resp.StatusCode)
+	}
+	hangUp()
+
+	// The handler notices the hang up asynchronously.
+	for i := 0; ; i++ {
+		hangUp = follow()
+		select {
+		case <-lc.started:
+			hangUp()
+			return
+		case <-time.After(time.Second):
+		}
+		if code := hangUp(); code != http.StatusServiceUnavailable {
+			t.Fatalf("Expected %d while the first stream ends, got %d", http.StatusServiceUnavailable, code)
+		}
+		if i == 10 {
+			t.Fatal("Stream slot was never freed.")
+		}
+	}
+}
+
+type fpjc struct {
+	pj       kube.ProwJob
+	created  []kube.ProwJob
+	replaced []kube.ProwJob
+}
 
 func (fc *fpjc) GetProwJob(name string) (kube.ProwJob, error) {
-	return kube.ProwJob(*fc), nil
+	return fc.pj, nil
+}
+
+func (fc *fpjc) CreateProwJob(pj kube.ProwJob) (kube.ProwJob, error) {
+	fc.created = append(fc.created, pj)
+	return pj, nil
+}
+
+func (fc *fpjc) ReplaceProwJob(name string, pj kube.ProwJob) (kube.ProwJob, error) {
+	fc.replaced = append(fc.replaced, pj)
+	return pj, nil
 }
 
 // TestRerun just checks that the result can be unmarshaled properly, has an
 // updated status, and has equal spec.
 func TestRerun(t *testing.T) {
-	fc := fpjc(kube.ProwJob{
+	fc := fpjc{pj: kube.ProwJob{
 		Spec: kube.ProwJobSpec{
 			Job: "whoa",
 		},
 		Status: kube.ProwJobStatus{
 			State: kube.PendingState,
 		},
-	})
-	handler := handleRerun(&fc)
+	}}
+	handler := handleRerun(&fc, nil)
 	req, err := http.NewRequest(http.MethodGet, "/rerun?prowjob=wowsuch", nil)
 	if err != nil {
 		t.Fatalf("Error making request: %v", err)
@@ -173,3 +308,217 @@ func TestRerun(t *testing.T) {
 		t.Errorf("Wrong state, expected \"%v\", got \"%v\"", kube.TriggeredState, res.Status.State)
 	}
 }
+
+func TestRerunAndAbortAuth(t *testing.T) {
+	aa := testAuthAgent("")
+	var testcases = []struct {
+		name       string
+		path       string
+		login      string
+		csrf       string
+		complete   bool
+		code       int
+		created    int
+		replaced   int
+		annotation string
+	}{
+		{
+			name: "rerun without session",
+			path: "/rerun?prowjob=wowsuch",
+			code: http.StatusUnauthorized,
+		},
+		{
+			name:  "rerun with bad CSRF token",
+			path:  "/rerun?prowjob=wowsuch",
+			login: "alice",
+			csrf:  "nope",
+			code:  http.StatusUnauthorized,
+		},
+		{
+			name:  "rerun by non-member",
+			path:  "/rerun?prowjob=wowsuch",
+			login: "bob",
+			code:  http.StatusForbidden,
+		},
+		{
+			name:       "rerun by member",
+			path:       "/rerun?prowjob=wowsuch",
+			login:      "alice",
+			code:       http.StatusOK,
+			created:    1,
+			annotation: triggeredByAnnotation,
+		},
+		{
+			name:       "abort by member",
+			path:       "/abort?prowjob=wowsuch",
+			login:      "alice",
+			code:       http.StatusOK,
+			replaced:   1,
+			annotation: abortedByAnnotation,
+		},
+		{
+			name:     "abort complete job",
+			path:     "/abort?prowjob=wowsuch",
+			login:    "alice",
+			complete: true,
+			code:     http.StatusConflict,
+		},
+	}
+	for _, tc := range testcases {
+		fc := fpjc{pj: kube.ProwJob{
+			Metadata: kube.ObjectMeta{Name: "wowsuch"},
+			Spec: kube.ProwJobSpec{
+				Job:  "whoa",
+				Refs: kube.Refs{Org: "org", Repo: "repo"},
+			},
+			Status: kube.ProwJobStatus{State: kube.PendingState},
+		}}
+		if tc.complete {
+			fc.pj.Status.CompletionTime = time.Now()
+		}
+		handler := handleRerun(&fc, aa)
+		if strings.HasPrefix(tc.path, "/abort") {
+			handler = handleAbort(&fc, aa)
+		}
+		req := httptest.NewRequest(http.MethodPost, tc.path, nil)
+		if tc.login != "" {
+			s := aa.newSession(tc.login, time.Now().Add(time.Hour))
+			req.AddCookie(&http.Cookie{Name: sessionCookie, Value: s})
+			csrf := tc.csrf
+			if csrf == "" {
+				csrf = aa.csrfToken(s)
+			}
+			req.Header.Set(csrfHeader, csrf)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != tc.code {
+			t.Errorf("For case %s, expected code %d, got %d: %s", tc.name, tc.code, rr.Code, rr.Body.String())
+		}
+		if len(fc.created) != tc.created || len(fc.replaced) != tc.replaced {
+			t.Errorf("For case %s, expected %d created and %d replaced, got %d and %d", tc.name, tc.created, tc.replaced, len(fc.created), len(fc.replaced))
+			continue
+		}
+		for _, pj := range append(fc.created, fc.replaced...) {
+			if pj.Metadata.Annotations[tc.annotation] != tc.login {
+				t.Errorf("For case %s, expected %s annotation %q, got %v", tc.name, tc.annotation, tc.login, pj.Metadata.Annotations)
+			}
+		}
+		for _, pj := range fc.replaced {
+			if pj.Status.State != kube.AbortedState || !pj.Complete() {
+				t.Errorf("For case %s, expected an aborted job, got %+v", tc.name, pj.Status)
+			}
+		}
+	}
+}
+
+func TestHandleData(t *testing.T) {
+	var testcases = []struct {
+		name  string
+		path  string
+		code  int
+		count int
+	}{
+		{
+			name:  "no filter",
+			path:  "/data.js",
+			code:  http.StatusOK,
+			count: 6,
+		},
+		{
+			name:  "repo and pull",
+			path:  "/data.js?repo=kubernetes/test-infra&pull=2",
+			code:  http.StatusOK,
+			count: 2,
+		},
+		{
+			name:  "since",
+			path:  "/data.js?since=2017-08-01T04:00:00Z",
+			code:  http.StatusOK,
+			count: 2,
+		},
+		{
+			name: "bad pull",
+			path: "/data.js?pull=abc",
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "bad until",
+			path: "/data.js?until=yesterday",
+			code: http.StatusBadRequest,
+		},
+	}
+	handler := handleData(historyAgent(t))
+	for _, tc := range testcases {
+		req, err := http.NewRequest(http.MethodGet, tc.path, nil)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != tc.code {
+			t.Errorf("For case %s, expected code %d, got %d", tc.name, tc.code, rr.Code)
+			continue
+		}
+		if rr.Code != http.StatusOK {
+			continue
+		}
+		var jobs []Job
+		if err := json.Unmarshal(rr.Body.Bytes(), &jobs); err != nil {
+			t.Errorf("For case %s, error unmarshaling jobs: %v", tc.name, err)
+		} else if len(jobs) != tc.count {
+			t.Errorf("For case %s, expected %d jobs, got %d", tc.name, tc.count, len(jobs))
+		}
+	}
+}
+
+func TestHandleHistory(t *testing.T) {
+	ja := historyAgent(t)
+	var testcases = []struct {
+		name    string
+		handler http.HandlerFunc
+		path    string
+		code    int
+		body    string
+	}{
+		{
+			name:    "PR",
+			handler: handlePullHistory(ja),
+			path:    "/pr-data.js?repo=kubernetes/test-infra&pull=2&var=pr",
+			code:    http.StatusOK,
+			body:    "var pr = ",
+		},
+		{
+			name:    "PR without number",
+			handler: handlePullHistory(ja),
+			path:    "/pr-data.js?repo=kubernetes/test-infra",
+			code:    http.StatusBadRequest,
+		},
+		{
+			name:    "job",
+			handler: handleJobHistory(ja),
+			path:    "/job-data.js?job=unit",
+			code:    http.StatusOK,
+			body:    `{"job":"unit"`,
+		},
+		{
+			name:    "bad job",
+			handler: handleJobHistory(ja),
+			path:    "/job-data.js?job=" + url.QueryEscape("a/b"),
+			code:    http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testcases {
+		req, err := http.NewRequest(http.MethodGet, tc.path, nil)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		rr := httptest.NewRecorder()
+		tc.handler.ServeHTTP(rr, req)
+		if rr.Code != tc.code {
+			t.Errorf("For case %s, expected code %d, got %d", tc.name, tc.code, rr.Code)
+		} else if !strings.HasPrefix(rr.Body.String(), tc.body) {
+			t.Errorf("For case %s, expected body to start with %q, got %q", tc.name, tc.body, rr.Body.String())
+		}
+	}
+}
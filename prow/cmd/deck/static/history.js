@@ -0,0 +1,107 @@
+"use strict";
+
+// The history pages use the cell helpers from script.js. Their body onload
+// replaces the one script.js sets for the main page.
+
+function loadHistory(path, render) {
+    var summary = document.getElementById("summary");
+    var req = new XMLHttpRequest();
+    req.onreadystatechange = function() {
+        if (req.readyState !== XMLHttpRequest.DONE) {
+            return;
+        }
+        if (req.status === 200) {
+            render(JSON.parse(req.responseText));
+        } else {
+            summary.textContent = req.responseText;
+        }
+    };
+    req.open("GET", path + window.location.search, true);
+    req.send();
+}
+
+function jobCell(build) {
+    if (build.url === "") {
+        return createTextCell(build.job);
+    }
+    return createLinkCell(build.job, build.url, "");
+}
+
+function loadPullHistory() {
+    loadHistory("pr-data.js", function(pr) {
+        var summary = document.getElementById("summary");
+        summary.appendChild(createLinkCell(pr.repo + "#" + pr.number,
+            "https://github.com/" + pr.repo + "/pull/" + pr.number, ""));
+        if (pr.author) {
+            summary.appendChild(document.createTextNode(" by " + pr.author));
+        }
+        var runs = document.getElementById("runs").getElementsByTagName("tbody")[0];
+        for (var i = 0; i < pr.commits.length; i++) {
+            var commit = pr.commits[i];
+            for (var j = 0; j < commit.runs.length; j++) {
+                var build = commit.runs[j];
+                var r = document.createElement("tr");
+                r.appendChild(stateCell(build.state));
+                r.appendChild(logCell(build));
+                if (j === 0) {
+                    r.className = "changed";
+                    r.appendChild(createLinkCell(commit.sha.slice(0, 7),
+                        "https://github.com/" + pr.repo + "/pull/" + pr.number + "/commits/" + commit.sha, ""));
+                } else {
+                    r.appendChild(createTextCell(""));
+                }
+                r.appendChild(jobCell(build));
+                r.appendChild(createTextCell(build.type));
+                r.appendChild(createTextCell(build.started));
+                r.appendChild(createTextCell(build.duration));
+                runs.appendChild(r);
+            }
+        }
+    });
+}
+
+function loadJobHistory() {
+    loadHistory("job-data.js", function(job) {
+        var summary = document.getElementById("summary");
+        var text = job.job + ": " + job.passed + " passed, " + job.failed + " failed";
+        if (job.average_duration) {
+            text += ", " + job.average_duration + " on average, " + job.max_duration + " at most";
+        }
+        summary.textContent = text + ".";
+
+        // Newest runs are on the left, like the table's top.
+        var strip = document.getElementById("strip");
+        for (var i = 0; i < job.runs.length; i++) {
+            var s = document.createElement("span");
+            s.className = job.runs[i].state;
+            s.title = job.runs[i].started + " " + job.runs[i].state;
+            s.appendChild(document.createTextNode(stateSymbol(job.runs[i].state)));
+            strip.appendChild(s);
+        }
+
+        var runs = document.getElementById("runs").getElementsByTagName("tbody")[0];
+        for (var i = 0; i < job.runs.length; i++) {
+            var build = job.runs[i];
+            var r = document.createElement("tr");
+            r.appendChild(stateCell(build.state));
+            r.appendChild(logCell(build));
+            if (build.type === "periodic") {
+                r.appendChild(createTextCell(""));
+            } else {
+                r.appendChild(createLinkCell(build.repo, "https://github.com/" + build.repo, ""));
+            }
+            if (build.type === "presubmit") {
+                r.appendChild(prRevisionCell(build));
+            } else if (build.type === "batch") {
+                r.appendChild(batchRevisionCell(build));
+            } else if (build.type === "postsubmit") {
+                r.appendChild(pushRevisionCell(build));
+            } else {
+                r.appendChild(createTextCell(""));
+            }
+            r.appendChild(createTextCell(build.started));
+            r.appendChild(createTextCell(build.duration));
+            runs.appendChild(r);
+        }
+    });
+}
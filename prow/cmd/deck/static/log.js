@@ -0,0 +1,45 @@
+"use strict";
+
+// Bytes of the log received so far, so that we can pick up where we left off
+// if the connection drops.
+var offset = 0;
+
+function setStatus(text) {
+    document.getElementById("status").textContent = text;
+}
+
+function followLog() {
+    var log = document.getElementById("log");
+    var decoder = new TextDecoder();
+    setStatus("Following " + window.location.search.slice(1) + "...");
+    fetch("log" + window.location.search + "&follow=true&offset=" + offset).then(function(resp) {
+        if (!resp.ok) {
+            return resp.text().then(function(text) {
+                setStatus(text);
+                if (resp.status === 503) {
+                    setTimeout(followLog, 30000);
+                }
+            });
+        }
+        var reader = resp.body.getReader();
+        var read = function() {
+            return reader.read().then(function(result) {
+                if (result.done) {
+                    setStatus("Done.");
+                    return;
+                }
+                offset += result.value.length;
+                var atBottom = window.innerHeight + window.scrollY >= document.body.offsetHeight;
+                log.appendChild(document.createTextNode(decoder.decode(result.value, {stream: true})));
+                if (atBottom) {
+                    window.scrollTo(0, document.body.scrollHeight);
+                }
+                return read();
+            });
+        };
+        return read();
+    }).catch(function(err) {
+        setStatus("Lost the log (" + err + "), reconnecting...");
+        setTimeout(followLog, 5000);
+    });
+}
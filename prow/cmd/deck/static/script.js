@@ -6,6 +6,8 @@ var jobs = {};
 var authors = {};
 var pulls = {};
 var states = {};
+// user is the session from /user, once it has loaded.
+var user;
 
 function getParameterByName(name) {  // http://stackoverflow.com/a/5158301/3694
     var match = RegExp('[?&]' + name + '=([^&/]*)').exec(window.location.search);
@@ -51,9 +53,53 @@ window.onload = function() {
     var ss = Array.from(Object.keys(states)).sort();
     addOptions(ss, "state");
 
+    loadUser();
     redraw();
 };
 
+// loadUser fetches the session and then shows who is logged in.
+function loadUser() {
+    var req = new XMLHttpRequest();
+    req.open("GET", "/user");
+    req.onload = function() {
+        if (req.status === 200) {
+            user = JSON.parse(req.responseText);
+            drawUser();
+        }
+    };
+    req.send();
+}
+
+// loggedIn returns whether /user found a session, so that we can offer to
+// rerun and abort jobs.
+function loggedIn() {
+    return typeof user !== "undefined" && user.login;
+}
+
+function drawUser() {
+    var u = document.getElementById("user");
+    if (typeof user === "undefined" || !user.login_enabled) {
+        return;
+    }
+    if (user.login) {
+        var f = document.createElement("form");
+        f.method = "post";
+        f.action = "/logout";
+        var b = document.createElement("button");
+        b.type = "submit";
+        b.appendChild(document.createTextNode("log out"));
+        f.appendChild(document.createTextNode(user.login + " "));
+        f.appendChild(b);
+        u.appendChild(f);
+        return;
+    }
+    var a = document.createElement("a");
+    a.href = "/github-login";
+    a.title = "Log in to rerun and abort jobs.";
+    a.appendChild(document.createTextNode("log in with GitHub"));
+    u.appendChild(a);
+}
+
 function addOptions(s, p) {
     var sel = document.getElementById(p);
     var param = getParameterByName(p);
@@ -84,7 +130,6 @@ function groupKey(build) {
 
 function redraw() {
     var modal = document.getElementById('rerun');
-    var rerun_command = document.getElementById('rerun-content');
     window.onclick = function(event) {
         if (event.target == modal) {
             modal.style.display = "none";
@@ -138,12 +183,8 @@ function redraw() {
 
         var r = document.createElement("tr");
         r.appendChild(stateCell(build.state));
-        if (build.pod_name) {
-            r.appendChild(createLinkCell("\u2261", "log?job=" + build.job + "&id=" + build.build_id, "Build log."));
-        } else {
-            r.appendChild(createTextCell(""));
-        }
-        r.appendChild(createRerunCell(modal, rerun_command, build.prow_job));
+        r.appendChild(logCell(build));
+        r.appendChild(createRerunCell(modal, build));
         var key = groupKey(build);
         if (key !== lastKey) {
             // This is a different PR or commit than the previous row.
@@ -169,17 +210,31 @@ function redraw() {
             r.appendChild(createTextCell(""));
             r.appendChild(createTextCell(""));
         }
+        var jc;
         if (build.url === "") {
-            r.appendChild(createTextCell(build.job));
+            jc = createTextCell(build.job);
         } else {
-            r.appendChild(createLinkCell(build.job, build.url, ""));
+            jc = createLinkCell(build.job, build.url, "");
         }
+        jc.appendChild(historyLink("job.html?job=" + encodeURIComponent(build.job), "Recent runs of this job."));
+        r.appendChild(jc);
         r.appendChild(createTextCell(build.started));
         r.appendChild(createTextCell(build.duration));
         builds.appendChild(r);
     }
 }
 
+function logCell(build) {
+    if (!build.pod_name) {
+        return createTextCell("");
+    }
+    var query = "?job=" + build.job + "&id=" + build.build_id;
+    if (build.state === "pending") {
+        return createLinkCell("\u2261", "log.html" + query, "Follow the build log.");
+    }
+    return createLinkCell("\u2261", "log" + query, "Build log.");
+}
+
 function createTextCell(text) {
     var c = document.createElement("td");
     c.appendChild(document.createTextNode(text));
@@ -198,31 +253,73 @@ function createLinkCell(text, url, title) {
     return c;
 }
 
-function createRerunCell(modal, rerun_command, prowjob) {
-    var url = "https://" + window.location.hostname + "/rerun?prowjob=" + prowjob;
+function createRerunCell(modal, build) {
+    var url = "https://" + window.location.hostname + "/rerun?prowjob=" + build.prow_job;
     var c = document.createElement("td");
     var a = document.createElement("a");
     a.href = "#";
     a.title = "Show instructions for rerunning this job.";
     a.onclick = function() {
         modal.style.display = "block";
-        rerun_command.textContent = "kubectl create -f \"" + url + "\"";
+        document.getElementById("rerun-command").textContent = "kubectl create -f \"" + url + "\"";
+        document.getElementById("rerun-status").textContent = "";
+        var actions = document.getElementById("rerun-actions");
+        while (actions.firstChild)
+            actions.removeChild(actions.firstChild);
+        if (!loggedIn()) {
+            return;
+        }
+        actions.appendChild(actionButton("Rerun", "/rerun", build.prow_job));
+        if (build.state === "triggered" || build.state === "pending") {
+            actions.appendChild(actionButton("Abort", "/abort", build.prow_job));
+        }
     };
     a.appendChild(document.createTextNode("\u27F3"));
     c.appendChild(a);
     return c;
 }
 
-function stateCell(state) {
-    var c = document.createElement("td");
-    c.className = state;
+// actionButton POSTs to path as the logged-in user when clicked, and shows
+// deck's answer in the rerun dialog.
+function actionButton(text, path, prowjob) {
+    var b = document.createElement("button");
+    b.appendChild(document.createTextNode(text));
+    b.onclick = function() {
+        var status = document.getElementById("rerun-status");
+        var req = new XMLHttpRequest();
+        req.open("POST", path + "?prowjob=" + prowjob);
+        req.setRequestHeader("X-CSRF-Token", user.csrf_token);
+        req.onload = function() {
+            if (req.status === 200) {
+                status.textContent = text + " done.";
+            } else {
+                status.textContent = text + " failed: " + req.responseText;
+            }
+        };
+        req.onerror = function() {
+            status.textContent = text + " failed.";
+        };
+        req.send();
+        status.textContent = text + "…";
+    };
+    return b;
+}
+
+function stateSymbol(state) {
     if (state === "triggered" || state === "pending") {
-        c.appendChild(document.createTextNode("\u2022"));
+        return "\u2022";
     } else if (state === "success") {
-        c.appendChild(document.createTextNode("\u2713"));
+        return "\u2713";
     } else if (state === "failure" || state === "error" || state === "aborted") {
-        c.appendChild(document.createTextNode("\u2717"));
+        return "\u2717";
     }
+    return "";
+}
+
+function stateCell(state) {
+    var c = document.createElement("td");
+    c.className = state;
+    c.appendChild(document.createTextNode(stateSymbol(state)));
     return c;
 }
 
@@ -267,5 +364,16 @@ function prRevisionCell(build) {
     al.href = "https://github.com/" + build.author;
     al.text = build.author;
     c.appendChild(al);
+    c.appendChild(historyLink("pr.html?repo=" + encodeURIComponent(build.repo) + "&pull=" + build.number,
+        "Every run for this PR."));
     return c;
 }
+
+function historyLink(url, title) {
+    var a = document.createElement("a");
+    a.href = url;
+    a.title = title;
+    a.className = "history";
+    a.appendChild(document.createTextNode("\u231A"));
+    return a;
+}
@@ -0,0 +1,121 @@
+"use strict";
+
+// The merge queue page uses the cell helpers from script.js. Its body onload
+// replaces the one script.js sets for the main page.
+
+var poolStates = {
+    "idle": "Nothing to batch.",
+    "testing": "Testing the batch.",
+    "cooling_down": "Giving the submit queue time to merge the batch."
+};
+
+function loadPools() {
+    var article = document.getElementById("pools");
+    var req = new XMLHttpRequest();
+    req.onreadystatechange = function() {
+        if (req.readyState !== XMLHttpRequest.DONE) {
+            return;
+        }
+        if (req.status !== 200) {
+            article.textContent = req.responseText;
+            return;
+        }
+        var pools = JSON.parse(req.responseText);
+        if (pools.length === 0) {
+            article.textContent = "No merge pools.";
+        }
+        for (var i = 0; i < pools.length; i++) {
+            article.appendChild(poolSection(pools[i]));
+        }
+    };
+    req.open("GET", "tide-data.js", true);
+    req.send();
+}
+
+function poolSection(pool) {
+    var repo = pool.org + "/" + pool.repo;
+    var section = document.createElement("div");
+    var h = document.createElement("h2");
+    h.appendChild(document.createTextNode(repo));
+    section.appendChild(h);
+
+    var text = poolStates[pool.state] || pool.state;
+    text += " Updated " + new Date(pool.updated).toLocaleString() + ".";
+    if (pool.error) {
+        text += " " + pool.error;
+    }
+    var p = document.createElement("p");
+    p.appendChild(document.createTextNode(text));
+    section.appendChild(p);
+
+    if (pool.batch.pulls && pool.batch.pulls.length > 0) {
+        var bp = document.createElement("p");
+        bp.appendChild(document.createTextNode("Batch on " + pool.batch.base_ref + " at " + pool.batch.base_sha.slice(0, 7) + ": "));
+        for (var i = 0; i < pool.batch.pulls.length; i++) {
+            if (i > 0) bp.appendChild(document.createTextNode(", "));
+            bp.appendChild(pullLink(repo, pool.batch.pulls[i].number));
+        }
+        section.appendChild(bp);
+        section.appendChild(batchJobsTable(pool.batch_jobs));
+    }
+    section.appendChild(pullsTable(repo, pool.prs));
+    return section;
+}
+
+function pullLink(repo, number) {
+    var a = document.createElement("a");
+    a.href = "https://github.com/" + repo + "/pull/" + number;
+    a.appendChild(document.createTextNode("#" + number));
+    return a;
+}
+
+function table(headings) {
+    var t = document.createElement("table");
+    var head = document.createElement("thead");
+    var r = document.createElement("tr");
+    for (var i = 0; i < headings.length; i++) {
+        var th = document.createElement("th");
+        th.appendChild(document.createTextNode(headings[i]));
+        r.appendChild(th);
+    }
+    head.appendChild(r);
+    t.appendChild(head);
+    t.appendChild(document.createElement("tbody"));
+    return t;
+}
+
+function batchJobsTable(jobs) {
+    var t = table(["", "", "Batch job", "Started", "Duration"]);
+    var body = t.getElementsByTagName("tbody")[0];
+    for (var i = 0; i < jobs.length; i++) {
+        var build = jobs[i];
+        var r = document.createElement("tr");
+        r.appendChild(stateCell(build.state));
+        r.appendChild(logCell(build));
+        if (build.url === "") {
+            r.appendChild(createTextCell(build.job));
+        } else {
+            r.appendChild(createLinkCell(build.job, build.url, ""));
+        }
+        r.appendChild(createTextCell(build.started));
+        r.appendChild(createTextCell(build.duration));
+        body.appendChild(r);
+    }
+    return t;
+}
+
+function pullsTable(repo, prs) {
+    var t = table(["Queued PR", "Branch", "Status"]);
+    var body = t.getElementsByTagName("tbody")[0];
+    for (var i = 0; i < prs.length; i++) {
+        var pr = prs[i];
+        var r = document.createElement("tr");
+        var c = document.createElement("td");
+        c.appendChild(pullLink(repo, pr.number));
+        r.appendChild(c);
+        r.appendChild(createTextCell(pr.base_ref || "master"));
+        r.appendChild(createTextCell(pr.in_batch ? "In the batch." : pr.reason));
+        body.appendChild(r);
+    }
+    return t;
+}
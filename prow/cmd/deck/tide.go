@@ -0,0 +1,121 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/kube"
+	"k8s.io/test-infra/prow/splice"
+)
+
+// PoolAgent polls splice for the merge pools it batches PRs from.
+type PoolAgent struct {
+	url    string
+	client *http.Client
+	pools  []splice.Pool
+	mut    sync.Mutex
+}
+
+func (pa *PoolAgent) Start() {
+	pa.client = &http.Client{Timeout: period}
+	pa.tryUpdate()
+	go func() {
+		for range time.Tick(period) {
+			pa.tryUpdate()
+		}
+	}()
+}
+
+// Pools returns the pools that splice last told us about.
+func (pa *PoolAgent) Pools() []splice.Pool {
+	pa.mut.Lock()
+	defer pa.mut.Unlock()
+	res := make([]splice.Pool, len(pa.pools))
+	copy(res, pa.pools)
+	return res
+}
+
+func (pa *PoolAgent) tryUpdate() {
+	if err := pa.update(); err != nil {
+		logrus.WithError(err).Warning("Error updating merge pools.")
+	}
+}
+
+func (pa *PoolAgent) update() error {
+	resp, err := pa.client.Get(pa.url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("response has status %q", resp.Status)
+	}
+	var pools []splice.Pool
+	if err := json.NewDecoder(resp.Body).Decode(&pools); err != nil {
+		return err
+	}
+	pa.mut.Lock()
+	defer pa.mut.Unlock()
+	pa.pools = pools
+	return nil
+}
+
+// MergePool is a splice pool along with the jobs that test its batch.
+type MergePool struct {
+	splice.Pool
+	BatchJobs []Job `json:"batch_jobs"`
+}
+
+type poolLister interface {
+	Pools() []splice.Pool
+}
+
+// mergePools finds the batch jobs of each pool. Batch jobs test the pool's
+// batch if they have the same refs.
+func mergePools(pools []splice.Pool, batchJobs []Job) []MergePool {
+	mps := []MergePool{}
+	for _, pool := range pools {
+		mp := MergePool{Pool: pool, BatchJobs: []Job{}}
+		if len(pool.Batch.Pulls) > 0 {
+			repo := pool.Org + "/" + pool.Repo
+			refs := pool.Batch.String()
+			for _, j := range batchJobs {
+				if j.Repo == repo && j.Refs == refs {
+					mp.BatchJobs = append(mp.BatchJobs, j)
+				}
+			}
+		}
+		mps = append(mps, mp)
+	}
+	return mps
+}
+
+// handleTide serves splice's merge pools with the jobs testing their batches.
+func handleTide(pa poolLister, ja jobLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		batchJobs := ja.FilteredJobs(JobFilter{Type: string(kube.BatchJob)})
+		writeData(w, r, mergePools(pa.Pools(), batchJobs), "[]")
+	}
+}
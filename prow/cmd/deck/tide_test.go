@@ -0,0 +1,86 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"k8s.io/test-infra/prow/kube"
+	"k8s.io/test-infra/prow/splice"
+)
+
+func TestMergePools(t *testing.T) {
+	batch := kube.Refs{
+		Org:     "kubernetes",
+		Repo:    "kubernetes",
+		BaseRef: "master",
+		BaseSHA: "abc",
+		Pulls:   []kube.Pull{{Number: 1, SHA: "qwe"}, {Number: 2, SHA: "rty"}},
+	}
+	old := batch
+	old.BaseSHA = "def"
+	pools := []splice.Pool{
+		{Org: "kubernetes", Repo: "kubernetes", Batch: batch},
+		{Org: "kubernetes", Repo: "test-infra"},
+	}
+	jobs := []Job{
+		{Job: "current", Type: "batch", Repo: "kubernetes/kubernetes", Refs: batch.String()},
+		{Job: "old", Type: "batch", Repo: "kubernetes/kubernetes", Refs: old.String()},
+		{Job: "other-repo", Type: "batch", Repo: "kubernetes/test-infra", Refs: batch.String()},
+	}
+	mps := mergePools(pools, jobs)
+	if len(mps) != 2 {
+		t.Fatalf("Expected 2 pools, got %d", len(mps))
+	}
+	if len(mps[0].BatchJobs) != 1 || mps[0].BatchJobs[0].Job != "current" {
+		t.Errorf("Expected only the current batch job, got %+v", mps[0].BatchJobs)
+	}
+	if len(mps[1].BatchJobs) != 0 {
+		t.Errorf("Expected no batch jobs for a pool without a batch, got %+v", mps[1].BatchJobs)
+	}
+}
+
+func TestHandleTide(t *testing.T) {
+	pool := splice.NewPool("kubernetes", "test-infra", []splice.QueuedPR{{Number: 1}, {Number: 2, BaseRef: "release"}}, []int{1}, 5)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]splice.Pool{pool})
+	}))
+	defer s.Close()
+	pa := &PoolAgent{url: s.URL, client: &http.Client{}}
+	if err := pa.update(); err != nil {
+		t.Fatalf("Error updating pools: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handleTide(pa, historyAgent(t)).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/tide-data.js", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Bad error code: %d", rr.Code)
+	}
+	var mps []MergePool
+	if err := json.Unmarshal(rr.Body.Bytes(), &mps); err != nil {
+		t.Fatalf("Error unmarshaling: %v", err)
+	}
+	if len(mps) != 1 || len(mps[0].PRs) != 2 {
+		t.Fatalf("Expected one pool with two PRs, got %+v", mps)
+	}
+	if mps[0].PRs[1].Reason == "" {
+		t.Errorf("Expected a reason for a PR against another branch, got %+v", mps[0].PRs[1])
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/fakeghserver"
+)
+
+var (
+	port    = flag.Int("port", 8889, "Port to listen on.")
+	hookURL = flag.String("hook-url", "http://localhost:8888/hook", "Where to send webhooks.")
+	hmac    = flag.String("hmac", "abcde12345", "HMAC token to sign webhooks with and to validate events from phony with.")
+	botName = flag.String("github-bot-name", "fake-robot", "User that API calls are made as.")
+	members = flag.String("members", "", "Comma-separated org/login pairs of org members, such as kubernetes/bob.")
+)
+
+func main() {
+	flag.Parse()
+
+	s := fakeghserver.NewServer(*botName, *hookURL, []byte(*hmac))
+	for _, member := range strings.Split(*members, ",") {
+		if member == "" {
+			continue
+		}
+		parts := strings.SplitN(member, "/", 2)
+		if len(parts) != 2 {
+			logrus.Fatalf("Member %q is not of the form org/login.", member)
+		}
+		s.AddMember(parts[0], parts[1])
+	}
+	logrus.Infof("Fake GitHub listening on port %d. Send events to /hook with phony.", *port)
+	logrus.Fatal(http.ListenAndServe(":"+strconv.Itoa(*port), s))
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Ghproxy is a caching proxy for the GitHub API, shared by the components
+// that use the same tokens. Point prow components at it with
+// --github-endpoint=http://ghproxy and mungegithub with url: http://ghproxy/.
+// Repeated GETs become conditional requests, which GitHub answers with a free
+// 304 when nothing changed. Cache statistics are served at /metrics.
+package main
+
+import (
+	"flag"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"k8s.io/test-infra/prow/ghcache"
+)
+
+var (
+	port      = flag.Int("port", 8888, "Port to listen on.")
+	upstream  = flag.String("upstream", "https://api.github.com", "Scheme, host, and base path of the GitHub API.")
+	cacheDir  = flag.String("cache-dir", "", "Directory to cache responses in. If unset, responses are cached in memory.")
+	cacheSize = flag.Int("cache-size", 1000, "Maximum size of the cache, in megabytes.")
+)
+
+func main() {
+	flag.Parse()
+	logrus.SetFormatter(&logrus.JSONFormatter{})
+
+	u, err := url.Parse(*upstream)
+	if err != nil {
+		logrus.WithError(err).Fatal("Bad --upstream.")
+	}
+	var cache http.RoundTripper
+	if *cacheDir == "" {
+		logrus.Info("Caching responses in memory.")
+		cache = ghcache.NewMemCache(nil, *cacheSize)
+	} else {
+		logrus.Infof("Caching responses in %s.", *cacheDir)
+		cache = ghcache.NewDiskCache(nil, *cacheDir, *cacheSize)
+	}
+
+	http.Handle("/metrics", promhttp.Handler())
+	http.Handle("/", &proxy{upstream: u, transport: cache})
+	logrus.Fatal(http.ListenAndServe(":"+strconv.Itoa(*port), nil))
+}
+
+// hopHeaders only apply to a single connection, so they are not forwarded.
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+type proxy struct {
+	upstream  *url.URL
+	transport http.RoundTripper
+}
+
+func (p *proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u := *p.upstream
+	u.Path = strings.TrimSuffix(u.Path, "/") + r.URL.Path
+	u.RawQuery = r.URL.RawQuery
+	req, err := http.NewRequest(r.Method, u.String(), r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	copyHeaders(req.Header, r.Header)
+	resp, err := p.transport.RoundTrip(req)
+	if err != nil {
+		logrus.WithError(err).WithField("path", r.URL.Path).Warning("Error contacting GitHub.")
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	copyHeaders(w.Header(), resp.Header)
+	// Point pagination at ourselves, so that later pages are cached too.
+	if link := resp.Header.Get("Link"); link != "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		self := scheme + "://" + r.Host
+		w.Header().Set("Link", strings.Replace(link, strings.TrimSuffix(p.upstream.String(), "/"), self, -1))
+	}
+	w.WriteHeader(resp.StatusCode)
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		logrus.WithError(err).WithField("path", r.URL.Path).Warning("Error copying response.")
+	}
+}
+
+func copyHeaders(dst, src http.Header) {
+	for k, vs := range src {
+		dst[k] = append([]string(nil), vs...)
+	}
+	for _, h := range hopHeaders {
+		dst.Del(h)
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"k8s.io/test-infra/prow/ghcache"
+)
+
+func TestProxy(t *testing.T) {
+	var upstreamURL string
+	var requests, notModified int
+	gh := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/api/v3/repos/k8s/kuber/labels" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Token abc" {
+			t.Errorf("Authorization not forwarded: %v", r.Header)
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Link", fmt.Sprintf(`<%s/api/v3/repos/k8s/kuber/labels?page=2>; rel="next"`, upstreamURL))
+		fmt.Fprint(w, `[{"name": "lgtm"}]`)
+	}))
+	defer gh.Close()
+	upstreamURL = gh.URL
+
+	u, err := url.Parse(gh.URL + "/api/v3/")
+	if err != nil {
+		t.Fatalf("Bad URL: %v", err)
+	}
+	ts := httptest.NewServer(&proxy{upstream: u, transport: ghcache.NewMemCache(nil, 10)})
+	defer ts.Close()
+
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/repos/k8s/kuber/labels", nil)
+		if err != nil {
+			t.Fatalf("Error creating request: %v", err)
+		}
+		req.Header.Set("Authorization", "Token abc")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error from proxy: %v", err)
+		}
+		b, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Error reading body: %v", err)
+		}
+		if resp.StatusCode != http.StatusOK || string(b) != `[{"name": "lgtm"}]` {
+			t.Errorf("Wrong response %d: %s", resp.StatusCode, string(b))
+		}
+		expectedLink := fmt.Sprintf(`<%s/repos/k8s/kuber/labels?page=2>; rel="next"`, ts.URL)
+		if link := resp.Header.Get("Link"); link != expectedLink {
+			t.Errorf("Expected Link %s, got %s", expectedLink, link)
+		}
+	}
+	if requests != 2 || notModified != 1 {
+		t.Errorf("Expected the second request to be conditional, got %d requests and %d not modified", requests, notModified)
+	}
+}
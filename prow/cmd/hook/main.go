@@ -19,13 +19,16 @@ package main
 import (
 	"bytes"
 	"flag"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/git"
@@ -36,19 +39,27 @@ import (
 	"k8s.io/test-infra/prow/slack"
 
 	_ "k8s.io/test-infra/prow/plugins/assign"
+	_ "k8s.io/test-infra/prow/plugins/cherrypick"
 	_ "k8s.io/test-infra/prow/plugins/cla"
 	_ "k8s.io/test-infra/prow/plugins/close"
 	_ "k8s.io/test-infra/prow/plugins/golint"
 	_ "k8s.io/test-infra/prow/plugins/heart"
+	_ "k8s.io/test-infra/prow/plugins/hold"
 	_ "k8s.io/test-infra/prow/plugins/label"
 	_ "k8s.io/test-infra/prow/plugins/lgtm"
+	_ "k8s.io/test-infra/prow/plugins/lifecycle"
+	_ "k8s.io/test-infra/prow/plugins/needsrebase"
 	_ "k8s.io/test-infra/prow/plugins/releasenote"
 	_ "k8s.io/test-infra/prow/plugins/reopen"
+	_ "k8s.io/test-infra/prow/plugins/size"
+	_ "k8s.io/test-infra/prow/plugins/triage"
 	_ "k8s.io/test-infra/prow/plugins/trigger"
 	_ "k8s.io/test-infra/prow/plugins/updateconfig"
 	_ "k8s.io/test-infra/prow/plugins/yuks"
 )
 
+const defaultGitHubEndpoint = "https://api.github.com"
+
 var (
 	port = flag.Int("port", 8888, "Port to listen on.")
 
@@ -61,7 +72,16 @@ var (
 	githubBotName     = flag.String("github-bot-name", "", "Name of the GitHub bot.")
 	webhookSecretFile = flag.String("hmac-secret-file", "/etc/webhook/hmac", "Path to the file containing the GitHub HMAC secret.")
 	githubTokenFile   = flag.String("github-token-file", "/etc/github/oauth", "Path to the file containing the GitHub OAuth secret.")
+	githubEndpoint    = flag.String("github-endpoint", defaultGitHubEndpoint, "GitHub's API endpoint, or that of a ghproxy in front of it. With --local, a fakeghserver to use instead of a fake client.")
+	githubAppID       = flag.Int("github-app-id", 0, "ID of the GitHub App to authenticate as instead of using --github-token-file. The bot name is then <app-name>[bot].")
+	githubAppKeyFile  = flag.String("github-app-private-key-file", "/etc/github-app/key", "Path to the file containing the GitHub App's private key.")
 	slackTokenFile    = flag.String("slack-token-file", "", "Path to the file containing the Slack Kubernetes Team Token.")
+
+	githubHourlyTokens = flag.Int("github-hourly-tokens", 0, "GitHub requests to allow per hour on average. Zero means no throttling.")
+	githubAllowedBurst = flag.Int("github-allowed-burst", 0, "GitHub requests to allow in a burst while throttling.")
+
+	shadowLogSize = flag.Int("shadow-log-size", 1000, "Number of mutating calls by plugins in shadow mode to keep for /debug/shadow.")
+	debugPort     = flag.Int("debug-port", 0, "Port to serve /debug/shadow on, on localhost only. Zero disables it.")
 )
 
 func main() {
@@ -77,6 +97,7 @@ func main() {
 	var githubClient *github.Client
 	var kubeClient *kube.Client
 	var slackClient *slack.Client
+	var oauthSecret string
 	if *local {
 		logrus.Warning("Running in local mode for dev only.")
 
@@ -86,7 +107,15 @@ func main() {
 		if *githubBotName == "" {
 			*githubBotName = "fake-robot"
 		}
-		githubClient = github.NewFakeClient(*githubBotName)
+		if *githubEndpoint != defaultGitHubEndpoint {
+			// Such as a fakeghserver, which keeps state and sends webhooks
+			// back so that whole conversations can be tested.
+			logrus.Infof("Using GitHub at %s.", *githubEndpoint)
+			githubClient = github.NewClient(*githubBotName, "")
+			githubClient.SetEndpoint(*githubEndpoint)
+		} else {
+			githubClient = github.NewFakeClient(*githubBotName)
+		}
 		kubeClient = kube.NewFakeClient()
 	} else {
 		logrus.SetFormatter(&logrus.JSONFormatter{})
@@ -102,11 +131,13 @@ func main() {
 		}
 		webhookSecret = bytes.TrimSpace(webhookSecretRaw)
 
-		oauthSecretRaw, err := ioutil.ReadFile(*githubTokenFile)
-		if err != nil {
-			logrus.WithError(err).Fatal("Could not read oauth secret file.")
+		if *githubAppID == 0 {
+			oauthSecretRaw, err := ioutil.ReadFile(*githubTokenFile)
+			if err != nil {
+				logrus.WithError(err).Fatal("Could not read oauth secret file.")
+			}
+			oauthSecret = string(bytes.TrimSpace(oauthSecretRaw))
 		}
-		oauthSecret := string(bytes.TrimSpace(oauthSecretRaw))
 
 		var teamToken string
 		if *slackTokenFile != "" {
@@ -125,6 +156,17 @@ func main() {
 		} else {
 			githubClient = github.NewClient(*githubBotName, oauthSecret)
 		}
+		githubClient.SetEndpoint(*githubEndpoint)
+		if *githubAppID != 0 {
+			key, err := ioutil.ReadFile(*githubAppKeyFile)
+			if err != nil {
+				logrus.WithError(err).Fatal("Could not read GitHub App private key file.")
+			}
+			if err := githubClient.UseApp(*githubAppID, key); err != nil {
+				logrus.WithError(err).Fatal("Error using GitHub App.")
+			}
+		}
+		githubClient.Throttle(*githubHourlyTokens, *githubAllowedBurst)
 
 		kubeClient, err = kube.NewClientInCluster(configAgent.Config().ProwJobNamespace)
 		if err != nil {
@@ -145,6 +187,11 @@ func main() {
 	if err != nil {
 		logrus.WithError(err).Fatal("Error getting git client.")
 	}
+	if !*local && !*dryRun && oauthSecret != "" {
+		// Plugins such as cherrypick push to the bot's forks. GitHub Apps
+		// don't have forks, so this needs a token.
+		gitClient.SetCredentials(*githubBotName, oauthSecret)
+	}
 
 	githubClient.Logger = logger.WithField("client", "github")
 	kubeClient.Logger = logger.WithField("client", "kube")
@@ -158,6 +205,17 @@ func main() {
 			SlackClient:  slackClient,
 			Logger:       logrus.NewEntry(logrus.StandardLogger()),
 		},
+		ShadowLog: plugins.NewShadowLog(*shadowLogSize),
+	}
+	if *githubAppID != 0 {
+		// Checked on every reload, so that the plugin can't be turned on
+		// later either.
+		pluginAgent.Validate = func(c plugins.Configuration) error {
+			if repos := c.EnabledRepos("cherrypick"); len(repos) > 0 {
+				return fmt.Errorf("the cherrypick plugin is enabled on %s, but it needs --github-token-file to push to the bot's forks", strings.Join(repos, ", "))
+			}
+			return nil
+		}
 	}
 	if err := pluginAgent.Start(*pluginConfig); err != nil {
 		logrus.WithError(err).Fatal("Error starting plugins.")
@@ -168,10 +226,23 @@ func main() {
 		ConfigAgent: configAgent,
 		Plugins:     pluginAgent,
 	}
+	server.StartPeriodics()
 
 	// Return 200 on / for health checks.
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
 	// For /hook, handle a webhook normally.
 	http.Handle("/hook", server)
+	// Serve metrics, such as those about external plugins.
+	http.Handle("/metrics", promhttp.Handler())
+	if *debugPort != 0 {
+		// Show the calls that plugins in shadow mode would have made. These
+		// include the bodies of would-be comments, so keep them off the
+		// webhook port.
+		debug := http.NewServeMux()
+		debug.Handle("/debug/shadow", pluginAgent.ShadowLog)
+		go func() {
+			logrus.Fatal(http.ListenAndServe("localhost:"+strconv.Itoa(*debugPort), debug))
+		}()
+	}
 	logrus.Fatal(http.ListenAndServe(":"+strconv.Itoa(*port), nil))
 }
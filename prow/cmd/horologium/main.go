@@ -25,10 +25,14 @@ import (
 
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/kube"
+	"k8s.io/test-infra/prow/metrics"
 	"k8s.io/test-infra/prow/plank"
 )
 
-var configPath = flag.String("config-path", "/etc/config/config", "Path to config.yaml.")
+var (
+	configPath  = flag.String("config-path", "/etc/config/config", "Path to config.yaml.")
+	metricsPort = flag.Int("metrics-port", 9090, "Port to serve Prometheus metrics on.")
+)
 
 func main() {
 	flag.Parse()
@@ -44,11 +48,14 @@ func main() {
 		logrus.WithError(err).Fatal("Error getting kube client.")
 	}
 
+	metrics.Serve(*metricsPort)
 	for now := range time.Tick(1 * time.Minute) {
 		start := time.Now()
-		if err := sync(kc, configAgent.Config(), now); err != nil {
+		err := sync(kc, configAgent.Config(), now)
+		if err != nil {
 			logrus.WithError(err).Error("Error syncing periodic jobs.")
 		}
+		metrics.RecordSync("horologium", start, err)
 		logrus.Infof("Sync time: %v", time.Since(start))
 	}
 }
@@ -28,6 +28,7 @@ import (
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/jenkins"
 	"k8s.io/test-infra/prow/kube"
+	"k8s.io/test-infra/prow/metrics"
 	"k8s.io/test-infra/prow/plank"
 )
 
@@ -43,7 +44,16 @@ var (
 
 	githubBotName   = flag.String("github-bot-name", "", "Name of the GitHub bot.")
 	githubTokenFile = flag.String("github-token-file", "/etc/github/oauth", "Path to the file containing the GitHub OAuth token.")
+	githubEndpoint  = flag.String("github-endpoint", "https://api.github.com", "GitHub's API endpoint, or that of a ghproxy in front of it.")
 	dryRun          = flag.Bool("dry-run", true, "Whether or not to make mutating API calls to GitHub.")
+
+	githubHourlyTokens = flag.Int("github-hourly-tokens", 0, "GitHub requests to allow per hour on average. Zero means no throttling.")
+	githubAllowedBurst = flag.Int("github-allowed-burst", 0, "GitHub requests to allow in a burst while throttling.")
+
+	githubAppID      = flag.Int("github-app-id", 0, "ID of the GitHub App to authenticate as instead of using --github-token-file. The bot name is then <app-name>[bot].")
+	githubAppKeyFile = flag.String("github-app-private-key-file", "/etc/github-app/key", "Path to the file containing the GitHub App's private key.")
+
+	metricsPort = flag.Int("metrics-port", 9090, "Port to serve Prometheus metrics on.")
 )
 
 func main() {
@@ -81,11 +91,14 @@ func main() {
 		jc = jenkins.NewClient(*jenkinsURL, *jenkinsUserName, jenkinsToken)
 	}
 
-	oauthSecretRaw, err := ioutil.ReadFile(*githubTokenFile)
-	if err != nil {
-		logrus.WithError(err).Fatalf("Could not read oauth secret file.")
+	var oauthSecret string
+	if *githubAppID == 0 {
+		oauthSecretRaw, err := ioutil.ReadFile(*githubTokenFile)
+		if err != nil {
+			logrus.WithError(err).Fatalf("Could not read oauth secret file.")
+		}
+		oauthSecret = string(bytes.TrimSpace(oauthSecretRaw))
 	}
-	oauthSecret := string(bytes.TrimSpace(oauthSecretRaw))
 
 	var ghc *github.Client
 	if *dryRun {
@@ -93,16 +106,30 @@ func main() {
 	} else {
 		ghc = github.NewClient(*githubBotName, oauthSecret)
 	}
+	ghc.SetEndpoint(*githubEndpoint)
+	if *githubAppID != 0 {
+		key, err := ioutil.ReadFile(*githubAppKeyFile)
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not read GitHub App private key file.")
+		}
+		if err := ghc.UseApp(*githubAppID, key); err != nil {
+			logrus.WithError(err).Fatal("Error using GitHub App.")
+		}
+	}
+	ghc.Throttle(*githubHourlyTokens, *githubAllowedBurst)
 
 	c, err := plank.NewController(kc, pkc, jc, ghc, configAgent, *totURL)
 	if err != nil {
 		logrus.WithError(err).Fatal("Error creating plank controller.")
 	}
+	metrics.Serve(*metricsPort)
 	for range time.Tick(30 * time.Second) {
 		start := time.Now()
-		if err := c.Sync(); err != nil {
+		err := c.Sync()
+		if err != nil {
 			logrus.WithError(err).Error("Error syncing.")
 		}
+		metrics.RecordSync("plank", start, err)
 		logrus.Infof("Sync time: %v", time.Since(start))
 	}
 }
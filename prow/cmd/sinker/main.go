@@ -18,14 +18,26 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"time"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/kube"
+	"k8s.io/test-infra/prow/metrics"
 )
 
+var cleaned = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "prow_sinker_cleaned_total",
+	Help: "Objects deleted by sinker, by kind.",
+}, []string{"kind"})
+
+func init() {
+	prometheus.MustRegister(cleaned)
+}
+
 type kubeClient interface {
 	ListPods(labels map[string]string) ([]kube.Pod, error)
 	DeletePod(name string) error
@@ -38,7 +50,10 @@ type configAgent interface {
 	Config() *config.Config
 }
 
-var configPath = flag.String("config-path", "/etc/config/config", "Path to config.yaml.")
+var (
+	configPath  = flag.String("config-path", "/etc/config/config", "Path to config.yaml.")
+	metricsPort = flag.Int("metrics-port", 9090, "Port to serve Prometheus metrics on.")
+)
 
 func main() {
 	flag.Parse()
@@ -57,27 +72,36 @@ func main() {
 	pkc := kc.Namespace(configAgent.Config().PodNamespace)
 
 	// Clean now and regularly from now on.
-	clean(kc, pkc, configAgent)
+	metrics.Serve(*metricsPort)
 	for {
+		start := time.Now()
+		err := clean(kc, pkc, configAgent)
+		if err != nil {
+			logrus.WithError(err).Error("Error cleaning.")
+		}
+		metrics.RecordSync("sinker", start, err)
 		time.Sleep(configAgent.Config().Sinker.ResyncPeriod)
-		clean(kc, pkc, configAgent)
 	}
 }
 
-func clean(kc, pkc kubeClient, configAgent configAgent) {
+// clean deletes old prow jobs and pods. It returns an error if it couldn't
+// list them or failed to delete some.
+func clean(kc, pkc kubeClient, configAgent configAgent) error {
 	// Clean up old prow jobs first.
 	prowJobs, err := kc.ListProwJobs(nil)
 	if err != nil {
-		logrus.WithError(err).Error("Error listing prow jobs.")
-		return
+		return fmt.Errorf("error listing prow jobs: %v", err)
 	}
+	failed := 0
 	maxProwJobAge := configAgent.Config().Sinker.MaxProwJobAge
 	for _, prowJob := range prowJobs {
 		if prowJob.Complete() && time.Since(prowJob.Status.StartTime) > maxProwJobAge {
 			if err := kc.DeleteProwJob(prowJob.Metadata.Name); err == nil {
 				logrus.WithField("prowjob", prowJob.Metadata.Name).Info("Deleted prowjob.")
+				cleaned.WithLabelValues("prowjob").Inc()
 			} else {
 				logrus.WithField("prowjob", prowJob.Metadata.Name).WithError(err).Error("Error deleting prowjob.")
+				failed++
 			}
 		}
 	}
@@ -85,8 +109,7 @@ func clean(kc, pkc kubeClient, configAgent configAgent) {
 	// Now clean up old pods.
 	pods, err := pkc.ListPods(nil)
 	if err != nil {
-		logrus.WithError(err).Error("Error listing pods.")
-		return
+		return fmt.Errorf("error listing pods: %v", err)
 	}
 	maxPodAge := configAgent.Config().Sinker.MaxPodAge
 	for _, pod := range pods {
@@ -95,9 +118,15 @@ func clean(kc, pkc kubeClient, configAgent configAgent) {
 			// Delete old completed pods. Don't quit if we fail to delete one.
 			if err := pkc.DeletePod(pod.Metadata.Name); err == nil {
 				logrus.WithField("pod", pod.Metadata.Name).Info("Deleted old completed pod.")
+				cleaned.WithLabelValues("pod").Inc()
 			} else {
 				logrus.WithField("pod", pod.Metadata.Name).WithError(err).Error("Error deleting pod.")
+				failed++
 			}
 		}
 	}
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d objects", failed)
+	}
+	return nil
 }
@@ -21,6 +21,8 @@ import (
 	"testing"
 	"time"
 
+	dto "github.com/prometheus/client_model/go"
+
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/kube"
 )
@@ -193,7 +195,13 @@ func TestClean(t *testing.T) {
 		Pods:     pods,
 		ProwJobs: prowJobs,
 	}
-	clean(kc, kc, newFakeConfigAgent())
+	before := cleanedCount(t, "pod")
+	if err := clean(kc, kc, newFakeConfigAgent()); err != nil {
+		t.Fatalf("Error cleaning: %v", err)
+	}
+	if d := cleanedCount(t, "pod") - before; int(d) != len(deletedPods) {
+		t.Errorf("Expected %d cleaned pods counted, got %v", len(deletedPods), d)
+	}
 	if len(deletedPods) != len(kc.DeletedPods) {
 		t.Errorf("Deleted wrong number of pods: got %v expected %v", kc.DeletedPods, deletedPods)
 	}
@@ -223,3 +231,11 @@ func TestClean(t *testing.T) {
 		}
 	}
 }
+
+func cleanedCount(t *testing.T, kind string) float64 {
+	var m dto.Metric
+	if err := cleaned.WithLabelValues(kind).Write(&m); err != nil {
+		t.Fatalf("Error reading metric: %v", err)
+	}
+	return m.GetCounter().GetValue()
+}
@@ -24,14 +24,20 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/kube"
+	"k8s.io/test-infra/prow/metrics"
 	"k8s.io/test-infra/prow/plank"
+	"k8s.io/test-infra/prow/splice"
 )
 
 var (
@@ -41,8 +47,26 @@ var (
 	repoName       = flag.String("repo", "kubernetes", "Repo name")
 	configPath     = flag.String("config-path", "/etc/config/config", "Path to config.yaml.")
 	maxBatchSize   = flag.Int("batch-size", 5, "Maximum batch size")
+	port           = flag.Int("port", 8080, "Port to serve the pool on, for deck.")
 )
 
+var (
+	queuedPRs = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "prow_splice_queued_prs",
+		Help: "PRs in the submit queue, as of the last look.",
+	})
+	batchSize = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "prow_splice_batch_size",
+		Help:    "Number of PRs in each batch that splice starts.",
+		Buckets: prometheus.LinearBuckets(2, 1, 9),
+	})
+)
+
+func init() {
+	prometheus.MustRegister(queuedPRs)
+	prometheus.MustRegister(batchSize)
+}
+
 // Call a binary and return its output and success status.
 func call(binary string, args ...string) (string, error) {
 	cmdout := "+ " + binary + " "
@@ -57,7 +81,7 @@ func call(binary string, args ...string) (string, error) {
 }
 
 // getQueuedPRs reads the list of queued PRs from the Submit Queue.
-func getQueuedPRs(url string) ([]int, error) {
+func getQueuedPRs(url string) ([]splice.QueuedPR, error) {
 	resp, err := http.Get(url)
 	if err != nil {
 		return nil, err
@@ -69,23 +93,56 @@ func getQueuedPRs(url string) ([]int, error) {
 	}
 
 	queue := struct {
-		E2EQueue []struct {
-			Number  int
-			BaseRef string
-		}
+		E2EQueue []splice.QueuedPR
 	}{}
 	err = json.Unmarshal(body, &queue)
 	if err != nil {
 		return nil, err
 	}
+	return queue.E2EQueue, nil
+}
 
-	ret := []int{}
-	for _, e := range queue.E2EQueue {
-		if e.BaseRef == "" || e.BaseRef == "master" {
-			ret = append(ret, e.Number)
+// runningBatch returns the names of the batch jobs that haven't completed
+// and the refs of the batch that they are testing.
+func runningBatch(jobs []kube.ProwJob) ([]string, kube.Refs) {
+	running := []string{}
+	var batch kube.Refs
+	for _, job := range jobs {
+		if job.Spec.Type != kube.BatchJob {
+			continue
+		}
+		if !job.Complete() {
+			running = append(running, job.Spec.Job)
+			batch = job.Spec.Refs
 		}
 	}
-	return ret, nil
+	return running, batch
+}
+
+// poolStatus serves the pool that splice last looked at.
+type poolStatus struct {
+	sync.Mutex
+	pool splice.Pool
+}
+
+// update applies f to the pool and records when splice last looked at it.
+func (ps *poolStatus) update(f func(*splice.Pool)) {
+	ps.Lock()
+	defer ps.Unlock()
+	f(&ps.pool)
+	ps.pool.Updated = time.Now()
+}
+
+func (ps *poolStatus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ps.Lock()
+	b, err := json.Marshal([]splice.Pool{ps.pool})
+	ps.Unlock()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error marshaling pool: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
 }
 
 // Splicer manages a git repo in specific directory.
@@ -279,68 +336,111 @@ func main() {
 		log.WithError(err).Fatal("Error getting kube client.")
 	}
 
-	cooldown := 0
+	status := &poolStatus{pool: splice.NewPool(*orgName, *repoName, nil, nil, *maxBatchSize)}
+	http.Handle("/pool", status)
+	http.Handle("/metrics", promhttp.Handler())
+	go func() {
+		log.WithError(http.ListenAndServe(":"+strconv.Itoa(*port), nil)).Fatal("ListenAndServe returned.")
+	}()
+
+	c := &controller{
+		splicer: splicer,
+		kc:      kc,
+		ca:      configAgent,
+		status:  status,
+	}
 	// Loop endlessly, sleeping a minute between iterations
 	for range time.Tick(1 * time.Minute) {
-		// List batch jobs, only start a new one if none are active.
-		currentJobs, err := kc.ListProwJobs(nil)
+		start := time.Now()
+		err := c.sync()
 		if err != nil {
-			log.WithError(err).Error("Error listing prow jobs.")
-			continue
+			log.WithError(err).Error("Error syncing.")
 		}
+		metrics.RecordSync("splice", start, err)
+	}
+}
 
-		running := []string{}
-		for _, job := range currentJobs {
-			if job.Spec.Type != kube.BatchJob {
-				continue
-			}
-			if !job.Complete() {
-				running = append(running, job.Spec.Job)
-			}
-		}
-		if len(running) > 0 {
-			log.Infof("Waiting on %d jobs: %v", len(running), running)
-			continue
-		}
+// controller starts batches of the PRs in the submit queue.
+type controller struct {
+	splicer  *splicer
+	kc       *kube.Client
+	ca       *config.Agent
+	status   *poolStatus
+	cooldown int
+}
 
-		// Start a new batch if the cooldown is 0, otherwise wait. This gives
-		// the SQ some time to merge before we start a new batch.
-		if cooldown > 0 {
-			cooldown--
-			continue
-		}
+// sync starts a batch if no batch jobs are running and the queue has PRs
+// that merge together.
+func (c *controller) sync() error {
+	// List batch jobs, only start a new one if none are active.
+	currentJobs, err := c.kc.ListProwJobs(nil)
+	if err != nil {
+		return fmt.Errorf("error listing prow jobs: %v", err)
+	}
 
-		queue, err := getQueuedPRs(*submitQueueURL)
-		if err != nil {
-			log.WithError(err).Warning("Error getting queued PRs. Is the submit queue down?")
-			continue
-		}
-		// No need to check for mergeable PRs if none is in the queue.
-		if len(queue) == 0 {
-			continue
-		}
-		log.Infof("PRs in queue: %v", queue)
-		batchPRs, err := splicer.findMergeable(*remoteURL, queue)
-		if err != nil {
-			log.WithError(err).Error("Error computing mergeable PRs.")
-			continue
-		}
-		// No need to start batches for single PRs
-		if len(batchPRs) <= 1 {
-			continue
-		}
-		// Trim down to the desired batch size.
-		if len(batchPRs) > *maxBatchSize {
-			batchPRs = batchPRs[:*maxBatchSize]
-		}
-		log.Infof("Starting a batch for the following PRs: %v", batchPRs)
-		refs := splicer.makeBuildRefs(*orgName, *repoName, batchPRs)
-		presubmits := configAgent.Config().Presubmits[fmt.Sprintf("%s/%s", *orgName, *repoName)]
-		for _, job := range neededPresubmits(presubmits, currentJobs, refs) {
-			if _, err := kc.CreateProwJob(plank.NewProwJob(plank.BatchSpec(job, refs))); err != nil {
-				log.WithError(err).WithField("job", job.Name).Error("Error starting batch job.")
+	running, batch := runningBatch(currentJobs)
+	if len(running) > 0 {
+		log.Infof("Waiting on %d jobs: %v", len(running), running)
+		c.status.update(func(p *splice.Pool) {
+			p.State = splice.Testing
+			p.Error = ""
+			// After a restart splice doesn't know which batch it started, so
+			// take it from the jobs that are testing it.
+			if len(p.Batch.Pulls) == 0 {
+				p.Batch = batch
 			}
+		})
+		return nil
+	}
+
+	// Start a new batch if the cooldown is 0, otherwise wait. This gives
+	// the SQ some time to merge before we start a new batch.
+	if c.cooldown > 0 {
+		c.cooldown--
+		c.status.update(func(p *splice.Pool) {
+			p.State = splice.CoolingDown
+			p.Error = ""
+		})
+		return nil
+	}
+
+	queue, err := getQueuedPRs(*submitQueueURL)
+	if err != nil {
+		c.status.update(func(p *splice.Pool) { p.Error = "Error getting queued PRs. Is the submit queue down?" })
+		return fmt.Errorf("error getting queued PRs, is the submit queue down? %v", err)
+	}
+	queuedPRs.Set(float64(len(queue)))
+	batchable := splice.Batchable(queue)
+	// No need to check for mergeable PRs if none is in the queue.
+	if len(batchable) == 0 {
+		c.status.update(func(p *splice.Pool) { *p = splice.NewPool(*orgName, *repoName, queue, nil, *maxBatchSize) })
+		return nil
+	}
+	log.Infof("PRs in queue: %v", batchable)
+	mergeable, err := c.splicer.findMergeable(*remoteURL, batchable)
+	if err != nil {
+		c.status.update(func(p *splice.Pool) { p.Error = "Error computing mergeable PRs." })
+		return fmt.Errorf("error computing mergeable PRs: %v", err)
+	}
+	pool := splice.NewPool(*orgName, *repoName, queue, mergeable, *maxBatchSize)
+	batchPRs := pool.BatchPRs()
+	// No need to start batches for single PRs
+	if len(batchPRs) == 0 {
+		c.status.update(func(p *splice.Pool) { *p = pool })
+		return nil
+	}
+	log.Infof("Starting a batch for the following PRs: %v", batchPRs)
+	batchSize.Observe(float64(len(batchPRs)))
+	refs := c.splicer.makeBuildRefs(*orgName, *repoName, batchPRs)
+	pool.Batch = refs
+	pool.State = splice.Testing
+	c.status.update(func(p *splice.Pool) { *p = pool })
+	presubmits := c.ca.Config().Presubmits[fmt.Sprintf("%s/%s", *orgName, *repoName)]
+	for _, job := range neededPresubmits(presubmits, currentJobs, refs) {
+		if _, err := c.kc.CreateProwJob(plank.NewProwJob(plank.BatchSpec(job, refs))); err != nil {
+			log.WithError(err).WithField("job", job.Name).Error("Error starting batch job.")
 		}
-		cooldown = 5
 	}
+	c.cooldown = 5
+	return nil
 }
@@ -27,6 +27,7 @@ import (
 
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/kube"
+	"k8s.io/test-infra/prow/splice"
 )
 
 func expectEqual(t *testing.T, msg string, have interface{}, want interface{}) {
@@ -55,7 +56,12 @@ func TestGetQueuedPRs(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	expectEqual(t, "queued PRs", q, []int{3, 4, 1})
+	expectEqual(t, "queued PRs", q, []splice.QueuedPR{
+		{Number: 3},
+		{Number: 4, BaseRef: "master"},
+		{Number: 1},
+		{Number: 5, BaseRef: "release-1.5"},
+	})
 }
 
 // Since the splicer object already has helpers for doing git operations,
@@ -280,6 +286,24 @@ func TestCompletedJobs(t *testing.T) {
 	}
 }
 
+func TestRunningBatch(t *testing.T) {
+	refs := fakeRefs("ref", "sha")
+	refs.Pulls = []kube.Pull{{Number: 1}, {Number: 2}}
+	jobs := []kube.ProwJob{
+		fakeProwJob("passed-a", kube.BatchJob, true, kube.SuccessState, fakeRefs("oldref", "oldsha")),
+		fakeProwJob("pending-b", kube.BatchJob, false, kube.PendingState, refs),
+		fakeProwJob("pending-presubmit", kube.PresubmitJob, false, kube.PendingState, fakeRefs("otherref", "othersha")),
+	}
+	jobs[1].Spec.Job = "pending-b"
+	running, batch := runningBatch(jobs)
+	expectEqual(t, "running jobs", running, []string{"pending-b"})
+	expectEqual(t, "batch", batch, refs)
+
+	running, batch = runningBatch(jobs[:1])
+	expectEqual(t, "running jobs", running, []string{})
+	expectEqual(t, "batch", batch, kube.Refs{})
+}
+
 func TestRequiredPresubmits(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -373,3 +397,18 @@ func TestNeededPresubmits(t *testing.T) {
 		expectEqual(t, tc.name, names, tc.required)
 	}
 }
+
+func TestPoolStatusUpdate(t *testing.T) {
+	ps := &poolStatus{pool: splice.Pool{Error: "Error computing mergeable PRs."}}
+	before := time.Now()
+	ps.update(func(p *splice.Pool) {
+		p.State = splice.CoolingDown
+		p.Error = ""
+	})
+	if ps.pool.Error != "" {
+		t.Errorf("Expected the error to be cleared, got %q.", ps.pool.Error)
+	}
+	if ps.pool.Updated.Before(before) {
+		t.Errorf("Expected the update time to be refreshed, got %v.", ps.pool.Updated)
+	}
+}
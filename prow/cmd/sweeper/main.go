@@ -0,0 +1,246 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Sweeper periodically moves inactive issues and PRs through the lifecycle
+// configured under lifecycle in config.yaml: they are marked stale, then
+// rotten, and finally closed. Anything with lifecycle/frozen or one of the
+// exempt labels is left alone.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/plugins"
+	"k8s.io/test-infra/prow/plugins/lifecycle"
+)
+
+var (
+	configPath      = flag.String("config-path", "/etc/config/config", "Path to config.yaml.")
+	githubBotName   = flag.String("github-bot-name", "", "Name of the GitHub bot.")
+	githubTokenFile = flag.String("github-token-file", "/etc/github/oauth", "Path to the file containing the GitHub OAuth secret.")
+	githubEndpoint  = flag.String("github-endpoint", "https://api.github.com", "GitHub's API endpoint, or that of a ghproxy in front of it.")
+	dryRun          = flag.Bool("dry-run", true, "Dry run for testing. Uses API tokens but does not mutate.")
+	interval        = flag.Duration("interval", time.Hour, "How often to sweep.")
+
+	githubHourlyTokens = flag.Int("github-hourly-tokens", 0, "GitHub requests to allow per hour on average. Zero means no throttling.")
+	githubAllowedBurst = flag.Int("github-allowed-burst", 0, "GitHub requests to allow in a burst while throttling.")
+
+	githubAppID      = flag.Int("github-app-id", 0, "ID of the GitHub App to authenticate as instead of using --github-token-file. The bot name is then <app-name>[bot].")
+	githubAppKeyFile = flag.String("github-app-private-key-file", "/etc/github-app/key", "Path to the file containing the GitHub App's private key.")
+)
+
+func main() {
+	flag.Parse()
+	logrus.SetFormatter(&logrus.JSONFormatter{})
+
+	configAgent := config.Agent{}
+	if err := configAgent.Start(*configPath); err != nil {
+		logrus.WithError(err).Fatal("Error starting config agent.")
+	}
+
+	var oauthSecret string
+	if *githubAppID == 0 {
+		oauthSecretRaw, err := ioutil.ReadFile(*githubTokenFile)
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not read oauth secret file.")
+		}
+		oauthSecret = string(bytes.TrimSpace(oauthSecretRaw))
+	}
+	if *githubBotName == "" {
+		logrus.Fatal("Must specify --github-bot-name.")
+	}
+	var gc *github.Client
+	if *dryRun {
+		gc = github.NewDryRunClient(*githubBotName, oauthSecret)
+	} else {
+		gc = github.NewClient(*githubBotName, oauthSecret)
+	}
+	gc.SetEndpoint(*githubEndpoint)
+	if *githubAppID != 0 {
+		key, err := ioutil.ReadFile(*githubAppKeyFile)
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not read GitHub App private key file.")
+		}
+		if err := gc.UseApp(*githubAppID, key); err != nil {
+			logrus.WithError(err).Fatal("Error using GitHub App.")
+		}
+	}
+	gc.Throttle(*githubHourlyTokens, *githubAllowedBurst)
+	gc.Logger = logrus.WithField("client", "github")
+
+	for now := time.Now(); ; now = <-time.After(*interval) {
+		start := time.Now()
+		if err := sweep(gc, configAgent.Config(), now); err != nil {
+			logrus.WithError(err).Error("Error sweeping.")
+		}
+		logrus.Infof("Sweep time: %v", time.Since(start))
+	}
+}
+
+type githubClient interface {
+	FindIssues(query, sort string, asc bool) ([]github.Issue, error)
+	AddLabel(owner, repo string, number int, label string) error
+	RemoveLabel(owner, repo string, number int, label string) error
+	CreateComment(owner, repo string, number int, comment string) error
+	CloseIssue(owner, repo string, number int) error
+	ClosePR(owner, repo string, number int) error
+}
+
+// sweep runs every lifecycle config once.
+func sweep(gc githubClient, cfg *config.Config, now time.Time) error {
+	var errs []error
+	for _, lc := range cfg.Lifecycle {
+		for _, r := range lc.Repos {
+			if err := sweepRepo(gc, lc, r, now); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("errors sweeping: %v", errs)
+	}
+	return nil
+}
+
+func sweepRepo(gc githubClient, lc config.Lifecycle, r string, now time.Time) error {
+	scope := "org:" + r
+	if strings.Contains(r, "/") {
+		scope = "repo:" + r
+	}
+	exempt := fmt.Sprintf("-label:%q", lifecycle.FrozenLabel)
+	for _, l := range lc.ExemptLabels {
+		exempt += fmt.Sprintf(" -label:%q", l)
+	}
+	query := func(labels string, inactive time.Duration) string {
+		return fmt.Sprintf("%s is:open %s %s updated:<%s", scope, labels, exempt, now.Add(-inactive).UTC().Format(time.RFC3339))
+	}
+	log := logrus.WithField("repos", r)
+
+	// Close first, then rot, then mark stale. Marking bumps the updated
+	// time, so an issue that was just marked is not found by the next step.
+	rotten, err := gc.FindIssues(query(fmt.Sprintf("label:%q", lifecycle.RottenLabel), lc.CloseAfter), "updated", true)
+	if err != nil {
+		return err
+	}
+	for _, i := range rotten {
+		if err := closeIssue(gc, log, lc, i); err != nil {
+			return err
+		}
+	}
+	stale, err := gc.FindIssues(query(fmt.Sprintf("label:%q -label:%q", lifecycle.StaleLabel, lifecycle.RottenLabel), lc.RottenAfter), "updated", true)
+	if err != nil {
+		return err
+	}
+	for _, i := range stale {
+		if err := mark(gc, log, i, lifecycle.StaleLabel, lifecycle.RottenLabel, rottenMessage(lc, i)); err != nil {
+			return err
+		}
+	}
+	fresh, err := gc.FindIssues(query(fmt.Sprintf("-label:%q -label:%q", lifecycle.StaleLabel, lifecycle.RottenLabel), lc.StaleAfter), "updated", true)
+	if err != nil {
+		return err
+	}
+	for _, i := range fresh {
+		if err := mark(gc, log, i, "", lifecycle.StaleLabel, staleMessage(lc, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mark replaces the from label, if any, with the to label and explains why.
+func mark(gc githubClient, log *logrus.Entry, i github.Issue, from, to, msg string) error {
+	org, repo, err := i.OrgRepo()
+	if err != nil {
+		return err
+	}
+	log.Infof("Marking %s/%s#%d as %s.", org, repo, i.Number, to)
+	if from != "" {
+		if err := gc.RemoveLabel(org, repo, i.Number, from); err != nil {
+			return err
+		}
+	}
+	if err := gc.AddLabel(org, repo, i.Number, to); err != nil {
+		return err
+	}
+	return gc.CreateComment(org, repo, i.Number, msg)
+}
+
+func closeIssue(gc githubClient, log *logrus.Entry, lc config.Lifecycle, i github.Issue) error {
+	org, repo, err := i.OrgRepo()
+	if err != nil {
+		return err
+	}
+	log.Infof("Closing rotten %s/%s#%d.", org, repo, i.Number)
+	if err := gc.CreateComment(org, repo, i.Number, closeMessage(lc, i)); err != nil {
+		return err
+	}
+	if i.IsPullRequest() {
+		return gc.ClosePR(org, repo, i.Number)
+	}
+	return gc.CloseIssue(org, repo, i.Number)
+}
+
+func kind(i github.Issue) string {
+	if i.IsPullRequest() {
+		return "PRs"
+	}
+	return "Issues"
+}
+
+func staleMessage(lc config.Lifecycle, i github.Issue) string {
+	return fmt.Sprintf(`%s go stale after %s of inactivity.
+Mark this as fresh with `+"`/remove-lifecycle stale`"+`.
+Stale %s rot after an additional %s of inactivity and eventually close.
+
+Prevent this from happening again with `+"`/lifecycle frozen`"+`.
+
+%s`, kind(i), days(lc.StaleAfter), strings.ToLower(kind(i)), days(lc.RottenAfter), plugins.AboutThisBot)
+}
+
+func rottenMessage(lc config.Lifecycle, i github.Issue) string {
+	return fmt.Sprintf(`Stale %s rot after %s of inactivity.
+Mark this as fresh with `+"`/remove-lifecycle rotten`"+`.
+Rotten %s close after an additional %s of inactivity.
+
+Prevent this from happening again with `+"`/lifecycle frozen`"+`.
+
+%s`, strings.ToLower(kind(i)), days(lc.RottenAfter), strings.ToLower(kind(i)), days(lc.CloseAfter), plugins.AboutThisBot)
+}
+
+func closeMessage(lc config.Lifecycle, i github.Issue) string {
+	return fmt.Sprintf(`Rotten %s close after %s of inactivity.
+Reopen this with `+"`/reopen`"+` and mark it as fresh with `+"`/remove-lifecycle rotten`"+`.
+
+%s`, strings.ToLower(kind(i)), days(lc.CloseAfter), plugins.AboutThisBot)
+}
+
+// days formats whole days as "90d" and anything else as a duration.
+func days(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	return d.String()
+}
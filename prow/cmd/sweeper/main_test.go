@@ -0,0 +1,144 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+)
+
+type fakeGitHub struct {
+	rotten, stale, fresh []github.Issue
+
+	queries  []string
+	added    []string
+	removed  []string
+	comments []string
+	closed   []string
+}
+
+func (f *fakeGitHub) FindIssues(query, sort string, asc bool) ([]github.Issue, error) {
+	f.queries = append(f.queries, query)
+	switch {
+	case strings.Contains(query, ` label:"lifecycle/rotten"`):
+		return f.rotten, nil
+	case strings.Contains(query, ` label:"lifecycle/stale"`):
+		return f.stale, nil
+	}
+	return f.fresh, nil
+}
+
+func (f *fakeGitHub) AddLabel(owner, repo string, number int, label string) error {
+	f.added = append(f.added, fmt.Sprintf("%s/%s#%d:%s", owner, repo, number, label))
+	return nil
+}
+
+func (f *fakeGitHub) RemoveLabel(owner, repo string, number int, label string) error {
+	f.removed = append(f.removed, fmt.Sprintf("%s/%s#%d:%s", owner, repo, number, label))
+	return nil
+}
+
+func (f *fakeGitHub) CreateComment(owner, repo string, number int, comment string) error {
+	f.comments = append(f.comments, fmt.Sprintf("%s/%s#%d", owner, repo, number))
+	return nil
+}
+
+func (f *fakeGitHub) CloseIssue(owner, repo string, number int) error {
+	f.closed = append(f.closed, fmt.Sprintf("issue %s/%s#%d", owner, repo, number))
+	return nil
+}
+
+func (f *fakeGitHub) ClosePR(owner, repo string, number int) error {
+	f.closed = append(f.closed, fmt.Sprintf("pr %s/%s#%d", owner, repo, number))
+	return nil
+}
+
+func issue(number int, pr bool) github.Issue {
+	i := github.Issue{
+		Number:  number,
+		HTMLURL: fmt.Sprintf("https://github.com/org/repo/issues/%d", number),
+	}
+	if pr {
+		i.PullRequest = &struct{}{}
+	}
+	return i
+}
+
+func TestSweep(t *testing.T) {
+	now := time.Date(2017, 7, 1, 0, 0, 0, 0, time.UTC)
+	lc := config.Lifecycle{
+		Repos:        []string{"org/repo", "other"},
+		ExemptLabels: []string{"keep me"},
+	}
+	if err := lc.SetDurations(); err != nil {
+		t.Fatalf("Setting durations: %v", err)
+	}
+	fc := &fakeGitHub{
+		rotten: []github.Issue{issue(1, false), issue(2, true)},
+		stale:  []github.Issue{issue(3, false)},
+		fresh:  []github.Issue{issue(4, true)},
+	}
+	if err := sweep(fc, &config.Config{Lifecycle: []config.Lifecycle{lc}}, now); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+
+	expectedQueries := []string{
+		`repo:org/repo is:open label:"lifecycle/rotten" -label:"lifecycle/frozen" -label:"keep me" updated:<2017-06-01T00:00:00Z`,
+		`repo:org/repo is:open label:"lifecycle/stale" -label:"lifecycle/rotten" -label:"lifecycle/frozen" -label:"keep me" updated:<2017-06-01T00:00:00Z`,
+		`repo:org/repo is:open -label:"lifecycle/stale" -label:"lifecycle/rotten" -label:"lifecycle/frozen" -label:"keep me" updated:<2017-04-02T00:00:00Z`,
+		`org:other is:open label:"lifecycle/rotten" -label:"lifecycle/frozen" -label:"keep me" updated:<2017-06-01T00:00:00Z`,
+		`org:other is:open label:"lifecycle/stale" -label:"lifecycle/rotten" -label:"lifecycle/frozen" -label:"keep me" updated:<2017-06-01T00:00:00Z`,
+		`org:other is:open -label:"lifecycle/stale" -label:"lifecycle/rotten" -label:"lifecycle/frozen" -label:"keep me" updated:<2017-04-02T00:00:00Z`,
+	}
+	if !reflect.DeepEqual(fc.queries, expectedQueries) {
+		t.Errorf("Expected queries\n%s\ngot\n%s", strings.Join(expectedQueries, "\n"), strings.Join(fc.queries, "\n"))
+	}
+	// Both repos return the same fake results, so everything happens twice.
+	var expectedClosed, expectedAdded, expectedRemoved []string
+	for range lc.Repos {
+		expectedClosed = append(expectedClosed, "issue org/repo#1", "pr org/repo#2")
+		expectedAdded = append(expectedAdded, "org/repo#3:lifecycle/rotten", "org/repo#4:lifecycle/stale")
+		expectedRemoved = append(expectedRemoved, "org/repo#3:lifecycle/stale")
+	}
+	if !reflect.DeepEqual(fc.closed, expectedClosed) {
+		t.Errorf("Expected %v to be closed, got %v", expectedClosed, fc.closed)
+	}
+	if !reflect.DeepEqual(fc.added, expectedAdded) {
+		t.Errorf("Expected labels %v to be added, got %v", expectedAdded, fc.added)
+	}
+	if !reflect.DeepEqual(fc.removed, expectedRemoved) {
+		t.Errorf("Expected labels %v to be removed, got %v", expectedRemoved, fc.removed)
+	}
+	if len(fc.comments) != 8 {
+		t.Errorf("Expected a comment on every issue changed, got %v", fc.comments)
+	}
+}
+
+func TestDays(t *testing.T) {
+	if d := days(90 * 24 * time.Hour); d != "90d" {
+		t.Errorf("Expected 90d, got %s", d)
+	}
+	if d := days(36 * time.Hour); d != "36h0m0s" {
+		t.Errorf("Expected 36h0m0s, got %s", d)
+	}
+}
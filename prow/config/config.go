@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"regexp"
+	"strings"
 	"text/template"
 	"time"
 
@@ -40,6 +41,17 @@ type Config struct {
 	Sinker   Sinker    `json:"sinker,omitempty"`
 	Triggers []Trigger `json:"triggers,omitempty"`
 	Heart    Heart     `json:"heart,omitempty"`
+	Labels   []Label   `json:"labels,omitempty"`
+	Lint     []Lint    `json:"lint,omitempty"`
+	Triage   []Triage  `json:"triage,omitempty"`
+	// Lifecycle configures the sweeper that marks inactive issues and PRs
+	// stale, then rotten, then closes them.
+	Lifecycle []Lifecycle `json:"lifecycle,omitempty"`
+	// BranchProtection holds per-repo overrides for the branchprotector,
+	// which requires the contexts of presubmits in branch protection.
+	BranchProtection []BranchProtection `json:"branch_protection,omitempty"`
+	// Deck decides who may rerun and abort jobs from deck.
+	Deck Deck `json:"deck,omitempty"`
 
 	// ProwJobNamespace is the namespace in the cluster that prow
 	// components will use for looking up ProwJobs. The namespace
@@ -93,6 +105,52 @@ type Trigger struct {
 	// TrustedOrg is the org whose members' PRs will be automatically built
 	// for PRs to the above repos.
 	TrustedOrg string `json:"trusted_org,omitempty"`
+	// TrustedOrgs are more orgs whose members are trusted like those of
+	// TrustedOrg.
+	TrustedOrgs []string `json:"trusted_orgs,omitempty"`
+	// TrustCollaborators trusts the collaborators of the repo as well.
+	TrustCollaborators bool `json:"trust_collaborators,omitempty"`
+	// TrustedUsersFiles are files at the root of the repo that list trusted
+	// users. OWNERS files are read for approvers and reviewers. Any other
+	// file, such as SECURITY_CONTACTS, lists one login per line, and lines
+	// starting with # are comments.
+	TrustedUsersFiles []string `json:"trusted_users_files,omitempty"`
+	// StaleResults, if set, re-runs the presubmits of PRs with the lgtm
+	// label when their results are stale.
+	StaleResults *StaleResults `json:"stale_results,omitempty"`
+}
+
+// StaleResults says when presubmit results are too old to trust. Zero values
+// disable the corresponding check.
+type StaleResults struct {
+	// MaxAgeString compiles into MaxAge at load time.
+	MaxAgeString string `json:"max_age,omitempty"`
+	// MaxAge is how long ago a job may have started before its result is
+	// stale.
+	MaxAge time.Duration `json:"-"`
+	// MaxBaseDistance is how many commits the base branch may gain after the
+	// SHA that a job tested before its result is stale.
+	MaxBaseDistance int `json:"max_base_distance,omitempty"`
+}
+
+// Orgs returns TrustedOrg followed by TrustedOrgs, without duplicates.
+func (t *Trigger) Orgs() []string {
+	var orgs []string
+	seen := map[string]bool{}
+	for _, org := range append([]string{t.TrustedOrg}, t.TrustedOrgs...) {
+		if org == "" || seen[org] {
+			continue
+		}
+		seen[org] = true
+		orgs = append(orgs, org)
+	}
+	return orgs
+}
+
+// TrustsAnyone returns whether or not any users are trusted. If not, the
+// trigger plugin ignores the repo.
+func (t *Trigger) TrustsAnyone() bool {
+	return len(t.Orgs()) > 0 || t.TrustCollaborators || len(t.TrustedUsersFiles) > 0
 }
 
 // Heart is config for the heart plugin
@@ -102,6 +160,262 @@ type Heart struct {
 	Adorees []string `json:"adorees,omitempty"`
 }
 
+// Label is config for the label plugin.
+type Label struct {
+	// Repos is either of the form org/repos or just org.
+	Repos []string `json:"repos,omitempty"`
+	// Prefixes are the label prefixes, such as "area" or "kind", that may be
+	// added with /<prefix> and removed with /remove-<prefix>.
+	Prefixes []LabelPrefix `json:"prefixes,omitempty"`
+	// RestrictedLabels are full label names, such as "priority/critical-urgent",
+	// that may only be added or removed by org members or by users listed in
+	// the root OWNERS file of the repo.
+	RestrictedLabels []string `json:"restricted_labels,omitempty"`
+	// SigMentionPattern matches team mentions that should add a sig label. The
+	// first capture group is the sig name. The optional second capture group
+	// is the team suffix, which may imply a kind label. Leave it empty to
+	// disable sig mentions.
+	SigMentionPattern string `json:"sig_mention_pattern,omitempty"`
+	// SigLabelPrefix is the prefix of the labels that sig mentions add.
+	// Defaults to "sig".
+	SigLabelPrefix string `json:"sig_label_prefix,omitempty"`
+
+	// We'll set these when we load it.
+	labelRe       *regexp.Regexp // from Prefixes
+	removeLabelRe *regexp.Regexp // from Prefixes
+	sigMentionRe  *regexp.Regexp // from SigMentionPattern
+}
+
+// Triage is config for the triage plugin.
+type Triage struct {
+	// Repos is either of the form org/repos or just org.
+	Repos []string `json:"repos,omitempty"`
+	// Prefixes are the label prefixes, such as "sig" or "kind", that every
+	// issue should have a label for. Issues without one get a
+	// needs-<prefix> label until one is added.
+	Prefixes []string `json:"prefixes,omitempty"`
+	// Checklist, if true, comments a list of the missing labels and the
+	// commands that add them on new issues.
+	Checklist bool `json:"checklist,omitempty"`
+	// EnforceMilestone, if true, takes issues that still need labels back out
+	// of the milestone they were added to. Otherwise they only get a reminder.
+	EnforceMilestone bool `json:"enforce_milestone,omitempty"`
+}
+
+// BranchProtection is config for the branchprotector. By default it
+// updates every protected branch of every repo that has presubmits, requiring
+// the contexts of presubmits that always run, report, and are not optional.
+type BranchProtection struct {
+	// Repos is either of the form org/repos or just org. An org/repo entry
+	// takes precedence over an org entry.
+	Repos []string `json:"repos,omitempty"`
+	// Skip leaves the branch protection of these repos alone.
+	Skip bool `json:"skip,omitempty"`
+	// Branches, if set, restricts the branches that are reconciled. With
+	// --protect-new-branches, those that aren't protected yet are protected.
+	Branches []string `json:"branches,omitempty"`
+	// Contexts are required in addition to those from presubmits. This
+	// also reconciles repos without presubmits.
+	Contexts []string `json:"contexts,omitempty"`
+	// ExcludeContexts are never required, even if a presubmit reports them.
+	ExcludeContexts []string `json:"exclude_contexts,omitempty"`
+}
+
+// Lifecycle is config for the lifecycle sweeper.
+type Lifecycle struct {
+	// Repos is either of the form org/repos or just org.
+	Repos []string `json:"repos,omitempty"`
+	// StaleAfterString compiles into StaleAfter at load time.
+	StaleAfterString string `json:"stale_after,omitempty"`
+	// StaleAfter is how long an issue or PR may be inactive before it is
+	// marked stale. Defaults to 90 days.
+	StaleAfter time.Duration `json:"-"`
+	// RottenAfterString compiles into RottenAfter at load time.
+	RottenAfterString string `json:"rotten_after,omitempty"`
+	// RottenAfter is how long a stale issue or PR may be inactive before it
+	// is marked rotten. Defaults to 30 days.
+	RottenAfter time.Duration `json:"-"`
+	// CloseAfterString compiles into CloseAfter at load time.
+	CloseAfterString string `json:"close_after,omitempty"`
+	// CloseAfter is how long a rotten issue or PR may be inactive before it
+	// is closed. Defaults to 30 days.
+	CloseAfter time.Duration `json:"-"`
+	// ExemptLabels are labels that keep issues and PRs from going stale, in
+	// addition to lifecycle/frozen.
+	ExemptLabels []string `json:"exempt_labels,omitempty"`
+}
+
+// SetDurations parses the durations, falling back to the defaults for the
+// ones that aren't set. It is called automatically when loading the config.
+func (l *Lifecycle) SetDurations() error {
+	for _, d := range []struct {
+		name     string
+		value    string
+		fallback time.Duration
+		dest     *time.Duration
+	}{
+		{"stale_after", l.StaleAfterString, 90 * 24 * time.Hour, &l.StaleAfter},
+		{"rotten_after", l.RottenAfterString, 30 * 24 * time.Hour, &l.RottenAfter},
+		{"close_after", l.CloseAfterString, 30 * 24 * time.Hour, &l.CloseAfter},
+	} {
+		if d.value == "" {
+			*d.dest = d.fallback
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil {
+			return fmt.Errorf("cannot parse duration for %s: %v", d.name, err)
+		}
+		if parsed <= 0 {
+			return fmt.Errorf("%s must be positive", d.name)
+		}
+		*d.dest = parsed
+	}
+	return nil
+}
+
+// LabelPrefix is a label prefix along with who may use it.
+type LabelPrefix struct {
+	Name string `json:"name"`
+	// Users are the GitHub logins that may add or remove labels with this
+	// prefix. If empty, anyone may.
+	Users []string `json:"users,omitempty"`
+}
+
+// SetRegexes compiles the label commands and the sig mention pattern. It is
+// called automatically when loading the config.
+func (l *Label) SetRegexes() error {
+	var names []string
+	for _, p := range l.Prefixes {
+		if p.Name == "" || strings.ContainsAny(p.Name, " /") {
+			return fmt.Errorf("invalid label prefix %q", p.Name)
+		}
+		names = append(names, regexp.QuoteMeta(p.Name))
+	}
+	if len(names) > 0 {
+		// The word boundary keeps a short prefix such as "p" from
+		// swallowing a longer one such as "priority".
+		alternatives := strings.Join(names, "|")
+		l.labelRe = regexp.MustCompile(`(?m)^/(` + alternatives + `)\b\s*(.*)$`)
+		l.removeLabelRe = regexp.MustCompile(`(?m)^/remove-(` + alternatives + `)\b\s*(.*)$`)
+	} else {
+		l.labelRe = nil
+		l.removeLabelRe = nil
+	}
+	if strings.ContainsAny(l.SigLabelPrefix, " /") {
+		return fmt.Errorf("invalid sig label prefix %q", l.SigLabelPrefix)
+	}
+	l.sigMentionRe = nil
+	if l.SigMentionPattern != "" {
+		re, err := regexp.Compile(l.SigMentionPattern)
+		if err != nil {
+			return fmt.Errorf("could not compile sig mention pattern: %v", err)
+		}
+		if re.NumSubexp() < 1 {
+			return fmt.Errorf("sig mention pattern %q must have a capture group for the sig name", l.SigMentionPattern)
+		}
+		l.sigMentionRe = re
+	}
+	return nil
+}
+
+// SigLabel returns the label that a mention of the sig adds.
+func (l *Label) SigLabel(sig string) string {
+	prefix := l.SigLabelPrefix
+	if prefix == "" {
+		prefix = "sig"
+	}
+	return strings.ToLower(prefix + "/" + strings.TrimSpace(sig))
+}
+
+// LabelRegexp matches label commands such as "/area foo". It is nil if no
+// prefixes are configured.
+func (l *Label) LabelRegexp() *regexp.Regexp {
+	return l.labelRe
+}
+
+// RemoveLabelRegexp matches label removal commands such as "/remove-area foo".
+// It is nil if no prefixes are configured.
+func (l *Label) RemoveLabelRegexp() *regexp.Regexp {
+	return l.removeLabelRe
+}
+
+// SigMentionRegexp matches team mentions. It is nil if sig mentions are
+// disabled.
+func (l *Label) SigMentionRegexp() *regexp.Regexp {
+	return l.sigMentionRe
+}
+
+// Lint is config for the golint plugin, which runs linters in response to
+// /lint and leaves their findings as review comments.
+type Lint struct {
+	// Repos is either of the form org/repos or just org.
+	Repos []string `json:"repos,omitempty"`
+	// Linters are the built-in linters to run: golint, govet or gofmt. If
+	// empty, only golint runs.
+	Linters []string `json:"linters,omitempty"`
+	// External are commands that run from the root of the repo with the
+	// files to lint appended to their arguments. They must print a JSON list
+	// of {"path", "line", "message"} objects to stdout.
+	External []ExternalLinter `json:"external,omitempty"`
+}
+
+// ExternalLinter is a command that lints files.
+type ExternalLinter struct {
+	// Name identifies the linter in review comments. It may only contain
+	// letters, digits, underscores and dashes.
+	Name    string   `json:"name"`
+	Command []string `json:"command"`
+	// FilePattern matches the files that the linter understands. If empty,
+	// it runs on every modified file.
+	FilePattern string `json:"file_pattern,omitempty"`
+
+	// We'll set this when we load it.
+	re *regexp.Regexp
+}
+
+var (
+	builtinLinters = map[string]bool{"golint": true, "govet": true, "gofmt": true}
+	linterNameRe   = regexp.MustCompile(`^[\w-]+$`)
+)
+
+// Validate checks the linter names and compiles the file patterns. It is
+// called automatically when loading the config.
+func (l *Lint) Validate() error {
+	for _, name := range l.Linters {
+		if !builtinLinters[name] {
+			return fmt.Errorf("unknown linter %q", name)
+		}
+	}
+	for i := range l.External {
+		e := &l.External[i]
+		if !linterNameRe.MatchString(e.Name) {
+			return fmt.Errorf("invalid external linter name %q", e.Name)
+		}
+		if builtinLinters[e.Name] {
+			return fmt.Errorf("external linter %q has the name of a built-in linter", e.Name)
+		}
+		if len(e.Command) == 0 {
+			return fmt.Errorf("external linter %s has no command", e.Name)
+		}
+		e.re = nil
+		if e.FilePattern != "" {
+			re, err := regexp.Compile(e.FilePattern)
+			if err != nil {
+				return fmt.Errorf("could not compile file pattern for %s: %v", e.Name, err)
+			}
+			e.re = re
+		}
+	}
+	return nil
+}
+
+// FileRegexp matches the files that the linter understands. It is nil if the
+// linter runs on every file.
+func (e *ExternalLinter) FileRegexp() *regexp.Regexp {
+	return e.re
+}
+
 // SlackEvent is config for the slackevents plugin.
 // If a PR is pushed to any of the repos listed in the config
 // then sent message to the all the  slack channels listed if pusher is NOT in the whitelist.
@@ -114,6 +428,49 @@ type SlackEvent struct {
 	WhiteList []string `json:"whitelist,omitempty"`
 }
 
+// Deck is config for deck.
+type Deck struct {
+	// Admins may rerun and abort every job, including periodics, which
+	// belong to no repo.
+	Admins []string `json:"admins,omitempty"`
+	// RerunAuth says who may rerun and abort the jobs of repos. Users must
+	// be members of the org of repos without an entry.
+	RerunAuth []RerunAuth `json:"rerun_auth,omitempty"`
+}
+
+// Permissions that RerunAuth may require.
+const (
+	OrgMemberPermission    = "org_member"
+	CollaboratorPermission = "collaborator"
+	ApproverPermission     = "approver"
+)
+
+// RerunAuth says who may rerun and abort jobs from deck.
+type RerunAuth struct {
+	// Repos is either of the form org/repos or just org. An org/repo entry
+	// takes precedence over an org entry.
+	Repos []string `json:"repos,omitempty"`
+	// Permission is "org_member", "collaborator" or "approver", meaning an
+	// approver in the repo's root OWNERS file.
+	Permission string `json:"permission,omitempty"`
+}
+
+// RerunPermission returns the permission needed to rerun and abort the jobs
+// of org/repo.
+func (d Deck) RerunPermission(org, repo string) string {
+	permission := OrgMemberPermission
+	for _, ra := range d.RerunAuth {
+		for _, r := range ra.Repos {
+			if r == org+"/"+repo {
+				return ra.Permission
+			} else if r == org {
+				permission = ra.Permission
+			}
+		}
+	}
+	return permission
+}
+
 // Load loads and parses the config at path.
 func Load(path string) (*Config, error) {
 	b, err := ioutil.ReadFile(path)
@@ -138,6 +495,39 @@ func parseConfig(c *Config) error {
 		}
 	}
 
+	for i := range c.Labels {
+		if err := c.Labels[i].SetRegexes(); err != nil {
+			return fmt.Errorf("label config for %v: %v", c.Labels[i].Repos, err)
+		}
+	}
+	for _, tr := range c.Triggers {
+		if tr.StaleResults == nil || tr.StaleResults.MaxAgeString == "" {
+			continue
+		}
+		maxAge, err := time.ParseDuration(tr.StaleResults.MaxAgeString)
+		if err != nil {
+			return fmt.Errorf("cannot parse duration for max_age of %v: %v", tr.Repos, err)
+		}
+		tr.StaleResults.MaxAge = maxAge
+	}
+	for i := range c.Lifecycle {
+		if err := c.Lifecycle[i].SetDurations(); err != nil {
+			return fmt.Errorf("lifecycle config for %v: %v", c.Lifecycle[i].Repos, err)
+		}
+	}
+	for i := range c.Lint {
+		if err := c.Lint[i].Validate(); err != nil {
+			return fmt.Errorf("lint config for %v: %v", c.Lint[i].Repos, err)
+		}
+	}
+	for _, ra := range c.Deck.RerunAuth {
+		switch ra.Permission {
+		case OrgMemberPermission, CollaboratorPermission, ApproverPermission:
+		default:
+			return fmt.Errorf("deck rerun_auth for %v: unknown permission %q", ra.Repos, ra.Permission)
+		}
+	}
+
 	// Ensure that postsubmits have a pod spec.
 	for _, js := range c.Postsubmits {
 		for j := range js {
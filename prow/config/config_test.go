@@ -25,6 +25,7 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ghodss/yaml"
 	"k8s.io/test-infra/prow/kube"
@@ -691,3 +692,205 @@ func TestReportTemplate(t *testing.T) {
 	}
 
 }
+
+func TestLabelRegexes(t *testing.T) {
+	var testcases = []struct {
+		name        string
+		label       Label
+		expectError bool
+
+		body       string
+		add        bool
+		remove     bool
+		sigMention bool
+	}{
+		{
+			name:  "add command",
+			label: Label{Prefixes: []LabelPrefix{{Name: "area"}, {Name: "kind"}}},
+			body:  "/kind bug",
+			add:   true,
+		},
+		{
+			name:   "remove command",
+			label:  Label{Prefixes: []LabelPrefix{{Name: "area"}}},
+			body:   "/remove-area foo",
+			remove: true,
+		},
+		{
+			name:  "prefix is quoted",
+			label: Label{Prefixes: []LabelPrefix{{Name: "a.b"}}},
+			body:  "/axb foo",
+		},
+		{
+			name:       "sig mention",
+			label:      Label{SigMentionPattern: `@org/sig-([\w-]+)`},
+			body:       "cc @org/sig-node",
+			sigMention: true,
+		},
+		{
+			name:        "invalid prefix",
+			label:       Label{Prefixes: []LabelPrefix{{Name: "a/b"}}},
+			expectError: true,
+		},
+		{
+			name:  "prefix must end at a word boundary",
+			label: Label{Prefixes: []LabelPrefix{{Name: "p"}}},
+			body:  "/priority high",
+		},
+		{
+			name:        "invalid sig label prefix",
+			label:       Label{SigLabelPrefix: "sig/x"},
+			expectError: true,
+		},
+		{
+			name:        "invalid sig pattern",
+			label:       Label{SigMentionPattern: `(`},
+			expectError: true,
+		},
+		{
+			name:        "sig pattern without capture group",
+			label:       Label{SigMentionPattern: `@org/sig-\w+`},
+			expectError: true,
+		},
+	}
+	for _, tc := range testcases {
+		err := tc.label.SetRegexes()
+		if err != nil != tc.expectError {
+			t.Errorf("For case %s, expected error: %t, got %v", tc.name, tc.expectError, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		matches := func(re *regexp.Regexp) bool { return re != nil && re.MatchString(tc.body) }
+		if add := matches(tc.label.LabelRegexp()); add != tc.add {
+			t.Errorf("For case %s, expected add match: %t, got %t", tc.name, tc.add, add)
+		}
+		if remove := matches(tc.label.RemoveLabelRegexp()); remove != tc.remove {
+			t.Errorf("For case %s, expected remove match: %t, got %t", tc.name, tc.remove, remove)
+		}
+		if sig := matches(tc.label.SigMentionRegexp()); sig != tc.sigMention {
+			t.Errorf("For case %s, expected sig mention match: %t, got %t", tc.name, tc.sigMention, sig)
+		}
+	}
+}
+
+func TestLintValidate(t *testing.T) {
+	var testcases = []struct {
+		name        string
+		lint        Lint
+		expectError bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "built-in and external linters",
+			lint: Lint{
+				Linters:  []string{"golint", "govet", "gofmt"},
+				External: []ExternalLinter{{Name: "shell-check", Command: []string{"check"}, FilePattern: `\.sh$`}},
+			},
+		},
+		{
+			name:        "unknown built-in linter",
+			lint:        Lint{Linters: []string{"pylint"}},
+			expectError: true,
+		},
+		{
+			name:        "external linter with a bad name",
+			lint:        Lint{External: []ExternalLinter{{Name: "a b", Command: []string{"check"}}}},
+			expectError: true,
+		},
+		{
+			name:        "external linter named like a built-in one",
+			lint:        Lint{External: []ExternalLinter{{Name: "gofmt", Command: []string{"check"}}}},
+			expectError: true,
+		},
+		{
+			name:        "external linter without a command",
+			lint:        Lint{External: []ExternalLinter{{Name: "check"}}},
+			expectError: true,
+		},
+		{
+			name:        "external linter with a bad file pattern",
+			lint:        Lint{External: []ExternalLinter{{Name: "check", Command: []string{"check"}, FilePattern: "("}}},
+			expectError: true,
+		},
+	}
+	for _, tc := range testcases {
+		if err := tc.lint.Validate(); err != nil != tc.expectError {
+			t.Errorf("For case %s, expected error: %t, got %v", tc.name, tc.expectError, err)
+		}
+	}
+}
+
+func TestLifecycleDurations(t *testing.T) {
+	var testcases = []struct {
+		name        string
+		lifecycle   Lifecycle
+		stale       time.Duration
+		rotten      time.Duration
+		close       time.Duration
+		expectError bool
+	}{
+		{
+			name:   "defaults",
+			stale:  90 * 24 * time.Hour,
+			rotten: 30 * 24 * time.Hour,
+			close:  30 * 24 * time.Hour,
+		},
+		{
+			name:      "overridden",
+			lifecycle: Lifecycle{StaleAfterString: "720h", CloseAfterString: "48h"},
+			stale:     30 * 24 * time.Hour,
+			rotten:    30 * 24 * time.Hour,
+			close:     48 * time.Hour,
+		},
+		{
+			name:        "unparseable",
+			lifecycle:   Lifecycle{RottenAfterString: "30d"},
+			expectError: true,
+		},
+		{
+			name:        "negative",
+			lifecycle:   Lifecycle{StaleAfterString: "-1h"},
+			expectError: true,
+		},
+	}
+	for _, tc := range testcases {
+		err := tc.lifecycle.SetDurations()
+		if err != nil != tc.expectError {
+			t.Errorf("For case %s, expected error: %t, got %v", tc.name, tc.expectError, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if tc.lifecycle.StaleAfter != tc.stale || tc.lifecycle.RottenAfter != tc.rotten || tc.lifecycle.CloseAfter != tc.close {
+			t.Errorf("For case %s, expected %v/%v/%v, got %v/%v/%v", tc.name, tc.stale, tc.rotten, tc.close,
+				tc.lifecycle.StaleAfter, tc.lifecycle.RottenAfter, tc.lifecycle.CloseAfter)
+		}
+	}
+}
+
+func TestRerunPermission(t *testing.T) {
+	d := Deck{
+		RerunAuth: []RerunAuth{
+			{Repos: []string{"kubernetes/test-infra"}, Permission: ApproverPermission},
+			{Repos: []string{"kubernetes"}, Permission: CollaboratorPermission},
+		},
+	}
+	var testcases = []struct {
+		org, repo string
+		expected  string
+	}{
+		{"kubernetes", "test-infra", ApproverPermission},
+		{"kubernetes", "kubernetes", CollaboratorPermission},
+		{"other", "repo", OrgMemberPermission},
+	}
+	for _, tc := range testcases {
+		if p := d.RerunPermission(tc.org, tc.repo); p != tc.expected {
+			t.Errorf("For %s/%s, expected %s, got %s", tc.org, tc.repo, tc.expected, p)
+		}
+	}
+}
@@ -39,6 +39,9 @@ type Presubmit struct {
 	RerunCommand string `json:"rerun_command"`
 	// Whether or not to skip commenting and setting status on GitHub.
 	SkipReport bool `json:"skip_report"`
+	// Optional jobs report as usual but are not required by branch
+	// protection.
+	Optional bool `json:"optional"`
 	// Maximum number of this job running concurrently, 0 implies no limit.
 	MaxConcurrency int `json:"max_concurrency"`
 	// Kubernetes pod spec.
@@ -148,6 +151,66 @@ func (c *Config) MatchingPresubmits(fullRepoName, body string, testAll *regexp.R
 	return result
 }
 
+// TriggerMatches returns whether or not the comment body triggers the job.
+func (ps Presubmit) TriggerMatches(body string) bool {
+	return ps.re != nil && ps.re.MatchString(body)
+}
+
+// flatten returns the jobs along with all the jobs that run after them.
+func flatten(jobs []Presubmit) []Presubmit {
+	var res []Presubmit
+	for _, job := range jobs {
+		res = append(res, job)
+		res = append(res, flatten(job.RunAfterSuccess)...)
+	}
+	return res
+}
+
+// RepoPresubmits returns every presubmit for the repo, including those that
+// run after others succeed.
+func (c *Config) RepoPresubmits(fullRepoName string) []Presubmit {
+	return flatten(c.Presubmits[fullRepoName])
+}
+
+// NamedPresubmits resolves the arguments of a "/test" command, such as
+// "/test pull-foo-e2e all". "all" selects the jobs that always run along with
+// the jobs whose trigger matches "/test all". Any other name selects the job
+// with that name or whose trigger matches "/test <name>". Names that select
+// nothing are returned as unknown.
+func (c *Config) NamedPresubmits(fullRepoName string, names []string) (jobs []Presubmit, unknown []string) {
+	all := c.RepoPresubmits(fullRepoName)
+	seen := map[string]bool{}
+	add := func(job Presubmit) {
+		if !seen[job.Name] {
+			seen[job.Name] = true
+			jobs = append(jobs, job)
+		}
+	}
+	for _, name := range names {
+		found := false
+		if name == "all" {
+			// "all" is known even if no job always runs. Jobs that run
+			// after others succeed aren't run on their own.
+			found = true
+			for _, job := range c.Presubmits[fullRepoName] {
+				if job.AlwaysRun {
+					add(job)
+				}
+			}
+		}
+		for _, job := range all {
+			if job.Name == name || job.TriggerMatches("/test "+name) {
+				found = true
+				add(job)
+			}
+		}
+		if !found {
+			unknown = append(unknown, name)
+		}
+	}
+	return jobs, unknown
+}
+
 // RetestPresubmits returns all presubmits that should be run given a /retest command.
 // This is the set of all presubmits intersected with ((alwaysRun + runContexts) - skipContexts)
 func (c *Config) RetestPresubmits(fullRepoName string, skipContexts, runContexts map[string]bool) []Presubmit {
@@ -167,12 +230,8 @@ func (c *Config) SetPresubmits(jobs map[string][]Presubmit) error {
 	for k, v := range jobs {
 		nj[k] = make([]Presubmit, len(v))
 		copy(nj[k], v)
-		for i := range v {
-			re, err := regexp.Compile(v[i].Trigger)
-			if err != nil {
-				return err
-			}
-			nj[k][i].re = re
+		if err := setRegexes(nj[k]); err != nil {
+			return err
 		}
 	}
 	c.Presubmits = nj
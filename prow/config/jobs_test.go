@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"reflect"
 	"regexp"
 	"strings"
 	"testing"
@@ -597,3 +598,72 @@ func TestNoDuplicateJobs(t *testing.T) {
 		allJobs[j] = true
 	}
 }
+
+func TestNamedPresubmits(t *testing.T) {
+	c := &Config{}
+	if err := c.SetPresubmits(map[string][]Presubmit{
+		"org/repo": {
+			{
+				Name:      "pull-unit",
+				AlwaysRun: true,
+				Trigger:   `(?m)^/test( all| pull-unit),?(\s+|$)`,
+				RunAfterSuccess: []Presubmit{
+					{Name: "pull-after-unit", AlwaysRun: true, Trigger: `(?m)^/test pull-after-unit,?(\s+|$)`},
+				},
+			},
+			{
+				Name:    "pull-e2e",
+				Trigger: `(?m)^/test (e2e|pull-e2e),?(\s+|$)`,
+			},
+			{
+				Name:    "pull-lint",
+				Trigger: `@k8s-bot lint this`,
+			},
+		},
+	}); err != nil {
+		t.Fatalf("Setting presubmits: %v", err)
+	}
+	var testcases = []struct {
+		names   []string
+		jobs    []string
+		unknown []string
+	}{
+		{
+			names: []string{"all"},
+			jobs:  []string{"pull-unit"},
+		},
+		{
+			names: []string{"pull-lint", "e2e"},
+			jobs:  []string{"pull-lint", "pull-e2e"},
+		},
+		{
+			names: []string{"pull-e2e", "e2e"},
+			jobs:  []string{"pull-e2e"},
+		},
+		{
+			names: []string{"pull-after-unit"},
+			jobs:  []string{"pull-after-unit"},
+		},
+		{
+			names:   []string{"pull-e3e", "pull-unit"},
+			jobs:    []string{"pull-unit"},
+			unknown: []string{"pull-e3e"},
+		},
+	}
+	for _, tc := range testcases {
+		jobs, unknown := c.NamedPresubmits("org/repo", tc.names)
+		var names []string
+		for _, job := range jobs {
+			names = append(names, job.Name)
+		}
+		if !reflect.DeepEqual(names, tc.jobs) {
+			t.Errorf("For names %v, expected jobs %v, got %v", tc.names, tc.jobs, names)
+		}
+		if !reflect.DeepEqual(unknown, tc.unknown) {
+			t.Errorf("For names %v, expected unknown %v, got %v", tc.names, tc.unknown, unknown)
+		}
+	}
+	if jobs, unknown := c.NamedPresubmits("org/other", []string{"all"}); len(jobs) != 0 || len(unknown) != 0 {
+		t.Errorf("Expected all to be known but match nothing on a repo without jobs, got jobs %v and unknown %v", jobs, unknown)
+	}
+}
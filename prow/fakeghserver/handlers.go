@@ -0,0 +1,631 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fakeghserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"k8s.io/test-infra/prow/github"
+)
+
+var routes = []route{
+	{http.MethodGet, "/orgs/:org/members", listMembers},
+	{http.MethodGet, "/orgs/:org/members/:user", isMember},
+	{http.MethodGet, "/repos/:org/:repo/collaborators", listCollaborators},
+	{http.MethodGet, "/repos/:org/:repo/collaborators/:user", isCollaborator},
+	{http.MethodGet, "/repos/:org/:repo/labels", getRepoLabels},
+	{http.MethodPost, "/repos/:org/:repo/labels", addRepoLabel},
+	{http.MethodPatch, "/repos/:org/:repo/labels/*label", updateRepoLabel},
+	{http.MethodGet, "/repos/:org/:repo/issues/:number", getIssue},
+	{http.MethodPatch, "/repos/:org/:repo/issues/:number", editIssue},
+	{http.MethodGet, "/repos/:org/:repo/issues/:number/comments", listComments},
+	{http.MethodPost, "/repos/:org/:repo/issues/:number/comments", createComment},
+	{http.MethodPatch, "/repos/:org/:repo/issues/comments/:id", editComment},
+	{http.MethodDelete, "/repos/:org/:repo/issues/comments/:id", deleteComment},
+	{http.MethodPost, "/repos/:org/:repo/issues/comments/:id/reactions", createReaction},
+	{http.MethodPost, "/repos/:org/:repo/issues/:number/reactions", createReaction},
+	{http.MethodGet, "/repos/:org/:repo/issues/:number/labels", getIssueLabels},
+	{http.MethodPost, "/repos/:org/:repo/issues/:number/labels", addLabels},
+	{http.MethodDelete, "/repos/:org/:repo/issues/:number/labels/*label", removeLabel},
+	{http.MethodPost, "/repos/:org/:repo/issues/:number/assignees", assign},
+	{http.MethodDelete, "/repos/:org/:repo/issues/:number/assignees", unassign},
+	{http.MethodGet, "/repos/:org/:repo/pulls/:number", getPullRequest},
+	{http.MethodPatch, "/repos/:org/:repo/pulls/:number", editPullRequest},
+	{http.MethodGet, "/repos/:org/:repo/pulls/:number/files", getPullRequestChanges},
+	{http.MethodGet, "/repos/:org/:repo/pulls/:number/reviews", listReviews},
+	{http.MethodPost, "/repos/:org/:repo/pulls/:number/reviews", createReview},
+	{http.MethodPut, "/repos/:org/:repo/pulls/:number/merge", merge},
+	{http.MethodPost, "/repos/:org/:repo/statuses/:sha", createStatus},
+	{http.MethodGet, "/repos/:org/:repo/commits/:ref/status", getCombinedStatus},
+}
+
+var notFound = message("Not Found")
+
+func (r *repo) ghRepo() github.Repo {
+	return github.Repo{
+		Owner:    github.User{Login: r.org},
+		Name:     r.name,
+		FullName: r.org + "/" + r.name,
+		HTMLURL:  fmt.Sprintf("https://github.com/%s/%s", r.org, r.name),
+	}
+}
+
+// pullRequest returns the pull request with the fields that live on its
+// issue filled in.
+func (i *issue) pullRequest() github.PullRequest {
+	pr := *i.pr
+	pr.Assignees = i.Assignees
+	pr.Milestone = i.Milestone
+	return pr
+}
+
+// findRepo returns the repo named by the org and repo params.
+func (s *Server) findRepo(params map[string]string) (*repo, bool) {
+	r, ok := s.repos[params["org"]+"/"+params["repo"]]
+	return r, ok
+}
+
+// findIssue returns the issue named by the org, repo and number params.
+func (s *Server) findIssue(params map[string]string) (*repo, *issue, bool) {
+	r, ok := s.findRepo(params)
+	if !ok {
+		return nil, nil, false
+	}
+	n, err := strconv.Atoi(params["number"])
+	if err != nil {
+		return nil, nil, false
+	}
+	i, ok := r.issues[n]
+	return r, i, ok
+}
+
+// findComment returns the comment named by the org, repo and id params.
+func (s *Server) findComment(params map[string]string) (*repo, *issue, int, bool) {
+	r, ok := s.findRepo(params)
+	if !ok {
+		return nil, nil, 0, false
+	}
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		return nil, nil, 0, false
+	}
+	for _, i := range r.issues {
+		for n, c := range i.comments {
+			if c.ID == id {
+				return r, i, n, true
+			}
+		}
+	}
+	return nil, nil, 0, false
+}
+
+// emitIssueEvent sends the issues or pull_request webhook for a change to
+// an issue.
+func (s *Server) emitIssueEvent(r *repo, i *issue, action string, label github.Label) {
+	if i.pr != nil {
+		s.emit("pull_request", github.PullRequestEvent{
+			Action:      action,
+			Number:      i.Number,
+			PullRequest: i.pullRequest(),
+			Label:       label,
+		})
+	} else {
+		s.emit("issues", github.IssueEvent{
+			Action: action,
+			Issue:  i.Issue,
+			Repo:   r.ghRepo(),
+			Label:  label,
+		})
+	}
+}
+
+func sortedUsers(logins map[string]bool) []github.User {
+	users := []github.User{}
+	for login := range logins {
+		users = append(users, github.User{Login: login})
+	}
+	sort.Slice(users, func(a, b int) bool { return users[a].Login < users[b].Login })
+	return users
+}
+
+func listMembers(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	return http.StatusOK, sortedUsers(s.members[params["org"]])
+}
+
+func isMember(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	if s.isMember(params["org"], params["user"]) {
+		return http.StatusNoContent, nil
+	}
+	return http.StatusNotFound, notFound
+}
+
+func listCollaborators(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	r, ok := s.findRepo(params)
+	if !ok {
+		return http.StatusNotFound, notFound
+	}
+	logins := make(map[string]bool)
+	for login := range r.collaborators {
+		logins[login] = true
+	}
+	for login := range s.members[r.org] {
+		logins[login] = true
+	}
+	return http.StatusOK, sortedUsers(logins)
+}
+
+func isCollaborator(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	r, ok := s.findRepo(params)
+	if !ok || !s.isCollaborator(r, params["user"]) {
+		return http.StatusNotFound, notFound
+	}
+	return http.StatusNoContent, nil
+}
+
+func getRepoLabels(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	r, ok := s.findRepo(params)
+	if !ok {
+		return http.StatusNotFound, notFound
+	}
+	return http.StatusOK, append([]github.Label{}, r.labels...)
+}
+
+func (r *repo) label(name string) (int, bool) {
+	for n, l := range r.labels {
+		if l.Name == name {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+func addRepoLabel(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	r, ok := s.findRepo(params)
+	if !ok {
+		return http.StatusNotFound, notFound
+	}
+	var l github.Label
+	if err := json.Unmarshal(body, &l); err != nil || l.Name == "" {
+		return http.StatusBadRequest, message("Problems parsing JSON")
+	}
+	if _, exists := r.label(l.Name); exists {
+		return http.StatusUnprocessableEntity, message("Validation Failed")
+	}
+	r.labels = append(r.labels, l)
+	return http.StatusCreated, l
+}
+
+// updateRepoLabel renames or recolors a label, including on every issue that
+// has it.
+func updateRepoLabel(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	r, ok := s.findRepo(params)
+	if !ok {
+		return http.StatusNotFound, notFound
+	}
+	n, ok := r.label(params["label"])
+	if !ok {
+		return http.StatusNotFound, notFound
+	}
+	var update github.Label
+	if err := json.Unmarshal(body, &update); err != nil {
+		return http.StatusBadRequest, message("Problems parsing JSON")
+	}
+	old := r.labels[n]
+	if update.Name != "" {
+		r.labels[n].Name = update.Name
+	}
+	if update.Color != "" {
+		r.labels[n].Color = update.Color
+	}
+	for _, i := range r.issues {
+		for m, l := range i.Labels {
+			if l.Name == old.Name {
+				i.Labels[m] = r.labels[n]
+			}
+		}
+	}
+	return http.StatusOK, r.labels[n]
+}
+
+func getIssue(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	_, i, ok := s.findIssue(params)
+	if !ok {
+		return http.StatusNotFound, notFound
+	}
+	return http.StatusOK, i.Issue
+}
+
+// edit applies an issue or pull request edit: a new state or milestone.
+func (s *Server) edit(r *repo, i *issue, body []byte) bool {
+	var edit map[string]json.RawMessage
+	if err := json.Unmarshal(body, &edit); err != nil {
+		return false
+	}
+	if raw, ok := edit["milestone"]; ok {
+		var number *int
+		if err := json.Unmarshal(raw, &number); err != nil {
+			return false
+		}
+		if number == nil {
+			i.Milestone = nil
+		} else {
+			i.Milestone = &github.Milestone{Number: *number}
+		}
+	}
+	if raw, ok := edit["state"]; ok {
+		var state string
+		if err := json.Unmarshal(raw, &state); err != nil {
+			return false
+		}
+		if state != i.State {
+			if i.pr != nil && i.pr.Merged {
+				return false
+			}
+			i.State = state
+			action := "reopened"
+			if state == "closed" {
+				action = "closed"
+			}
+			s.emitIssueEvent(r, i, action, github.Label{})
+		}
+	}
+	return true
+}
+
+func editIssue(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	r, i, ok := s.findIssue(params)
+	if !ok {
+		return http.StatusNotFound, notFound
+	}
+	if !s.edit(r, i, body) {
+		return http.StatusUnprocessableEntity, message("Validation Failed")
+	}
+	return http.StatusOK, i.Issue
+}
+
+func editPullRequest(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	r, i, ok := s.findIssue(params)
+	if !ok || i.pr == nil {
+		return http.StatusNotFound, notFound
+	}
+	if !s.edit(r, i, body) {
+		return http.StatusUnprocessableEntity, message("Validation Failed")
+	}
+	return http.StatusOK, i.pullRequest()
+}
+
+func listComments(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	_, i, ok := s.findIssue(params)
+	if !ok {
+		return http.StatusNotFound, notFound
+	}
+	return http.StatusOK, append([]github.IssueComment{}, i.comments...)
+}
+
+func createComment(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	r, i, ok := s.findIssue(params)
+	if !ok {
+		return http.StatusNotFound, notFound
+	}
+	var c github.IssueComment
+	if err := json.Unmarshal(body, &c); err != nil {
+		return http.StatusBadRequest, message("Problems parsing JSON")
+	}
+	s.nextCommentID++
+	c.ID = s.nextCommentID
+	c.User = github.User{Login: s.BotName}
+	c.HTMLURL = fmt.Sprintf("%s#issuecomment-%d", i.HTMLURL, c.ID)
+	i.comments = append(i.comments, c)
+	s.emit("issue_comment", github.IssueCommentEvent{
+		Action:  "created",
+		Issue:   i.Issue,
+		Comment: c,
+		Repo:    r.ghRepo(),
+	})
+	return http.StatusCreated, c
+}
+
+func editComment(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	r, i, n, ok := s.findComment(params)
+	if !ok {
+		return http.StatusNotFound, notFound
+	}
+	var edit github.IssueComment
+	if err := json.Unmarshal(body, &edit); err != nil {
+		return http.StatusBadRequest, message("Problems parsing JSON")
+	}
+	i.comments[n].Body = edit.Body
+	s.emit("issue_comment", github.IssueCommentEvent{
+		Action:  "edited",
+		Issue:   i.Issue,
+		Comment: i.comments[n],
+		Repo:    r.ghRepo(),
+	})
+	return http.StatusOK, i.comments[n]
+}
+
+func deleteComment(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	r, i, n, ok := s.findComment(params)
+	if !ok {
+		return http.StatusNotFound, notFound
+	}
+	c := i.comments[n]
+	i.comments = append(i.comments[:n], i.comments[n+1:]...)
+	s.emit("issue_comment", github.IssueCommentEvent{
+		Action:  "deleted",
+		Issue:   i.Issue,
+		Comment: c,
+		Repo:    r.ghRepo(),
+	})
+	return http.StatusNoContent, nil
+}
+
+// createReaction accepts reactions without keeping them. Nothing in prow
+// reads them back.
+func createReaction(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	var reaction github.Reaction
+	if err := json.Unmarshal(body, &reaction); err != nil {
+		return http.StatusBadRequest, message("Problems parsing JSON")
+	}
+	return http.StatusCreated, reaction
+}
+
+func getIssueLabels(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	_, i, ok := s.findIssue(params)
+	if !ok {
+		return http.StatusNotFound, notFound
+	}
+	return http.StatusOK, append([]github.Label{}, i.Labels...)
+}
+
+// addLabels adds labels to an issue, creating the ones the repo doesn't have
+// yet just like GitHub does.
+func addLabels(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	r, i, ok := s.findIssue(params)
+	if !ok {
+		return http.StatusNotFound, notFound
+	}
+	var names []string
+	if err := json.Unmarshal(body, &names); err != nil {
+		return http.StatusBadRequest, message("Problems parsing JSON")
+	}
+	for _, name := range names {
+		if i.HasLabel(name) {
+			continue
+		}
+		n, exists := r.label(name)
+		if !exists {
+			r.labels = append(r.labels, github.Label{Name: name, Color: "ededed"})
+			n = len(r.labels) - 1
+		}
+		i.Labels = append(i.Labels, r.labels[n])
+		s.emitIssueEvent(r, i, "labeled", r.labels[n])
+	}
+	return http.StatusOK, append([]github.Label{}, i.Labels...)
+}
+
+func removeLabel(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	r, i, ok := s.findIssue(params)
+	if !ok {
+		return http.StatusNotFound, notFound
+	}
+	for n, l := range i.Labels {
+		if l.Name == params["label"] {
+			i.Labels = append(i.Labels[:n], i.Labels[n+1:]...)
+			s.emitIssueEvent(r, i, "unlabeled", l)
+			return http.StatusOK, append([]github.Label{}, i.Labels...)
+		}
+	}
+	return http.StatusNotFound, message("Label does not exist")
+}
+
+func parseAssignees(body []byte) ([]string, bool) {
+	var req struct {
+		Assignees []string `json:"assignees"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, false
+	}
+	return req.Assignees, true
+}
+
+// assign assigns the users that can be assigned, which are collaborators,
+// and silently ignores the rest like GitHub does.
+func assign(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	r, i, ok := s.findIssue(params)
+	if !ok {
+		return http.StatusNotFound, notFound
+	}
+	logins, ok := parseAssignees(body)
+	if !ok {
+		return http.StatusBadRequest, message("Problems parsing JSON")
+	}
+	for _, login := range logins {
+		if i.IsAssignee(login) || !s.isCollaborator(r, login) {
+			continue
+		}
+		i.Assignees = append(i.Assignees, github.User{Login: login})
+		s.emitIssueEvent(r, i, "assigned", github.Label{})
+	}
+	return http.StatusCreated, i.Issue
+}
+
+func unassign(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	r, i, ok := s.findIssue(params)
+	if !ok {
+		return http.StatusNotFound, notFound
+	}
+	logins, ok := parseAssignees(body)
+	if !ok {
+		return http.StatusBadRequest, message("Problems parsing JSON")
+	}
+	for _, login := range logins {
+		for n, a := range i.Assignees {
+			if a.Login == login {
+				i.Assignees = append(i.Assignees[:n], i.Assignees[n+1:]...)
+				s.emitIssueEvent(r, i, "unassigned", github.Label{})
+				break
+			}
+		}
+	}
+	return http.StatusOK, i.Issue
+}
+
+func getPullRequest(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	_, i, ok := s.findIssue(params)
+	if !ok || i.pr == nil {
+		return http.StatusNotFound, notFound
+	}
+	return http.StatusOK, i.pullRequest()
+}
+
+func getPullRequestChanges(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	_, i, ok := s.findIssue(params)
+	if !ok || i.pr == nil {
+		return http.StatusNotFound, notFound
+	}
+	return http.StatusOK, append([]github.PullRequestChange{}, i.changes...)
+}
+
+func listReviews(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	_, i, ok := s.findIssue(params)
+	if !ok || i.pr == nil {
+		return http.StatusNotFound, notFound
+	}
+	return http.StatusOK, append([]github.Review{}, i.reviews...)
+}
+
+// reviewStates maps the actions of draft reviews to the states of the
+// reviews they create. A draft without an action stays pending.
+var reviewStates = map[github.ReviewAction]string{
+	github.Approve:        "APPROVED",
+	github.RequestChanges: "CHANGES_REQUESTED",
+	github.Comment:        "COMMENTED",
+	"":                    "PENDING",
+}
+
+func createReview(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	r, i, ok := s.findIssue(params)
+	if !ok || i.pr == nil {
+		return http.StatusNotFound, notFound
+	}
+	var draft github.DraftReview
+	if err := json.Unmarshal(body, &draft); err != nil {
+		return http.StatusBadRequest, message("Problems parsing JSON")
+	}
+	state, ok := reviewStates[draft.Action]
+	if !ok {
+		return http.StatusUnprocessableEntity, message("Validation Failed")
+	}
+	s.nextReviewID++
+	review := github.Review{
+		ID:      s.nextReviewID,
+		User:    github.User{Login: s.BotName},
+		Body:    draft.Body,
+		State:   state,
+		HTMLURL: fmt.Sprintf("%s#pullrequestreview-%d", i.pr.HTMLURL, s.nextReviewID),
+	}
+	i.reviews = append(i.reviews, review)
+	if state != "PENDING" {
+		s.emit("pull_request_review", github.ReviewEvent{
+			Action:      "submitted",
+			PullRequest: i.pullRequest(),
+			Repo:        r.ghRepo(),
+			Review:      review,
+		})
+	}
+	return http.StatusOK, review
+}
+
+// merge merges an open, mergeable pull request whose head matches the SHA
+// asked for, if any. The merge commit's SHA is made up.
+func merge(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	r, i, ok := s.findIssue(params)
+	if !ok || i.pr == nil {
+		return http.StatusNotFound, notFound
+	}
+	var details github.MergeDetails
+	if err := json.Unmarshal(body, &details); err != nil {
+		return http.StatusBadRequest, message("Problems parsing JSON")
+	}
+	if i.State != "open" || (i.pr.Mergeable != nil && !*i.pr.Mergeable) {
+		return http.StatusMethodNotAllowed, message("Pull Request is not mergeable")
+	}
+	if details.SHA != "" && details.SHA != i.pr.Head.SHA {
+		return http.StatusConflict, message("Head branch was modified. Review and try the merge again.")
+	}
+	sha := fmt.Sprintf("merge-%s", i.pr.Head.SHA)
+	i.pr.Merged = true
+	i.pr.MergeSHA = &sha
+	i.State = "closed"
+	s.emitIssueEvent(r, i, "closed", github.Label{})
+	return http.StatusOK, map[string]interface{}{
+		"sha":     sha,
+		"merged":  true,
+		"message": "Pull Request successfully merged",
+	}
+}
+
+func createStatus(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	r, ok := s.findRepo(params)
+	if !ok {
+		return http.StatusNotFound, notFound
+	}
+	var status github.Status
+	if err := json.Unmarshal(body, &status); err != nil {
+		return http.StatusBadRequest, message("Problems parsing JSON")
+	}
+	if status.Context == "" {
+		status.Context = "default"
+	}
+	sha := params["sha"]
+	r.statuses[sha] = append(r.statuses[sha], status)
+	s.emit("status", github.StatusEvent{
+		SHA:         sha,
+		State:       status.State,
+		Description: status.Description,
+		TargetURL:   status.TargetURL,
+		Context:     status.Context,
+		Sender:      github.User{Login: s.BotName},
+		Repo:        r.ghRepo(),
+	})
+	return http.StatusCreated, status
+}
+
+// getCombinedStatus returns the latest status for each context, in the order
+// the contexts were first seen.
+func getCombinedStatus(s *Server, params map[string]string, body []byte) (int, interface{}) {
+	r, ok := s.findRepo(params)
+	if !ok {
+		return http.StatusNotFound, notFound
+	}
+	combined := github.CombinedStatus{Statuses: []github.Status{}}
+	index := make(map[string]int)
+	for _, status := range r.statuses[params["ref"]] {
+		if n, seen := index[status.Context]; seen {
+			combined.Statuses[n] = status
+		} else {
+			index[status.Context] = len(combined.Statuses)
+			combined.Statuses = append(combined.Statuses, status)
+		}
+	}
+	return http.StatusOK, combined
+}
@@ -0,0 +1,458 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fakeghserver is an in-memory GitHub API for hermetic end-to-end
+// tests of hook and its plugins. It keeps repos, issues, pull requests,
+// comments, labels, statuses and reviews, and like GitHub it sends a signed
+// webhook to hook whenever one of them changes. Events that GitHub users would
+// cause, such as opening a PR or commenting, are fed in with SendEvent or by
+// pointing phony at the server's /hook path.
+package fakeghserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/phony"
+)
+
+// Server implements http.Handler. Point a github.Client at it with
+// SetEndpoint.
+type Server struct {
+	// HookURL is where webhooks are sent. If empty, none are sent.
+	HookURL string
+	// HMACSecret signs outgoing webhooks and validates events sent to /hook.
+	HMACSecret []byte
+	// BotName is the user that every API call is made as.
+	BotName string
+
+	lock          sync.Mutex
+	repos         map[string]*repo
+	members       map[string]map[string]bool
+	nextCommentID int
+	nextReviewID  int
+	// pending holds the webhooks caused by the request being served. They
+	// are sent once the lock is released so that hook may call back in.
+	pending []webhook
+
+	// inFlight and lastActivity let WaitForIdle tell when hook is done.
+	inFlight     int
+	lastActivity time.Time
+}
+
+type webhook struct {
+	eventType string
+	payload   []byte
+}
+
+type repo struct {
+	org           string
+	name          string
+	labels        []github.Label
+	collaborators map[string]bool
+	issues        map[int]*issue
+	// statuses maps SHAs to their statuses, oldest first.
+	statuses map[string][]github.Status
+}
+
+// issue is an issue or, if pr is set, a pull request. As on GitHub, labels,
+// assignees, state and comments of a pull request live on its issue.
+type issue struct {
+	github.Issue
+	pr       *github.PullRequest
+	comments []github.IssueComment
+	reviews  []github.Review
+	changes  []github.PullRequestChange
+}
+
+// NewServer returns an empty server that sends webhooks to hookURL.
+func NewServer(botName, hookURL string, hmacSecret []byte) *Server {
+	return &Server{
+		HookURL:    hookURL,
+		HMACSecret: hmacSecret,
+		BotName:    botName,
+		repos:      make(map[string]*repo),
+		members:    make(map[string]map[string]bool),
+	}
+}
+
+// AddRepo creates an empty repo. It is a no-op if the repo exists.
+func (s *Server) AddRepo(org, name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.repo(org, name)
+}
+
+// AddMember makes login a member of org, and so a collaborator on its repos.
+func (s *Server) AddMember(org, login string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.members[org] == nil {
+		s.members[org] = make(map[string]bool)
+	}
+	s.members[org][login] = true
+}
+
+// AddCollaborator makes login a collaborator on org/name.
+func (s *Server) AddCollaborator(org, name, login string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.repo(org, name).collaborators[login] = true
+}
+
+// SetPullRequestChanges sets the files that a pull request changes. Webhooks
+// don't carry them, so tests set them up separately.
+func (s *Server) SetPullRequestChanges(org, name string, number int, changes []github.PullRequestChange) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	i, ok := s.repo(org, name).issues[number]
+	if !ok || i.pr == nil {
+		return fmt.Errorf("%s/%s#%d is not a pull request", org, name, number)
+	}
+	i.changes = changes
+	return nil
+}
+
+// repo returns org/name, creating it if need be. The lock must be held.
+func (s *Server) repo(org, name string) *repo {
+	key := org + "/" + name
+	r, ok := s.repos[key]
+	if !ok {
+		r = &repo{
+			org:           org,
+			name:          name,
+			collaborators: make(map[string]bool),
+			issues:        make(map[int]*issue),
+			statuses:      make(map[string][]github.Status),
+		}
+		s.repos[key] = r
+	}
+	return r
+}
+
+func (s *Server) isMember(org, login string) bool {
+	return s.members[org][login]
+}
+
+func (s *Server) isCollaborator(r *repo, login string) bool {
+	return r.collaborators[login] || s.isMember(r.org, login)
+}
+
+// ServeHTTP serves the GitHub API, plus events from phony on /hook.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	s.begin()
+	defer s.end()
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, message("Failed to read request body."))
+		return
+	}
+	if r.URL.Path == "/hook" {
+		s.serveEvent(w, r, body)
+		return
+	}
+
+	h, params := match(r.Method, r.URL.Path)
+	if h == nil {
+		logrus.Warningf("Fake GitHub does not implement %s %s.", r.Method, r.URL.Path)
+		writeJSON(w, http.StatusNotFound, notFound)
+		return
+	}
+	s.lock.Lock()
+	code, ret := h(s, params, body)
+	// Marshal while locked since responses may share slices with the state.
+	b, err := json.Marshal(ret)
+	pending := s.pending
+	s.pending = nil
+	s.lock.Unlock()
+
+	s.deliver(pending)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, message(err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	if ret != nil {
+		w.Write(b)
+	}
+}
+
+// serveEvent handles an event sent by phony as though it was sent to hook.
+func (s *Server) serveEvent(w http.ResponseWriter, r *http.Request, body []byte) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "405 Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !github.ValidatePayload(body, r.Header.Get("X-Hub-Signature"), s.HMACSecret) {
+		http.Error(w, "403 Forbidden: Invalid X-Hub-Signature", http.StatusForbidden)
+		return
+	}
+	if err := s.SendEvent(r.Header.Get("X-GitHub-Event"), body); err != nil {
+		http.Error(w, fmt.Sprintf("400 Bad Request: %v", err), http.StatusBadRequest)
+		return
+	}
+	fmt.Fprint(w, "Event received. Have a nice day.")
+}
+
+// SendEvent records the effects of a GitHub event, such as a PR being opened
+// or a comment being made, and then sends the event to hook unchanged.
+// Events it doesn't understand are sent along without being recorded.
+func (s *Server) SendEvent(eventType string, payload []byte) error {
+	s.begin()
+	defer s.end()
+
+	s.lock.Lock()
+	err := s.apply(eventType, payload)
+	s.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.send(eventType, payload)
+}
+
+func (s *Server) apply(eventType string, payload []byte) error {
+	switch eventType {
+	case "issues":
+		var ie github.IssueEvent
+		if err := json.Unmarshal(payload, &ie); err != nil {
+			return err
+		}
+		s.upsertIssue(ie.Repo, ie.Issue)
+	case "issue_comment":
+		var ic github.IssueCommentEvent
+		if err := json.Unmarshal(payload, &ic); err != nil {
+			return err
+		}
+		i := s.upsertIssue(ic.Repo, ic.Issue)
+		if ic.Action == "created" {
+			if ic.Comment.ID == 0 {
+				s.nextCommentID++
+				ic.Comment.ID = s.nextCommentID
+			} else if ic.Comment.ID > s.nextCommentID {
+				s.nextCommentID = ic.Comment.ID
+			}
+			i.comments = append(i.comments, ic.Comment)
+		}
+	case "pull_request":
+		var pe github.PullRequestEvent
+		if err := json.Unmarshal(payload, &pe); err != nil {
+			return err
+		}
+		pr := pe.PullRequest
+		if pr.Number == 0 {
+			pr.Number = pe.Number
+		}
+		r := s.repo(pr.Base.Repo.Owner.Login, pr.Base.Repo.Name)
+		i, ok := r.issues[pr.Number]
+		if !ok {
+			i = &issue{Issue: github.Issue{
+				Number:      pr.Number,
+				State:       "open",
+				HTMLURL:     fmt.Sprintf("https://github.com/%s/%s/pull/%d", r.org, r.name, pr.Number),
+				PullRequest: &struct{}{},
+			}}
+			r.issues[pr.Number] = i
+		}
+		i.User = pr.User
+		i.Title = pr.Title
+		i.Body = pr.Body
+		switch pe.Action {
+		case "closed":
+			i.State = "closed"
+		case "opened", "reopened":
+			i.State = "open"
+		}
+		if pr.HTMLURL == "" {
+			pr.HTMLURL = i.HTMLURL
+		}
+		i.pr = &pr
+	case "pull_request_review":
+		var re github.ReviewEvent
+		if err := json.Unmarshal(payload, &re); err != nil {
+			return err
+		}
+		r := s.repo(re.Repo.Owner.Login, re.Repo.Name)
+		if i, ok := r.issues[re.PullRequest.Number]; ok && re.Action == "submitted" {
+			i.reviews = append(i.reviews, re.Review)
+		}
+	case "status":
+		var se github.StatusEvent
+		if err := json.Unmarshal(payload, &se); err != nil {
+			return err
+		}
+		r := s.repo(se.Repo.Owner.Login, se.Repo.Name)
+		r.statuses[se.SHA] = append(r.statuses[se.SHA], github.Status{
+			State:       se.State,
+			TargetURL:   se.TargetURL,
+			Description: se.Description,
+			Context:     se.Context,
+		})
+	}
+	return nil
+}
+
+// upsertIssue stores the issue from an event, keeping the comments and so on
+// of an issue that already exists.
+func (s *Server) upsertIssue(gr github.Repo, gi github.Issue) *issue {
+	r := s.repo(gr.Owner.Login, gr.Name)
+	if gi.HTMLURL == "" {
+		kind := "issues"
+		if gi.IsPullRequest() {
+			kind = "pull"
+		}
+		gi.HTMLURL = fmt.Sprintf("https://github.com/%s/%s/%s/%d", r.org, r.name, kind, gi.Number)
+	}
+	i, ok := r.issues[gi.Number]
+	if !ok {
+		i = &issue{}
+		r.issues[gi.Number] = i
+	}
+	i.Issue = gi
+	return i
+}
+
+// WaitForIdle waits until neither hook nor a test has called the server for
+// the quiet period, which is as close as we can get to knowing that hook has
+// finished handling every webhook.
+func (s *Server) WaitForIdle(quiet, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		s.lock.Lock()
+		idle := s.inFlight == 0 && time.Since(s.lastActivity) >= quiet
+		s.lock.Unlock()
+		if idle {
+			return nil
+		}
+		time.Sleep(quiet / 10)
+	}
+	return fmt.Errorf("server still busy after %v", timeout)
+}
+
+func (s *Server) begin() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.inFlight++
+}
+
+func (s *Server) end() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.inFlight--
+	s.lastActivity = time.Now()
+}
+
+// emit queues a webhook to send once the current request is handled. The
+// lock must be held, and the payload is marshalled right away so that it
+// shows the state as of the change.
+func (s *Server) emit(eventType string, payload interface{}) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		logrus.WithError(err).Errorf("Error marshalling %s webhook.", eventType)
+		return
+	}
+	s.pending = append(s.pending, webhook{eventType: eventType, payload: b})
+}
+
+func (s *Server) deliver(hooks []webhook) {
+	for _, h := range hooks {
+		if err := s.send(h.eventType, h.payload); err != nil {
+			logrus.WithError(err).Errorf("Error sending %s webhook.", h.eventType)
+		}
+	}
+}
+
+func (s *Server) send(eventType string, payload []byte) error {
+	if s.HookURL == "" {
+		return nil
+	}
+	return phony.SendHook(s.HookURL, eventType, payload, s.HMACSecret)
+}
+
+type handler func(s *Server, params map[string]string, body []byte) (int, interface{})
+
+type route struct {
+	method  string
+	pattern string
+	handler handler
+}
+
+// match finds the handler for a request.
+func match(method, path string) (handler, map[string]string) {
+	segments := splitPath(path)
+	for _, r := range routes {
+		if r.method != method {
+			continue
+		}
+		if params, ok := matchPattern(r.pattern, segments); ok {
+			return r.handler, params
+		}
+	}
+	return nil, nil
+}
+
+// matchPattern matches path segments against a pattern. Pattern segments
+// starting with ':' match any one segment, and a last segment starting with
+// '*' matches the rest of the path, which label names with slashes need.
+func matchPattern(pattern string, segments []string) (map[string]string, bool) {
+	parts := splitPath(pattern)
+	params := make(map[string]string)
+	for n, p := range parts {
+		if n >= len(segments) {
+			return nil, false
+		}
+		switch {
+		case strings.HasPrefix(p, "*"):
+			params[p[1:]] = strings.Join(segments[n:], "/")
+			return params, true
+		case strings.HasPrefix(p, ":"):
+			params[p[1:]] = segments[n]
+		case p != segments[n]:
+			return nil, false
+		}
+	}
+	return params, len(parts) == len(segments)
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+type apiMessage struct {
+	Message string `json:"message"`
+}
+
+func message(msg string) apiMessage {
+	return apiMessage{Message: msg}
+}
+
+func writeJSON(w http.ResponseWriter, code int, ret interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(ret); err != nil {
+		logrus.WithError(err).Error("Error writing response.")
+	}
+}
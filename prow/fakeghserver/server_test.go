@@ -0,0 +1,341 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fakeghserver
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/hook"
+	"k8s.io/test-infra/prow/plugins"
+	_ "k8s.io/test-infra/prow/plugins/hold"
+)
+
+var secret = []byte("abcde12345")
+
+func TestMatchPattern(t *testing.T) {
+	var testcases = []struct {
+		name    string
+		pattern string
+		path    string
+		matched bool
+		params  map[string]string
+	}{
+		{
+			name:    "literal",
+			pattern: "/orgs/:org/members",
+			path:    "/orgs/kubernetes/members",
+			matched: true,
+			params:  map[string]string{"org": "kubernetes"},
+		},
+		{
+			name:    "too short",
+			pattern: "/orgs/:org/members/:user",
+			path:    "/orgs/kubernetes/members",
+		},
+		{
+			name:    "too long",
+			pattern: "/orgs/:org/members",
+			path:    "/orgs/kubernetes/members/bob",
+		},
+		{
+			name:    "literal mismatch",
+			pattern: "/repos/:org/:repo/issues/comments/:id",
+			path:    "/repos/k/t/issues/5/comments",
+		},
+		{
+			name:    "rest of path",
+			pattern: "/repos/:org/:repo/issues/:number/labels/*label",
+			path:    "/repos/k/t/issues/5/labels/do-not-merge/hold",
+			matched: true,
+			params:  map[string]string{"org": "k", "repo": "t", "number": "5", "label": "do-not-merge/hold"},
+		},
+	}
+	for _, tc := range testcases {
+		params, matched := matchPattern(tc.pattern, splitPath(tc.path))
+		if matched != tc.matched {
+			t.Errorf("For case %s, expected matched %t, got %t.", tc.name, tc.matched, matched)
+		} else if matched && !reflect.DeepEqual(params, tc.params) {
+			t.Errorf("For case %s, expected params %v, got %v.", tc.name, tc.params, params)
+		}
+	}
+}
+
+// recorder is a stand-in for hook that records the webhooks it gets.
+type recorder struct {
+	sync.Mutex
+	t      *testing.T
+	events []string
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	payload, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		r.t.Errorf("Reading webhook: %v", err)
+	}
+	if !github.ValidatePayload(payload, req.Header.Get("X-Hub-Signature"), secret) {
+		r.t.Errorf("Invalid signature on %s webhook.", req.Header.Get("X-GitHub-Event"))
+	}
+	var action struct {
+		Action string `json:"action"`
+	}
+	json.Unmarshal(payload, &action)
+	r.Lock()
+	defer r.Unlock()
+	r.events = append(r.events, req.Header.Get("X-GitHub-Event")+" "+action.Action)
+}
+
+func (r *recorder) take() []string {
+	r.Lock()
+	defer r.Unlock()
+	events := r.events
+	r.events = nil
+	return events
+}
+
+func openPR(t *testing.T, s *Server, number int, title, sha string) {
+	payload, err := json.Marshal(github.PullRequestEvent{
+		Action: "opened",
+		Number: number,
+		PullRequest: github.PullRequest{
+			Number: number,
+			Title:  title,
+			User:   github.User{Login: "author"},
+			Base: github.PullRequestBranch{
+				Ref:  "master",
+				Repo: github.Repo{Owner: github.User{Login: "k"}, Name: "t"},
+			},
+			Head: github.PullRequestBranch{SHA: sha},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Marshalling PR event: %v", err)
+	}
+	if err := s.SendEvent("pull_request", payload); err != nil {
+		t.Fatalf("Opening PR: %v", err)
+	}
+}
+
+func TestAPI(t *testing.T) {
+	rec := &recorder{t: t}
+	hookServer := httptest.NewServer(rec)
+	defer hookServer.Close()
+	s := NewServer("k8s-ci-robot", hookServer.URL, secret)
+	ghServer := httptest.NewServer(s)
+	defer ghServer.Close()
+	gc := github.NewClient("k8s-ci-robot", "")
+	gc.SetEndpoint(ghServer.URL)
+
+	s.AddMember("k", "alice")
+	openPR(t, s, 5, "Fix things", "abc")
+
+	if err := gc.CreateComment("k", "t", 5, "hello"); err != nil {
+		t.Fatalf("Creating comment: %v", err)
+	}
+	comments, err := gc.ListIssueComments("k", "t", 5)
+	if err != nil {
+		t.Fatalf("Listing comments: %v", err)
+	}
+	if len(comments) != 1 || comments[0].Body != "hello" || comments[0].User.Login != "k8s-ci-robot" {
+		t.Errorf("Wrong comments: %+v", comments)
+	}
+	if err := gc.EditComment("k", "t", comments[0].ID, "goodbye"); err != nil {
+		t.Fatalf("Editing comment: %v", err)
+	}
+	if err := gc.DeleteComment("k", "t", comments[0].ID); err != nil {
+		t.Fatalf("Deleting comment: %v", err)
+	}
+
+	if err := gc.AddLabel("k", "t", 5, "do-not-merge/hold"); err != nil {
+		t.Fatalf("Adding label: %v", err)
+	}
+	if err := gc.AddLabel("k", "t", 5, "lgtm"); err != nil {
+		t.Fatalf("Adding label: %v", err)
+	}
+	if err := gc.RemoveLabel("k", "t", 5, "do-not-merge/hold"); err != nil {
+		t.Fatalf("Removing label: %v", err)
+	}
+	labels, err := gc.GetIssueLabels("k", "t", 5)
+	if err != nil {
+		t.Fatalf("Getting labels: %v", err)
+	}
+	if len(labels) != 1 || labels[0].Name != "lgtm" {
+		t.Errorf("Expected only lgtm, got %+v", labels)
+	}
+	repoLabels, err := gc.GetRepoLabels("k", "t")
+	if err != nil {
+		t.Fatalf("Getting repo labels: %v", err)
+	}
+	if len(repoLabels) != 2 {
+		t.Errorf("Expected both labels to be created on the repo, got %+v", repoLabels)
+	}
+
+	if err := gc.AssignIssue("k", "t", 5, []string{"alice", "mallory"}); err == nil {
+		t.Error("Expected an error assigning a non-collaborator.")
+	}
+
+	for _, state := range []string{github.StatusPending, github.StatusSuccess} {
+		if err := gc.CreateStatus("k", "t", "abc", github.Status{State: state, Context: "test"}); err != nil {
+			t.Fatalf("Creating status: %v", err)
+		}
+	}
+	combined, err := gc.GetCombinedStatus("k", "t", "abc")
+	if err != nil {
+		t.Fatalf("Getting combined status: %v", err)
+	}
+	if len(combined.Statuses) != 1 || combined.Statuses[0].State != github.StatusSuccess {
+		t.Errorf("Expected the latest status only, got %+v", combined.Statuses)
+	}
+
+	if err := gc.CreateReview("k", "t", 5, github.DraftReview{Body: "nice", Action: github.Approve}); err != nil {
+		t.Fatalf("Creating review: %v", err)
+	}
+	reviews, err := gc.ListReviews("k", "t", 5)
+	if err != nil {
+		t.Fatalf("Listing reviews: %v", err)
+	}
+	if len(reviews) != 1 || reviews[0].State != "APPROVED" {
+		t.Errorf("Wrong reviews: %+v", reviews)
+	}
+
+	if err := gc.Merge("k", "t", 5, github.MergeDetails{SHA: "def"}); err == nil {
+		t.Error("Expected an error merging the wrong SHA.")
+	} else if _, ok := err.(github.ModifiedHeadError); !ok {
+		t.Errorf("Expected a ModifiedHeadError, got %v", err)
+	}
+	if err := gc.Merge("k", "t", 5, github.MergeDetails{SHA: "abc"}); err != nil {
+		t.Fatalf("Merging: %v", err)
+	}
+	pr, err := gc.GetPullRequest("k", "t", 5)
+	if err != nil {
+		t.Fatalf("Getting PR: %v", err)
+	}
+	if !pr.Merged {
+		t.Error("Expected the PR to be merged.")
+	}
+
+	expected := []string{
+		"pull_request opened",
+		"issue_comment created",
+		"issue_comment edited",
+		"issue_comment deleted",
+		"pull_request labeled",
+		"pull_request labeled",
+		"pull_request unlabeled",
+		"pull_request assigned",
+		"status ",
+		"status ",
+		"pull_request_review submitted",
+		"pull_request closed",
+	}
+	if events := rec.take(); !reflect.DeepEqual(events, expected) {
+		t.Errorf("Expected webhooks %v, got %v", expected, events)
+	}
+}
+
+// TestHook runs hook with the hold plugin against the server: a comment
+// makes hold add a label, and GitHub's labeled webhook goes back to hook.
+func TestHook(t *testing.T) {
+	pa := &plugins.PluginAgent{}
+	if err := pa.Set(map[string][]string{"k/t": {"hold"}}); err != nil {
+		t.Fatalf("Setting plugins: %v", err)
+	}
+	hookServer := httptest.NewServer(&hook.Server{
+		Plugins:     pa,
+		ConfigAgent: &config.Agent{},
+		HMACSecret:  secret,
+	})
+	defer hookServer.Close()
+	s := NewServer("k8s-ci-robot", hookServer.URL, secret)
+	ghServer := httptest.NewServer(s)
+	defer ghServer.Close()
+	gc := github.NewClient("k8s-ci-robot", "")
+	gc.SetEndpoint(ghServer.URL)
+	pa.PluginClient = plugins.PluginClient{GitHubClient: gc, Logger: logrus.NewEntry(logrus.StandardLogger())}
+
+	openPR(t, s, 1, "WIP: Fix things", "abc")
+	if err := s.WaitForIdle(200*time.Millisecond, 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if labels := labelNames(t, gc); !reflect.DeepEqual(labels, []string{"do-not-merge/work-in-progress"}) {
+		t.Errorf("Expected the WIP label after opening, got %v", labels)
+	}
+
+	comment(t, s, gc, "/hold")
+	if err := s.WaitForIdle(200*time.Millisecond, 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if labels := labelNames(t, gc); !reflect.DeepEqual(labels, []string{"do-not-merge/work-in-progress", "do-not-merge/hold"}) {
+		t.Errorf("Expected the hold label after /hold, got %v", labels)
+	}
+
+	comment(t, s, gc, "/hold cancel")
+	if err := s.WaitForIdle(200*time.Millisecond, 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if labels := labelNames(t, gc); !reflect.DeepEqual(labels, []string{"do-not-merge/work-in-progress"}) {
+		t.Errorf("Expected no hold label after /hold cancel, got %v", labels)
+	}
+}
+
+func labelNames(t *testing.T, gc *github.Client) []string {
+	labels, err := gc.GetIssueLabels("k", "t", 1)
+	if err != nil {
+		t.Fatalf("Getting labels: %v", err)
+	}
+	var names []string
+	for _, l := range labels {
+		names = append(names, l.Name)
+	}
+	return names
+}
+
+// comment sends the event for a user commenting on PR 1, with the PR's
+// current labels as GitHub would.
+func comment(t *testing.T, s *Server, gc *github.Client, body string) {
+	labels, err := gc.GetIssueLabels("k", "t", 1)
+	if err != nil {
+		t.Fatalf("Getting labels: %v", err)
+	}
+	payload, err := json.Marshal(github.IssueCommentEvent{
+		Action: "created",
+		Issue: github.Issue{
+			Number:      1,
+			State:       "open",
+			Labels:      labels,
+			PullRequest: &struct{}{},
+		},
+		Comment: github.IssueComment{Body: body, User: github.User{Login: "author"}},
+		Repo:    github.Repo{Owner: github.User{Login: "k"}, Name: "t"},
+	})
+	if err != nil {
+		t.Fatalf("Marshalling comment event: %v", err)
+	}
+	if err := s.SendEvent("issue_comment", payload); err != nil {
+		t.Fatalf("Sending comment: %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ghcache caches GitHub API responses. Every GET is sent as a
+// conditional request with the ETag or Last-Modified of the cached copy, and
+// a 304 Not Modified reply is answered from the cache. GitHub doesn't count
+// those against the rate limit. Responses are cached separately for each
+// token, so one token never sees what another token fetched, but all tokens
+// share one size budget and the least recently used responses are evicted
+// first.
+package ghcache
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gregjones/httpcache"
+	"github.com/peterbourgon/diskv"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var cacheResponses = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "prow_ghcache_responses_total",
+	Help: "GitHub responses by how the cache served them.",
+}, []string{"mode"})
+
+func init() {
+	prometheus.MustRegister(cacheResponses)
+}
+
+// Cache modes for the prow_ghcache_responses_total metric.
+const (
+	// ModeRevalidated means GitHub replied 304 and the cached copy was used.
+	ModeRevalidated = "revalidated"
+	// ModeChanged means GitHub sent a new copy, which is now cached.
+	ModeChanged = "changed"
+	// ModeSkipped means the request isn't cacheable, such as a POST.
+	ModeSkipped = "skipped"
+	// ModeError means the request failed.
+	ModeError = "error"
+)
+
+// NewMemCache returns a RoundTripper that caches up to maxMB megabytes of
+// responses in memory and sends requests on with delegate, or
+// http.DefaultTransport if nil.
+func NewMemCache(delegate http.RoundTripper, maxMB int) http.RoundTripper {
+	return newTransport(delegate, newLRUCache(httpcache.NewMemoryCache(), maxMB))
+}
+
+// NewDiskCache returns a RoundTripper that caches up to maxMB megabytes of
+// responses under dir and sends requests on with delegate, or
+// http.DefaultTransport if nil. Responses cached by a previous run count
+// against maxMB too.
+func NewDiskCache(delegate http.RoundTripper, dir string, maxMB int) http.RoundTripper {
+	d := diskv.New(diskv.Options{BasePath: dir})
+	c := newLRUCache(diskStore{d}, maxMB)
+	for key := range d.Keys(nil) {
+		if fi, err := os.Stat(filepath.Join(dir, key)); err == nil {
+			c.add(key, int(fi.Size()))
+		}
+	}
+	return newTransport(delegate, c)
+}
+
+func newTransport(delegate http.RoundTripper, cache *lruCache) *transport {
+	if delegate == nil {
+		delegate = http.DefaultTransport
+	}
+	return &transport{
+		delegate: delegate,
+		cache:    cache,
+	}
+}
+
+type transport struct {
+	delegate http.RoundTripper
+	// cache holds the responses for every token, so they all share the same
+	// size budget, and tokens that are no longer used simply age out.
+	cache *lruCache
+}
+
+// partition returns the cache for the token that authorizes req.
+func (t *transport) partition(req *http.Request) *httpcache.Transport {
+	p := httpcache.NewTransport(partitionCache{
+		cache:     t.cache,
+		partition: req.Header.Get("Authorization"),
+	})
+	p.Transport = t.delegate
+	return p
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// GitHub marks responses fresh for 60 seconds, but we would rather ask
+	// again every time: a conditional request is free, and stale mergeability
+	// or statuses are confusing.
+	r := new(http.Request)
+	*r = *req
+	r.Header = http.Header{}
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	r.Header.Set("Cache-Control", "max-age=0")
+
+	resp, err := t.partition(r).RoundTrip(r)
+	switch {
+	case err != nil:
+		cacheResponses.WithLabelValues(ModeError).Inc()
+	case r.Method != http.MethodGet:
+		cacheResponses.WithLabelValues(ModeSkipped).Inc()
+	case resp.Header.Get(httpcache.XFromCache) != "":
+		cacheResponses.WithLabelValues(ModeRevalidated).Inc()
+	default:
+		cacheResponses.WithLabelValues(ModeChanged).Inc()
+	}
+	return resp, err
+}
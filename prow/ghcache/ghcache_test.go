@@ -0,0 +1,171 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ghcache
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gregjones/httpcache"
+)
+
+// fakeGitHub serves a body that depends on the token, with an ETag, and
+// replies 304 when the client already has it.
+type fakeGitHub struct {
+	version     int
+	requests    int
+	notModified int
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests++
+	etag := fmt.Sprintf(`"%s-%d"`, r.Header.Get("Authorization"), f.version)
+	if r.Header.Get("If-None-Match") == etag {
+		f.notModified++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, max-age=60, s-maxage=60")
+	w.Header().Set("Vary", "Accept, Authorization")
+	fmt.Fprintf(w, "%s v%d", r.Header.Get("Authorization"), f.version)
+}
+
+func get(t *testing.T, c *http.Client, url, token string) string {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	req.Header.Set("Authorization", token)
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("Error getting %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Error reading body: %v", err)
+	}
+	return string(b)
+}
+
+func testCache(t *testing.T, newCache func() http.RoundTripper, persistent bool) {
+	f := &fakeGitHub{}
+	ts := httptest.NewServer(f)
+	defer ts.Close()
+	c := &http.Client{Transport: newCache()}
+
+	if b := get(t, c, ts.URL, "token a"); b != "token a v0" {
+		t.Errorf("Wrong first body: %q", b)
+	}
+	// Fresh according to max-age, but we still ask.
+	if b := get(t, c, ts.URL, "token a"); b != "token a v0" {
+		t.Errorf("Wrong cached body: %q", b)
+	}
+	if f.requests != 2 || f.notModified != 1 {
+		t.Errorf("Expected 2 requests with 1 not modified, got %d and %d", f.requests, f.notModified)
+	}
+	// Another token doesn't see the first token's copy.
+	if b := get(t, c, ts.URL, "token b"); b != "token b v0" {
+		t.Errorf("Wrong body for other token: %q", b)
+	}
+	f.version++
+	if b := get(t, c, ts.URL, "token a"); b != "token a v1" {
+		t.Errorf("Wrong body after change: %q", b)
+	}
+	if f.notModified != 1 {
+		t.Errorf("Expected the changed response not to come from the cache")
+	}
+	// Only the disk cache survives a restart.
+	c = &http.Client{Transport: newCache()}
+	get(t, c, ts.URL, "token a")
+	if persistent && f.notModified != 2 {
+		t.Errorf("Expected the disk cache to be reused after a restart")
+	} else if !persistent && f.notModified != 1 {
+		t.Errorf("Expected the memory cache to start empty")
+	}
+}
+
+func TestMemCache(t *testing.T) {
+	testCache(t, func() http.RoundTripper { return NewMemCache(nil, 10) }, false)
+}
+
+func TestDiskCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ghcache")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	testCache(t, func() http.RoundTripper { return NewDiskCache(nil, dir, 10) }, true)
+}
+
+func TestLRUCache(t *testing.T) {
+	c := newLRUCache(httpcache.NewMemoryCache(), 1)
+	a := partitionCache{cache: c, partition: "token a"}
+	b := partitionCache{cache: c, partition: "token b"}
+	half := make([]byte, 400000)
+	a.Set("x", half)
+	b.Set("x", half)
+	if _, ok := a.Get("x"); !ok {
+		t.Fatal("Expected token a's copy to be cached")
+	}
+	// Token b's copy is now the least recently used, and the budget is
+	// shared between the tokens.
+	b.Set("y", half)
+	if _, ok := b.Get("x"); ok {
+		t.Error("Expected token b's first copy to be evicted")
+	}
+	if _, ok := a.Get("x"); !ok {
+		t.Error("Expected token a's copy to still be cached")
+	}
+	if _, ok := b.Get("y"); !ok {
+		t.Error("Expected token b's second copy to be cached")
+	}
+	if c.size != 800000 || c.order.Len() != 2 {
+		t.Errorf("Expected 2 entries of 800000 bytes in total, got %d of %d bytes", c.order.Len(), c.size)
+	}
+}
+
+func TestDiskCacheSeeded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ghcache")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"a", "b"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), make([]byte, 600000), 0644); err != nil {
+			t.Fatalf("Error writing %s: %v", name, err)
+		}
+	}
+	// Responses left by a previous run count against the budget.
+	NewDiskCache(nil, dir, 1)
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Error reading dir: %v", err)
+	}
+	if len(files) != 1 {
+		t.Errorf("Expected one response to be evicted, got %d left", len(files))
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ghcache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	"github.com/gregjones/httpcache"
+	"github.com/peterbourgon/diskv"
+)
+
+// partitionCache keeps the responses for one token apart from the others in
+// a shared cache. Keys are hashed so that they are safe file names and don't
+// reveal the token.
+type partitionCache struct {
+	cache     *lruCache
+	partition string
+}
+
+func (p partitionCache) key(key string) string {
+	sum := sha256.Sum256([]byte(p.partition + "\n" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (p partitionCache) Get(key string) ([]byte, bool) {
+	return p.cache.Get(p.key(key))
+}
+
+func (p partitionCache) Set(key string, b []byte) {
+	p.cache.Set(p.key(key), b)
+}
+
+func (p partitionCache) Delete(key string) {
+	p.cache.Delete(p.key(key))
+}
+
+// lruCache bounds the size of a cache by deleting the least recently used
+// responses.
+type lruCache struct {
+	cache    httpcache.Cache
+	maxBytes int
+
+	lock    sync.Mutex
+	size    int
+	order   *list.List // of *lruEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key  string
+	size int
+}
+
+func newLRUCache(cache httpcache.Cache, maxMB int) *lruCache {
+	return &lruCache{
+		cache:    cache,
+		maxBytes: maxMB * 1000000,
+		order:    list.New(),
+		entries:  map[string]*list.Element{},
+	}
+}
+
+func (c *lruCache) Get(key string) ([]byte, bool) {
+	b, ok := c.cache.Get(key)
+	if ok {
+		c.add(key, len(b))
+	}
+	return b, ok
+}
+
+func (c *lruCache) Set(key string, b []byte) {
+	c.cache.Set(key, b)
+	c.add(key, len(b))
+}
+
+func (c *lruCache) Delete(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.removeLocked(key)
+	c.cache.Delete(key)
+}
+
+// add marks the key as the most recently used, with a response of the given
+// size, and evicts responses until the cache fits in its budget again.
+func (c *lruCache) add(key string, size int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.removeLocked(key)
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, size: size})
+	c.size += size
+	for c.size > c.maxBytes && c.order.Len() > 0 {
+		e := c.order.Back().Value.(*lruEntry)
+		c.removeLocked(e.key)
+		c.cache.Delete(e.key)
+	}
+}
+
+func (c *lruCache) removeLocked(key string) {
+	if el, ok := c.entries[key]; ok {
+		c.size -= el.Value.(*lruEntry).size
+		c.order.Remove(el)
+		delete(c.entries, key)
+	}
+}
+
+// diskStore is an httpcache.Cache on disk.
+type diskStore struct {
+	d *diskv.Diskv
+}
+
+func (s diskStore) Get(key string) ([]byte, bool) {
+	b, err := s.d.Read(key)
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+func (s diskStore) Set(key string, b []byte) {
+	s.d.Write(key, b)
+}
+
+func (s diskStore) Delete(key string) {
+	s.d.Erase(key)
+}
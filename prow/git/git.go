@@ -23,6 +23,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -44,13 +45,21 @@ type Client struct {
 	// base is the base path for git clone calls. For users it will be set to
 	// GitHub, but for tests set it to a directory with git repos.
 	base string
+	// user and token are used to push to user's forks. Pushing fails if user
+	// is not set. The token may be empty when pushing to local repos.
+	user  string
+	token string
 
 	// The mutex protects repoLocks which protect individual repos. This is
 	// necessary because Clone calls for the same repo are racy. Rather than
-	// one lock for all repos, use a lock per repo.
+	// one lock for all repos, use a lock per repo. Dry-run copies share them.
 	// Lock with Client.lockRepo, unlock with Client.unlockRepo.
-	rlm       sync.Mutex
+	rlm       *sync.Mutex
 	repoLocks map[string]*sync.Mutex
+
+	// If dry is set, repos call onSkip, if it is not nil, instead of pushing.
+	dry    bool
+	onSkip func(method, path string, body interface{})
 }
 
 // Clean removes the local repo cache. The Client is unusable after calling.
@@ -73,10 +82,21 @@ func NewClient() (*Client, error) {
 		dir:       t,
 		git:       g,
 		base:      github,
+		rlm:       &sync.Mutex{},
 		repoLocks: make(map[string]*sync.Mutex),
 	}, nil
 }
 
+// DryRunCopy returns a copy of the client that shares its cache, but whose
+// repos call onSkip with the fork and branch instead of pushing. Don't Clean
+// the copy, since that removes the cache.
+func (c *Client) DryRunCopy(onSkip func(method, path string, body interface{})) *Client {
+	nc := *c
+	nc.dry = true
+	nc.onSkip = onSkip
+	return &nc
+}
+
 // SetRemote sets the remote for the client. This is not thread-safe, and is
 // useful for testing. The client will clone from remote/org/repo, and Repo
 // objects spun out of the client will also hit that path.
@@ -84,6 +104,13 @@ func (c *Client) SetRemote(remote string) {
 	c.base = remote
 }
 
+// SetCredentials sets the GitHub user and token used to push. Repos can only
+// push to the user's fork. This is not thread-safe.
+func (c *Client) SetCredentials(user, token string) {
+	c.user = user
+	c.token = token
+}
+
 func (c *Client) lockRepo(repo string) {
 	c.rlm.Lock()
 	if _, ok := c.repoLocks[repo]; !ok {
@@ -143,6 +170,10 @@ func (c *Client) Clone(repo string) (*Repo, error) {
 		git:    c.git,
 		base:   c.base,
 		repo:   repo,
+		user:   c.user,
+		token:  c.token,
+		dry:    c.dry,
+		onSkip: c.onSkip,
 	}, nil
 }
 
@@ -158,6 +189,12 @@ type Repo struct {
 	base string
 	// repo is the full repo name: "org/repo".
 	repo string
+	// user and token are used to push to user's fork of repo.
+	user  string
+	token string
+	// If dry is set, Push calls onSkip, if it is not nil, instead.
+	dry    bool
+	onSkip func(method, path string, body interface{})
 
 	logger *logrus.Entry
 }
@@ -186,6 +223,102 @@ func (r *Repo) CheckoutPullRequest(number int) error {
 	return nil
 }
 
+// Checkout runs git checkout.
+func (r *Repo) Checkout(commitlike string) error {
+	r.logger.Infof("Checkout %s.", commitlike)
+	co := r.gitCommand("checkout", commitlike)
+	if b, err := co.CombinedOutput(); err != nil {
+		return fmt.Errorf("error checking out %s: %v. output: %s", commitlike, err, string(b))
+	}
+	return nil
+}
+
+// CheckoutNewBranch creates a new branch from HEAD and checks it out.
+func (r *Repo) CheckoutNewBranch(branch string) error {
+	r.logger.Infof("Create and checkout %s.", branch)
+	co := r.gitCommand("checkout", "-b", branch)
+	if b, err := co.CombinedOutput(); err != nil {
+		return fmt.Errorf("error checking out new branch %s: %v. output: %s", branch, err, string(b))
+	}
+	return nil
+}
+
+// MergeBase returns the best common ancestor of the two commits.
+func (r *Repo) MergeBase(a, b string) (string, error) {
+	out, err := r.gitCommand("merge-base", a, b).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("error finding the merge base of %s and %s: %v. output: %s", a, b, err, string(out))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// RevList returns the non-merge commits in the range, such as "base..head",
+// oldest first.
+func (r *Repo) RevList(rng string) ([]string, error) {
+	b, err := r.gitCommand("rev-list", "--reverse", "--no-merges", rng).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("error listing commits in %s: %v. output: %s", rng, err, string(b))
+	}
+	return strings.Fields(string(b)), nil
+}
+
+// CherryPick applies the commits on top of HEAD, in order. If one of them
+// doesn't apply cleanly, the cherry-pick is aborted and an error returned.
+func (r *Repo) CherryPick(commits ...string) error {
+	r.logger.Infof("Cherry-picking %d commits.", len(commits))
+	// The cherry-picked commits keep their authors but are committed by us.
+	args := []string{
+		"-c", "user.name=" + r.committer(),
+		"-c", "user.email=" + r.committer() + "@users.noreply.github.com",
+		"cherry-pick",
+	}
+	cp := r.gitCommand(append(args, commits...)...)
+	if b, err := cp.CombinedOutput(); err != nil {
+		if ab, aerr := r.gitCommand("cherry-pick", "--abort").CombinedOutput(); aerr != nil {
+			r.logger.WithError(aerr).Warningf("Aborting cherry-pick failed: %s", string(ab))
+		}
+		return fmt.Errorf("error cherry-picking: %v. output: %s", err, string(b))
+	}
+	return nil
+}
+
+func (r *Repo) committer() string {
+	if r.user == "" {
+		return "prow"
+	}
+	return r.user
+}
+
+// Push pushes HEAD to the branch in the user's fork of the repo, replacing
+// whatever is there.
+func (r *Repo) Push(branch string) error {
+	if r.user == "" {
+		return fmt.Errorf("no credentials to push %s with", branch)
+	}
+	name := r.repo[strings.Index(r.repo, "/")+1:]
+	if r.dry {
+		if r.onSkip != nil {
+			r.onSkip("push", r.user+"/"+name, branch)
+		}
+		return nil
+	}
+	remote := fmt.Sprintf("%s/%s/%s", r.base, r.user, name)
+	if r.token != "" && strings.HasPrefix(remote, "https://") {
+		remote = fmt.Sprintf("https://%s:%s@%s", r.user, r.token, strings.TrimPrefix(remote, "https://"))
+	}
+	r.logger.Infof("Pushing to %s/%s (branch: %s).", r.user, name, branch)
+	b, err := r.gitCommand("push", "--force", remote, "HEAD:refs/heads/"+branch).CombinedOutput()
+	if err != nil {
+		// Don't leak the token into logs or comments.
+		out := string(b)
+		if r.token != "" {
+			out = strings.Replace(out, r.token, "CENSORED", -1)
+		}
+		return fmt.Errorf("error pushing %s: %v. output: %s", branch, err, out)
+	}
+	return nil
+}
+
 // retryCmd will retry the command a few times with backoff. Use this for any
 // commands that will be talking to GitHub, such as clones or fetches.
 func retryCmd(l *logrus.Entry, dir, cmd string, arg ...string) ([]byte, error) {
@@ -133,3 +133,144 @@ func TestCheckoutPR(t *testing.T) {
 		t.Errorf("Didn't find file in PR after checking out: %v", err)
 	}
 }
+
+func TestCherryPickAndPush(t *testing.T) {
+	lg, c, err := localgit.New()
+	if err != nil {
+		t.Fatalf("Making local git repo: %v", err)
+	}
+	defer func() {
+		if err := lg.Clean(); err != nil {
+			t.Errorf("Error cleaning LocalGit: %v", err)
+		}
+		if err := c.Clean(); err != nil {
+			t.Errorf("Error cleaning Client: %v", err)
+		}
+	}()
+	if err := lg.MakeFakeRepo("foo", "bar"); err != nil {
+		t.Fatalf("Making fake repo: %v", err)
+	}
+	if err := lg.MakeFakeRepo("bot", "bar"); err != nil {
+		t.Fatalf("Making fake fork: %v", err)
+	}
+	if err := lg.CheckoutNewBranch("foo", "bar", "release-1.0"); err != nil {
+		t.Fatalf("Checkout new branch: %v", err)
+	}
+	if err := lg.CheckoutNewBranch("foo", "bar", "pull/5/head"); err != nil {
+		t.Fatalf("Checkout new branch: %v", err)
+	}
+	if err := lg.AddCommit("foo", "bar", map[string][]byte{"fix": []byte("fixed")}); err != nil {
+		t.Fatalf("Add commit: %v", err)
+	}
+
+	c.SetCredentials("bot", "")
+	r, err := c.Clone("foo/bar")
+	if err != nil {
+		t.Fatalf("Cloning: %v", err)
+	}
+	defer func() {
+		if err := r.Clean(); err != nil {
+			t.Errorf("Cleaning repo: %v", err)
+		}
+	}()
+	if err := r.CheckoutPullRequest(5); err != nil {
+		t.Fatalf("Checking out PR: %v", err)
+	}
+	commits, err := r.RevList("origin/release-1.0..pull5")
+	if err != nil {
+		t.Fatalf("Listing commits: %v", err)
+	}
+	if len(commits) != 1 {
+		t.Fatalf("Expected one commit in the PR, got %v", commits)
+	}
+	if err := r.Checkout("origin/release-1.0"); err != nil {
+		t.Fatalf("Checking out release branch: %v", err)
+	}
+	if err := r.CheckoutNewBranch("cherry-pick"); err != nil {
+		t.Fatalf("Creating branch: %v", err)
+	}
+	if err := r.CherryPick(commits...); err != nil {
+		t.Fatalf("Cherry-picking: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(r.Dir, "fix")); err != nil {
+		t.Errorf("Didn't find file from PR after cherry-picking: %v", err)
+	}
+	if err := r.Push("cherry-pick"); err != nil {
+		t.Fatalf("Pushing: %v", err)
+	}
+	show := exec.Command("git", "show", "cherry-pick:fix")
+	show.Dir = filepath.Join(lg.Dir, "bot", "bar")
+	if b, err := show.CombinedOutput(); err != nil {
+		t.Errorf("Pushed branch is missing the fix: %v, %s", err, string(b))
+	} else if string(b) != "fixed" {
+		t.Errorf("Expected pushed fix to contain \"fixed\", got %q", string(b))
+	}
+}
+
+func TestCherryPickConflict(t *testing.T) {
+	lg, c, err := localgit.New()
+	if err != nil {
+		t.Fatalf("Making local git repo: %v", err)
+	}
+	defer func() {
+		if err := lg.Clean(); err != nil {
+			t.Errorf("Error cleaning LocalGit: %v", err)
+		}
+		if err := c.Clean(); err != nil {
+			t.Errorf("Error cleaning Client: %v", err)
+		}
+	}()
+	if err := lg.MakeFakeRepo("foo", "bar"); err != nil {
+		t.Fatalf("Making fake repo: %v", err)
+	}
+	if err := lg.CheckoutNewBranch("foo", "bar", "pull/5/head"); err != nil {
+		t.Fatalf("Checkout new branch: %v", err)
+	}
+	if err := lg.AddCommit("foo", "bar", map[string][]byte{"initial": []byte("pull")}); err != nil {
+		t.Fatalf("Add commit: %v", err)
+	}
+	co := exec.Command("git", "checkout", "master")
+	co.Dir = filepath.Join(lg.Dir, "foo", "bar")
+	if b, err := co.CombinedOutput(); err != nil {
+		t.Fatalf("Checking out master: %v, %s", err, string(b))
+	}
+	if err := lg.CheckoutNewBranch("foo", "bar", "release-1.0"); err != nil {
+		t.Fatalf("Checkout new branch: %v", err)
+	}
+	if err := lg.AddCommit("foo", "bar", map[string][]byte{"initial": []byte("release")}); err != nil {
+		t.Fatalf("Add commit: %v", err)
+	}
+
+	r, err := c.Clone("foo/bar")
+	if err != nil {
+		t.Fatalf("Cloning: %v", err)
+	}
+	defer func() {
+		if err := r.Clean(); err != nil {
+			t.Errorf("Cleaning repo: %v", err)
+		}
+	}()
+	if err := r.CheckoutPullRequest(5); err != nil {
+		t.Fatalf("Checking out PR: %v", err)
+	}
+	commits, err := r.RevList("origin/master..pull5")
+	if err != nil {
+		t.Fatalf("Listing commits: %v", err)
+	}
+	if err := r.Checkout("origin/release-1.0"); err != nil {
+		t.Fatalf("Checking out release branch: %v", err)
+	}
+	if err := r.CherryPick(commits...); err == nil {
+		t.Error("Expected the cherry-pick to conflict.")
+	}
+	status := exec.Command("git", "status", "--porcelain")
+	status.Dir = r.Dir
+	if b, err := status.CombinedOutput(); err != nil {
+		t.Errorf("git status: %v, %s", err, string(b))
+	} else if len(b) != 0 {
+		t.Errorf("Expected a clean tree after the failed cherry-pick, got %s", string(b))
+	}
+	if err := r.Push("cherry-pick"); err == nil {
+		t.Error("Expected pushing without credentials to fail.")
+	}
+}
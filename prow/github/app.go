@@ -0,0 +1,347 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// appAccept is needed while the GitHub App API is in preview.
+	appAccept = "application/vnd.github.machine-man-preview+json"
+	// tokenRefreshMargin is how long before they expire that installation
+	// tokens are replaced, so that a token never expires mid-request.
+	tokenRefreshMargin = 5 * time.Minute
+	// installationMissTTL is how long we remember that the app isn't
+	// installed on an account before listing the installations again.
+	installationMissTTL = time.Minute
+)
+
+var timeNow = time.Now
+
+// appAuth mints and caches installation tokens for a GitHub App. It is shared
+// by a client and all of its copies.
+type appAuth struct {
+	id  int
+	key *rsa.PrivateKey
+
+	lock sync.Mutex
+	// installations maps the login of each account the app is installed on
+	// to the installation ID.
+	installations map[string]int
+	// misses maps the accounts that we found no installation for to when we
+	// last looked.
+	misses map[string]time.Time
+	// listing is the listing of installations in progress, if any.
+	listing *flight
+	// tokens maps installation IDs to their current token.
+	tokens map[int]installationToken
+	// minting maps installation IDs to the token being minted for them.
+	minting map[int]*flight
+}
+
+// flight is a request to GitHub that concurrent callers wait for instead of
+// making it again. The lock is not held while it is in the air.
+type flight struct {
+	done chan struct{}
+	// token is the result of minting a token.
+	token installationToken
+	err   error
+}
+
+type installationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type installation struct {
+	ID      int  `json:"id"`
+	Account User `json:"account"`
+}
+
+// UseApp makes the client authenticate as the GitHub App with the given ID
+// instead of with a token. Each request uses the token of the installation on
+// the org or user that owns the repo it is about. privateKey is the app's
+// PEM-encoded RSA key.
+func (c *Client) UseApp(appID int, privateKey []byte) error {
+	block, _ := pem.Decode(privateKey)
+	if block == nil {
+		return errors.New("no PEM data found in private key")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("error parsing private key: %v", err)
+	}
+	c.app = &appAuth{
+		id:            appID,
+		key:           key,
+		installations: map[string]int{},
+		misses:        map[string]time.Time{},
+		tokens:        map[int]installationToken{},
+		minting:       map[int]*flight{},
+	}
+	return nil
+}
+
+// jwt returns a JSON Web Token that authenticates as the app itself.
+func (a *appAuth) jwt() (string, error) {
+	now := timeNow()
+	claims, err := json.Marshal(map[string]interface{}{
+		// Backdate a little in case our clock is ahead of GitHub's.
+		"iat": now.Add(-time.Minute).Unix(),
+		// GitHub accepts at most ten minutes.
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": a.id,
+	})
+	if err != nil {
+		return "", err
+	}
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	payload := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(payload))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return payload + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// authorization returns the Authorization header for a request about the
+// org or user account. The account is only needed with an app.
+func (c *Client) authorization(account string) (string, error) {
+	if c.app == nil {
+		return "Token " + c.token, nil
+	}
+	token, err := c.installationToken(account)
+	if err != nil {
+		return "", err
+	}
+	return "token " + token, nil
+}
+
+// accountOf returns the org or user that a request is about, such as "k8s"
+// for /repos/k8s/kuber/issues. It returns the empty string if it can't tell.
+func accountOf(path string) string {
+	u, err := url.Parse(path)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+	if len(parts) >= 2 && (parts[0] == "repos" || parts[0] == "orgs" || parts[0] == "users") {
+		return parts[1]
+	}
+	return ""
+}
+
+// searchAccount returns the org or user that a search query is scoped to,
+// such as "k8s" for "repo:k8s/kuber is:open", or the empty string if it isn't.
+// It returns an error if the query spans several accounts.
+func searchAccount(query string) (string, error) {
+	var account string
+	for _, term := range strings.Fields(query) {
+		for _, prefix := range []string{"repo:", "org:", "user:"} {
+			if !strings.HasPrefix(term, prefix) {
+				continue
+			}
+			a := strings.SplitN(strings.TrimPrefix(term, prefix), "/", 2)[0]
+			if account != "" && !strings.EqualFold(a, account) {
+				return "", fmt.Errorf("query %q spans both %s and %s", query, account, a)
+			}
+			account = a
+		}
+	}
+	return account, nil
+}
+
+// installationToken returns a valid token for the installation on account,
+// minting a new one if needed. If account is empty and the app only has one
+// installation, that one is used. Concurrent callers share one mint.
+func (c *Client) installationToken(account string) (string, error) {
+	id, err := c.installationID(account)
+	if err != nil {
+		return "", err
+	}
+	a := c.app
+	a.lock.Lock()
+	if t, ok := a.tokens[id]; ok && timeNow().Add(tokenRefreshMargin).Before(t.ExpiresAt) {
+		a.lock.Unlock()
+		return t.Token, nil
+	}
+	f, inFlight := a.minting[id]
+	if !inFlight {
+		f = &flight{done: make(chan struct{})}
+		a.minting[id] = f
+	}
+	a.lock.Unlock()
+	if inFlight {
+		<-f.done
+		return f.token.Token, f.err
+	}
+
+	err = c.appRequest(http.MethodPost, fmt.Sprintf("%s/installations/%d/access_tokens", c.base, id), &f.token)
+	a.lock.Lock()
+	delete(a.minting, id)
+	if err != nil {
+		f.err = fmt.Errorf("error creating token for installation %d: %v", id, err)
+	} else {
+		a.tokens[id] = f.token
+	}
+	a.lock.Unlock()
+	close(f.done)
+	return f.token.Token, f.err
+}
+
+// installationID returns the ID of the installation on account. It lists the
+// installations again if it doesn't know the account, since the app may have
+// been installed somewhere new, but not more than once per
+// installationMissTTL for the same account.
+func (c *Client) installationID(account string) (int, error) {
+	a := c.app
+	a.lock.Lock()
+	if id, ok := a.lookupInstallation(account); ok {
+		a.lock.Unlock()
+		return id, nil
+	}
+	if t, ok := a.misses[account]; ok && timeNow().Before(t.Add(installationMissTTL)) {
+		a.lock.Unlock()
+		return 0, a.missError(account)
+	}
+	f := a.listing
+	inFlight := f != nil
+	if !inFlight {
+		f = &flight{done: make(chan struct{})}
+		a.listing = f
+	}
+	a.lock.Unlock()
+
+	if inFlight {
+		<-f.done
+	} else {
+		installations, err := c.listInstallations()
+		a.lock.Lock()
+		a.listing = nil
+		if err != nil {
+			f.err = fmt.Errorf("error listing installations: %v", err)
+		} else {
+			a.installations = installations
+		}
+		a.lock.Unlock()
+		close(f.done)
+	}
+	if f.err != nil {
+		return 0, f.err
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if id, ok := a.lookupInstallation(account); ok {
+		return id, nil
+	}
+	a.misses[account] = timeNow()
+	return 0, a.missError(account)
+}
+
+func (a *appAuth) missError(account string) error {
+	if account == "" {
+		return fmt.Errorf("can't tell which of the %d installations to use", len(a.installations))
+	}
+	return fmt.Errorf("app %d is not installed on %s", a.id, account)
+}
+
+func (a *appAuth) lookupInstallation(account string) (int, bool) {
+	if account == "" {
+		if len(a.installations) != 1 {
+			return 0, false
+		}
+		for _, id := range a.installations {
+			return id, true
+		}
+	}
+	id, ok := a.installations[strings.ToLower(account)]
+	return id, ok
+}
+
+// listInstallations returns the installation ID of each account that the app
+// is installed on.
+func (c *Client) listInstallations() (map[string]int, error) {
+	installations := map[string]int{}
+	nextURL := fmt.Sprintf("%s/app/installations?per_page=100", c.base)
+	for nextURL != "" {
+		var page []installation
+		next, err := c.appRequestPage(http.MethodGet, nextURL, &page)
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range page {
+			installations[strings.ToLower(i.Account.Login)] = i.ID
+		}
+		nextURL = next
+	}
+	return installations, nil
+}
+
+func (c *Client) appRequest(method, path string, ret interface{}) error {
+	_, err := c.appRequestPage(method, path, ret)
+	return err
+}
+
+// appRequestPage makes a request as the app itself, and returns the URL of
+// the next page, if any. These requests don't use installation tokens, so
+// they skip throttling and retries.
+func (c *Client) appRequestPage(method, path string, ret interface{}) (string, error) {
+	jwt, err := c.app.jwt()
+	if err != nil {
+		return "", fmt.Errorf("error signing JWT: %v", err)
+	}
+	req, err := http.NewRequest(method, path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", appAccept)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("return code not 2XX: %s, body: %s", resp.Status, string(b))
+	}
+	if err := json.Unmarshal(b, ret); err != nil {
+		return "", err
+	}
+	return parseLinks(resp.Header.Get("Link"))["next"], nil
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAccountOf(t *testing.T) {
+	var testcases = []struct {
+		path    string
+		account string
+	}{
+		{"/repos/k8s/kuber/issues/5/labels", "k8s"},
+		{"/orgs/k8s/members/person", "k8s"},
+		{"/repositories/1234/issues?page=2", ""},
+		{"/user", ""},
+	}
+	for _, tc := range testcases {
+		if account := accountOf(tc.path); account != tc.account {
+			t.Errorf("For path %s, expected account %q, got %q", tc.path, tc.account, account)
+		}
+	}
+}
+
+func TestSearchAccount(t *testing.T) {
+	var testcases = []struct {
+		query   string
+		account string
+		err     bool
+	}{
+		{query: "repo:k8s/kuber is:open", account: "k8s"},
+		{query: "is:open org:k8s", account: "k8s"},
+		{query: "repo:k8s/kuber repo:k8s/test-infra", account: "k8s"},
+		{query: "is:open", account: ""},
+		{query: "repo:k8s/kuber repo:other/repo", err: true},
+	}
+	for _, tc := range testcases {
+		account, err := searchAccount(tc.query)
+		if tc.err {
+			if err == nil {
+				t.Errorf("For query %q, expected an error.", tc.query)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("For query %q, didn't expect error: %v", tc.query, err)
+		}
+		if account != tc.account {
+			t.Errorf("For query %q, expected account %q, got %q", tc.query, tc.account, account)
+		}
+	}
+}
+
+// verifyJWT checks that the bearer token in r is signed by key for app 42.
+func verifyJWT(t *testing.T, r *http.Request, key *rsa.PrivateKey) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		t.Errorf("Expected a bearer token, got %q", auth)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(auth, "Bearer "), ".")
+	if len(parts) != 3 {
+		t.Errorf("Malformed JWT: %q", auth)
+		return
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Errorf("Malformed JWT signature: %v", err)
+		return
+	}
+	sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, sum[:], sig); err != nil {
+		t.Errorf("Bad JWT signature: %v", err)
+	}
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Errorf("Malformed JWT claims: %v", err)
+		return
+	}
+	var claims struct {
+		Iss int   `json:"iss"`
+		Iat int64 `json:"iat"`
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(b, &claims); err != nil {
+		t.Errorf("Malformed JWT claims: %v", err)
+	} else if claims.Iss != 42 || claims.Exp-claims.Iat > 600 {
+		t.Errorf("Bad JWT claims: %+v", claims)
+	}
+}
+
+func TestUseApp(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	var lock sync.Mutex
+	minted := map[int]int{}
+	listed := 0
+	limited := false
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		switch {
+		case r.URL.Path == "/app/installations":
+			verifyJWT(t, r, key)
+			listed++
+			fmt.Fprint(w, `[{"id": 1, "account": {"login": "K8s"}}, {"id": 2, "account": {"login": "other"}}]`)
+		case strings.HasPrefix(r.URL.Path, "/installations/"):
+			verifyJWT(t, r, key)
+			var id int
+			if _, err := fmt.Sscanf(r.URL.Path, "/installations/%d/access_tokens", &id); err != nil {
+				t.Errorf("Bad request path: %s", r.URL.Path)
+			}
+			minted[id]++
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"token": "t%d-%d", "expires_at": %q}`, id, minted[id], now.Add(time.Hour).Format(time.RFC3339))
+		case r.URL.Path == "/repos/k8s/kuber/labels" || r.URL.Path == "/repos/other/repo/labels":
+			// GitHub links to the next page by repo ID.
+			w.Header().Set("Link", fmt.Sprintf(`<https://%s/repositories/7/labels?page=2>; rel="next"`, r.Host))
+			fmt.Fprintf(w, `[{"name": %q}]`, r.Header.Get("Authorization"))
+		case r.URL.Path == "/repositories/7/labels":
+			fmt.Fprint(w, `[]`)
+		case r.URL.Path == "/teams/5/members":
+			fmt.Fprintf(w, `[{"login": %q}]`, r.Header.Get("Authorization"))
+		case r.URL.Path == "/repos/k8s/limited/labels":
+			if limited {
+				limited = false
+				w.Header().Set("Retry-After", "3600")
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			fmt.Fprintf(w, `[{"name": %q}]`, r.Header.Get("Authorization"))
+		default:
+			t.Errorf("Bad request path: %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := c.UseApp(42, keyPEM); err != nil {
+		t.Fatalf("Error using app: %v", err)
+	}
+
+	expectAuth := func(org, repo, expected string) {
+		labels, err := c.GetRepoLabels(org, repo)
+		if err != nil {
+			t.Errorf("Didn't expect error getting labels of %s/%s: %v", org, repo, err)
+		} else if len(labels) != 1 || labels[0].Name != expected {
+			t.Errorf("Expected %s/%s to use %q, got %v", org, repo, expected, labels)
+		}
+	}
+	// Concurrent requests share one token.
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			expectAuth("k8s", "kuber", "token t1-1")
+		}()
+	}
+	wg.Wait()
+	expectAuth("other", "repo", "token t2-1")
+	// Team endpoints don't name the org, so callers pass it.
+	if members, err := c.ListTeamMembers("other", 5); err != nil {
+		t.Errorf("Didn't expect error listing team members: %v", err)
+	} else if len(members) != 1 || members[0].Login != "token t2-1" {
+		t.Errorf("Expected team members of other to use %q, got %v", "token t2-1", members)
+	}
+	// No one installation can search several orgs.
+	if _, err := c.FindIssues("repo:k8s/kuber repo:other/repo", "", false); err == nil {
+		t.Error("Expected an error for a search that spans two orgs")
+	}
+	// Cached until shortly before it expires.
+	now = now.Add(50 * time.Minute)
+	expectAuth("k8s", "kuber", "token t1-1")
+	now = now.Add(6 * time.Minute)
+	expectAuth("k8s", "kuber", "token t1-2")
+
+	if _, err := c.GetRepoLabels("nobody", "repo"); err == nil {
+		t.Error("Expected an error for an org without the app installed")
+	}
+	// Without an org in the request, it's ambiguous with two installations.
+	if _, err := c.FindIssues("is:open", "", false); err == nil {
+		t.Error("Expected an error for a search that isn't scoped to an org")
+	}
+	// Misses are remembered for a while.
+	lock.Lock()
+	listed = 0
+	lock.Unlock()
+	c.GetRepoLabels("nobody", "repo")
+	c.FindIssues("is:open", "", false)
+	if listed != 0 {
+		t.Errorf("Expected misses to be cached, listed installations %d times", listed)
+	}
+	now = now.Add(installationMissTTL + time.Second)
+	c.GetRepoLabels("nobody", "repo")
+	if listed != 1 {
+		t.Errorf("Expected to list installations again after a while, listed %d times", listed)
+	}
+
+	// The token expires while we wait out the rate limit, so the retry uses a
+	// new one.
+	limited = true
+	timeSleep = func(d time.Duration) { now = now.Add(d) }
+	defer func() { timeSleep = time.Sleep }()
+	expectAuth("k8s", "limited", "token t1-3")
+}
+
+func TestUseAppBadKey(t *testing.T) {
+	c := getClient("")
+	if err := c.UseApp(42, []byte("not a key")); err == nil {
+		t.Error("Expected an error for a bad key")
+	}
+}
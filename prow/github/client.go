@@ -25,7 +25,6 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -44,13 +43,22 @@ type Client struct {
 	base    string
 	dry     bool
 	fake    bool
+	// onSkip, if set, is told about every mutating request that a dry-run
+	// client skips.
+	onSkip func(method, path string, body interface{})
+	// throttle, if set, limits how fast this client and its copies may make
+	// requests.
+	throttle *throttler
+	// app, if set, authenticates as a GitHub App installation instead of
+	// with token.
+	app *appAuth
 }
 
 const (
 	githubBase    = "https://api.github.com"
 	maxRetries    = 8
 	max404Retries = 2
-	maxSleepTime  = 2 * time.Minute
+	maxSleepTime  = time.Hour
 	initialDelay  = 2 * time.Second
 )
 
@@ -87,6 +95,22 @@ func NewFakeClient(botName string) *Client {
 	}
 }
 
+// DryRunCopy returns a copy of the client that will not perform mutating
+// actions. If onSkip is non-nil, it is called with every mutating request that
+// the copy skips.
+func (c *Client) DryRunCopy(onSkip func(method, path string, body interface{})) *Client {
+	nc := *c
+	nc.dry = true
+	nc.onSkip = onSkip
+	return &nc
+}
+
+// SetEndpoint makes the client talk to another API server, such as a ghproxy
+// or GitHub Enterprise, instead of https://api.github.com.
+func (c *Client) SetEndpoint(endpoint string) {
+	c.base = strings.TrimSuffix(endpoint, "/")
+}
+
 func (c *Client) log(methodName string, args ...interface{}) {
 	if c.Logger == nil {
 		return
@@ -111,10 +135,13 @@ type request struct {
 // Make a request with retries. If ret is not nil, unmarshal the response body
 // into it. Returns an error if the exit code is not one of the provided codes.
 func (c *Client) request(r *request, ret interface{}) (int, error) {
+	if c.dry && r.method != http.MethodGet && c.onSkip != nil {
+		c.onSkip(r.method, r.path, r.requestBody)
+	}
 	if c.fake || (c.dry && r.method != http.MethodGet) {
 		return r.exitCodes[0], nil
 	}
-	resp, err := c.requestRetry(r.method, r.path, r.accept, r.requestBody)
+	resp, err := c.requestRetry(r.method, r.path, r.accept, accountOf(strings.TrimPrefix(r.path, c.base)), r.requestBody)
 	if err != nil {
 		return 0, err
 	}
@@ -142,14 +169,25 @@ func (c *Client) request(r *request, ret interface{}) (int, error) {
 }
 
 // Retry on transport failures. Retries on 500s, retries after sleep on
-// ratelimit exceeded, and retries 404s a couple times.
-func (c *Client) requestRetry(method, path, accept string, body interface{}) (*http.Response, error) {
+// ratelimit exceeded and abuse detection, and retries 404s a couple times.
+// account is the org or user that the request is about, which picks the
+// installation token when the client uses an app.
+func (c *Client) requestRetry(method, path, accept, account string, body interface{}) (*http.Response, error) {
 	var resp *http.Response
+	var auth string
 	var err error
 	backoff := initialDelay
 	for retries := 0; retries < maxRetries; retries++ {
-		resp, err = c.doRequest(method, path, accept, body)
+		c.throttle.wait()
+		// We may have slept for a while, so the token could have expired.
+		auth, err = c.authorization(account)
+		if err != nil {
+			return nil, err
+		}
+		resp, err = c.doRequest(method, path, accept, auth, body)
+		recordRequest(method, resp)
 		if err == nil {
+			recordRateLimit(resp.Header)
 			if resp.StatusCode == 404 && retries < max404Retries {
 				// Retry 404s a couple times. Sometimes GitHub is inconsistent in
 				// the sense that they send us an event such as "PR opened" but an
@@ -160,26 +198,29 @@ func (c *Client) requestRetry(method, path, accept string, body interface{}) (*h
 				resp.Body.Close()
 				timeSleep(backoff)
 				backoff *= 2
-			} else if resp.StatusCode == 403 {
-				if resp.Header.Get("X-RateLimit-Remaining") == "0" {
-					// If we are out of API tokens, sleep first. The X-RateLimit-Reset
-					// header tells us the time at which we can request again.
-					var t int
-					if t, err = strconv.Atoi(resp.Header.Get("X-RateLimit-Reset")); err == nil {
-						// Sleep an extra second plus how long GitHub wants us to
-						// sleep. If it's going to take too long, then break.
-						sleepTime := time.Until(time.Unix(int64(t), 0)) + time.Second
-						if sleepTime > 0 && sleepTime < maxSleepTime {
-							timeSleep(sleepTime)
-						} else {
-							break
-						}
+			} else if resp.StatusCode == 403 || resp.StatusCode == 429 {
+				if sleepTime, ok := rateLimitWait(resp.Header); ok {
+					resp.Body.Close()
+					if retries == maxRetries-1 {
+						return nil, fmt.Errorf("rate limited on %s %s after %d retries", method, path, retries)
 					}
+					// If we are out of API tokens or tripped abuse detection,
+					// sleep for as long as GitHub tells us to. Tokens come back
+					// at least hourly, so anything longer is bogus.
+					if sleepTime > maxSleepTime {
+						sleepTime = maxSleepTime
+					}
+					if c.Logger != nil {
+						c.Logger.Printf("Rate limited on %s %s, sleeping for %v.", method, path, sleepTime)
+					}
+					timeSleep(sleepTime)
 				} else if oauthScopes := resp.Header.Get("X-Accepted-OAuth-Scopes"); len(oauthScopes) > 0 {
 					err = fmt.Errorf("is %s using at least one of the following oauth scopes?: %s", c.botName, oauthScopes)
 					break
+				} else {
+					// Some other forbidden, such as a missing permission.
+					break
 				}
-				resp.Body.Close()
 			} else if resp.StatusCode < 500 {
 				// Normal, happy case.
 				break
@@ -197,7 +238,7 @@ func (c *Client) requestRetry(method, path, accept string, body interface{}) (*h
 	return resp, err
 }
 
-func (c *Client) doRequest(method, path, accept string, body interface{}) (*http.Response, error) {
+func (c *Client) doRequest(method, path, accept, auth string, body interface{}) (*http.Response, error) {
 	var buf io.Reader
 	if body != nil {
 		b, err := json.Marshal(body)
@@ -210,7 +251,7 @@ func (c *Client) doRequest(method, path, accept string, body interface{}) (*http
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", "Token "+c.token)
+	req.Header.Set("Authorization", auth)
 	if accept == "" {
 		req.Header.Add("Accept", "application/vnd.github.v3+json")
 	} else {
@@ -224,10 +265,53 @@ func (c *Client) doRequest(method, path, accept string, body interface{}) (*http
 	return c.client.Do(req)
 }
 
+// readPaginatedResults reads every page of a paginated GET, starting at path
+// and following the "next" links that GitHub sends. Each page is unmarshalled
+// into a fresh object from newObj, which is then handed to accumulate. The
+// next links don't always name the org, so account is passed along as well.
+func (c *Client) readPaginatedResults(path, accept, account string, newObj func() interface{}, accumulate func(interface{})) error {
+	for nextURL := path; nextURL != ""; {
+		resp, err := c.requestRetry(http.MethodGet, nextURL, accept, account, nil)
+		if err != nil {
+			return err
+		}
+		b, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("return code not 2XX: %s", resp.Status)
+		}
+		obj := newObj()
+		if err := json.Unmarshal(b, obj); err != nil {
+			return err
+		}
+		accumulate(obj)
+		nextURL = parseLinks(resp.Header.Get("Link"))["next"]
+	}
+	return nil
+}
+
 func (c *Client) BotName() string {
 	return c.botName
 }
 
+// GetUser returns the user that the client authenticates as.
+func (c *Client) GetUser() (*User, error) {
+	c.log("GetUser")
+	var u User
+	_, err := c.request(&request{
+		method:    http.MethodGet,
+		path:      fmt.Sprintf("%s/user", c.base),
+		exitCodes: []int{200},
+	}, &u)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
 // IsMember returns whether or not the user is a member of the org.
 func (c *Client) IsMember(org, user string) (bool, error) {
 	c.log("IsMember", org, user)
@@ -250,6 +334,21 @@ func (c *Client) IsMember(org, user string) (bool, error) {
 	return false, fmt.Errorf("unexpected status: %d", code)
 }
 
+// IsCollaborator returns whether or not the user is a collaborator of the
+// repo. Org members with access to the repo are collaborators too.
+func (c *Client) IsCollaborator(org, repo, user string) (bool, error) {
+	c.log("IsCollaborator", org, repo, user)
+	code, err := c.request(&request{
+		method:    http.MethodGet,
+		path:      fmt.Sprintf("%s/repos/%s/%s/collaborators/%s", c.base, org, repo, user),
+		exitCodes: []int{204, 404},
+	}, nil)
+	if err != nil {
+		return false, err
+	}
+	return code == 204, nil
+}
+
 // CreateComment creates a comment on the issue.
 func (c *Client) CreateComment(org, repo string, number int, comment string) error {
 	c.log("CreateComment", org, repo, number, comment)
@@ -323,31 +422,15 @@ func (c *Client) ListIssueComments(org, repo string, number int) ([]IssueComment
 	if c.fake {
 		return nil, nil
 	}
-	nextURL := fmt.Sprintf("%s/repos/%s/%s/issues/%d/comments?per_page=100", c.base, org, repo, number)
 	var comments []IssueComment
-	for nextURL != "" {
-		resp, err := c.requestRetry(http.MethodGet, nextURL, "", nil)
-		if err != nil {
-			return nil, err
-		}
-		defer resp.Body.Close()
-		if resp.StatusCode < 200 || resp.StatusCode > 299 {
-			return nil, fmt.Errorf("return code not 2XX: %s", resp.Status)
-		}
-
-		b, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
-		}
-
-		var ics []IssueComment
-		if err := json.Unmarshal(b, &ics); err != nil {
-			return nil, err
-		}
-		comments = append(comments, ics...)
-		nextURL = parseLinks(resp.Header.Get("Link"))["next"]
-	}
-	return comments, nil
+	err := c.readPaginatedResults(
+		fmt.Sprintf("%s/repos/%s/%s/issues/%d/comments?per_page=100", c.base, org, repo, number),
+		"",
+		org,
+		func() interface{} { return &[]IssueComment{} },
+		func(obj interface{}) { comments = append(comments, *(obj.(*[]IssueComment))...) },
+	)
+	return comments, err
 }
 
 // GetPullRequest gets a pull request.
@@ -368,31 +451,15 @@ func (c *Client) GetPullRequestChanges(org, repo string, number int) ([]PullRequ
 	if c.fake {
 		return []PullRequestChange{}, nil
 	}
-	nextURL := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/files?per_page=100", c.base, org, repo, number)
 	var changes []PullRequestChange
-	for nextURL != "" {
-		resp, err := c.requestRetry(http.MethodGet, nextURL, "", nil)
-		if err != nil {
-			return nil, err
-		}
-		defer resp.Body.Close()
-		if resp.StatusCode < 200 || resp.StatusCode > 299 {
-			return nil, fmt.Errorf("return code not 2XX: %s", resp.Status)
-		}
-
-		b, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
-		}
-
-		var newChanges []PullRequestChange
-		if err := json.Unmarshal(b, &newChanges); err != nil {
-			return nil, err
-		}
-		changes = append(changes, newChanges...)
-		nextURL = parseLinks(resp.Header.Get("Link"))["next"]
-	}
-	return changes, nil
+	err := c.readPaginatedResults(
+		fmt.Sprintf("%s/repos/%s/%s/pulls/%d/files?per_page=100", c.base, org, repo, number),
+		"",
+		org,
+		func() interface{} { return &[]PullRequestChange{} },
+		func(obj interface{}) { changes = append(changes, *(obj.(*[]PullRequestChange))...) },
+	)
+	return changes, err
 }
 
 // ListPullRequestComments returns all comments on a pull request. This may use
@@ -402,31 +469,15 @@ func (c *Client) ListPullRequestComments(org, repo string, number int) ([]Review
 	if c.fake {
 		return nil, nil
 	}
-	nextURL := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/comments?per_page=100", c.base, org, repo, number)
 	var comments []ReviewComment
-	for nextURL != "" {
-		resp, err := c.requestRetry(http.MethodGet, nextURL, "", nil)
-		if err != nil {
-			return nil, err
-		}
-		defer resp.Body.Close()
-		if resp.StatusCode < 200 || resp.StatusCode > 299 {
-			return nil, fmt.Errorf("return code not 2XX: %s", resp.Status)
-		}
-
-		b, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
-		}
-
-		var cs []ReviewComment
-		if err := json.Unmarshal(b, &cs); err != nil {
-			return nil, err
-		}
-		comments = append(comments, cs...)
-		nextURL = parseLinks(resp.Header.Get("Link"))["next"]
-	}
-	return comments, nil
+	err := c.readPaginatedResults(
+		fmt.Sprintf("%s/repos/%s/%s/pulls/%d/comments?per_page=100", c.base, org, repo, number),
+		"",
+		org,
+		func() interface{} { return &[]ReviewComment{} },
+		func(obj interface{}) { comments = append(comments, *(obj.(*[]ReviewComment))...) },
+	)
+	return comments, err
 }
 
 // CreateStatus creates or updates the status of a commit.
@@ -454,45 +505,29 @@ func (c *Client) GetCombinedStatus(org, repo, ref string) (*CombinedStatus, erro
 }
 
 // getLabels is a helper function that retrieves a paginated list of labels from a github URI path.
-func (c *Client) getLabels(path string) ([]Label, error) {
+func (c *Client) getLabels(org, path string) ([]Label, error) {
 	if c.fake {
 		return nil, nil
 	}
-	nextURL := strings.Join([]string{c.base, path}, "")
 	var labels []Label
-	for nextURL != "" {
-		resp, err := c.requestRetry(http.MethodGet, nextURL, "", nil)
-		if err != nil {
-			return nil, err
-		}
-		defer resp.Body.Close()
-		if resp.StatusCode < 200 || resp.StatusCode > 299 {
-			return nil, fmt.Errorf("return code not 2XX: %s", resp.Status)
-		}
-
-		b, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
-		}
-
-		var labs []Label
-		if err := json.Unmarshal(b, &labs); err != nil {
-			return nil, err
-		}
-		labels = append(labels, labs...)
-		nextURL = parseLinks(resp.Header.Get("Link"))["next"]
-	}
-	return labels, nil
+	err := c.readPaginatedResults(
+		c.base+path,
+		"",
+		org,
+		func() interface{} { return &[]Label{} },
+		func(obj interface{}) { labels = append(labels, *(obj.(*[]Label))...) },
+	)
+	return labels, err
 }
 
 func (c *Client) GetRepoLabels(org, repo string) ([]Label, error) {
 	c.log("GetRepoLabels", org, repo)
-	return c.getLabels(fmt.Sprintf("/repos/%s/%s/labels", org, repo))
+	return c.getLabels(org, fmt.Sprintf("/repos/%s/%s/labels", org, repo))
 }
 
 func (c *Client) GetIssueLabels(org, repo string, number int) ([]Label, error) {
 	c.log("GetIssueLabels", org, repo, number)
-	return c.getLabels(fmt.Sprintf("/repos/%s/%s/issues/%d/labels", org, repo, number))
+	return c.getLabels(org, fmt.Sprintf("/repos/%s/%s/issues/%d/labels", org, repo, number))
 }
 
 func (c *Client) AddLabel(org, repo string, number int, label string) error {
@@ -729,6 +764,74 @@ func (c *Client) ReopenPR(org, repo string, number int) error {
 	return err
 }
 
+// CreatePullRequest opens a pull request against base and returns its number.
+// Head is the branch with the changes. For branches in forks, use the form
+// "user:branch".
+func (c *Client) CreatePullRequest(org, repo, title, body, head, base string) (int, error) {
+	c.log("CreatePullRequest", org, repo, title, head, base)
+	data := struct {
+		Title string `json:"title"`
+		Body  string `json:"body"`
+		Head  string `json:"head"`
+		Base  string `json:"base"`
+	}{
+		Title: title,
+		Body:  body,
+		Head:  head,
+		Base:  base,
+	}
+	var res PullRequest
+	_, err := c.request(&request{
+		method:      http.MethodPost,
+		path:        fmt.Sprintf("%s/repos/%s/%s/pulls", c.base, org, repo),
+		requestBody: &data,
+		exitCodes:   []int{201},
+	}, &res)
+	return res.Number, err
+}
+
+// FindPullRequest returns the open PR from head, such as "user:branch", into
+// base, or nil if there is none.
+func (c *Client) FindPullRequest(org, repo, head, base string) (*PullRequest, error) {
+	c.log("FindPullRequest", org, repo, head, base)
+	var prs []PullRequest
+	_, err := c.request(&request{
+		method:    http.MethodGet,
+		path:      fmt.Sprintf("%s/repos/%s/%s/pulls?state=open&head=%s&base=%s", c.base, org, repo, url.QueryEscape(head), url.QueryEscape(base)),
+		exitCodes: []int{200},
+	}, &prs)
+	if err != nil || len(prs) == 0 {
+		return nil, err
+	}
+	return &prs[0], nil
+}
+
+// CreateFork forks the repo into the bot's account. Forking is asynchronous
+// on GitHub's side, and forking a repo that is already forked is fine.
+func (c *Client) CreateFork(org, repo string) error {
+	c.log("CreateFork", org, repo)
+	_, err := c.request(&request{
+		method:    http.MethodPost,
+		path:      fmt.Sprintf("%s/repos/%s/%s/forks", c.base, org, repo),
+		exitCodes: []int{202},
+	}, nil)
+	return err
+}
+
+// RepoExists returns whether or not the repo exists and the bot can see it.
+func (c *Client) RepoExists(org, repo string) (bool, error) {
+	c.log("RepoExists", org, repo)
+	code, err := c.request(&request{
+		method:    http.MethodGet,
+		path:      fmt.Sprintf("%s/repos/%s/%s", c.base, org, repo),
+		exitCodes: []int{200, 404},
+	}, nil)
+	if err != nil {
+		return false, err
+	}
+	return code == 200, nil
+}
+
 // GetRef returns the SHA of the given ref, such as "heads/master".
 func (c *Client) GetRef(org, repo, ref string) (string, error) {
 	c.log("GetRef", org, repo, ref)
@@ -743,6 +846,22 @@ func (c *Client) GetRef(org, repo, ref string) (string, error) {
 	return res.Object["sha"], err
 }
 
+// CompareCommits compares base with head. AheadBy in the result is the number
+// of commits in head that aren't in base.
+func (c *Client) CompareCommits(org, repo, base, head string) (*CommitComparison, error) {
+	c.log("CompareCommits", org, repo, base, head)
+	var res CommitComparison
+	_, err := c.request(&request{
+		method:    http.MethodGet,
+		path:      fmt.Sprintf("%s/repos/%s/%s/compare/%s...%s", c.base, org, repo, base, head),
+		exitCodes: []int{200},
+	}, &res)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
 // FindIssues uses the github search API to find issues which match a particular query.
 //
 // Input query the same way you would into the website.
@@ -750,22 +869,35 @@ func (c *Client) GetRef(org, repo, ref string) (string, error) {
 // Control whether oldest/newest is first with asc.
 //
 // See https://help.github.com/articles/searching-issues-and-pull-requests/ for details.
+//
+// All pages of results are read, which may use more than one API token.
+// GitHub returns at most 1000 results for a search.
 func (c *Client) FindIssues(query, sort string, asc bool) ([]Issue, error) {
 	c.log("FindIssues", query)
-	path := fmt.Sprintf("%s/search/issues?q=%s", c.base, url.QueryEscape(query))
+	if c.fake {
+		return nil, nil
+	}
+	path := fmt.Sprintf("%s/search/issues?per_page=100&q=%s", c.base, url.QueryEscape(query))
 	if sort != "" {
 		path += "&sort=" + url.QueryEscape(sort)
 		if asc {
 			path += "&order=asc"
 		}
 	}
-	var issSearchResult IssuesSearchResult
-	_, err := c.request(&request{
-		method:    http.MethodGet,
-		path:      path,
-		exitCodes: []int{200},
-	}, &issSearchResult)
-	return issSearchResult.Issues, err
+	account, err := searchAccount(query)
+	if err != nil && c.app != nil {
+		// Each installation's token only finds the issues of its own account.
+		return nil, err
+	}
+	var issues []Issue
+	err = c.readPaginatedResults(
+		path,
+		"",
+		account,
+		func() interface{} { return &IssuesSearchResult{} },
+		func(obj interface{}) { issues = append(issues, obj.(*IssuesSearchResult).Issues...) },
+	)
+	return issues, err
 }
 
 // GetFile uses github repo contents API to retrieve the content of a file with commit sha.
@@ -794,3 +926,276 @@ func (c *Client) GetFile(org, repo, filepath, commit string) ([]byte, error) {
 		return decoded, nil
 	}
 }
+
+// branchProtectionAccept is needed while the protection API is in preview.
+const branchProtectionAccept = "application/vnd.github.loki-preview+json"
+
+// GetBranches returns all branches of the repo. This may use more than one
+// API token.
+func (c *Client) GetBranches(org, repo string) ([]Branch, error) {
+	c.log("GetBranches", org, repo)
+	if c.fake {
+		return nil, nil
+	}
+	var branches []Branch
+	err := c.readPaginatedResults(
+		fmt.Sprintf("%s/repos/%s/%s/branches?per_page=100", c.base, org, repo),
+		branchProtectionAccept,
+		org,
+		func() interface{} { return &[]Branch{} },
+		func(obj interface{}) { branches = append(branches, *(obj.(*[]Branch))...) },
+	)
+	return branches, err
+}
+
+// GetRequiredStatusChecks returns the required status checks of a protected
+// branch, or nil if it doesn't require any.
+func (c *Client) GetRequiredStatusChecks(org, repo, branch string) (*RequiredStatusChecks, error) {
+	c.log("GetRequiredStatusChecks", org, repo, branch)
+	var res RequiredStatusChecks
+	code, err := c.request(&request{
+		method:    http.MethodGet,
+		path:      fmt.Sprintf("%s/repos/%s/%s/branches/%s/protection/required_status_checks", c.base, org, repo, branch),
+		accept:    branchProtectionAccept,
+		exitCodes: []int{200, 404},
+	}, &res)
+	if err != nil {
+		return nil, err
+	}
+	if code == 404 {
+		return nil, nil
+	}
+	return &res, nil
+}
+
+// UpdateRequiredStatusChecks replaces the required status checks of a branch
+// that is already protected, leaving the rest of its protection alone.
+func (c *Client) UpdateRequiredStatusChecks(org, repo, branch string, checks RequiredStatusChecks) error {
+	c.log("UpdateRequiredStatusChecks", org, repo, branch, checks)
+	_, err := c.request(&request{
+		method:      http.MethodPatch,
+		path:        fmt.Sprintf("%s/repos/%s/%s/branches/%s/protection/required_status_checks", c.base, org, repo, branch),
+		accept:      branchProtectionAccept,
+		requestBody: &checks,
+		exitCodes:   []int{200},
+	}, nil)
+	return err
+}
+
+// ProtectBranch protects an unprotected branch, requiring only the given
+// status checks.
+func (c *Client) ProtectBranch(org, repo, branch string, checks RequiredStatusChecks) error {
+	c.log("ProtectBranch", org, repo, branch, checks)
+	protection := struct {
+		RequiredStatusChecks       RequiredStatusChecks `json:"required_status_checks"`
+		EnforceAdmins              bool                 `json:"enforce_admins"`
+		RequiredPullRequestReviews *struct{}            `json:"required_pull_request_reviews"`
+		Restrictions               *struct{}            `json:"restrictions"`
+	}{RequiredStatusChecks: checks}
+	_, err := c.request(&request{
+		method:      http.MethodPut,
+		path:        fmt.Sprintf("%s/repos/%s/%s/branches/%s/protection", c.base, org, repo, branch),
+		accept:      branchProtectionAccept,
+		requestBody: &protection,
+		exitCodes:   []int{200},
+	}, nil)
+	return err
+}
+
+// ListPullRequestCommits returns the commits of a pull request, oldest first.
+// GitHub lists at most 250 commits.
+func (c *Client) ListPullRequestCommits(org, repo string, number int) ([]RepositoryCommit, error) {
+	c.log("ListPullRequestCommits", org, repo, number)
+	if c.fake {
+		return nil, nil
+	}
+	var commits []RepositoryCommit
+	err := c.readPaginatedResults(
+		fmt.Sprintf("%s/repos/%s/%s/pulls/%d/commits?per_page=100", c.base, org, repo, number),
+		"",
+		org,
+		func() interface{} { return &[]RepositoryCommit{} },
+		func(obj interface{}) { commits = append(commits, *(obj.(*[]RepositoryCommit))...) },
+	)
+	return commits, err
+}
+
+// ListReviews returns all reviews of a pull request. This may use more than
+// one API token.
+func (c *Client) ListReviews(org, repo string, number int) ([]Review, error) {
+	c.log("ListReviews", org, repo, number)
+	if c.fake {
+		return nil, nil
+	}
+	var reviews []Review
+	err := c.readPaginatedResults(
+		fmt.Sprintf("%s/repos/%s/%s/pulls/%d/reviews?per_page=100", c.base, org, repo, number),
+		"application/vnd.github.black-cat-preview+json",
+		org,
+		func() interface{} { return &[]Review{} },
+		func(obj interface{}) { reviews = append(reviews, *(obj.(*[]Review))...) },
+	)
+	return reviews, err
+}
+
+// UnmergablePRError means GitHub refused to merge a PR, such as because it
+// has conflicts or is missing required statuses.
+type UnmergablePRError string
+
+func (e UnmergablePRError) Error() string {
+	return fmt.Sprintf("PR is unmergable: %s", string(e))
+}
+
+// ModifiedHeadError means the PR's head didn't match MergeDetails.SHA.
+type ModifiedHeadError string
+
+func (e ModifiedHeadError) Error() string {
+	return fmt.Sprintf("PR head was modified: %s", string(e))
+}
+
+// Merge merges a PR. It returns an UnmergablePRError or ModifiedHeadError if
+// GitHub refuses.
+func (c *Client) Merge(org, repo string, number int, details MergeDetails) error {
+	c.log("Merge", org, repo, number, details)
+	var res struct {
+		Message string `json:"message"`
+	}
+	code, err := c.request(&request{
+		method: http.MethodPut,
+		path:   fmt.Sprintf("%s/repos/%s/%s/pulls/%d/merge", c.base, org, repo, number),
+		// Needed for the rebase merge method while it is in preview.
+		accept:      "application/vnd.github.polaris-preview+json",
+		requestBody: &details,
+		exitCodes:   []int{200, 405, 409},
+	}, &res)
+	if err != nil {
+		return err
+	}
+	switch code {
+	case 405:
+		return UnmergablePRError(res.Message)
+	case 409:
+		return ModifiedHeadError(res.Message)
+	}
+	return nil
+}
+
+// listUsers reads a paginated list of users.
+func (c *Client) listUsers(org, path string) ([]User, error) {
+	if c.fake {
+		return nil, nil
+	}
+	var users []User
+	err := c.readPaginatedResults(
+		path,
+		"",
+		org,
+		func() interface{} { return &[]User{} },
+		func(obj interface{}) { users = append(users, *(obj.(*[]User))...) },
+	)
+	return users, err
+}
+
+// ListOrgMembers returns the members of an org that the bot can see.
+func (c *Client) ListOrgMembers(org string) ([]User, error) {
+	c.log("ListOrgMembers", org)
+	return c.listUsers(org, fmt.Sprintf("%s/orgs/%s/members?per_page=100", c.base, org))
+}
+
+// ListTeams returns the teams of an org.
+func (c *Client) ListTeams(org string) ([]Team, error) {
+	c.log("ListTeams", org)
+	if c.fake {
+		return nil, nil
+	}
+	var teams []Team
+	err := c.readPaginatedResults(
+		fmt.Sprintf("%s/orgs/%s/teams?per_page=100", c.base, org),
+		"",
+		org,
+		func() interface{} { return &[]Team{} },
+		func(obj interface{}) { teams = append(teams, *(obj.(*[]Team))...) },
+	)
+	return teams, err
+}
+
+// ListTeamMembers returns the members of the team in org with the given ID, as
+// found with ListTeams.
+func (c *Client) ListTeamMembers(org string, id int) ([]User, error) {
+	c.log("ListTeamMembers", org, id)
+	return c.listUsers(org, fmt.Sprintf("%s/teams/%d/members?per_page=100", c.base, id))
+}
+
+// ListCollaborators returns the collaborators of a repo, including org
+// members with access to it.
+func (c *Client) ListCollaborators(org, repo string) ([]User, error) {
+	c.log("ListCollaborators", org, repo)
+	return c.listUsers(org, fmt.Sprintf("%s/repos/%s/%s/collaborators?per_page=100", c.base, org, repo))
+}
+
+// AddRepoLabel creates a label in a repo. color is six hex digits, such as
+// "ededed".
+func (c *Client) AddRepoLabel(org, repo, label, color string) error {
+	c.log("AddRepoLabel", org, repo, label, color)
+	_, err := c.request(&request{
+		method:      http.MethodPost,
+		path:        fmt.Sprintf("%s/repos/%s/%s/labels", c.base, org, repo),
+		requestBody: Label{Name: label, Color: color},
+		exitCodes:   []int{201},
+	}, nil)
+	return err
+}
+
+// UpdateRepoLabel renames and recolors a label in a repo. Issues and PRs keep
+// the label under its new name.
+func (c *Client) UpdateRepoLabel(org, repo, label, newName, color string) error {
+	c.log("UpdateRepoLabel", org, repo, label, newName, color)
+	_, err := c.request(&request{
+		method:      http.MethodPatch,
+		path:        fmt.Sprintf("%s/repos/%s/%s/labels/%s", c.base, org, repo, url.PathEscape(label)),
+		requestBody: Label{Name: newName, Color: color},
+		exitCodes:   []int{200},
+	}, nil)
+	return err
+}
+
+// ListMilestones returns the open milestones of a repo.
+func (c *Client) ListMilestones(org, repo string) ([]Milestone, error) {
+	c.log("ListMilestones", org, repo)
+	if c.fake {
+		return nil, nil
+	}
+	var milestones []Milestone
+	err := c.readPaginatedResults(
+		fmt.Sprintf("%s/repos/%s/%s/milestones?per_page=100", c.base, org, repo),
+		"",
+		org,
+		func() interface{} { return &[]Milestone{} },
+		func(obj interface{}) { milestones = append(milestones, *(obj.(*[]Milestone))...) },
+	)
+	return milestones, err
+}
+
+// SetMilestone puts an issue or PR in the milestone with the given number.
+func (c *Client) SetMilestone(org, repo string, number, milestone int) error {
+	c.log("SetMilestone", org, repo, number, milestone)
+	_, err := c.request(&request{
+		method:      http.MethodPatch,
+		path:        fmt.Sprintf("%s/repos/%s/%s/issues/%d", c.base, org, repo, number),
+		requestBody: map[string]int{"milestone": milestone},
+		exitCodes:   []int{200},
+	}, nil)
+	return err
+}
+
+// ClearMilestone takes an issue or PR out of its milestone.
+func (c *Client) ClearMilestone(org, repo string, number int) error {
+	c.log("ClearMilestone", org, repo, number)
+	_, err := c.request(&request{
+		method:      http.MethodPatch,
+		path:        fmt.Sprintf("%s/repos/%s/%s/issues/%d", c.base, org, repo, number),
+		requestBody: map[string]interface{}{"milestone": nil},
+		exitCodes:   []int{200},
+	}, nil)
+	return err
+}
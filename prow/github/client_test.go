@@ -24,10 +24,13 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
+
+	dto "github.com/prometheus/client_model/go"
 )
 
 func getClient(url string) *Client {
@@ -54,7 +57,7 @@ func TestRequestRateLimit(t *testing.T) {
 	}))
 	defer ts.Close()
 	c := getClient(ts.URL)
-	resp, err := c.requestRetry(http.MethodGet, c.base, "", nil)
+	resp, err := c.requestRetry(http.MethodGet, c.base, "", "", nil)
 	if err != nil {
 		t.Errorf("Error from request: %v", err)
 	} else if resp.StatusCode != 200 {
@@ -64,6 +67,38 @@ func TestRequestRateLimit(t *testing.T) {
 	}
 }
 
+func TestRequestMetrics(t *testing.T) {
+	timeSleep = func(d time.Duration) {}
+	defer func() { timeSleep = time.Sleep }()
+	calls := 0
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "403 Forbidden", http.StatusForbidden)
+		}
+	}))
+	defer ts.Close()
+	count := func(code string) float64 {
+		var m dto.Metric
+		if err := requests.WithLabelValues(http.MethodPut, code).Write(&m); err != nil {
+			t.Fatalf("Error reading metric: %v", err)
+		}
+		return m.GetCounter().GetValue()
+	}
+	forbidden, ok := count("403"), count("200")
+	c := getClient(ts.URL)
+	if _, err := c.requestRetry(http.MethodPut, c.base, "", "", nil); err != nil {
+		t.Fatalf("Error from request: %v", err)
+	}
+	if d := count("403") - forbidden; d != 1 {
+		t.Errorf("Expected 1 rate limited request counted, got %v", d)
+	}
+	if d := count("200") - ok; d != 1 {
+		t.Errorf("Expected 1 successful request counted, got %v", d)
+	}
+}
+
 func TestRetry404(t *testing.T) {
 	var slept int
 	timeSleep = func(d time.Duration) { slept++ }
@@ -75,7 +110,7 @@ func TestRetry404(t *testing.T) {
 	}))
 	defer ts.Close()
 	c := getClient(ts.URL)
-	resp, err := c.requestRetry(http.MethodGet, c.base, "", nil)
+	resp, err := c.requestRetry(http.MethodGet, c.base, "", "", nil)
 	if err != nil {
 		t.Errorf("Error from request: %v", err)
 	} else if resp.StatusCode != 200 {
@@ -103,6 +138,90 @@ func TestIsMember(t *testing.T) {
 	}
 }
 
+func TestRepoExists(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/repos/k8s/kuber" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"name": "kuber"}`)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	exists, err := c.RepoExists("k8s", "kuber")
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	} else if !exists {
+		t.Error("Expected the repo to exist.")
+	}
+}
+
+func TestFindPullRequest(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/repos/k8s/kuber/pulls" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		q := r.URL.Query()
+		if q.Get("state") != "open" || q.Get("head") != "bot:fix" || q.Get("base") != "release" {
+			t.Errorf("Bad query: %s", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, `[{"number": 12}]`)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	pr, err := c.FindPullRequest("k8s", "kuber", "bot:fix", "release")
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	} else if pr == nil || pr.Number != 12 {
+		t.Errorf("Expected PR #12, got %+v", pr)
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/user" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"login": "person"}`)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	u, err := c.GetUser()
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	} else if u.Login != "person" {
+		t.Errorf("Wrong login: %s", u.Login)
+	}
+}
+
+func TestIsCollaborator(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/repos/k8s/kuber/collaborators/person" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		http.Error(w, "404 Not Found", http.StatusNotFound)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	collab, err := c.IsCollaborator("k8s", "kuber", "person")
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	} else if collab {
+		t.Errorf("Should not be a collaborator.")
+	}
+}
+
 func TestCreateComment(t *testing.T) {
 	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -242,6 +361,26 @@ func TestGetRef(t *testing.T) {
 	}
 }
 
+func TestCompareCommits(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/repos/k8s/kuber/compare/abc...def" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"status": "ahead", "ahead_by": 3, "behind_by": 0}`)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	cc, err := c.CompareCommits("k8s", "kuber", "abc", "def")
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	} else if cc.Status != "ahead" || cc.AheadBy != 3 {
+		t.Errorf("Wrong comparison: %+v", cc)
+	}
+}
+
 func TestCreateStatus(t *testing.T) {
 	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -887,3 +1026,507 @@ func TestGetLabels(t *testing.T) {
 		t.Errorf("Wrong label names: %v", labels)
 	}
 }
+
+func TestCreatePullRequest(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/repos/k8s/kuber/pulls" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("Could not read request body: %v", err)
+		}
+		var data map[string]string
+		if err := json.Unmarshal(b, &data); err != nil {
+			t.Errorf("Could not unmarshal request: %v", err)
+		} else if data["title"] != "title" || data["body"] != "body" || data["head"] != "bot:branch" || data["base"] != "release-1.7" {
+			t.Errorf("Wrong request: %v", data)
+		}
+		http.Error(w, `{"number": 42}`, http.StatusCreated)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	n, err := c.CreatePullRequest("k8s", "kuber", "title", "body", "bot:branch", "release-1.7")
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	} else if n != 42 {
+		t.Errorf("Expected PR number 42, got %d", n)
+	}
+}
+
+func TestCreateFork(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/repos/k8s/kuber/forks" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		http.Error(w, "{}", http.StatusAccepted)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	if err := c.CreateFork("k8s", "kuber"); err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	}
+}
+
+func TestGetBranches(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		var branches []Branch
+		switch r.URL.Path {
+		case "/repos/k8s/kuber/branches":
+			branches = []Branch{{Name: "master", Protected: true}}
+			w.Header().Set("Link", fmt.Sprintf(`<https://%s/someotherpath>; rel="next"`, r.Host))
+		case "/someotherpath":
+			branches = []Branch{{Name: "release-1.7"}}
+		default:
+			t.Errorf("Bad request path: %s", r.URL.Path)
+			return
+		}
+		b, err := json.Marshal(branches)
+		if err != nil {
+			t.Fatalf("Didn't expect error: %v", err)
+		}
+		fmt.Fprint(w, string(b))
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	branches, err := c.GetBranches("k8s", "kuber")
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	} else if len(branches) != 2 || branches[0] != (Branch{Name: "master", Protected: true}) || branches[1] != (Branch{Name: "release-1.7"}) {
+		t.Errorf("Wrong branches: %v", branches)
+	}
+}
+
+func TestGetRequiredStatusChecks(t *testing.T) {
+	timeSleep = func(time.Duration) {}
+	defer func() { timeSleep = time.Sleep }()
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		switch r.URL.Path {
+		case "/repos/k8s/kuber/branches/master/protection/required_status_checks":
+			fmt.Fprint(w, `{"strict": true, "contexts": ["a", "b"]}`)
+		case "/repos/k8s/kuber/branches/dev/protection/required_status_checks":
+			http.Error(w, `{"message": "Required status checks not enabled"}`, http.StatusNotFound)
+		default:
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	checks, err := c.GetRequiredStatusChecks("k8s", "kuber", "master")
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	} else if checks == nil || !checks.Strict || !reflect.DeepEqual(checks.Contexts, []string{"a", "b"}) {
+		t.Errorf("Wrong status checks: %+v", checks)
+	}
+	checks, err = c.GetRequiredStatusChecks("k8s", "kuber", "dev")
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	} else if checks != nil {
+		t.Errorf("Expected no status checks, got %+v", checks)
+	}
+}
+
+func TestUpdateRequiredStatusChecks(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/repos/k8s/kuber/branches/master/protection/required_status_checks" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("Could not read request body: %v", err)
+		}
+		var checks RequiredStatusChecks
+		if err := json.Unmarshal(b, &checks); err != nil {
+			t.Errorf("Could not unmarshal request: %v", err)
+		} else if !reflect.DeepEqual(checks.Contexts, []string{"a"}) {
+			t.Errorf("Wrong contexts: %v", checks.Contexts)
+		}
+		fmt.Fprint(w, "{}")
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	if err := c.UpdateRequiredStatusChecks("k8s", "kuber", "master", RequiredStatusChecks{Contexts: []string{"a"}}); err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	}
+}
+
+func TestProtectBranch(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("Bad method: %s", r.Method)
+		}
+		if r.URL.Path != "/repos/k8s/kuber/branches/master/protection" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("Could not read request body: %v", err)
+		}
+		var data map[string]interface{}
+		if err := json.Unmarshal(b, &data); err != nil {
+			t.Errorf("Could not unmarshal request: %v", err)
+		}
+		for _, k := range []string{"required_status_checks", "enforce_admins", "required_pull_request_reviews", "restrictions"} {
+			if _, ok := data[k]; !ok {
+				t.Errorf("Request is missing %s: %s", k, string(b))
+			}
+		}
+		fmt.Fprint(w, "{}")
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	if err := c.ProtectBranch("k8s", "kuber", "master", RequiredStatusChecks{Contexts: []string{"a"}}); err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	}
+}
+
+func TestRequestAbuseRetryAfter(t *testing.T) {
+	for _, code := range []int{http.StatusForbidden, http.StatusTooManyRequests} {
+		var slept time.Duration
+		timeSleep = func(d time.Duration) { slept = d }
+		ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if slept == 0 {
+				w.Header().Set("Retry-After", "30")
+				http.Error(w, "abuse", code)
+			}
+		}))
+		c := getClient(ts.URL)
+		resp, err := c.requestRetry(http.MethodGet, c.base, "", "", nil)
+		if err != nil {
+			t.Errorf("Error from request with code %d: %v", code, err)
+		} else if resp.StatusCode != 200 {
+			t.Errorf("Expected status code 200 after %d, got %d", code, resp.StatusCode)
+		} else if slept != 30*time.Second {
+			t.Errorf("Expected to sleep for 30s after %d, got %v", code, slept)
+		}
+		ts.Close()
+	}
+	timeSleep = time.Sleep
+}
+
+func TestRequestRateLimitFarReset(t *testing.T) {
+	var slept time.Duration
+	timeSleep = func(d time.Duration) { slept = d }
+	defer func() { timeSleep = time.Sleep }()
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slept == 0 {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.Itoa(int(time.Now().Add(48*time.Hour).Unix())))
+			http.Error(w, "403 Forbidden", http.StatusForbidden)
+		}
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	resp, err := c.requestRetry(http.MethodGet, c.base, "", "", nil)
+	if err != nil {
+		t.Errorf("Error from request: %v", err)
+	} else if resp.StatusCode != 200 {
+		t.Errorf("Expected status code 200, got %d", resp.StatusCode)
+	} else if slept != maxSleepTime {
+		t.Errorf("Expected to sleep for %v, got %v", maxSleepTime, slept)
+	}
+}
+
+func TestRequestRateLimitExhausted(t *testing.T) {
+	timeSleep = func(time.Duration) {}
+	defer func() { timeSleep = time.Sleep }()
+	var requests int
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "429 Too Many Requests", http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	if _, err := c.requestRetry(http.MethodGet, c.base, "", "", nil); err == nil {
+		t.Error("Expected an error after running out of retries.")
+	}
+	if requests != maxRetries {
+		t.Errorf("Expected %d requests, got %d", maxRetries, requests)
+	}
+}
+
+func TestForbiddenNoRetry(t *testing.T) {
+	var requests int
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	resp, err := c.requestRetry(http.MethodGet, c.base, "", "", nil)
+	if err != nil {
+		t.Errorf("Error from request: %v", err)
+	} else if resp.StatusCode != 403 {
+		t.Errorf("Expected status code 403, got %d", resp.StatusCode)
+	} else if requests != 1 {
+		t.Errorf("Expected one request, got %d", requests)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "42")
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	c.Throttle(1, 2)
+	if len(c.throttle.tokens) != 2 {
+		t.Fatalf("Expected a burst of 2 tokens, got %d", len(c.throttle.tokens))
+	}
+	dc := c.DryRunCopy(nil)
+	if _, err := c.requestRetry(http.MethodGet, c.base, "", "", nil); err != nil {
+		t.Errorf("Error from request: %v", err)
+	}
+	if _, err := dc.requestRetry(http.MethodGet, c.base, "", "", nil); err != nil {
+		t.Errorf("Error from request: %v", err)
+	}
+	if len(c.throttle.tokens) != 0 {
+		t.Errorf("Expected the copy to share the bucket, %d tokens left", len(c.throttle.tokens))
+	}
+	var m dto.Metric
+	if err := tokensRemaining.Write(&m); err != nil {
+		t.Errorf("Error reading metric: %v", err)
+	} else if v := m.GetGauge().GetValue(); v != 42 {
+		t.Errorf("Expected 42 remaining tokens exported, got %v", v)
+	}
+	old := c.throttle
+	c.Throttle(0, 0)
+	if c.throttle != nil {
+		t.Errorf("Expected throttling to be off")
+	}
+	select {
+	case <-old.done:
+	default:
+		t.Error("Expected the old throttler to be stopped")
+	}
+	if _, err := c.requestRetry(http.MethodGet, c.base, "", "", nil); err != nil {
+		t.Errorf("Error from request: %v", err)
+	}
+}
+
+func TestFindIssuesPages(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search/issues" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<https://%s/search/issues?q=x&page=2>; rel="next"`, r.Host))
+			fmt.Fprint(w, `{"total_count": 2, "items": [{"number": 1}]}`)
+		} else {
+			fmt.Fprint(w, `{"total_count": 2, "items": [{"number": 2}]}`)
+		}
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	issues, err := c.FindIssues("x", "", false)
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	} else if len(issues) != 2 || issues[0].Number != 1 || issues[1].Number != 2 {
+		t.Errorf("Expected issues 1 and 2, got %v", issues)
+	}
+}
+
+func TestListPullRequestCommits(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/k8s/kuber/pulls/5/commits" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `[{"sha": "abc", "commit": {"message": "fix", "author": {"name": "Person"}}, "author": {"login": "person"}}]`)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	commits, err := c.ListPullRequestCommits("k8s", "kuber", 5)
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	} else if len(commits) != 1 || commits[0].SHA != "abc" || commits[0].Commit.Message != "fix" || commits[0].Commit.Author.Name != "Person" || commits[0].Author.Login != "person" {
+		t.Errorf("Wrong commits: %+v", commits)
+	}
+}
+
+func TestListReviews(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/k8s/kuber/pulls/5/reviews" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `[{"id": 1, "user": {"login": "person"}, "state": "APPROVED"}]`)
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	reviews, err := c.ListReviews("k8s", "kuber", 5)
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	} else if len(reviews) != 1 || reviews[0].User.Login != "person" || reviews[0].State != "APPROVED" {
+		t.Errorf("Wrong reviews: %+v", reviews)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	var testcases = []struct {
+		name     string
+		code     int
+		expected error
+	}{
+		{
+			name: "merged",
+			code: http.StatusOK,
+		},
+		{
+			name:     "unmergable",
+			code:     http.StatusMethodNotAllowed,
+			expected: UnmergablePRError("msg"),
+		},
+		{
+			name:     "head modified",
+			code:     http.StatusConflict,
+			expected: ModifiedHeadError("msg"),
+		},
+	}
+	for _, tc := range testcases {
+		ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPut {
+				t.Errorf("Bad method: %s", r.Method)
+			}
+			if r.URL.Path != "/repos/k8s/kuber/pulls/5/merge" {
+				t.Errorf("Bad request path: %s", r.URL.Path)
+			}
+			b, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatalf("Could not read request body: %v", err)
+			}
+			var details MergeDetails
+			if err := json.Unmarshal(b, &details); err != nil {
+				t.Errorf("Could not unmarshal request: %v", err)
+			} else if details.SHA != "abc" || details.MergeMethod != "squash" {
+				t.Errorf("Wrong merge details: %+v", details)
+			}
+			http.Error(w, `{"message": "msg"}`, tc.code)
+		}))
+		c := getClient(ts.URL)
+		if err := c.Merge("k8s", "kuber", 5, MergeDetails{SHA: "abc", MergeMethod: "squash"}); err != tc.expected {
+			t.Errorf("For case %s, expected error %v, got %v", tc.name, tc.expected, err)
+		}
+		ts.Close()
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/orgs/k8s/members", "/teams/3/members", "/repos/k8s/kuber/collaborators":
+			fmt.Fprintf(w, `[{"login": %q}]`, r.URL.Path)
+		case "/orgs/k8s/teams":
+			fmt.Fprint(w, `[{"id": 3, "name": "Team", "slug": "team"}]`)
+		default:
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	check := func(users []User, err error, path string) {
+		if err != nil {
+			t.Errorf("Didn't expect error for %s: %v", path, err)
+		} else if len(users) != 1 || users[0].Login != path {
+			t.Errorf("Wrong users for %s: %v", path, users)
+		}
+	}
+	users, err := c.ListOrgMembers("k8s")
+	check(users, err, "/orgs/k8s/members")
+	users, err = c.ListCollaborators("k8s", "kuber")
+	check(users, err, "/repos/k8s/kuber/collaborators")
+	teams, err := c.ListTeams("k8s")
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	} else if len(teams) != 1 || teams[0] != (Team{ID: 3, Name: "Team", Slug: "team"}) {
+		t.Errorf("Wrong teams: %v", teams)
+	}
+	users, err = c.ListTeamMembers("k8s", 3)
+	check(users, err, "/teams/3/members")
+}
+
+func TestRepoLabels(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("Could not read request body: %v", err)
+		}
+		var l Label
+		if err := json.Unmarshal(b, &l); err != nil {
+			t.Errorf("Could not unmarshal request: %v", err)
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/k8s/kuber/labels":
+			if l.Name != "size/XS" || l.Color != "009900" {
+				t.Errorf("Wrong label: %+v", l)
+			}
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPatch && r.URL.EscapedPath() == "/repos/k8s/kuber/labels/size%2FXS":
+			if l.Name != "size/xs" || l.Color != "00ff00" {
+				t.Errorf("Wrong label: %+v", l)
+			}
+		default:
+			t.Errorf("Bad request: %s %s", r.Method, r.URL.EscapedPath())
+		}
+		fmt.Fprint(w, "{}")
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	if err := c.AddRepoLabel("k8s", "kuber", "size/XS", "009900"); err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	}
+	if err := c.UpdateRepoLabel("k8s", "kuber", "size/XS", "size/xs", "00ff00"); err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	}
+}
+
+func TestMilestones(t *testing.T) {
+	var bodies []string
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/k8s/kuber/milestones":
+			fmt.Fprint(w, `[{"number": 2, "title": "v1.8", "state": "open"}]`)
+		case r.Method == http.MethodPatch && r.URL.Path == "/repos/k8s/kuber/issues/5":
+			b, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatalf("Could not read request body: %v", err)
+			}
+			bodies = append(bodies, string(b))
+			fmt.Fprint(w, "{}")
+		default:
+			t.Errorf("Bad request: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	milestones, err := c.ListMilestones("k8s", "kuber")
+	if err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	} else if len(milestones) != 1 || milestones[0] != (Milestone{Number: 2, Title: "v1.8", State: "open"}) {
+		t.Errorf("Wrong milestones: %v", milestones)
+	}
+	if err := c.SetMilestone("k8s", "kuber", 5, 2); err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	}
+	if err := c.ClearMilestone("k8s", "kuber", 5); err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	}
+	expected := []string{`{"milestone":2}`, `{"milestone":null}`}
+	if !reflect.DeepEqual(bodies, expected) {
+		t.Errorf("Expected requests %v, got %v", expected, bodies)
+	}
+}
@@ -19,6 +19,7 @@ package fakegithub
 import (
 	"fmt"
 	"regexp"
+	"strings"
 
 	"k8s.io/test-infra/prow/github"
 )
@@ -26,11 +27,20 @@ import (
 type FakeClient struct {
 	Issues             []github.Issue
 	OrgMembers         []string
+	Collaborators      []string
 	IssueComments      map[int][]github.IssueComment
 	IssueCommentID     int
 	PullRequests       map[int]*github.PullRequest
 	PullRequestChanges map[int][]github.PullRequestChange
 	CombinedStatuses   map[string]*github.CombinedStatus
+	// ref:statuses created
+	CreatedStatuses map[string][]github.Status
+	// base...head:comparison
+	Comparisons map[string]*github.CommitComparison
+	// Pull requests opened with CreatePullRequest, numbered from 1000.
+	CreatedPullRequests []github.PullRequest
+	// org/repo for each fork created
+	Forks []string
 
 	//All Labels That Exist In The Repo
 	ExistingLabels []string
@@ -47,6 +57,21 @@ type FakeClient struct {
 
 	// Fake remote git storage
 	RemoteFiles map[string]map[string]string
+
+	PullRequestCommits map[int][]github.RepositoryCommit
+	Reviews            map[int][]github.Review
+	// org/repo#number for each PR merged
+	Merged []string
+
+	Teams       []github.Team
+	TeamMembers map[int][]string
+
+	// Open milestones of the repo.
+	Milestones []github.Milestone
+	// org/repo#number:milestone for each milestone set, and org/repo#number
+	// for each one cleared.
+	MilestonesSet     []string
+	MilestonesCleared []string
 }
 
 func (f *FakeClient) BotName() string {
@@ -62,6 +87,15 @@ func (f *FakeClient) IsMember(org, user string) (bool, error) {
 	return false, nil
 }
 
+func (f *FakeClient) IsCollaborator(org, repo, user string) (bool, error) {
+	for _, c := range f.Collaborators {
+		if c == user {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (f *FakeClient) ListIssueComments(owner, repo string, number int) ([]github.IssueComment, error) {
 	return append([]github.IssueComment{}, f.IssueComments[number]...), nil
 }
@@ -105,14 +139,66 @@ func (f *FakeClient) GetPullRequestChanges(org, repo string, number int) ([]gith
 	return f.PullRequestChanges[number], nil
 }
 
+func (f *FakeClient) CreatePullRequest(org, repo, title, body, head, base string) (int, error) {
+	pr := github.PullRequest{
+		Number: 1000 + len(f.CreatedPullRequests),
+		Title:  title,
+		Body:   body,
+		Head:   github.PullRequestBranch{Ref: head},
+		Base:   github.PullRequestBranch{Ref: base},
+	}
+	f.CreatedPullRequests = append(f.CreatedPullRequests, pr)
+	return pr.Number, nil
+}
+
+// FindPullRequest only knows about the PRs opened with CreatePullRequest.
+func (f *FakeClient) FindPullRequest(org, repo, head, base string) (*github.PullRequest, error) {
+	for i, pr := range f.CreatedPullRequests {
+		if pr.Head.Ref == head && pr.Base.Ref == base {
+			return &f.CreatedPullRequests[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *FakeClient) CreateFork(org, repo string) error {
+	f.Forks = append(f.Forks, org+"/"+repo)
+	return nil
+}
+
+// RepoExists only knows about the bot's forks. Every other repo exists.
+func (f *FakeClient) RepoExists(org, repo string) (bool, error) {
+	if org != f.BotName() {
+		return true, nil
+	}
+	for _, fork := range f.Forks {
+		if strings.HasSuffix(fork, "/"+repo) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (f *FakeClient) GetRef(owner, repo, ref string) (string, error) {
 	return "abcde", nil
 }
 
 func (f *FakeClient) CreateStatus(owner, repo, ref string, s github.Status) error {
+	if f.CreatedStatuses == nil {
+		f.CreatedStatuses = make(map[string][]github.Status)
+	}
+	f.CreatedStatuses[ref] = append(f.CreatedStatuses[ref], s)
 	return nil
 }
 
+func (f *FakeClient) CompareCommits(org, repo, base, head string) (*github.CommitComparison, error) {
+	c, ok := f.Comparisons[base+"..."+head]
+	if !ok {
+		return nil, fmt.Errorf("could not compare %s...%s", base, head)
+	}
+	return c, nil
+}
+
 func (f *FakeClient) GetCombinedStatus(owner, repo, ref string) (*github.CombinedStatus, error) {
 	return f.CombinedStatuses[ref], nil
 }
@@ -127,7 +213,7 @@ func (f *FakeClient) GetRepoLabels(owner, repo string) ([]github.Label, error) {
 
 func (f *FakeClient) GetIssueLabels(owner, repo string, number int) ([]github.Label, error) {
 	// Only labels added to an issue are considered. Removals are ignored by this fake.
-	re := regexp.MustCompile(fmt.Sprintf(`%s/%s#%d:([\w-]*)`, owner, repo, number))
+	re := regexp.MustCompile(fmt.Sprintf(`^%s/%s#%d:(.*)$`, owner, repo, number))
 	la := []github.Label{}
 	for _, l := range f.LabelsAdded {
 		groups := re.FindStringSubmatch(l)
@@ -196,3 +282,90 @@ func (f *FakeClient) GetFile(org, repo, file, commit string) ([]byte, error) {
 
 	return nil, fmt.Errorf("could not find file %s with ref %s", file, commit)
 }
+
+func (f *FakeClient) ListPullRequestCommits(org, repo string, number int) ([]github.RepositoryCommit, error) {
+	return f.PullRequestCommits[number], nil
+}
+
+func (f *FakeClient) ListReviews(org, repo string, number int) ([]github.Review, error) {
+	return append([]github.Review{}, f.Reviews[number]...), nil
+}
+
+// Merge refuses PRs that GitHub considers unmergeable and PRs whose head
+// doesn't match details.SHA.
+func (f *FakeClient) Merge(org, repo string, number int, details github.MergeDetails) error {
+	if pr, ok := f.PullRequests[number]; ok {
+		if pr.Mergeable != nil && !*pr.Mergeable {
+			return github.UnmergablePRError("Pull Request is not mergeable")
+		}
+		if details.SHA != "" && details.SHA != pr.Head.SHA {
+			return github.ModifiedHeadError("Head branch was modified. Review and try the merge again.")
+		}
+		pr.Merged = true
+	}
+	f.Merged = append(f.Merged, fmt.Sprintf("%s/%s#%d", org, repo, number))
+	return nil
+}
+
+func users(logins []string) []github.User {
+	var us []github.User
+	for _, l := range logins {
+		us = append(us, github.User{Login: l})
+	}
+	return us
+}
+
+func (f *FakeClient) ListOrgMembers(org string) ([]github.User, error) {
+	return users(f.OrgMembers), nil
+}
+
+func (f *FakeClient) ListTeams(org string) ([]github.Team, error) {
+	return f.Teams, nil
+}
+
+func (f *FakeClient) ListTeamMembers(org string, id int) ([]github.User, error) {
+	return users(f.TeamMembers[id]), nil
+}
+
+func (f *FakeClient) ListCollaborators(org, repo string) ([]github.User, error) {
+	return users(f.Collaborators), nil
+}
+
+func (f *FakeClient) AddRepoLabel(org, repo, label, color string) error {
+	for _, l := range f.ExistingLabels {
+		if l == label {
+			return fmt.Errorf("label %s already exists", label)
+		}
+	}
+	f.ExistingLabels = append(f.ExistingLabels, label)
+	return nil
+}
+
+func (f *FakeClient) UpdateRepoLabel(org, repo, label, newName, color string) error {
+	for i, l := range f.ExistingLabels {
+		if l == label {
+			f.ExistingLabels[i] = newName
+			return nil
+		}
+	}
+	return fmt.Errorf("label %s does not exist", label)
+}
+
+func (f *FakeClient) ListMilestones(org, repo string) ([]github.Milestone, error) {
+	return f.Milestones, nil
+}
+
+func (f *FakeClient) SetMilestone(org, repo string, number, milestone int) error {
+	for _, m := range f.Milestones {
+		if m.Number == milestone {
+			f.MilestonesSet = append(f.MilestonesSet, fmt.Sprintf("%s/%s#%d:%d", org, repo, number, milestone))
+			return nil
+		}
+	}
+	return fmt.Errorf("milestone %d does not exist", milestone)
+}
+
+func (f *FakeClient) ClearMilestone(org, repo string, number int) error {
+	f.MilestonesCleared = append(f.MilestonesCleared, fmt.Sprintf("%s/%s#%d", org, repo, number))
+	return nil
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	tokensRemaining = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "prow_github_tokens_remaining",
+		Help: "API requests left in the current GitHub rate limit window, as of the last response.",
+	})
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "prow_github_requests_total",
+		Help: "GitHub API requests made, including retries, by method and response code. Failed requests have code \"error\".",
+	}, []string{"method", "code"})
+)
+
+func init() {
+	prometheus.MustRegister(tokensRemaining)
+	prometheus.MustRegister(requests)
+}
+
+// throttler is a token bucket shared by a client and all of its copies.
+type throttler struct {
+	ticker *time.Ticker
+	tokens chan struct{}
+	// done stops the goroutine that refills tokens.
+	done chan struct{}
+}
+
+// Throttle limits the client to hourlyTokens requests per hour on average,
+// allowing bursts of up to burst requests. Every copy of the client made
+// afterwards, such as with DryRunCopy, draws from the same budget. Set either
+// to zero to stop throttling.
+func (c *Client) Throttle(hourlyTokens, burst int) {
+	if c.throttle != nil {
+		c.throttle.stop()
+		c.throttle = nil
+	}
+	if hourlyTokens <= 0 || burst <= 0 {
+		return
+	}
+	t := &throttler{
+		ticker: time.NewTicker(time.Hour / time.Duration(hourlyTokens)),
+		tokens: make(chan struct{}, burst),
+		done:   make(chan struct{}),
+	}
+	for i := 0; i < burst; i++ {
+		t.tokens <- struct{}{}
+	}
+	go func() {
+		for {
+			select {
+			case <-t.ticker.C:
+				select {
+				case t.tokens <- struct{}{}:
+				default:
+				}
+			case <-t.done:
+				return
+			}
+		}
+	}()
+	c.throttle = t
+}
+
+// stop stops refilling tokens.
+func (t *throttler) stop() {
+	t.ticker.Stop()
+	close(t.done)
+}
+
+// wait blocks until the client may make another request.
+func (t *throttler) wait() {
+	if t != nil {
+		<-t.tokens
+	}
+}
+
+// recordRequest counts a request and its response, which is nil if the
+// request failed.
+func recordRequest(method string, resp *http.Response) {
+	code := "error"
+	if resp != nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	requests.WithLabelValues(method, code).Inc()
+}
+
+// recordRateLimit exports the remaining tokens from a response, if GitHub
+// told us.
+func recordRateLimit(h http.Header) {
+	if r, err := strconv.Atoi(h.Get("X-RateLimit-Remaining")); err == nil {
+		tokensRemaining.Set(float64(r))
+	}
+}
+
+// rateLimitWait returns how long GitHub wants us to wait before retrying a
+// rejected request, if it said. Abuse detection sets Retry-After in seconds,
+// while running out of tokens sets X-RateLimit-Reset to when they come back.
+func rateLimitWait(h http.Header) (time.Duration, bool) {
+	if s, err := strconv.Atoi(h.Get("Retry-After")); err == nil {
+		return time.Duration(s) * time.Second, true
+	}
+	if h.Get("X-RateLimit-Remaining") != "0" {
+		return 0, false
+	}
+	t, err := strconv.Atoi(h.Get("X-RateLimit-Reset"))
+	if err != nil {
+		return 0, false
+	}
+	// Sleep an extra second to be safe about clock skew.
+	wait := time.Until(time.Unix(int64(t), 0)) + time.Second
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return wait, true
+}
@@ -17,7 +17,9 @@ limitations under the License.
 package github
 
 import (
+	"fmt"
 	"strings"
+	"time"
 )
 
 // These are possible State entries for a Status.
@@ -48,6 +50,8 @@ type Status struct {
 	TargetURL   string `json:"target_url,omitempty"`
 	Description string `json:"description,omitempty"`
 	Context     string `json:"context,omitempty"`
+	// UpdatedAt is set by GitHub when listing statuses.
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
 }
 
 // CombinedStatus is the latest statuses for a ref.
@@ -55,6 +59,16 @@ type CombinedStatus struct {
 	Statuses []Status `json:"statuses"`
 }
 
+// CommitComparison is the result of comparing two commits.
+type CommitComparison struct {
+	// Status is one of "ahead", "behind", "diverged" or "identical".
+	Status   string `json:"status"`
+	AheadBy  int    `json:"ahead_by"`
+	BehindBy int    `json:"behind_by"`
+	// MergeBaseCommit is the best common ancestor of the two commits.
+	MergeBaseCommit RepositoryCommit `json:"merge_base_commit"`
+}
+
 // User is a GitHub user account.
 type User struct {
 	Login string `json:"login"`
@@ -75,12 +89,18 @@ type PullRequest struct {
 	Number             int               `json:"number"`
 	HTMLURL            string            `json:"html_url"`
 	User               User              `json:"user"`
+	Title              string            `json:"title"`
 	Base               PullRequestBranch `json:"base"`
 	Head               PullRequestBranch `json:"head"`
 	Body               string            `json:"body"`
 	RequestedReviewers []User            `json:"requested_reviewers"`
 	Assignees          []User            `json:"assignees"`
 	Merged             bool              `json:"merged"`
+	// Milestone is nil if the PR isn't in a milestone.
+	Milestone *Milestone `json:"milestone,omitempty"`
+	// Mergeable is nil while GitHub is still computing whether or not the PR
+	// can be merged cleanly into its base branch.
+	Mergeable *bool `json:"mergeable,omitempty"`
 	// ref https://developer.github.com/v3/pulls/#get-a-single-pull-request
 	// If Merged is true, MergeSHA is the SHA of the merge commit, or squashed commit
 	// If Merged is false, MergeSHA is a commit SHA that github created to test if
@@ -124,6 +144,9 @@ type IssueEvent struct {
 	Action string `json:"action"`
 	Issue  Issue  `json:"issue"`
 	Repo   Repo   `json:"repository"`
+	// Label is the label that was added or removed, for the labeled and
+	// unlabeled actions.
+	Label Label `json:"label"`
 }
 
 type IssueCommentEvent struct {
@@ -142,11 +165,32 @@ type Issue struct {
 	Labels    []Label `json:"labels"`
 	Assignees []User  `json:"assignees"`
 	Body      string  `json:"body"`
+	// Milestone is nil if the issue isn't in a milestone.
+	Milestone *Milestone `json:"milestone,omitempty"`
 
 	// This will be non-nil if it is a pull request.
 	PullRequest *struct{} `json:"pull_request,omitempty"`
 }
 
+// Milestone is a GitHub milestone.
+type Milestone struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	// State is "open" or "closed".
+	State string `json:"state,omitempty"`
+}
+
+// OrgRepo extracts the org and repo from the issue's HTML URL, such as
+// https://github.com/kubernetes/test-infra/pull/123. Search results do not
+// include the repository object.
+func (i Issue) OrgRepo() (string, string, error) {
+	parts := strings.Split(strings.TrimPrefix(i.HTMLURL, "https://github.com/"), "/")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("cannot determine repo from %q", i.HTMLURL)
+	}
+	return parts[0], parts[1], nil
+}
+
 func (i Issue) IsAssignee(login string) bool {
 	for _, assignee := range i.Assignees {
 		if login == assignee.Login {
@@ -296,3 +340,60 @@ type Content struct {
 	Content string `json:"content"`
 	SHA     string `json:"sha"`
 }
+
+// Branch is a branch of a repo, as listed by GitHub.
+type Branch struct {
+	Name      string `json:"name"`
+	Protected bool   `json:"protected"`
+}
+
+// RequiredStatusChecks is the status check part of a branch's protection.
+// Strict requires branches to be up to date with the base before merging.
+type RequiredStatusChecks struct {
+	Strict   bool     `json:"strict"`
+	Contexts []string `json:"contexts"`
+}
+
+// RepositoryCommit is a commit as GitHub lists it, such as in a PR.
+type RepositoryCommit struct {
+	SHA    string    `json:"sha"`
+	Commit GitCommit `json:"commit"`
+	// Author and Committer are the GitHub users that match the git author and
+	// committer, if GitHub knows them.
+	Author    User `json:"author"`
+	Committer User `json:"committer"`
+}
+
+// GitCommit is the git part of a RepositoryCommit.
+type GitCommit struct {
+	Message   string       `json:"message"`
+	Author    CommitAuthor `json:"author"`
+	Committer CommitAuthor `json:"committer"`
+}
+
+// CommitAuthor is the git author or committer of a commit.
+type CommitAuthor struct {
+	Name  string    `json:"name"`
+	Email string    `json:"email"`
+	Date  time.Time `json:"date"`
+}
+
+// MergeDetails are the optional parameters for merging a PR.
+type MergeDetails struct {
+	// CommitTitle defaults to the automatic message.
+	CommitTitle string `json:"commit_title,omitempty"`
+	// CommitMessage is appended to the title, and defaults to the automatic
+	// message.
+	CommitMessage string `json:"commit_message,omitempty"`
+	// SHA, if set, must match the PR's head for it to be merged.
+	SHA string `json:"sha,omitempty"`
+	// MergeMethod is "merge", "squash" or "rebase". Defaults to merge.
+	MergeMethod string `json:"merge_method,omitempty"`
+}
+
+// Team is a GitHub team.
+type Team struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
@@ -0,0 +1,33 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import "testing"
+
+func TestIssueOrgRepo(t *testing.T) {
+	i := Issue{HTMLURL: "https://github.com/kubernetes/test-infra/pull/123"}
+	org, repo, err := i.OrgRepo()
+	if err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if org != "kubernetes" || repo != "test-infra" {
+		t.Errorf("Expected kubernetes/test-infra, got %s/%s", org, repo)
+	}
+	if _, _, err := (Issue{}).OrgRepo(); err == nil {
+		t.Error("Expected error for empty URL.")
+	}
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package hook
 
 import (
+	"time"
+
 	"github.com/Sirupsen/logrus"
 
 	"k8s.io/test-infra/prow/github"
@@ -38,7 +40,11 @@ func (s *Server) handleReviewEvent(re github.ReviewEvent) {
 			pc := s.Plugins.PluginClient
 			pc.Logger = l.WithField("plugin", p)
 			pc.Config = s.ConfigAgent.Config()
-			if err := h(pc, re); err != nil {
+			pc = s.Plugins.ShadowClient(p, pc)
+			start := time.Now()
+			err := h(pc, re)
+			recordPlugin("pull_request_review", p, start, err)
+			if err != nil {
 				pc.Logger.WithError(err).Error("Error handling ReviewEvent.")
 			}
 		}(p, h)
@@ -60,7 +66,11 @@ func (s *Server) handleReviewCommentEvent(rce github.ReviewCommentEvent) {
 			pc := s.Plugins.PluginClient
 			pc.Logger = l.WithField("plugin", p)
 			pc.Config = s.ConfigAgent.Config()
-			if err := h(pc, rce); err != nil {
+			pc = s.Plugins.ShadowClient(p, pc)
+			start := time.Now()
+			err := h(pc, rce)
+			recordPlugin("pull_request_review_comment", p, start, err)
+			if err != nil {
 				pc.Logger.WithError(err).Error("Error handling ReviewCommentEvent.")
 			}
 		}(p, h)
@@ -81,7 +91,11 @@ func (s *Server) handlePullRequestEvent(pr github.PullRequestEvent) {
 			pc := s.Plugins.PluginClient
 			pc.Logger = l.WithField("plugin", p)
 			pc.Config = s.ConfigAgent.Config()
-			if err := h(pc, pr); err != nil {
+			pc = s.Plugins.ShadowClient(p, pc)
+			start := time.Now()
+			err := h(pc, pr)
+			recordPlugin("pull_request", p, start, err)
+			if err != nil {
 				pc.Logger.WithError(err).Error("Error handling PullRequestEvent.")
 			}
 		}(p, h)
@@ -101,7 +115,11 @@ func (s *Server) handlePushEvent(pe github.PushEvent) {
 			pc := s.Plugins.PluginClient
 			pc.Logger = l.WithField("plugin", p)
 			pc.Config = s.ConfigAgent.Config()
-			if err := h(pc, pe); err != nil {
+			pc = s.Plugins.ShadowClient(p, pc)
+			start := time.Now()
+			err := h(pc, pe)
+			recordPlugin("push", p, start, err)
+			if err != nil {
 				pc.Logger.WithError(err).Error("Error handling PushEvent.")
 			}
 		}(p, h)
@@ -122,7 +140,11 @@ func (s *Server) handleIssueEvent(i github.IssueEvent) {
 			pc := s.Plugins.PluginClient
 			pc.Logger = l.WithField("plugin", p)
 			pc.Config = s.ConfigAgent.Config()
-			if err := h(pc, i); err != nil {
+			pc = s.Plugins.ShadowClient(p, pc)
+			start := time.Now()
+			err := h(pc, i)
+			recordPlugin("issues", p, start, err)
+			if err != nil {
 				pc.Logger.WithError(err).Error("Error handleing IssueEvent.")
 			}
 		}(p, h)
@@ -143,7 +165,11 @@ func (s *Server) handleIssueCommentEvent(ic github.IssueCommentEvent) {
 			pc := s.Plugins.PluginClient
 			pc.Logger = l.WithField("plugin", p)
 			pc.Config = s.ConfigAgent.Config()
-			if err := h(pc, ic); err != nil {
+			pc = s.Plugins.ShadowClient(p, pc)
+			start := time.Now()
+			err := h(pc, ic)
+			recordPlugin("issue_comment", p, start, err)
+			if err != nil {
 				pc.Logger.WithError(err).Error("Error handling IssueCommentEvent.")
 			}
 		}(p, h)
@@ -165,7 +191,11 @@ func (s *Server) handleStatusEvent(se github.StatusEvent) {
 			pc := s.Plugins.PluginClient
 			pc.Logger = l.WithField("plugin", p)
 			pc.Config = s.ConfigAgent.Config()
-			if err := h(pc, se); err != nil {
+			pc = s.Plugins.ShadowClient(p, pc)
+			start := time.Now()
+			err := h(pc, se)
+			recordPlugin("status", p, start, err)
+			if err != nil {
 				pc.Logger.WithError(err).Error("Error handling StatusEvent.")
 			}
 		}(p, h)
@@ -0,0 +1,144 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+const (
+	// externalPluginTimeout bounds each attempt to deliver an event.
+	externalPluginTimeout = 10 * time.Second
+	// externalPluginRetries is the number of attempts made per event.
+	externalPluginRetries = 3
+)
+
+// externalPluginBackoff is the wait before the first retry. It doubles
+// after every further failed attempt.
+var externalPluginBackoff = 2 * time.Second
+
+var externalPluginClient = &http.Client{Timeout: externalPluginTimeout}
+
+var (
+	externalPluginRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "prow_hook_external_plugin_requests_total",
+		Help: "Number of events forwarded to external plugins, by plugin and result.",
+	}, []string{"plugin", "result"})
+	externalPluginLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "prow_hook_external_plugin_request_duration_seconds",
+		Help: "Time taken to deliver an event to an external plugin, including retries.",
+	}, []string{"plugin"})
+)
+
+func init() {
+	prometheus.MustRegister(externalPluginRequests)
+	prometheus.MustRegister(externalPluginLatency)
+}
+
+// handleExternal forwards the webhook to the external plugins that are
+// enabled on its repo and want its event type.
+func (s *Server) handleExternal(eventType, eventGUID string, payload []byte) {
+	var e struct {
+		Repo github.Repo `json:"repository"`
+	}
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return
+	}
+	// Push events only carry the owner's name.
+	org := e.Repo.Owner.Login
+	if org == "" {
+		org = e.Repo.Owner.Name
+	}
+	if org == "" {
+		// Events without a repository are never forwarded.
+		return
+	}
+	l := logrus.WithFields(logrus.Fields{
+		"org":   org,
+		"repo":  e.Repo.Name,
+		"event": eventType,
+		"guid":  eventGUID,
+	})
+	for _, ep := range s.Plugins.ExternalPlugins(org, e.Repo.Name, eventType) {
+		go func(ep plugins.ExternalPlugin) {
+			el := l.WithField("external-plugin", ep.Name)
+			start := time.Now()
+			err := s.dispatch(ep.Endpoint, eventType, eventGUID, payload)
+			externalPluginLatency.WithLabelValues(ep.Name).Observe(time.Since(start).Seconds())
+			if err != nil {
+				externalPluginRequests.WithLabelValues(ep.Name, "error").Inc()
+				el.WithError(err).Error("Error forwarding event to external plugin.")
+				return
+			}
+			externalPluginRequests.WithLabelValues(ep.Name, "success").Inc()
+			el.Info("Forwarded event to external plugin.")
+		}(ep)
+	}
+}
+
+// dispatch POSTs the payload to the endpoint the way GitHub would, signing it
+// with our HMAC secret. It retries on transport errors and 5XX responses.
+func (s *Server) dispatch(endpoint, eventType, eventGUID string, payload []byte) error {
+	backoff := externalPluginBackoff
+	var err error
+	for attempt := 0; attempt < externalPluginRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		var retry bool
+		retry, err = s.dispatchOnce(endpoint, eventType, eventGUID, payload)
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// dispatchOnce makes one delivery attempt. It returns whether or not the
+// attempt is worth retrying if it failed.
+func (s *Server) dispatchOnce(endpoint, eventType, eventGUID string, payload []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("X-GitHub-Event", eventType)
+	req.Header.Set("X-GitHub-Delivery", eventGUID)
+	req.Header.Set("X-Hub-Signature", github.PayloadSignature(payload, s.HMACSecret))
+	req.Header.Set("content-type", "application/json")
+	resp, err := externalPluginClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		rb, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode >= 500, fmt.Errorf("response has status %d and body %s", resp.StatusCode, string(bytes.TrimSpace(rb)))
+	}
+	return false, nil
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/phony"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// TestExternalPlugin sends a webhook to hook and ensures that it is forwarded
+// to an external plugin, signed with the same secret.
+func TestExternalPlugin(t *testing.T) {
+	type delivery struct {
+		event   string
+		valid   bool
+		payload string
+	}
+	delivered := make(chan delivery, 1)
+	secret := []byte("123abc")
+	ep := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("Reading forwarded body: %v", err)
+		}
+		delivered <- delivery{
+			event:   r.Header.Get("X-GitHub-Event"),
+			valid:   github.ValidatePayload(b, r.Header.Get("X-Hub-Signature"), secret),
+			payload: string(b),
+		}
+	}))
+	defer ep.Close()
+
+	payload, err := json.Marshal(&ice)
+	if err != nil {
+		t.Fatalf("Marshalling ICE: %v", err)
+	}
+	pa := &plugins.PluginAgent{}
+	if err := pa.SetExternal(map[string][]plugins.ExternalPlugin{
+		"foo": {{Name: "ext", Endpoint: ep.URL, Events: []string{"issue_comment"}}},
+	}); err != nil {
+		t.Fatalf("Setting external plugins: %v", err)
+	}
+	s := httptest.NewServer(&Server{
+		Plugins:     pa,
+		ConfigAgent: &config.Agent{},
+		HMACSecret:  secret,
+	})
+	defer s.Close()
+
+	if err := phony.SendHook(s.URL, "issues", payload, secret); err != nil {
+		t.Fatalf("Error sending hook: %v", err)
+	}
+	if err := phony.SendHook(s.URL, "issue_comment", payload, secret); err != nil {
+		t.Fatalf("Error sending hook: %v", err)
+	}
+	select {
+	case d := <-delivered:
+		if d.event != "issue_comment" {
+			t.Errorf("Expected only the issue_comment event to be forwarded, got %s.", d.event)
+		}
+		if !d.valid {
+			t.Error("Forwarded payload has an invalid signature.")
+		}
+		if d.payload != string(payload) {
+			t.Errorf("Expected payload %s, got %s.", payload, d.payload)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("External plugin not called after one second.")
+	}
+	select {
+	case d := <-delivered:
+		t.Errorf("Unexpected second delivery of %s event.", d.event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// TestExternalPluginPush ensures that push events, whose repository owner has
+// a name but no login, are forwarded too.
+func TestExternalPluginPush(t *testing.T) {
+	delivered := make(chan string, 1)
+	ep := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered <- r.Header.Get("X-GitHub-Event")
+	}))
+	defer ep.Close()
+
+	payload, err := json.Marshal(&github.PushEvent{
+		Ref:   "refs/heads/master",
+		After: "abcdef",
+		Repo: github.Repo{
+			Owner:    github.User{Name: "foo"},
+			Name:     "bar",
+			FullName: "foo/bar",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Marshalling push event: %v", err)
+	}
+	pa := &plugins.PluginAgent{}
+	if err := pa.SetExternal(map[string][]plugins.ExternalPlugin{
+		"foo": {{Name: "ext", Endpoint: ep.URL, Events: []string{"push"}}},
+	}); err != nil {
+		t.Fatalf("Setting external plugins: %v", err)
+	}
+	secret := []byte("123abc")
+	s := httptest.NewServer(&Server{
+		Plugins:     pa,
+		ConfigAgent: &config.Agent{},
+		HMACSecret:  secret,
+	})
+	defer s.Close()
+
+	if err := phony.SendHook(s.URL, "push", payload, secret); err != nil {
+		t.Fatalf("Error sending hook: %v", err)
+	}
+	select {
+	case event := <-delivered:
+		if event != "push" {
+			t.Errorf("Expected the push event to be forwarded, got %s.", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("External plugin not called after one second.")
+	}
+}
+
+func TestDispatchRetries(t *testing.T) {
+	oldBackoff := externalPluginBackoff
+	externalPluginBackoff = 0
+	defer func() { externalPluginBackoff = oldBackoff }()
+
+	var testcases = []struct {
+		name     string
+		codes    []int
+		err      bool
+		attempts int
+	}{
+		{
+			name:     "success",
+			codes:    []int{200},
+			attempts: 1,
+		},
+		{
+			name:     "server error then success",
+			codes:    []int{500, 503, 204},
+			attempts: 3,
+		},
+		{
+			name:     "server errors until we give up",
+			codes:    []int{500, 500, 500, 200},
+			err:      true,
+			attempts: 3,
+		},
+		{
+			name:     "client errors aren't retried",
+			codes:    []int{400, 200},
+			err:      true,
+			attempts: 1,
+		},
+	}
+	for _, tc := range testcases {
+		attempts := 0
+		ep := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tc.codes[attempts])
+			attempts++
+		}))
+		s := &Server{HMACSecret: []byte("abc")}
+		err := s.dispatch(ep.URL, "issues", "guid", []byte("{}"))
+		ep.Close()
+		if tc.err && err == nil {
+			t.Errorf("For case %s, expected an error.", tc.name)
+		} else if !tc.err && err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+		}
+		if attempts != tc.attempts {
+			t.Errorf("For case %s, expected %d attempts, got %d.", tc.name, tc.attempts, attempts)
+		}
+	}
+}
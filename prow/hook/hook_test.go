@@ -22,6 +22,8 @@ import (
 	"testing"
 	"time"
 
+	dto "github.com/prometheus/client_model/go"
+
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/phony"
@@ -63,6 +65,7 @@ func TestHook(t *testing.T) {
 		HMACSecret:  secret,
 	})
 	defer s.Close()
+	before := webhookCount(t, "issues")
 	if err := phony.SendHook(s.URL, "issues", payload, secret); err != nil {
 		t.Fatalf("Error sending hook: %v", err)
 	}
@@ -71,4 +74,15 @@ func TestHook(t *testing.T) {
 	case <-time.After(time.Second):
 		t.Error("Plugin not called after one second.")
 	}
+	if d := webhookCount(t, "issues") - before; d != 1 {
+		t.Errorf("Expected 1 issues webhook counted, got %v", d)
+	}
+}
+
+func webhookCount(t *testing.T, eventType string) float64 {
+	var m dto.Metric
+	if err := webhooks.WithLabelValues(eventType).Write(&m); err != nil {
+		t.Fatalf("Error reading metric: %v", err)
+	}
+	return m.GetCounter().GetValue()
 }
@@ -0,0 +1,54 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// StartPeriodics calls every registered periodic plugin handler on its own
+// timer. Each tick only considers the repos on which the plugin is currently
+// enabled, so changes to the plugin config take effect without a restart.
+func (s *Server) StartPeriodics() {
+	for p, h := range plugins.PeriodicHandlers() {
+		go func(p string, h plugins.Periodic) {
+			for range time.Tick(h.Interval) {
+				s.runPeriodic(p, h.Handler)
+			}
+		}(p, h)
+	}
+}
+
+func (s *Server) runPeriodic(p string, h plugins.PeriodicHandler) {
+	repos := s.Plugins.EnabledRepos(p)
+	if len(repos) == 0 {
+		return
+	}
+	l := logrus.WithField("plugin", p)
+	l.Infof("Running periodic handler for %v.", repos)
+	pc := s.Plugins.PluginClient
+	pc.Logger = l
+	pc.Config = s.ConfigAgent.Config()
+	pc = s.Plugins.ShadowClient(p, pc)
+	if err := h(pc, repos); err != nil {
+		pc.Logger.WithError(err).Error("Error running periodic handler.")
+	}
+}
@@ -21,14 +21,46 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"time"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/plugins"
 )
 
+var (
+	webhooks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "prow_hook_webhooks_total",
+		Help: "Valid webhooks received, by event type.",
+	}, []string{"event_type"})
+	pluginLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "prow_hook_plugin_handle_duration_seconds",
+		Help: "Time taken by a plugin to handle an event, by event type and plugin.",
+	}, []string{"event_type", "plugin"})
+	pluginErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "prow_hook_plugin_handle_errors_total",
+		Help: "Events that a plugin failed to handle, by event type and plugin.",
+	}, []string{"event_type", "plugin"})
+)
+
+func init() {
+	prometheus.MustRegister(webhooks)
+	prometheus.MustRegister(pluginLatency)
+	prometheus.MustRegister(pluginErrors)
+}
+
+// recordPlugin records a plugin handling an event of the type. It started at
+// start and returned err.
+func recordPlugin(eventType, plugin string, start time.Time, err error) {
+	pluginLatency.WithLabelValues(eventType, plugin).Observe(time.Since(start).Seconds())
+	if err != nil {
+		pluginErrors.WithLabelValues(eventType, plugin).Inc()
+	}
+}
+
 // Server implements http.Handler. It validates incoming GitHub webhooks and
 // then dispatches them to the appropriate plugins.
 type Server struct {
@@ -51,6 +83,7 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "400 Bad Request: Missing X-GitHub-Event Header", http.StatusBadRequest)
 		return
 	}
+	eventGUID := r.Header.Get("X-GitHub-Delivery")
 	sig := r.Header.Get("X-Hub-Signature")
 	if sig == "" {
 		http.Error(w, "403 Forbidden: Missing X-Hub-Signature", http.StatusForbidden)
@@ -74,13 +107,17 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	fmt.Fprint(w, "Event received. Have a nice day.")
+	webhooks.WithLabelValues(eventType).Inc()
 
-	if err := s.demuxEvent(eventType, payload); err != nil {
+	if err := s.demuxEvent(eventType, eventGUID, payload); err != nil {
 		logrus.WithError(err).Error("Error parsing event.")
 	}
 }
 
-func (s *Server) demuxEvent(eventType string, payload []byte) error {
+func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte) error {
+	// External plugins get the raw payload of every event they want, whether
+	// or not hook understands it.
+	go s.handleExternal(eventType, eventGUID, payload)
 	switch eventType {
 	case "issues":
 		var i github.IssueEvent
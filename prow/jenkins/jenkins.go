@@ -243,3 +243,29 @@ func (c *Client) GetLog(job string, build int) ([]byte, error) {
 	}
 	return buf, nil
 }
+
+// GetProgressiveLog returns the build's console output from byte offset
+// start, the offset to ask for next time, and whether the build is still
+// running and may write more.
+func (c *Client) GetProgressiveLog(job string, build int, start int64) ([]byte, int64, bool, error) {
+	u := fmt.Sprintf("%s/job/%s/%d/logText/progressiveText?start=%d", c.baseURL, job, build, start)
+	resp, err := c.request(http.MethodGet, u)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, 0, false, fmt.Errorf("response not 2XX: %s: (%s)", resp.Status, u)
+	}
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	next := start + int64(len(buf))
+	if size := resp.Header.Get("X-Text-Size"); size != "" {
+		if next, err = strconv.ParseInt(size, 10, 64); err != nil {
+			return nil, 0, false, fmt.Errorf("bad X-Text-Size %q: %v", size, err)
+		}
+	}
+	return buf, next, resp.Header.Get("X-More-Data") == "true", nil
+}
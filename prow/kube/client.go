@@ -52,6 +52,10 @@ type Client struct {
 	token     string
 	namespace string
 	fake      bool
+	dry       bool
+	// onSkip, if set, is told about every mutating request that a dry-run
+	// client skips.
+	onSkip func(method, path string, body interface{})
 }
 
 // Namespace returns a copy of the client pointing at the specified namespace.
@@ -61,6 +65,16 @@ func (c *Client) Namespace(ns string) *Client {
 	return &nc
 }
 
+// DryRunCopy returns a copy of the client that will not perform mutating
+// actions such as creating prowjobs. If onSkip is non-nil, it is called with
+// every mutating request that the copy skips.
+func (c *Client) DryRunCopy(onSkip func(method, path string, body interface{})) *Client {
+	nc := *c
+	nc.dry = true
+	nc.onSkip = onSkip
+	return &nc
+}
+
 func (c *Client) log(methodName string, args ...interface{}) {
 	if c.Logger == nil {
 		return
@@ -96,6 +110,12 @@ func (c *Client) request(r *request, ret interface{}) error {
 
 // Retry on transport failures. Does not retry on 500s.
 func (c *Client) requestRetry(r *request) ([]byte, error) {
+	if c.dry && r.method != http.MethodGet {
+		if c.onSkip != nil {
+			c.onSkip(r.method, r.path, r.requestBody)
+		}
+		return []byte("{}"), nil
+	}
 	if c.fake {
 		return []byte("{}"), nil
 	}
@@ -380,6 +400,25 @@ func (c *Client) GetLog(pod string) ([]byte, error) {
 	})
 }
 
+// StreamLog follows the pod's log from the start until the pod finishes or
+// the stream is closed. The caller must close it.
+func (c *Client) StreamLog(pod string) (io.ReadCloser, error) {
+	c.log("StreamLog", pod)
+	if c.fake {
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
+	}
+	resp, err := c.doRequest(http.MethodGet, fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/log", c.namespace, pod), map[string]string{"follow": "true"}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		rb, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("response has status \"%s\" and body \"%s\"", resp.Status, string(rb))
+	}
+	return resp.Body, nil
+}
+
 func (c *Client) CreateConfigMap(content ConfigMap) (ConfigMap, error) {
 	c.log("CreateConfigMap")
 	var retConfigMap ConfigMap
@@ -23,6 +23,7 @@ import (
 	"encoding/base64"
 	"encoding/pem"
 	"fmt"
+	"io/ioutil"
 	"math/big"
 	"math/rand"
 	"net"
@@ -259,3 +260,31 @@ func TestNewClient(t *testing.T) {
 		t.Fatalf("Failed to talk to server: %v", err)
 	}
 }
+
+func TestStreamLog(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/ns/pods/po/log" {
+			t.Errorf("Bad request path: %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("follow") != "true" {
+			t.Errorf("Expected follow=true, got query %s", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, "line one\n")
+		w.(http.Flusher).Flush()
+		fmt.Fprint(w, "line two\n")
+	}))
+	defer ts.Close()
+	c := getClient(ts.URL)
+	stream, err := c.StreamLog("po")
+	if err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	defer stream.Close()
+	b, err := ioutil.ReadAll(stream)
+	if err != nil {
+		t.Fatalf("Error reading stream: %v", err)
+	}
+	if string(b) != "line one\nline two\n" {
+		t.Errorf("Wrong log: %q", string(b))
+	}
+}
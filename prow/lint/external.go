@@ -0,0 +1,91 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+)
+
+// ExternalFinding is the format that external linters must print. They
+// print a JSON list of findings to stdout.
+type ExternalFinding struct {
+	// Path is relative to the root of the repo.
+	Path string `json:"path"`
+	// Line starts at 1. Zero or missing means the whole file.
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// External runs a command from the root of the repo with the files to lint
+// as its final arguments. The command must print a JSON list of
+// ExternalFindings to stdout.
+type External struct {
+	name    string
+	command []string
+	re      *regexp.Regexp
+}
+
+// NewExternal creates a linter named name that runs the command on files
+// matching re. If re is nil, it runs on every file.
+func NewExternal(name string, command []string, re *regexp.Regexp) *External {
+	return &External{
+		name:    name,
+		command: command,
+		re:      re,
+	}
+}
+
+func (e *External) Name() string {
+	return e.name
+}
+
+func (e *External) Matches(file string) bool {
+	return e.re == nil || e.re.MatchString(file)
+}
+
+func (e *External) Lint(dir string, files []string) ([]Problem, error) {
+	if len(e.command) == 0 {
+		return nil, fmt.Errorf("no command for %s", e.name)
+	}
+	args := append(append([]string{}, e.command[1:]...), files...)
+	cmd := exec.Command(e.command[0], args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", e.command, commandError(err))
+	}
+	return parseExternalOutput(e.name, out)
+}
+
+func parseExternalOutput(name string, out []byte) ([]Problem, error) {
+	var findings []ExternalFinding
+	if err := json.Unmarshal(out, &findings); err != nil {
+		return nil, fmt.Errorf("parsing output of %s: %v", name, err)
+	}
+	var problems []Problem
+	for _, f := range findings {
+		problems = append(problems, Problem{
+			File:    f.Path,
+			Line:    f.Line,
+			Message: fmt.Sprintf("%s: %s", name, f.Message),
+		})
+	}
+	return problems, nil
+}
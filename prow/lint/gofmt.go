@@ -0,0 +1,63 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Gofmt runs gofmt -l on go files and reports those that are not formatted.
+type Gofmt struct{}
+
+func (Gofmt) Name() string {
+	return "gofmt"
+}
+
+func (Gofmt) Matches(file string) bool {
+	return filepath.Ext(file) == ".go"
+}
+
+func (Gofmt) Lint(dir string, files []string) ([]Problem, error) {
+	cmd := exec.Command("gofmt", append([]string{"-l"}, files...)...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("gofmt -l: %v", commandError(err))
+	}
+	var problems []Problem
+	for _, f := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if f == "" {
+			continue
+		}
+		problems = append(problems, Problem{
+			File:    f,
+			Message: "Gofmt: this file is not formatted. Please run `gofmt -w " + f + "`.",
+		})
+	}
+	return problems, nil
+}
+
+// commandError includes stderr in the error if there is any.
+func commandError(err error) error {
+	if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(ee.Stderr)))
+	}
+	return err
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	golint "github.com/golang/lint"
+)
+
+// Golint runs golint on go files.
+type Golint struct{}
+
+func (Golint) Name() string {
+	return "golint"
+}
+
+func (Golint) Matches(file string) bool {
+	return filepath.Ext(file) == ".go"
+}
+
+func (Golint) Lint(dir string, files []string) ([]Problem, error) {
+	var problems []Problem
+	l := new(golint.Linter)
+	for _, f := range files {
+		src, err := ioutil.ReadFile(filepath.Join(dir, f))
+		if err != nil {
+			return nil, err
+		}
+		ps, err := l.Lint(f, src)
+		if err != nil {
+			return nil, fmt.Errorf("linting %s: %v", f, err)
+		}
+		for _, p := range ps {
+			var msg string
+			if p.Link == "" {
+				msg = fmt.Sprintf("Golint %s: %s.", p.Category, p.Text)
+			} else {
+				msg = fmt.Sprintf("Golint %s: %s. [More info](%s).", p.Category, p.Text, p.Link)
+			}
+			problems = append(problems, Problem{
+				File:    f,
+				Line:    p.Position.Line,
+				Message: msg,
+			})
+		}
+	}
+	return problems, nil
+}
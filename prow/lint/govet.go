@@ -0,0 +1,112 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GoVet runs go vet on the packages that contain modified go files.
+type GoVet struct{}
+
+func (GoVet) Name() string {
+	return "govet"
+}
+
+func (GoVet) Matches(file string) bool {
+	return filepath.Ext(file) == ".go"
+}
+
+func (GoVet) Lint(dir string, files []string) ([]Problem, error) {
+	pkgs := map[string]bool{}
+	for _, f := range files {
+		pkgs["./"+filepath.Dir(f)] = true
+	}
+	args := []string{"vet"}
+	for p := range pkgs {
+		args = append(args, p)
+	}
+	sort.Strings(args[1:])
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	problems, buildErrs := parseVetOutput(out.Bytes(), files)
+	// Without the repo's dependencies vet can't type-check, and what it
+	// says about that isn't something for the PR author to fix.
+	if len(buildErrs) > 0 {
+		return nil, fmt.Errorf("go vet could not type-check: %s", strings.Join(buildErrs, "; "))
+	}
+	// go vet exits non-zero when it finds something, so only fail if it
+	// didn't tell us what was wrong with our files.
+	if err != nil && len(problems) == 0 {
+		if _, ok := err.(*exec.ExitError); !ok {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%v: %s", err, out.String())
+	}
+	return problems, nil
+}
+
+// vetRe matches lines such as "pkg/foo.go:12:3: unreachable code".
+var vetRe = regexp.MustCompile(`^(?:\./)?(\S+\.go):(\d+)(?::\d+)?: (.*)$`)
+
+// buildErrRe matches the messages of go vet that mean it couldn't load or
+// type-check a package, rather than that it found a problem in it.
+var buildErrRe = regexp.MustCompile(`cannot find package|could not import|can't load package|undeclared name|undefined: `)
+
+// parseVetOutput returns the problems that go vet found in the files, and
+// separately the lines that say it couldn't build them.
+func parseVetOutput(out []byte, files []string) ([]Problem, []string) {
+	want := map[string]bool{}
+	for _, f := range files {
+		want[f] = true
+	}
+	var problems []Problem
+	var buildErrs []string
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		if buildErrRe.MatchString(s.Text()) {
+			buildErrs = append(buildErrs, s.Text())
+			continue
+		}
+		m := vetRe.FindStringSubmatch(s.Text())
+		if m == nil || !want[m[1]] {
+			continue
+		}
+		line, err := strconv.Atoi(m[2])
+		if err != nil {
+			continue
+		}
+		problems = append(problems, Problem{
+			File:    m[1],
+			Line:    line,
+			Message: fmt.Sprintf("Go vet: %s.", m[3]),
+		})
+	}
+	return problems, buildErrs
+}
@@ -0,0 +1,337 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lint runs analyzers against the files modified in a PR and reports
+// what they find as review comments on the lines that the PR added.
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/git"
+	"k8s.io/test-infra/prow/github"
+)
+
+// MaxComments is the most review comments we will leave on a PR, including
+// those left on previous runs.
+const MaxComments = 20
+
+// Problem is something that a linter found wrong with a file.
+type Problem struct {
+	// File is the path relative to the root of the repo.
+	File string
+	// Line is the line in the file, starting at 1. Zero means that the
+	// problem applies to the whole file.
+	Line int
+	// Message is the body of the review comment.
+	Message string
+}
+
+// Linter finds problems in files.
+type Linter interface {
+	// Name identifies the linter in logs and in review comments.
+	Name() string
+	// Matches returns whether or not the linter understands the file.
+	Matches(file string) bool
+	// Lint returns the problems in the files, which are relative to dir.
+	Lint(dir string, files []string) ([]Problem, error)
+}
+
+type githubClient interface {
+	GetPullRequestChanges(org, repo string, number int) ([]github.PullRequestChange, error)
+	CreateReview(org, repo string, number int, r github.DraftReview) error
+	ListPullRequestComments(org, repo string, number int) ([]github.ReviewComment, error)
+}
+
+// Tag is an HTML comment added to every review comment left by the linter so
+// that we can recognize it later.
+func Tag(l Linter) string {
+	return fmt.Sprintf("<!-- %s -->", l.Name())
+}
+
+// finding is a problem located in the patch.
+type finding struct {
+	linter   Linter
+	problem  Problem
+	position int
+}
+
+// ReviewPullRequest clones the PR, runs the linters against the files they
+// understand, and posts a single review with a comment for every problem on a
+// line that the PR added. Problems that we already commented on are skipped.
+// formatBody turns a summary such as "3 warnings" into the review body. A
+// linter that fails doesn't stop the others: the review is still posted and
+// the failures are returned afterwards.
+func ReviewPullRequest(ghc githubClient, gc *git.Client, log *logrus.Entry, org, repo string, number int, linters []Linter, formatBody func(string) string) error {
+	changes, err := ghc.GetPullRequestChanges(org, repo, number)
+	if err != nil {
+		return err
+	}
+	patches := map[string]string{}
+	files := make([][]string, len(linters))
+	for _, change := range changes {
+		for i, l := range linters {
+			if l.Matches(change.Filename) {
+				patches[change.Filename] = change.Patch
+				files[i] = append(files[i], change.Filename)
+			}
+		}
+	}
+	if len(patches) == 0 {
+		return nil
+	}
+	log.Infof("Will lint %d modified files.", len(patches))
+
+	// Clone the repo, checkout the PR.
+	startClone := time.Now()
+	r, err := gc.Clone(org + "/" + repo)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := r.Clean(); err != nil {
+			log.WithError(err).Error("Error cleaning up repo.")
+		}
+	}()
+	if err := r.CheckoutPullRequest(number); err != nil {
+		return err
+	}
+	finishClone := time.Now()
+	log.WithField("duration", time.Since(startClone)).Info("Cloned and checked out PR.")
+
+	// Run the linters and find the problems on added lines.
+	added := map[string]map[int]int{}
+	for f, patch := range patches {
+		al, err := addedLines(patch)
+		if err != nil {
+			return fmt.Errorf("computing added lines in %s: %v", f, err)
+		}
+		added[f] = al
+	}
+	var findings []finding
+	var failed []string
+	var lintErrs []string
+	for i, l := range linters {
+		if len(files[i]) == 0 {
+			continue
+		}
+		ps, err := l.Lint(r.Dir, files[i])
+		if err != nil {
+			log.WithError(err).Errorf("Failed to run %s.", l.Name())
+			failed = append(failed, l.Name())
+			lintErrs = append(lintErrs, fmt.Sprintf("running %s: %v", l.Name(), err))
+			continue
+		}
+		for _, p := range ps {
+			if pos, ok := position(added[p.File], p.Line); ok {
+				findings = append(findings, finding{linter: l, problem: p, position: pos})
+			}
+		}
+	}
+	log.WithField("duration", time.Since(finishClone)).Info("Linted.")
+
+	oldComments, err := ghc.ListPullRequestComments(org, repo, number)
+	if err != nil {
+		return err
+	}
+	nfs := newFindings(oldComments, findings)
+
+	// Make the list of comments.
+	var comments []github.DraftReviewComment
+	for _, f := range nfs {
+		comments = append(comments, github.DraftReviewComment{
+			Path:     f.problem.File,
+			Position: f.position,
+			Body:     fmt.Sprintf("%s %s", f.problem.Message, Tag(f.linter)),
+		})
+	}
+
+	// Trim down the number of comments if necessary.
+	totalProblems := len(findings)
+	oldProblems := totalProblems - len(nfs)
+	allowedComments := MaxComments - oldProblems
+	if allowedComments < 0 {
+		allowedComments = 0
+	}
+	if len(comments) > allowedComments {
+		comments = comments[:allowedComments]
+	}
+
+	// Make the review body.
+	s := "s"
+	if totalProblems == 1 {
+		s = ""
+	}
+	response := fmt.Sprintf("%d warning%s", totalProblems, s)
+	if len(failed) > 0 {
+		response += fmt.Sprintf(" (failed to run %s)", strings.Join(failed, ", "))
+	}
+
+	if err := ghc.CreateReview(org, repo, number, github.DraftReview{
+		Body:     formatBody(response),
+		Action:   github.Comment,
+		Comments: comments,
+	}); err != nil {
+		return err
+	}
+	if len(lintErrs) > 0 {
+		return errors.New(strings.Join(lintErrs, "; "))
+	}
+	return nil
+}
+
+// position returns the position in the patch of the line in the file. A
+// problem with the whole file is placed on the first added line.
+func position(added map[int]int, line int) (int, bool) {
+	if line != 0 {
+		pos, ok := added[line]
+		return pos, ok
+	}
+	first := 0
+	for l := range added {
+		if first == 0 || l < first {
+			first = l
+		}
+	}
+	if first == 0 {
+		return 0, false
+	}
+	return added[first], true
+}
+
+// newFindings compares the findings with the list of past comments on the PR
+// to decide which are new. The result is sorted by file and position.
+func newFindings(cs []github.ReviewComment, fs []finding) []finding {
+	type key struct {
+		path     string
+		position int
+		tag      string
+	}
+	old := map[key]bool{}
+	for _, c := range cs {
+		if c.Position == nil {
+			continue
+		}
+		old[key{path: c.Path, position: *c.Position, tag: tagIn(c.Body)}] = true
+	}
+	var res []finding
+	for _, f := range fs {
+		if old[key{path: f.problem.File, position: f.position, tag: Tag(f.linter)}] {
+			continue
+		}
+		res = append(res, f)
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].problem.File != res[j].problem.File {
+			return res[i].problem.File < res[j].problem.File
+		}
+		return res[i].position < res[j].position
+	})
+	return res
+}
+
+var tagRe = regexp.MustCompile(`<!-- [\w-]+ -->`)
+
+func tagIn(body string) string {
+	return tagRe.FindString(body)
+}
+
+// addedLines returns line numbers that were added in the patch, along with
+// their line in the patch itself as a map from line to patch line.
+// https://www.gnu.org/software/diffutils/manual/diffutils.html#Detailed-Unified
+// GitHub omits the ---/+++ lines since that information is in the
+// PullRequestChange object.
+func addedLines(patch string) (map[int]int, error) {
+	result := make(map[int]int)
+	if patch == "" {
+		return result, nil
+	}
+	lines := strings.Split(patch, "\n")
+	for i := 0; i < len(lines); i++ {
+		_, oldLen, newLine, newLen, err := parseHunkLine(lines[i])
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse hunk on line %d in patch %s: %v", i, patch, err)
+		}
+		oldAdd := 0
+		newAdd := 0
+		for oldAdd < oldLen || newAdd < newLen {
+			i++
+			if i >= len(lines) {
+				return nil, fmt.Errorf("invalid patch: %s", patch)
+			}
+			switch lines[i][0] {
+			case ' ':
+				oldAdd++
+				newAdd++
+			case '-':
+				oldAdd++
+			case '+':
+				result[newLine+newAdd] = i
+				newAdd++
+			default:
+				return nil, fmt.Errorf("bad prefix on line %d in patch %s", i, patch)
+			}
+		}
+	}
+	return result, nil
+}
+
+// Matches the hunk line in unified diffs. These are of the form:
+// @@ -l,s +l,s @@ section head
+// We need to extract the four numbers, but the command and s is optional.
+// See https://en.wikipedia.org/wiki/Diff_utility#Unified_format
+var hunkRe = regexp.MustCompile(`^@@ -(\d+),?(\d+)? \+(\d+),?(\d+)? @@.*`)
+
+func parseHunkLine(hunk string) (oldLine, oldLength, newLine, newLength int, err error) {
+	if !hunkRe.MatchString(hunk) {
+		err = fmt.Errorf("invalid hunk line: %s", hunk)
+		return
+	}
+	matches := hunkRe.FindStringSubmatch(hunk)
+	oldLine, err = strconv.Atoi(matches[1])
+	if err != nil {
+		return
+	}
+	if matches[2] != "" {
+		oldLength, err = strconv.Atoi(matches[2])
+		if err != nil {
+			return
+		}
+	} else {
+		oldLength = 1
+	}
+	newLine, err = strconv.Atoi(matches[3])
+	if err != nil {
+		return
+	}
+	if matches[4] != "" {
+		newLength, err = strconv.Atoi(matches[4])
+		if err != nil {
+			return
+		}
+	} else {
+		newLength = 1
+	}
+	return
+}
@@ -0,0 +1,311 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/git/localgit"
+	"k8s.io/test-infra/prow/github"
+)
+
+type ghc struct {
+	changes     []github.PullRequestChange
+	oldComments []github.ReviewComment
+	review      github.DraftReview
+}
+
+func (g *ghc) GetPullRequestChanges(org, repo string, number int) ([]github.PullRequestChange, error) {
+	return g.changes, nil
+}
+
+func (g *ghc) CreateReview(org, repo string, number int, r github.DraftReview) error {
+	g.review = r
+	return nil
+}
+
+func (g *ghc) ListPullRequestComments(org, repo string, number int) ([]github.ReviewComment, error) {
+	return g.oldComments, nil
+}
+
+// fakeLinter complains about every file it is given, or fails with err.
+type fakeLinter struct {
+	name string
+	line int
+	err  error
+}
+
+func (f fakeLinter) Name() string             { return f.name }
+func (f fakeLinter) Matches(file string) bool { return filepath.Ext(file) == ".txt" }
+func (f fakeLinter) Lint(dir string, files []string) ([]Problem, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	var ps []Problem
+	for _, file := range files {
+		ps = append(ps, Problem{File: file, Line: f.line, Message: "bad"})
+	}
+	return ps, nil
+}
+
+func TestReviewPullRequest(t *testing.T) {
+	lg, c, err := localgit.New()
+	if err != nil {
+		t.Fatalf("Making localgit: %v", err)
+	}
+	defer func() {
+		if err := lg.Clean(); err != nil {
+			t.Errorf("Cleaning up localgit: %v", err)
+		}
+		if err := c.Clean(); err != nil {
+			t.Errorf("Cleaning up client: %v", err)
+		}
+	}()
+	if err := lg.MakeFakeRepo("foo", "bar"); err != nil {
+		t.Fatalf("Making fake repo: %v", err)
+	}
+	if err := lg.CheckoutNewBranch("foo", "bar", "pull/1/head"); err != nil {
+		t.Fatalf("Checking out pull branch: %v", err)
+	}
+	if err := lg.AddCommit("foo", "bar", map[string][]byte{"a.txt": []byte("a\nb\n"), "b.go": []byte("package b\n")}); err != nil {
+		t.Fatalf("Adding PR commit: %v", err)
+	}
+
+	gh := &ghc{
+		changes: []github.PullRequestChange{
+			{Filename: "a.txt", Patch: "@@ -0,0 +1,2 @@\n+a\n+b"},
+			{Filename: "b.go", Patch: "@@ -0,0 +1 @@\n+package b"},
+		},
+	}
+	linters := []Linter{fakeLinter{name: "whole-file"}, fakeLinter{name: "second-line", line: 2}}
+	body := func(s string) string { return "body: " + s }
+	if err := ReviewPullRequest(gh, c, logrus.NewEntry(logrus.New()), "foo", "bar", 1, linters, body); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	expected := []github.DraftReviewComment{
+		{Path: "a.txt", Position: 1, Body: "bad <!-- whole-file -->"},
+		{Path: "a.txt", Position: 2, Body: "bad <!-- second-line -->"},
+	}
+	if !reflect.DeepEqual(gh.review.Comments, expected) {
+		t.Errorf("Expected comments %+v, got %+v", expected, gh.review.Comments)
+	}
+	if gh.review.Body != "body: 2 warnings" {
+		t.Errorf("Expected body %q, got %q", "body: 2 warnings", gh.review.Body)
+	}
+
+	// A different linter complaining about the same line still gets a comment.
+	pos := 1
+	gh.oldComments = []github.ReviewComment{{Path: "a.txt", Position: &pos, Body: "bad <!-- second-line -->"}}
+	if err := ReviewPullRequest(gh, c, logrus.NewEntry(logrus.New()), "foo", "bar", 1, linters, body); err != nil {
+		t.Fatalf("Didn't expect error on second try: %v", err)
+	}
+	if len(gh.review.Comments) != 2 {
+		t.Errorf("Expected two comments, got %+v", gh.review.Comments)
+	}
+	gh.oldComments = []github.ReviewComment{{Path: "a.txt", Position: &pos, Body: "bad <!-- whole-file -->"}}
+	if err := ReviewPullRequest(gh, c, logrus.NewEntry(logrus.New()), "foo", "bar", 1, linters, body); err != nil {
+		t.Fatalf("Didn't expect error on third try: %v", err)
+	}
+	if len(gh.review.Comments) != 1 || gh.review.Comments[0].Position != 2 {
+		t.Errorf("Expected only the comment on the second line, got %+v", gh.review.Comments)
+	}
+
+	// A failing linter doesn't keep the others from being reported.
+	gh.oldComments = nil
+	linters = []Linter{fakeLinter{name: "broken", err: errors.New("oops")}, fakeLinter{name: "second-line", line: 2}}
+	if err := ReviewPullRequest(gh, c, logrus.NewEntry(logrus.New()), "foo", "bar", 1, linters, body); err == nil {
+		t.Error("Expected an error from the broken linter.")
+	}
+	if len(gh.review.Comments) != 1 || gh.review.Comments[0].Body != "bad <!-- second-line -->" {
+		t.Errorf("Expected the second-line comment, got %+v", gh.review.Comments)
+	}
+	if gh.review.Body != "body: 1 warning (failed to run broken)" {
+		t.Errorf("Expected body %q, got %q", "body: 1 warning (failed to run broken)", gh.review.Body)
+	}
+}
+
+func TestParseVetOutput(t *testing.T) {
+	out := []byte(`# k8s.io/test-infra/foo
+foo/foo.go:12:3: unreachable code
+./foo/bar.go:7: missing argument for Sprintf("%s"): format reads arg 1, have only 0 args
+other/other.go:3:1: not one of ours
+exit status 2
+`)
+	ps, buildErrs := parseVetOutput(out, []string{"foo/foo.go", "foo/bar.go"})
+	expected := []Problem{
+		{File: "foo/foo.go", Line: 12, Message: "Go vet: unreachable code."},
+		{File: "foo/bar.go", Line: 7, Message: `Go vet: missing argument for Sprintf("%s"): format reads arg 1, have only 0 args.`},
+	}
+	if !reflect.DeepEqual(ps, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, ps)
+	}
+	if len(buildErrs) != 0 {
+		t.Errorf("Expected no build errors, got %v", buildErrs)
+	}
+
+	out = []byte(`foo/foo.go:5:2: cannot find package "k8s.io/test-infra/bar" in any of:
+	/usr/local/go/src/k8s.io/test-infra/bar (from $GOROOT)
+foo/foo.go:12:3: unreachable code
+`)
+	ps, buildErrs = parseVetOutput(out, []string{"foo/foo.go"})
+	if len(buildErrs) != 1 {
+		t.Errorf("Expected the missing package as a build error, got %v", buildErrs)
+	}
+	if len(ps) != 1 || ps[0].Line != 12 {
+		t.Errorf("Expected only the unreachable code problem, got %+v", ps)
+	}
+}
+
+func TestGofmt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gofmt")
+	if err != nil {
+		t.Fatalf("Making temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"good.go": "package foo\n\nfunc Foo() {}\n",
+		"bad.go":  "package foo\nfunc  Bar( ) {\n}\n",
+	}
+	for f, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, f), []byte(src), 0644); err != nil {
+			t.Fatalf("Writing %s: %v", f, err)
+		}
+	}
+	ps, err := Gofmt{}.Lint(dir, []string{"good.go", "bad.go"})
+	if err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if len(ps) != 1 || ps[0].File != "bad.go" || ps[0].Line != 0 {
+		t.Errorf("Expected a whole-file problem with bad.go, got %+v", ps)
+	}
+}
+
+func TestExternal(t *testing.T) {
+	var testcases = []struct {
+		name    string
+		script  string
+		err     bool
+		results []Problem
+	}{
+		{
+			name:   "findings",
+			script: `echo '[{"path": "'$0'", "line": 3, "message": "too long"}, {"path": "x.sh", "message": "no shebang"}]'`,
+			results: []Problem{
+				{File: "a.sh", Line: 3, Message: "ext: too long"},
+				{File: "x.sh", Message: "ext: no shebang"},
+			},
+		},
+		{
+			name:   "no findings",
+			script: "echo '[]'",
+		},
+		{
+			name:   "bad output",
+			script: "echo oops",
+			err:    true,
+		},
+		{
+			name:   "command fails",
+			script: "exit 1",
+			err:    true,
+		},
+	}
+	for _, tc := range testcases {
+		e := NewExternal("ext", []string{"sh", "-c", tc.script}, nil)
+		ps, err := e.Lint(os.TempDir(), []string{"a.sh"})
+		if err != nil {
+			if !tc.err {
+				t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			}
+			continue
+		} else if tc.err {
+			t.Errorf("For case %s, expected error.", tc.name)
+			continue
+		}
+		if !reflect.DeepEqual(ps, tc.results) {
+			t.Errorf("For case %s, expected %+v, got %+v", tc.name, tc.results, ps)
+		}
+	}
+}
+
+func TestAddedLines(t *testing.T) {
+	var testcases = []struct {
+		patch string
+		lines map[int]int
+		err   bool
+	}{
+		{
+			patch: "@@ -0,0 +1,5 @@\n+package bar\n+\n+func Qux() error {\n+   return nil\n+}",
+			lines: map[int]int{1: 1, 2: 2, 3: 3, 4: 4, 5: 5},
+		},
+		{
+			patch: "@@ -29,12 +29,14 @@ import (\n \t\"github.com/Sirupsen/logrus\"\n \t\"github.com/ghodss/yaml\"\n \n+\t\"k8s.io/test-infra/prow/config\"\n \t\"k8s.io/test-infra/prow/jenkins\"\n \t\"k8s.io/test-infra/prow/kube\"\n \t\"k8s.io/test-infra/prow/plank\"\n )\n \n var (\n+\tconfigPath   = flag.String(\"config-path\", \"/etc/config/config\", \"Path to config.yaml.\")\n \tbuildCluster = flag.String(\"build-cluster\", \"\", \"Path to file containing a YAML-marshalled kube.Cluster object. If empty, uses the local cluster.\")\n \n \tjenkinsURL       = flag.String(\"jenkins-url\", \"\", \"Jenkins URL\")\n@@ -47,18 +49,22 @@ var objReg = regexp.MustCompile(`^[\\w-]+$`)\n \n func main() {\n \tflag.Parse()\n-\n \tlogrus.SetFormatter(&logrus.JSONFormatter{})\n \n-\tkc, err := kube.NewClientInCluster(kube.ProwNamespace)\n+\tconfigAgent := &config.Agent{}\n+\tif err := configAgent.Start(*configPath); err != nil {\n+\t\tlogrus.WithError(err).Fatal(\"Error starting config agent.\")\n+\t}\n+\n+\tkc, err := kube.NewClientInCluster(configAgent.Config().ProwJobNamespace)\n \tif err != nil {\n \t\tlogrus.WithError(err).Fatal(\"Error getting client.\")\n \t}\n \tvar pkc *kube.Client\n \tif *buildCluster == \"\" {\n-\t\tpkc = kc.Namespace(kube.TestPodNamespace)\n+\t\tpkc = kc.Namespace(configAgent.Config().PodNamespace)\n \t} else {\n-\t\tpkc, err = kube.NewClientFromFile(*buildCluster, kube.TestPodNamespace)\n+\t\tpkc, err = kube.NewClientFromFile(*buildCluster, configAgent.Config().PodNamespace)\n \t\tif err != nil {\n \t\t\tlogrus.WithError(err).Fatal(\"Error getting kube client to build cluster.\")\n \t\t}",
+			lines: map[int]int{4: 32, 11: 39, 23: 54, 24: 55, 25: 56, 26: 57, 27: 58, 28: 59, 35: 65, 38: 67},
+		},
+		{
+			patch: "@@ -1 +0,0 @@\n-such",
+		},
+		{
+			patch: "@@ -1,3 +0,0 @@\n-such\n-a\n-doge",
+		},
+		{
+			patch: "@@ -0,0 +1 @@\n+wow",
+			lines: map[int]int{1: 1},
+		},
+		{
+			patch: "@@ -1 +1 @@\n-doge\n+wow",
+			lines: map[int]int{2: 1},
+		},
+		{
+			patch: "something strange",
+			err:   true,
+		},
+		{
+			patch: "@@ -a,3 +0,0 @@\n-wow",
+			err:   true,
+		},
+		{
+			patch: "@@ -1 +1 @@",
+			err:   true,
+		},
+		{
+			patch: "",
+		},
+	}
+	for _, tc := range testcases {
+		als, err := addedLines(tc.patch)
+		if err == nil == tc.err {
+			t.Errorf("For patch %s\nExpected error %v, got error %v", tc.patch, tc.err, err)
+			continue
+		}
+		if len(als) != len(tc.lines) {
+			t.Errorf("For patch %s\nAdded lines has wrong length. Got %v, expected %v", tc.patch, als, tc.lines)
+		}
+		for pl, l := range tc.lines {
+			if als[l] != pl {
+				t.Errorf("For patch %s\nExpected added line %d to be %d, but got %d", tc.patch, l, pl, als[l])
+			}
+		}
+	}
+}
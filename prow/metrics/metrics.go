@@ -0,0 +1,64 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics holds the Prometheus metrics that several prow components
+// export, and serves metrics for components that don't otherwise serve HTTP.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	syncDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "prow_sync_duration_seconds",
+		Help:    "Time taken by one iteration of a component's sync loop.",
+		Buckets: prometheus.ExponentialBuckets(0.5, 2, 10),
+	}, []string{"component"})
+	syncErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "prow_sync_errors_total",
+		Help: "Iterations of a component's sync loop that failed.",
+	}, []string{"component"})
+)
+
+func init() {
+	prometheus.MustRegister(syncDuration)
+	prometheus.MustRegister(syncErrors)
+}
+
+// RecordSync records an iteration of the component's sync loop that started
+// at start and returned err.
+func RecordSync(component string, start time.Time, err error) {
+	syncDuration.WithLabelValues(component).Observe(time.Since(start).Seconds())
+	if err != nil {
+		syncErrors.WithLabelValues(component).Inc()
+	}
+}
+
+// Serve serves /metrics on the port in the background.
+func Serve(port int) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	go func() {
+		logrus.WithError(http.ListenAndServe(":"+strconv.Itoa(port), mux)).Fatal("ListenAndServe returned.")
+	}()
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestRecordSync(t *testing.T) {
+	RecordSync("test", time.Now(), nil)
+	RecordSync("test", time.Now(), errors.New("oops"))
+
+	var m dto.Metric
+	if err := syncErrors.WithLabelValues("test").Write(&m); err != nil {
+		t.Fatalf("Error reading errors: %v", err)
+	}
+	if v := m.GetCounter().GetValue(); v != 1 {
+		t.Errorf("Expected 1 error, got %v", v)
+	}
+	var h dto.Metric
+	if err := syncDuration.WithLabelValues("test").Write(&h); err != nil {
+		t.Fatalf("Error reading durations: %v", err)
+	}
+	if c := h.GetHistogram().GetSampleCount(); c != 2 {
+		t.Errorf("Expected 2 syncs, got %d", c)
+	}
+}
@@ -27,6 +27,7 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/bwmarrin/snowflake"
+	"github.com/prometheus/client_golang/prometheus"
 	uuid "github.com/satori/go.uuid"
 
 	"k8s.io/test-infra/prow/config"
@@ -43,6 +44,23 @@ const (
 	maxSyncRoutines = 20
 )
 
+var (
+	prowJobs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "prow_plank_prowjobs",
+		Help: "Number of ProwJobs, by job, type and state.",
+	}, []string{"job", "type", "state"})
+	podStartLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "prow_plank_pod_start_duration_seconds",
+		Help:    "Time from a ProwJob being triggered to its pod starting, observed when the job finishes.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+	})
+)
+
+func init() {
+	prometheus.MustRegister(prowJobs)
+	prometheus.MustRegister(podStartLatency)
+}
+
 type kubeClient interface {
 	CreateProwJob(kube.ProwJob) (kube.ProwJob, error)
 	ListProwJobs(map[string]string) ([]kube.ProwJob, error)
@@ -148,6 +166,7 @@ func (c *Controller) Sync() error {
 		pm[pod.Metadata.Name] = pod
 	}
 	c.updatePendingJobs(pjs)
+	recordProwJobs(pjs)
 	var syncErrs []error
 	if err := c.terminateDupes(pjs); err != nil {
 		syncErrs = append(syncErrs, err)
@@ -187,6 +206,27 @@ func (c *Controller) Sync() error {
 	return fmt.Errorf("errors syncing: %v, errors reporting: %v", syncErrs, reportErrs)
 }
 
+// recordProwJobs exports how many ProwJobs there are of each job, type and
+// state.
+func recordProwJobs(pjs []kube.ProwJob) {
+	counts := make(map[[3]string]float64)
+	for _, pj := range pjs {
+		counts[[3]string{pj.Spec.Job, string(pj.Spec.Type), string(pj.Status.State)}]++
+	}
+	prowJobs.Reset()
+	for k, n := range counts {
+		prowJobs.WithLabelValues(k[0], k[1], k[2]).Set(n)
+	}
+}
+
+// recordPodStart exports how long the job waited for its pod to start.
+func recordPodStart(pj kube.ProwJob, pod kube.Pod) {
+	if pod.Status.StartTime.IsZero() || pod.Status.StartTime.Before(pj.Status.StartTime) {
+		return
+	}
+	podStartLatency.Observe(pod.Status.StartTime.Sub(pj.Status.StartTime).Seconds())
+}
+
 func (c *Controller) syncProwJob(wg *sync.WaitGroup, jobs <-chan kube.ProwJob, syncErrors chan<- error, reports chan<- kube.ProwJob, pm map[string]kube.Pod) {
 	defer wg.Done()
 	for pj := range jobs {
@@ -388,6 +428,7 @@ func (c *Controller) syncKubernetesJob(pj kube.ProwJob, pm map[string]kube.Pod,
 		pj.Status.State = kube.PendingState
 	} else if pod.Status.Phase == kube.PodSucceeded {
 		// Pod succeeded. Update ProwJob, talk to GitHub, and start next jobs.
+		recordPodStart(pj, pod)
 		pj.Status.CompletionTime = time.Now()
 		pj.Status.State = kube.SuccessState
 		pj.Status.Description = "Job succeeded."
@@ -404,6 +445,7 @@ func (c *Controller) syncKubernetesJob(pj kube.ProwJob, pm map[string]kube.Pod,
 			pj.Status.State = kube.PendingState
 		} else {
 			// Pod failed. Update ProwJob, talk to GitHub.
+			recordPodStart(pj, pod)
 			pj.Status.CompletionTime = time.Now()
 			pj.Status.State = kube.FailureState
 			pj.Status.Description = "Job failed."
@@ -27,6 +27,8 @@ import (
 	"text/template"
 	"time"
 
+	dto "github.com/prometheus/client_model/go"
+
 	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/jenkins"
 	"k8s.io/test-infra/prow/kube"
@@ -882,3 +884,30 @@ func TestPeriodic(t *testing.T) {
 		t.Fatalf("Wrong number of pods: %d", len(fc.pods))
 	}
 }
+
+func TestRecordProwJobs(t *testing.T) {
+	pj := func(job string, state kube.ProwJobState) kube.ProwJob {
+		return kube.ProwJob{
+			Spec:   kube.ProwJobSpec{Job: job, Type: kube.PresubmitJob},
+			Status: kube.ProwJobStatus{State: state},
+		}
+	}
+	count := func(job string, state kube.ProwJobState) float64 {
+		var m dto.Metric
+		if err := prowJobs.WithLabelValues(job, string(kube.PresubmitJob), string(state)).Write(&m); err != nil {
+			t.Fatalf("Error reading metric: %v", err)
+		}
+		return m.GetGauge().GetValue()
+	}
+	recordProwJobs([]kube.ProwJob{pj("a", kube.PendingState), pj("a", kube.PendingState), pj("b", kube.SuccessState)})
+	recordProwJobs([]kube.ProwJob{pj("a", kube.PendingState), pj("a", kube.SuccessState)})
+	if n := count("a", kube.PendingState); n != 1 {
+		t.Errorf("Expected 1 pending a, got %v", n)
+	}
+	if n := count("a", kube.SuccessState); n != 1 {
+		t.Errorf("Expected 1 successful a, got %v", n)
+	}
+	if n := count("b", kube.SuccessState); n != 0 {
+		t.Errorf("Expected b to be forgotten, got %v", n)
+	}
+}
@@ -0,0 +1,286 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cherrypick contains a plugin which backports merged PRs. When an
+// org member comments /cherrypick <branch> on a PR, the PR's commits are
+// applied on top of the branch in the bot's fork once the PR has merged, and a
+// new PR is opened against the branch.
+package cherrypick
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/git"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+const pluginName = "cherrypick"
+
+// GitHub creates forks asynchronously, so we wait up to forkAttempts times
+// forkInterval for the bot's fork to show up before pushing to it.
+const (
+	forkAttempts = 10
+	forkInterval = 5 * time.Second
+)
+
+var timeSleep = time.Sleep
+
+var cherryPickRe = regexp.MustCompile(`(?m)^/cherrypick\s+(\S+)\s*$`)
+
+func init() {
+	plugins.RegisterIssueCommentHandler(pluginName, handleIssueComment)
+	plugins.RegisterPullRequestHandler(pluginName, handlePullRequest)
+}
+
+type githubClient interface {
+	BotName() string
+	IsMember(org, user string) (bool, error)
+	GetPullRequest(org, repo string, number int) (*github.PullRequest, error)
+	ListIssueComments(org, repo string, number int) ([]github.IssueComment, error)
+	CreateComment(org, repo string, number int, comment string) error
+	FindPullRequest(org, repo, head, base string) (*github.PullRequest, error)
+	CreateFork(org, repo string) error
+	RepoExists(org, repo string) (bool, error)
+	CreatePullRequest(org, repo, title, body, head, base string) (int, error)
+}
+
+func handleIssueComment(pc plugins.PluginClient, ic github.IssueCommentEvent) error {
+	return handleIC(pc.GitHubClient, pc.GitClient, pc.Logger, ic)
+}
+
+func handlePullRequest(pc plugins.PluginClient, pr github.PullRequestEvent) error {
+	return handlePR(pc.GitHubClient, pc.GitClient, pc.Logger, pr)
+}
+
+// handleIC cherry-picks merged PRs right away. For PRs that haven't merged
+// yet, it promises to do so once they merge.
+func handleIC(gc githubClient, gitc *git.Client, log *logrus.Entry, ic github.IssueCommentEvent) error {
+	if ic.Action != "created" || !ic.Issue.IsPullRequest() || ic.Comment.User.Login == gc.BotName() {
+		return nil
+	}
+	branches := requestedBranches(ic.Comment.Body)
+	if len(branches) == 0 {
+		return nil
+	}
+
+	org := ic.Repo.Owner.Login
+	repo := ic.Repo.Name
+	number := ic.Issue.Number
+	if member, err := gc.IsMember(org, ic.Comment.User.Login); err != nil {
+		return err
+	} else if !member {
+		resp := fmt.Sprintf("only %s org members may request cherry-picks", org)
+		log.Infof("Commenting \"%s\".", resp)
+		return gc.CreateComment(org, repo, number, plugins.FormatICResponse(ic.Comment, resp))
+	}
+
+	pr, err := gc.GetPullRequest(org, repo, number)
+	if err != nil {
+		return err
+	}
+	if !pr.Merged {
+		resp := "this PR was closed without merging, so there is nothing to cherry-pick"
+		if ic.Issue.State == "open" {
+			resp = fmt.Sprintf("once this PR merges, I will cherry-pick it on top of %s in a new PR and assign it to you", list(branches))
+		}
+		log.Infof("Commenting \"%s\".", resp)
+		return gc.CreateComment(org, repo, number, plugins.FormatICResponse(ic.Comment, resp))
+	}
+	for _, branch := range branches {
+		if err := cherryPick(gc, gitc, log, org, repo, *pr, branch, ic.Comment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handlePR makes good on the cherry-picks requested by org members before the
+// PR merged.
+func handlePR(gc githubClient, gitc *git.Client, log *logrus.Entry, pre github.PullRequestEvent) error {
+	if pre.Action != "closed" || !pre.PullRequest.Merged {
+		return nil
+	}
+	pr := pre.PullRequest
+	org := pr.Base.Repo.Owner.Login
+	repo := pr.Base.Repo.Name
+	comments, err := gc.ListIssueComments(org, repo, pr.Number)
+	if err != nil {
+		return err
+	}
+
+	// The first member to ask for each branch gets the new PR.
+	requests := map[string]github.IssueComment{}
+	members := map[string]bool{}
+	for _, c := range comments {
+		author := c.User.Login
+		if author == gc.BotName() {
+			continue
+		}
+		branches := requestedBranches(c.Body)
+		if len(branches) == 0 {
+			continue
+		}
+		member, ok := members[author]
+		if !ok {
+			if member, err = gc.IsMember(org, author); err != nil {
+				return err
+			}
+			members[author] = member
+		}
+		if !member {
+			continue
+		}
+		for _, branch := range branches {
+			if _, ok := requests[branch]; !ok {
+				requests[branch] = c
+			}
+		}
+	}
+
+	var branches []string
+	for branch := range requests {
+		branches = append(branches, branch)
+	}
+	sort.Strings(branches)
+	for _, branch := range branches {
+		if err := cherryPick(gc, gitc, log, org, repo, pr, branch, requests[branch]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func requestedBranches(body string) []string {
+	var branches []string
+	seen := map[string]bool{}
+	for _, match := range cherryPickRe.FindAllStringSubmatch(body, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			branches = append(branches, match[1])
+		}
+	}
+	return branches
+}
+
+func list(branches []string) string {
+	if len(branches) == 1 {
+		return branches[0]
+	}
+	return fmt.Sprintf("each of %v", branches)
+}
+
+// cherryPick applies the PR's commits on top of the branch and opens a new PR
+// with the result. Problems that the requester can fix, such as conflicts or
+// a missing branch, are explained in a comment rather than returned.
+func cherryPick(gc githubClient, gitc *git.Client, log *logrus.Entry, org, repo string, pr github.PullRequest, branch string, request github.IssueComment) error {
+	respond := func(resp string) error {
+		log.Infof("Commenting \"%s\".", resp)
+		return gc.CreateComment(org, repo, pr.Number, plugins.FormatICResponse(request, resp))
+	}
+	if branch == pr.Base.Ref {
+		return respond(fmt.Sprintf("this PR already merged into %s", branch))
+	}
+	newBranch := fmt.Sprintf("cherry-pick-%d-to-%s", pr.Number, branch)
+	head := gc.BotName() + ":" + newBranch
+	if existing, err := gc.FindPullRequest(org, repo, head, branch); err != nil {
+		return err
+	} else if existing != nil {
+		return respond(fmt.Sprintf("a cherry-pick of this PR to %s is already open: #%d", branch, existing.Number))
+	}
+
+	r, err := gitc.Clone(org + "/" + repo)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := r.Clean(); err != nil {
+			log.WithError(err).Error("Error cleaning up repo.")
+		}
+	}()
+	if err := r.CheckoutPullRequest(pr.Number); err != nil {
+		return err
+	}
+	// Commits that reached the PR by merging its base branch into it are
+	// already in the base branch, so only pick those after the last point
+	// where the two met. For a merged PR, the first parent of the merge
+	// commit is the base branch as it was just before the merge.
+	base := "origin/" + pr.Base.Ref
+	if pr.MergeSHA != nil {
+		base = *pr.MergeSHA + "^1"
+	}
+	pull := fmt.Sprintf("pull%d", pr.Number)
+	forkPoint, err := r.MergeBase(base, pull)
+	if err != nil {
+		return err
+	}
+	commits, err := r.RevList(forkPoint + ".." + pull)
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		return respond("this PR has no commits to cherry-pick")
+	}
+	if err := r.Checkout("origin/" + branch); err != nil {
+		return respond(fmt.Sprintf("cannot cherry-pick onto %s, the branch doesn't exist", branch))
+	}
+	if err := r.CheckoutNewBranch(newBranch); err != nil {
+		return err
+	}
+	if err := r.CherryPick(commits...); err != nil {
+		log.WithError(err).Info("Cherry-pick failed.")
+		return respond(fmt.Sprintf("#%d failed to apply on top of %s. Please cherry-pick it manually:\n\n```\n%v\n```", pr.Number, branch, err))
+	}
+
+	if err := gc.CreateFork(org, repo); err != nil {
+		return err
+	}
+	if err := waitForFork(gc, repo); err != nil {
+		return err
+	}
+	if err := r.Push(newBranch); err != nil {
+		return err
+	}
+	title := fmt.Sprintf("[%s] %s", branch, pr.Title)
+	body := fmt.Sprintf("This is an automated cherry-pick of #%d.\n\n/assign %s", pr.Number, request.User.Login)
+	n, err := gc.CreatePullRequest(org, repo, title, body, head, branch)
+	if err != nil {
+		return err
+	}
+	return respond(fmt.Sprintf("new pull request created for %s: #%d", branch, n))
+}
+
+// waitForFork polls until the bot's fork of repo exists.
+func waitForFork(gc githubClient, repo string) error {
+	for attempt := 1; ; attempt++ {
+		exists, err := gc.RepoExists(gc.BotName(), repo)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return nil
+		}
+		if attempt == forkAttempts {
+			return fmt.Errorf("the fork of %s still doesn't exist after %v", repo, forkAttempts*forkInterval)
+		}
+		timeSleep(forkInterval)
+	}
+}
@@ -0,0 +1,372 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cherrypick
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/git"
+	"k8s.io/test-infra/prow/git/localgit"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+)
+
+// makeRepo creates foo/bar with a release-1.0 branch, a PR #5 that applies to
+// it, a PR #6 that conflicts with it, a PR #7 that merged master into itself
+// before it merged, and the bot's fork of the repo.
+func makeRepo(t *testing.T) (*localgit.LocalGit, *git.Client) {
+	lg, c, err := localgit.New()
+	if err != nil {
+		t.Fatalf("Making localgit: %v", err)
+	}
+	c.SetCredentials("k8s-ci-robot", "")
+	steps := []func() error{
+		func() error { return lg.MakeFakeRepo("foo", "bar") },
+		func() error { return lg.MakeFakeRepo("k8s-ci-robot", "bar") },
+		func() error { return lg.CheckoutNewBranch("foo", "bar", "pull/5/head") },
+		func() error { return lg.AddCommit("foo", "bar", map[string][]byte{"fix": []byte("fixed")}) },
+		func() error { return checkout(lg, "master") },
+		func() error { return lg.CheckoutNewBranch("foo", "bar", "pull/6/head") },
+		func() error { return lg.AddCommit("foo", "bar", map[string][]byte{"initial": []byte("pull")}) },
+		func() error { return checkout(lg, "master") },
+		func() error { return lg.CheckoutNewBranch("foo", "bar", "release-1.0") },
+		func() error { return lg.AddCommit("foo", "bar", map[string][]byte{"initial": []byte("release")}) },
+		func() error { return checkout(lg, "master") },
+		func() error { return lg.CheckoutNewBranch("foo", "bar", "pull/7/head") },
+		func() error { return lg.AddCommit("foo", "bar", map[string][]byte{"feature": []byte("feature")}) },
+		func() error { return checkout(lg, "master") },
+		func() error { return lg.AddCommit("foo", "bar", map[string][]byte{"master-only": []byte("master")}) },
+		func() error { return checkout(lg, "pull/7/head") },
+		func() error { return merge(lg, "master") },
+		func() error { return checkout(lg, "master") },
+		func() error { return merge(lg, "pull/7/head") },
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("Making fake repo: %v", err)
+		}
+	}
+	return lg, c
+}
+
+func checkout(lg *localgit.LocalGit, branch string) error {
+	co := exec.Command(lg.Git, "checkout", branch)
+	co.Dir = filepath.Join(lg.Dir, "foo", "bar")
+	return co.Run()
+}
+
+func merge(lg *localgit.LocalGit, branch string) error {
+	m := exec.Command(lg.Git, "merge", "--no-ff", "--no-edit", branch)
+	m.Dir = filepath.Join(lg.Dir, "foo", "bar")
+	return m.Run()
+}
+
+func pullRequest(number int, merged bool) *github.PullRequest {
+	return &github.PullRequest{
+		Number: number,
+		Title:  "Fix things",
+		Merged: merged,
+		Base: github.PullRequestBranch{
+			Ref:  "master",
+			SHA:  "origin/master",
+			Repo: github.Repo{Owner: github.User{Login: "foo"}, Name: "bar"},
+		},
+	}
+}
+
+func TestCherryPickComment(t *testing.T) {
+	lg, c := makeRepo(t)
+	defer func() {
+		if err := lg.Clean(); err != nil {
+			t.Errorf("Cleaning up localgit: %v", err)
+		}
+		if err := c.Clean(); err != nil {
+			t.Errorf("Cleaning up client: %v", err)
+		}
+	}()
+
+	var testcases = []struct {
+		name      string
+		body      string
+		commenter string
+		number    int
+		state     string
+		merged    bool
+
+		created     []string
+		commentHave string
+	}{
+		{
+			name:      "unrelated comment",
+			body:      "cherrypick this please",
+			commenter: "member",
+			number:    5,
+			state:     "open",
+		},
+		{
+			name:        "non-member",
+			body:        "/cherrypick release-1.0",
+			commenter:   "stranger",
+			number:      5,
+			state:       "open",
+			commentHave: "only foo org members may request cherry-picks",
+		},
+		{
+			name:        "open PR",
+			body:        "/cherrypick release-1.0",
+			commenter:   "member",
+			number:      5,
+			state:       "open",
+			commentHave: "once this PR merges, I will cherry-pick it on top of release-1.0",
+		},
+		{
+			name:        "closed without merging",
+			body:        "/cherrypick release-1.0",
+			commenter:   "member",
+			number:      5,
+			state:       "closed",
+			commentHave: "closed without merging",
+		},
+		{
+			name:        "merged PR",
+			body:        "/cherrypick release-1.0",
+			commenter:   "member",
+			number:      5,
+			state:       "closed",
+			merged:      true,
+			created:     []string{"[release-1.0] Fix things"},
+			commentHave: "new pull request created for release-1.0: #1000",
+		},
+		{
+			name:        "conflict",
+			body:        "/cherrypick release-1.0",
+			commenter:   "member",
+			number:      6,
+			state:       "closed",
+			merged:      true,
+			commentHave: "#6 failed to apply on top of release-1.0",
+		},
+		{
+			name:        "missing branch",
+			body:        "/cherrypick release-9.9",
+			commenter:   "member",
+			number:      5,
+			state:       "closed",
+			merged:      true,
+			commentHave: "the branch doesn't exist",
+		},
+	}
+	for _, tc := range testcases {
+		fc := &fakegithub.FakeClient{
+			OrgMembers:    []string{"member"},
+			IssueComments: map[int][]github.IssueComment{},
+			PullRequests:  map[int]*github.PullRequest{tc.number: pullRequest(tc.number, tc.merged)},
+		}
+		ic := github.IssueCommentEvent{
+			Action: "created",
+			Repo:   github.Repo{Owner: github.User{Login: "foo"}, Name: "bar"},
+			Issue: github.Issue{
+				Number:      tc.number,
+				State:       tc.state,
+				PullRequest: &struct{}{},
+			},
+			Comment: github.IssueComment{
+				Body: tc.body,
+				User: github.User{Login: tc.commenter},
+			},
+		}
+		if err := handleIC(fc, c, logrus.WithField("plugin", pluginName), ic); err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			continue
+		}
+		var created []string
+		for _, pr := range fc.CreatedPullRequests {
+			created = append(created, pr.Title)
+			if pr.Head.Ref != "k8s-ci-robot:cherry-pick-5-to-release-1.0" || pr.Base.Ref != "release-1.0" {
+				t.Errorf("For case %s, created PR from %s to %s", tc.name, pr.Head.Ref, pr.Base.Ref)
+			}
+			if !strings.Contains(pr.Body, "/assign member") {
+				t.Errorf("For case %s, expected the new PR to be assigned to the requester: %s", tc.name, pr.Body)
+			}
+		}
+		if strings.Join(created, ",") != strings.Join(tc.created, ",") {
+			t.Errorf("For case %s, expected PRs %v to be created, got %v", tc.name, tc.created, created)
+		}
+		comments := fc.IssueComments[tc.number]
+		if tc.commentHave == "" {
+			if len(comments) != 0 {
+				t.Errorf("For case %s, expected no comments, got %v", tc.name, comments)
+			}
+			continue
+		}
+		if len(comments) != 1 {
+			t.Errorf("For case %s, expected one comment, got %v", tc.name, comments)
+		} else if !strings.Contains(comments[0].Body, tc.commentHave) {
+			t.Errorf("For case %s, expected comment to contain %q, got %s", tc.name, tc.commentHave, comments[0].Body)
+		}
+	}
+
+	show := exec.Command(lg.Git, "show", "cherry-pick-5-to-release-1.0:fix")
+	show.Dir = filepath.Join(lg.Dir, "k8s-ci-robot", "bar")
+	if b, err := show.CombinedOutput(); err != nil {
+		t.Errorf("Cherry-pick wasn't pushed to the fork: %v, %s", err, string(b))
+	}
+}
+
+func TestCherryPickOnMerge(t *testing.T) {
+	lg, c := makeRepo(t)
+	defer func() {
+		if err := lg.Clean(); err != nil {
+			t.Errorf("Cleaning up localgit: %v", err)
+		}
+		if err := c.Clean(); err != nil {
+			t.Errorf("Cleaning up client: %v", err)
+		}
+	}()
+
+	fc := &fakegithub.FakeClient{
+		OrgMembers: []string{"member", "other-member"},
+		IssueComments: map[int][]github.IssueComment{
+			5: {
+				{ID: 1, Body: "/cherrypick release-1.0", User: github.User{Login: "stranger"}},
+				{ID: 2, Body: "/cherrypick release-1.0", User: github.User{Login: "member"}},
+				{ID: 3, Body: "/cherrypick release-1.0", User: github.User{Login: "other-member"}},
+				{ID: 4, Body: "/cherrypick release-1.0", User: github.User{Login: "k8s-ci-robot"}},
+			},
+		},
+	}
+	pre := github.PullRequestEvent{
+		Action:      "closed",
+		Number:      5,
+		PullRequest: *pullRequest(5, true),
+	}
+	if err := handlePR(fc, c, logrus.WithField("plugin", pluginName), pre); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if len(fc.CreatedPullRequests) != 1 {
+		t.Fatalf("Expected one PR to be created, got %v", fc.CreatedPullRequests)
+	}
+	if body := fc.CreatedPullRequests[0].Body; !strings.Contains(body, "/assign member") {
+		t.Errorf("Expected the first member to ask to be assigned, got %s", body)
+	}
+
+	fc.CreatedPullRequests = nil
+	pre.PullRequest.Merged = false
+	if err := handlePR(fc, c, logrus.WithField("plugin", pluginName), pre); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if len(fc.CreatedPullRequests) != 0 {
+		t.Errorf("Expected no PRs for unmerged PRs, got %v", fc.CreatedPullRequests)
+	}
+}
+
+func TestCherryPickMergedBase(t *testing.T) {
+	lg, c := makeRepo(t)
+	defer func() {
+		if err := lg.Clean(); err != nil {
+			t.Errorf("Cleaning up localgit: %v", err)
+		}
+		if err := c.Clean(); err != nil {
+			t.Errorf("Cleaning up client: %v", err)
+		}
+	}()
+
+	pr := pullRequest(7, true)
+	mergeSHA := "origin/master"
+	pr.MergeSHA = &mergeSHA
+	fc := &fakegithub.FakeClient{
+		OrgMembers:    []string{"member"},
+		IssueComments: map[int][]github.IssueComment{},
+		PullRequests:  map[int]*github.PullRequest{7: pr},
+	}
+	ic := github.IssueCommentEvent{
+		Action: "created",
+		Repo:   github.Repo{Owner: github.User{Login: "foo"}, Name: "bar"},
+		Issue: github.Issue{
+			Number:      7,
+			State:       "closed",
+			PullRequest: &struct{}{},
+		},
+		Comment: github.IssueComment{
+			Body: "/cherrypick release-1.0",
+			User: github.User{Login: "member"},
+		},
+	}
+	for i := 0; i < 2; i++ {
+		if err := handleIC(fc, c, logrus.WithField("plugin", pluginName), ic); err != nil {
+			t.Fatalf("Didn't expect error: %v", err)
+		}
+	}
+	if len(fc.CreatedPullRequests) != 1 {
+		t.Fatalf("Expected one PR to be created, got %v", fc.CreatedPullRequests)
+	}
+	comments := fc.IssueComments[7]
+	if len(comments) != 2 || !strings.Contains(comments[1].Body, "a cherry-pick of this PR to release-1.0 is already open: #1000") {
+		t.Errorf("Expected the second request to point at the first PR, got %v", comments)
+	}
+
+	dir := filepath.Join(lg.Dir, "k8s-ci-robot", "bar")
+	show := exec.Command(lg.Git, "show", "cherry-pick-7-to-release-1.0:feature")
+	show.Dir = dir
+	if b, err := show.CombinedOutput(); err != nil {
+		t.Errorf("Cherry-pick wasn't pushed to the fork: %v, %s", err, string(b))
+	}
+	show = exec.Command(lg.Git, "show", "cherry-pick-7-to-release-1.0:master-only")
+	show.Dir = dir
+	if err := show.Run(); err == nil {
+		t.Error("Expected the commit merged in from master not to be cherry-picked.")
+	}
+}
+
+// slowForks is a GitHub whose forks take a few checks to show up.
+type slowForks struct {
+	*fakegithub.FakeClient
+	checks int
+	ready  int
+}
+
+func (s *slowForks) RepoExists(org, repo string) (bool, error) {
+	s.checks++
+	return s.checks >= s.ready, nil
+}
+
+func TestWaitForFork(t *testing.T) {
+	slept := 0
+	timeSleep = func(time.Duration) { slept++ }
+	defer func() { timeSleep = time.Sleep }()
+
+	if err := waitForFork(&slowForks{FakeClient: &fakegithub.FakeClient{}, ready: 3}, "bar"); err != nil {
+		t.Errorf("Didn't expect error: %v", err)
+	}
+	if slept != 2 {
+		t.Errorf("Expected to wait twice, waited %d times", slept)
+	}
+
+	slept = 0
+	if err := waitForFork(&slowForks{FakeClient: &fakegithub.FakeClient{}, ready: forkAttempts + 1}, "bar"); err == nil {
+		t.Error("Expected an error for a fork that never shows up")
+	}
+	if slept != forkAttempts-1 {
+		t.Errorf("Expected to wait %d times, waited %d times", forkAttempts-1, slept)
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ExternalPlugin is a plugin that runs outside of hook. Hook forwards the
+// webhooks it receives for the plugin's repos to the plugin's endpoint.
+type ExternalPlugin struct {
+	// Name is used in logs and metrics.
+	Name string `json:"name"`
+	// Endpoint is the URL that webhooks are POSTed to.
+	Endpoint string `json:"endpoint"`
+	// Events are the GitHub event types, such as "issue_comment", that the
+	// plugin wants. If empty, the plugin gets every event.
+	Events []string `json:"events,omitempty"`
+}
+
+// WantsEvent returns whether or not the plugin should get events of the type.
+func (ep ExternalPlugin) WantsEvent(eventType string) bool {
+	if len(ep.Events) == 0 {
+		return true
+	}
+	for _, e := range ep.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// SetExternal sets the external plugins that are enabled on repos. The input
+// is keyed by repo or org, as in Set. It will return error if a plugin has no
+// name or a bad endpoint, or if a name is duplicated for a repo.
+func (pa *PluginAgent) SetExternal(ne map[string][]ExternalPlugin) error {
+	if err := validateExternal(ne); err != nil {
+		return err
+	}
+	pa.mut.Lock()
+	defer pa.mut.Unlock()
+	pa.external = ne
+	return nil
+}
+
+func validateExternal(ne map[string][]ExternalPlugin) error {
+	for k, eps := range ne {
+		names := map[string]bool{}
+		for _, ep := range eps {
+			if ep.Name == "" {
+				return fmt.Errorf("external plugin for %s has no name", k)
+			}
+			if names[ep.Name] {
+				return fmt.Errorf("external plugin %s is duplicated for %s", ep.Name, k)
+			}
+			names[ep.Name] = true
+			u, err := url.Parse(ep.Endpoint)
+			if err != nil {
+				return fmt.Errorf("external plugin %s has invalid endpoint: %v", ep.Name, err)
+			}
+			if u.Scheme != "http" && u.Scheme != "https" {
+				return fmt.Errorf("external plugin %s endpoint %q must be an http or https URL", ep.Name, ep.Endpoint)
+			}
+		}
+		if strings.Contains(k, "/") {
+			org := strings.Split(k, "/")[0]
+			for _, ep := range ne[org] {
+				if names[ep.Name] {
+					return fmt.Errorf("external plugin %s is duplicated for %s and %s", ep.Name, k, org)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ExternalPlugins returns the external plugins that are enabled on the repo
+// and want events of the given type.
+func (pa *PluginAgent) ExternalPlugins(owner, repo, eventType string) []ExternalPlugin {
+	pa.mut.Lock()
+	defer pa.mut.Unlock()
+
+	var eps []ExternalPlugin
+	for _, k := range []string{owner, owner + "/" + repo} {
+		for _, ep := range pa.external[k] {
+			if ep.WantsEvent(eventType) {
+				eps = append(eps, ep)
+			}
+		}
+	}
+	return eps
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetExternal(t *testing.T) {
+	var testcases = []struct {
+		name     string
+		external map[string][]ExternalPlugin
+		err      bool
+	}{
+		{
+			name: "valid",
+			external: map[string][]ExternalPlugin{
+				"org":      {{Name: "a", Endpoint: "http://a"}},
+				"org/repo": {{Name: "b", Endpoint: "https://b/hook"}},
+			},
+		},
+		{
+			name: "no name",
+			external: map[string][]ExternalPlugin{
+				"org": {{Endpoint: "http://a"}},
+			},
+			err: true,
+		},
+		{
+			name: "not an http endpoint",
+			external: map[string][]ExternalPlugin{
+				"org": {{Name: "a", Endpoint: "a.default.svc"}},
+			},
+			err: true,
+		},
+		{
+			name: "duplicated for a repo",
+			external: map[string][]ExternalPlugin{
+				"org": {{Name: "a", Endpoint: "http://a"}, {Name: "a", Endpoint: "http://b"}},
+			},
+			err: true,
+		},
+		{
+			name: "duplicated for org and repo",
+			external: map[string][]ExternalPlugin{
+				"org":      {{Name: "a", Endpoint: "http://a"}},
+				"org/repo": {{Name: "a", Endpoint: "http://a"}},
+			},
+			err: true,
+		},
+	}
+	for _, tc := range testcases {
+		pa := PluginAgent{}
+		err := pa.SetExternal(tc.external)
+		if tc.err && err == nil {
+			t.Errorf("For case %s, expected an error.", tc.name)
+		} else if !tc.err && err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+		}
+	}
+}
+
+func TestExternalPlugins(t *testing.T) {
+	pa := PluginAgent{}
+	if err := pa.SetExternal(map[string][]ExternalPlugin{
+		"org":       {{Name: "all", Endpoint: "http://all"}},
+		"org/repo":  {{Name: "comments", Endpoint: "http://comments", Events: []string{"issue_comment"}}},
+		"org/other": {{Name: "other", Endpoint: "http://other"}},
+	}); err != nil {
+		t.Fatalf("Setting external plugins: %v", err)
+	}
+	var testcases = []struct {
+		repo     string
+		event    string
+		expected []string
+	}{
+		{repo: "repo", event: "issue_comment", expected: []string{"all", "comments"}},
+		{repo: "repo", event: "push", expected: []string{"all"}},
+		{repo: "other", event: "push", expected: []string{"all", "other"}},
+		{repo: "nope", event: "issues", expected: []string{"all"}},
+	}
+	for _, tc := range testcases {
+		var names []string
+		for _, ep := range pa.ExternalPlugins("org", tc.repo, tc.event) {
+			names = append(names, ep.Name)
+		}
+		if !reflect.DeepEqual(names, tc.expected) {
+			t.Errorf("For %s event on org/%s, expected %v, got %v.", tc.event, tc.repo, tc.expected, names)
+		}
+	}
+}
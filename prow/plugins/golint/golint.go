@@ -14,30 +14,25 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
+// Package golint contains a plugin which runs linters against the go files
+// modified in a PR in response to /lint. Which linters run is configured
+// per repo in the lint section of the prow config.
 package golint
 
 import (
 	"fmt"
-	"io/ioutil"
-	"path/filepath"
 	"regexp"
-	"strconv"
-	"strings"
-	"time"
 
 	"github.com/Sirupsen/logrus"
-	"github.com/golang/lint"
 
+	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/git"
 	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/lint"
 	"k8s.io/test-infra/prow/plugins"
 )
 
-const (
-	pluginName  = "golint"
-	commentTag  = "<!-- golint -->"
-	maxComments = 20
-)
+const pluginName = "golint"
 
 var lintRe = regexp.MustCompile(`(?mi)^/lint\s*$`)
 
@@ -52,78 +47,63 @@ type githubClient interface {
 }
 
 func handleIC(pc plugins.PluginClient, ic github.IssueCommentEvent) error {
-	return handle(pc.GitHubClient, pc.GitClient, pc.Logger, ic)
-}
-
-// modifiedGoFiles returns a map from filename to patch string for all go files
-// that are modified in the PR.
-func modifiedGoFiles(ghc githubClient, org, repo string, number int) (map[string]string, error) {
-	changes, err := ghc.GetPullRequestChanges(org, repo, number)
+	cfg := lintConfig(pc.Config, ic.Repo.Owner.Login, ic.Repo.Name)
+	linters, err := makeLinters(cfg)
 	if err != nil {
-		return nil, err
-	}
-
-	modifiedFiles := make(map[string]string)
-	for _, change := range changes {
-		if filepath.Ext(change.Filename) == ".go" {
-			modifiedFiles[change.Filename] = change.Patch
-		}
+		return err
 	}
-	return modifiedFiles, nil
+	return handle(pc.GitHubClient, pc.GitClient, pc.Logger, linters, ic)
 }
 
-// newProblems compares the list of problems with the list of past comments on
-// the PR to decide which are new.
-func newProblems(cs []github.ReviewComment, ps map[string]map[int]lint.Problem) map[string]map[int]lint.Problem {
-	// Make a copy, then remove the old elements.
-	res := make(map[string]map[int]lint.Problem)
-	for f, ls := range ps {
-		res[f] = make(map[int]lint.Problem)
-		for l, p := range ls {
-			res[f][l] = p
-		}
+// lintConfig returns the lint config for the repo, or nil if there is none.
+// Config for the repo takes precedence over config for its org.
+func lintConfig(c *config.Config, org, repo string) *config.Lint {
+	if c == nil {
+		return nil
 	}
-	for _, c := range cs {
-		if c.Position == nil {
-			continue
-		}
-		if !strings.Contains(c.Body, commentTag) {
-			continue
+	var orgConfig *config.Lint
+	fullName := fmt.Sprintf("%s/%s", org, repo)
+	for i := range c.Lint {
+		for _, r := range c.Lint[i].Repos {
+			if r == fullName {
+				return &c.Lint[i]
+			} else if r == org && orgConfig == nil {
+				orgConfig = &c.Lint[i]
+			}
 		}
-		delete(res[c.Path], *c.Position)
 	}
-	return res
+	return orgConfig
 }
 
-// problemsInFiles runs golint on the files. It returns a map from the file to
-// a map from the line in the patch to the problem.
-func problemsInFiles(r *git.Repo, files map[string]string) (map[string]map[int]lint.Problem, error) {
-	problems := make(map[string]map[int]lint.Problem)
-	l := new(lint.Linter)
-	for f, patch := range files {
-		problems[f] = make(map[int]lint.Problem)
-		src, err := ioutil.ReadFile(filepath.Join(r.Dir, f))
-		if err != nil {
-			return nil, err
-		}
-		ps, err := l.Lint(f, src)
-		if err != nil {
-			return nil, fmt.Errorf("linting %s: %v", f, err)
-		}
-		al, err := addedLines(patch)
-		if err != nil {
-			return nil, fmt.Errorf("computing added lines in %s: %v", f, err)
-		}
-		for _, p := range ps {
-			if pl, ok := al[p.Position.Line]; ok {
-				problems[f][pl] = p
-			}
+// makeLinters returns the linters that the config asks for. Without any
+// config, only golint runs.
+func makeLinters(cfg *config.Lint) ([]lint.Linter, error) {
+	if cfg == nil {
+		return []lint.Linter{lint.Golint{}}, nil
+	}
+	var linters []lint.Linter
+	for _, name := range cfg.Linters {
+		switch name {
+		case "golint":
+			linters = append(linters, lint.Golint{})
+		case "govet":
+			linters = append(linters, lint.GoVet{})
+		case "gofmt":
+			linters = append(linters, lint.Gofmt{})
+		default:
+			return nil, fmt.Errorf("unknown linter %q", name)
 		}
 	}
-	return problems, nil
+	if len(cfg.Linters) == 0 {
+		linters = append(linters, lint.Golint{})
+	}
+	for _, e := range cfg.External {
+		linters = append(linters, lint.NewExternal(e.Name, e.Command, e.FileRegexp()))
+	}
+	return linters, nil
 }
 
-func handle(ghc githubClient, gc *git.Client, log *logrus.Entry, ic github.IssueCommentEvent) error {
+func handle(ghc githubClient, gc *git.Client, log *logrus.Entry, linters []lint.Linter, ic github.IssueCommentEvent) error {
 	// Only handle open PRs and new requests.
 	if ic.Issue.State != "open" || !ic.Issue.IsPullRequest() || ic.Action != "created" {
 		return nil
@@ -132,177 +112,8 @@ func handle(ghc githubClient, gc *git.Client, log *logrus.Entry, ic github.Issue
 		return nil
 	}
 
-	org := ic.Repo.Owner.Login
-	repo := ic.Repo.Name
-
-	// List modified files.
-	modifiedFiles, err := modifiedGoFiles(ghc, org, repo, ic.Issue.Number)
-	if err != nil {
-		return err
-	}
-	if len(modifiedFiles) == 0 {
-		return nil
-	}
-	log.Infof("Will lint %d modified go files.", len(modifiedFiles))
-
-	// Clone the repo, checkout the PR.
-	startClone := time.Now()
-	r, err := gc.Clone(ic.Repo.FullName)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		if err := r.Clean(); err != nil {
-			log.WithError(err).Error("Error cleaning up repo.")
-		}
-	}()
-	if err := r.CheckoutPullRequest(ic.Issue.Number); err != nil {
-		return err
-	}
-	finishClone := time.Now()
-	log.WithField("duration", time.Since(startClone)).Info("Cloned and checked out PR.")
-
-	// Compute lint errors.
-	problems, err := problemsInFiles(r, modifiedFiles)
-	if err != nil {
-		return err
-	}
-	log.WithField("duration", time.Since(finishClone)).Info("Linted.")
-
-	oldComments, err := ghc.ListPullRequestComments(org, repo, ic.Issue.Number)
-	if err != nil {
-		return err
-	}
-	nps := newProblems(oldComments, problems)
-
-	// Make the list of comments.
-	var comments []github.DraftReviewComment
-	for f, ls := range nps {
-		for l, p := range ls {
-			var body string
-			if p.Link == "" {
-				body = fmt.Sprintf("Golint %s: %s. %s", p.Category, p.Text, commentTag)
-			} else {
-				body = fmt.Sprintf("Golint %s: %s. [More info](%s). %s", p.Category, p.Text, p.Link, commentTag)
-			}
-			comments = append(comments, github.DraftReviewComment{
-				Path:     f,
-				Position: l,
-				Body:     body,
-			})
-		}
-	}
-
-	// Trim down the number of comments if necessary.
-	totalProblems := numProblems(problems)
-	newProblems := numProblems(nps)
-	oldProblems := totalProblems - newProblems
-
-	allowedComments := maxComments - oldProblems
-	if allowedComments < 0 {
-		allowedComments = 0
-	}
-	if len(comments) > allowedComments {
-		comments = comments[:allowedComments]
-	}
-
-	// Make the review body.
-	s := "s"
-	if totalProblems == 1 {
-		s = ""
-	}
-	response := fmt.Sprintf("%d warning%s", totalProblems, s)
-
-	return ghc.CreateReview(org, repo, ic.Issue.Number, github.DraftReview{
-		Body:     plugins.FormatICResponse(ic.Comment, response),
-		Action:   github.Comment,
-		Comments: comments,
-	})
-}
-
-func numProblems(ps map[string]map[int]lint.Problem) int {
-	var num int
-	for _, m := range ps {
-		num += len(m)
-	}
-	return num
-}
-
-// addedLines returns line numbers that were added in the patch, along with
-// their line in the patch itself as a map from line to patch line.
-// https://www.gnu.org/software/diffutils/manual/diffutils.html#Detailed-Unified
-// GitHub omits the ---/+++ lines since that information is in the
-// PullRequestChange object.
-func addedLines(patch string) (map[int]int, error) {
-	result := make(map[int]int)
-	if patch == "" {
-		return result, nil
-	}
-	lines := strings.Split(patch, "\n")
-	for i := 0; i < len(lines); i++ {
-		_, oldLen, newLine, newLen, err := parseHunkLine(lines[i])
-		if err != nil {
-			return nil, fmt.Errorf("couldn't parse hunk on line %d in patch %s: %v", i, patch, err)
-		}
-		oldAdd := 0
-		newAdd := 0
-		for oldAdd < oldLen || newAdd < newLen {
-			i++
-			if i >= len(lines) {
-				return nil, fmt.Errorf("invalid patch: %s", patch)
-			}
-			switch lines[i][0] {
-			case ' ':
-				oldAdd++
-				newAdd++
-			case '-':
-				oldAdd++
-			case '+':
-				result[newLine+newAdd] = i
-				newAdd++
-			default:
-				return nil, fmt.Errorf("bad prefix on line %d in patch %s", i, patch)
-			}
-		}
-	}
-	return result, nil
-}
-
-// Matches the hunk line in unified diffs. These are of the form:
-// @@ -l,s +l,s @@ section head
-// We need to extract the four numbers, but the command and s is optional.
-// See https://en.wikipedia.org/wiki/Diff_utility#Unified_format
-var hunkRe = regexp.MustCompile(`^@@ -(\d+),?(\d+)? \+(\d+),?(\d+)? @@.*`)
-
-func parseHunkLine(hunk string) (oldLine, oldLength, newLine, newLength int, err error) {
-	if !hunkRe.MatchString(hunk) {
-		err = fmt.Errorf("invalid hunk line: %s", hunk)
-		return
-	}
-	matches := hunkRe.FindStringSubmatch(hunk)
-	oldLine, err = strconv.Atoi(matches[1])
-	if err != nil {
-		return
-	}
-	if matches[2] != "" {
-		oldLength, err = strconv.Atoi(matches[2])
-		if err != nil {
-			return
-		}
-	} else {
-		oldLength = 1
-	}
-	newLine, err = strconv.Atoi(matches[3])
-	if err != nil {
-		return
-	}
-	if matches[4] != "" {
-		newLength, err = strconv.Atoi(matches[4])
-		if err != nil {
-			return
-		}
-	} else {
-		newLength = 1
+	formatBody := func(response string) string {
+		return plugins.FormatICResponse(ic.Comment, response)
 	}
-	return
+	return lint.ReviewPullRequest(ghc, gc, log, ic.Repo.Owner.Login, ic.Repo.Name, ic.Issue.Number, linters, formatBody)
 }
@@ -18,13 +18,16 @@ package golint
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/Sirupsen/logrus"
 
+	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/git/localgit"
 	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/lint"
 )
 
 var initialFiles = map[string][]byte{
@@ -117,7 +120,7 @@ func TestLint(t *testing.T) {
 			},
 		},
 	}
-	if err := handle(gh, c, logrus.NewEntry(logrus.New()), ice); err != nil {
+	if err := handle(gh, c, logrus.NewEntry(logrus.New()), []lint.Linter{lint.Golint{}}, ice); err != nil {
 		t.Fatalf("Got error from handle: %v", err)
 	}
 	if len(gh.comment.Comments) != 2 {
@@ -131,7 +134,7 @@ func TestLint(t *testing.T) {
 			Body:     c.Body,
 		})
 	}
-	if err := handle(gh, c, logrus.NewEntry(logrus.New()), ice); err != nil {
+	if err := handle(gh, c, logrus.NewEntry(logrus.New()), []lint.Linter{lint.Golint{}}, ice); err != nil {
 		t.Fatalf("Got error from handle on second try: %v", err)
 	}
 	if len(gh.comment.Comments) != 0 {
@@ -140,7 +143,7 @@ func TestLint(t *testing.T) {
 
 	// Test that we limit comments.
 	badFileLines := []string{"package baz", ""}
-	for i := 0; i < maxComments+5; i++ {
+	for i := 0; i < lint.MaxComments+5; i++ {
 		badFileLines = append(badFileLines, fmt.Sprintf("type PublicType%d int", i))
 	}
 	gh.changes = append(gh.changes, github.PullRequestChange{
@@ -151,71 +154,54 @@ func TestLint(t *testing.T) {
 		t.Fatalf("Adding PR commit: %v", err)
 	}
 	gh.oldComments = nil
-	if err := handle(gh, c, logrus.NewEntry(logrus.New()), ice); err != nil {
+	if err := handle(gh, c, logrus.NewEntry(logrus.New()), []lint.Linter{lint.Golint{}}, ice); err != nil {
 		t.Fatalf("Got error from handle on third try: %v", err)
 	}
-	if len(gh.comment.Comments) != maxComments {
-		t.Fatalf("Expected %d comments, got %d: %v", maxComments, len(gh.comment.Comments), gh.comment.Comments)
+	if len(gh.comment.Comments) != lint.MaxComments {
+		t.Fatalf("Expected %d comments, got %d: %v", lint.MaxComments, len(gh.comment.Comments), gh.comment.Comments)
 	}
 }
 
-func TestAddedLines(t *testing.T) {
+func TestMakeLinters(t *testing.T) {
+	c := &config.Config{
+		Lint: []config.Lint{
+			{
+				Repos: []string{"foo"},
+			},
+			{
+				Repos:   []string{"foo/bar"},
+				Linters: []string{"govet", "gofmt"},
+				External: []config.ExternalLinter{
+					{Name: "shellcheck", Command: []string{"lint-shell"}, FilePattern: `\.sh$`},
+				},
+			},
+		},
+	}
+	for i := range c.Lint {
+		if err := c.Lint[i].Validate(); err != nil {
+			t.Fatalf("Validating config: %v", err)
+		}
+	}
 	var testcases = []struct {
-		patch string
-		lines map[int]int
-		err   bool
+		org, repo string
+		linters   []string
 	}{
-		{
-			patch: "@@ -0,0 +1,5 @@\n+package bar\n+\n+func Qux() error {\n+   return nil\n+}",
-			lines: map[int]int{1: 1, 2: 2, 3: 3, 4: 4, 5: 5},
-		},
-		{
-			patch: "@@ -29,12 +29,14 @@ import (\n \t\"github.com/Sirupsen/logrus\"\n \t\"github.com/ghodss/yaml\"\n \n+\t\"k8s.io/test-infra/prow/config\"\n \t\"k8s.io/test-infra/prow/jenkins\"\n \t\"k8s.io/test-infra/prow/kube\"\n \t\"k8s.io/test-infra/prow/plank\"\n )\n \n var (\n+\tconfigPath   = flag.String(\"config-path\", \"/etc/config/config\", \"Path to config.yaml.\")\n \tbuildCluster = flag.String(\"build-cluster\", \"\", \"Path to file containing a YAML-marshalled kube.Cluster object. If empty, uses the local cluster.\")\n \n \tjenkinsURL       = flag.String(\"jenkins-url\", \"\", \"Jenkins URL\")\n@@ -47,18 +49,22 @@ var objReg = regexp.MustCompile(`^[\\w-]+$`)\n \n func main() {\n \tflag.Parse()\n-\n \tlogrus.SetFormatter(&logrus.JSONFormatter{})\n \n-\tkc, err := kube.NewClientInCluster(kube.ProwNamespace)\n+\tconfigAgent := &config.Agent{}\n+\tif err := configAgent.Start(*configPath); err != nil {\n+\t\tlogrus.WithError(err).Fatal(\"Error starting config agent.\")\n+\t}\n+\n+\tkc, err := kube.NewClientInCluster(configAgent.Config().ProwJobNamespace)\n \tif err != nil {\n \t\tlogrus.WithError(err).Fatal(\"Error getting client.\")\n \t}\n \tvar pkc *kube.Client\n \tif *buildCluster == \"\" {\n-\t\tpkc = kc.Namespace(kube.TestPodNamespace)\n+\t\tpkc = kc.Namespace(configAgent.Config().PodNamespace)\n \t} else {\n-\t\tpkc, err = kube.NewClientFromFile(*buildCluster, kube.TestPodNamespace)\n+\t\tpkc, err = kube.NewClientFromFile(*buildCluster, configAgent.Config().PodNamespace)\n \t\tif err != nil {\n \t\t\tlogrus.WithError(err).Fatal(\"Error getting kube client to build cluster.\")\n \t\t}",
-			lines: map[int]int{4: 32, 11: 39, 23: 54, 24: 55, 25: 56, 26: 57, 27: 58, 28: 59, 35: 65, 38: 67},
-		},
-		{
-			patch: "@@ -1 +0,0 @@\n-such",
-		},
-		{
-			patch: "@@ -1,3 +0,0 @@\n-such\n-a\n-doge",
-		},
-		{
-			patch: "@@ -0,0 +1 @@\n+wow",
-			lines: map[int]int{1: 1},
-		},
-		{
-			patch: "@@ -1 +1 @@\n-doge\n+wow",
-			lines: map[int]int{2: 1},
-		},
-		{
-			patch: "something strange",
-			err:   true,
-		},
-		{
-			patch: "@@ -a,3 +0,0 @@\n-wow",
-			err:   true,
-		},
-		{
-			patch: "@@ -1 +1 @@",
-			err:   true,
-		},
-		{
-			patch: "",
-		},
+		{org: "baz", repo: "qux", linters: []string{"golint"}},
+		{org: "foo", repo: "qux", linters: []string{"golint"}},
+		{org: "foo", repo: "bar", linters: []string{"govet", "gofmt", "shellcheck"}},
 	}
 	for _, tc := range testcases {
-		als, err := addedLines(tc.patch)
-		if err == nil == tc.err {
-			t.Errorf("For patch %s\nExpected error %v, got error %v", tc.patch, tc.err, err)
+		linters, err := makeLinters(lintConfig(c, tc.org, tc.repo))
+		if err != nil {
+			t.Errorf("For %s/%s, didn't expect error: %v", tc.org, tc.repo, err)
 			continue
 		}
-		if len(als) != len(tc.lines) {
-			t.Errorf("For patch %s\nAdded lines has wrong length. Got %v, expected %v", tc.patch, als, tc.lines)
+		var names []string
+		for _, l := range linters {
+			names = append(names, l.Name())
 		}
-		for pl, l := range tc.lines {
-			if als[l] != pl {
-				t.Errorf("For patch %s\nExpected added line %d to be %d, but got %d", tc.patch, l, pl, als[l])
-			}
+		if !reflect.DeepEqual(names, tc.linters) {
+			t.Errorf("For %s/%s, expected linters %v, got %v", tc.org, tc.repo, tc.linters, names)
 		}
 	}
 }
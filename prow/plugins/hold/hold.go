@@ -0,0 +1,123 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package hold contains a plugin which manages the do-not-merge labels that
+// signal that a PR should not be merged yet: /hold and /hold cancel toggle
+// do-not-merge/hold, and PRs whose titles start with WIP get
+// do-not-merge/work-in-progress.
+package hold
+
+import (
+	"regexp"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+const pluginName = "hold"
+
+const (
+	holdLabel = "do-not-merge/hold"
+	wipLabel  = "do-not-merge/work-in-progress"
+)
+
+var (
+	holdRe       = regexp.MustCompile(`(?mi)^/hold\s*$`)
+	holdCancelRe = regexp.MustCompile(`(?mi)^/hold cancel\s*$`)
+	wipRe        = regexp.MustCompile(`(?i)^\s*(\[WIP\]|WIP\b)`)
+)
+
+func init() {
+	plugins.RegisterIssueCommentHandler(pluginName, handleIssueComment)
+	plugins.RegisterPullRequestHandler(pluginName, handlePullRequest)
+}
+
+type githubClient interface {
+	AddLabel(owner, repo string, number int, label string) error
+	RemoveLabel(owner, repo string, number int, label string) error
+	GetIssueLabels(org, repo string, number int) ([]github.Label, error)
+}
+
+func handleIssueComment(pc plugins.PluginClient, ic github.IssueCommentEvent) error {
+	return handleIC(pc.GitHubClient, pc.Logger, ic)
+}
+
+func handlePullRequest(pc plugins.PluginClient, pr github.PullRequestEvent) error {
+	return handlePR(pc.GitHubClient, pc.Logger, pr)
+}
+
+// handleIC adds or removes the hold label in response to /hold and
+// /hold cancel. Anyone may put a PR on hold or take it off hold.
+func handleIC(gc githubClient, log *logrus.Entry, ic github.IssueCommentEvent) error {
+	if ic.Action != "created" || !ic.Issue.IsPullRequest() || ic.Issue.State != "open" {
+		return nil
+	}
+
+	var wantHold bool
+	if holdCancelRe.MatchString(ic.Comment.Body) {
+		wantHold = false
+	} else if holdRe.MatchString(ic.Comment.Body) {
+		wantHold = true
+	} else {
+		return nil
+	}
+
+	org := ic.Repo.Owner.Login
+	repo := ic.Repo.Name
+	number := ic.Issue.Number
+	hasHold := ic.Issue.HasLabel(holdLabel)
+	if wantHold && !hasHold {
+		log.Infof("Adding %s label.", holdLabel)
+		return gc.AddLabel(org, repo, number, holdLabel)
+	} else if !wantHold && hasHold {
+		log.Infof("Removing %s label.", holdLabel)
+		return gc.RemoveLabel(org, repo, number, holdLabel)
+	}
+	return nil
+}
+
+// handlePR keeps the work-in-progress label in sync with the PR title.
+func handlePR(gc githubClient, log *logrus.Entry, pr github.PullRequestEvent) error {
+	if pr.Action != "opened" && pr.Action != "reopened" && pr.Action != "edited" {
+		return nil
+	}
+
+	org := pr.PullRequest.Base.Repo.Owner.Login
+	repo := pr.PullRequest.Base.Repo.Name
+	labels, err := gc.GetIssueLabels(org, repo, pr.Number)
+	if err != nil {
+		return err
+	}
+	hasWIP := false
+	for _, l := range labels {
+		if l.Name == wipLabel {
+			hasWIP = true
+			break
+		}
+	}
+
+	wantWIP := wipRe.MatchString(pr.PullRequest.Title)
+	if wantWIP && !hasWIP {
+		log.Infof("Adding %s label.", wipLabel)
+		return gc.AddLabel(org, repo, pr.Number, wipLabel)
+	} else if !wantWIP && hasWIP {
+		log.Infof("Removing %s label.", wipLabel)
+		return gc.RemoveLabel(org, repo, pr.Number, wipLabel)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hold
+
+import (
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+)
+
+func TestHoldComment(t *testing.T) {
+	var testcases = []struct {
+		name     string
+		body     string
+		hasLabel bool
+
+		added   bool
+		removed bool
+	}{
+		{
+			name: "unrelated comment",
+			body: "looks good to me",
+		},
+		{
+			name:  "hold",
+			body:  "/hold",
+			added: true,
+		},
+		{
+			name:  "hold with trailing space",
+			body:  "/hold \r",
+			added: true,
+		},
+		{
+			name:     "hold when already held",
+			body:     "/hold",
+			hasLabel: true,
+		},
+		{
+			name:     "hold cancel",
+			body:     "/hold cancel",
+			hasLabel: true,
+			removed:  true,
+		},
+		{
+			name: "hold cancel when not held",
+			body: "/hold cancel",
+		},
+	}
+	for _, tc := range testcases {
+		fc := &fakegithub.FakeClient{}
+		ice := github.IssueCommentEvent{
+			Action:  "created",
+			Comment: github.IssueComment{Body: tc.body},
+			Issue: github.Issue{
+				Number:      1,
+				State:       "open",
+				PullRequest: &struct{}{},
+			},
+			Repo: github.Repo{
+				Owner: github.User{Login: "org"},
+				Name:  "repo",
+			},
+		}
+		if tc.hasLabel {
+			ice.Issue.Labels = []github.Label{{Name: holdLabel}}
+		}
+		if err := handleIC(fc, logrus.WithField("plugin", pluginName), ice); err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			continue
+		}
+		if added := len(fc.LabelsAdded) == 1 && fc.LabelsAdded[0] == "org/repo#1:"+holdLabel; added != tc.added {
+			t.Errorf("For case %s, expected label added: %t, got %v", tc.name, tc.added, fc.LabelsAdded)
+		}
+		if removed := len(fc.LabelsRemoved) == 1 && fc.LabelsRemoved[0] == "org/repo#1:"+holdLabel; removed != tc.removed {
+			t.Errorf("For case %s, expected label removed: %t, got %v", tc.name, tc.removed, fc.LabelsRemoved)
+		}
+	}
+}
+
+func TestWIPTitle(t *testing.T) {
+	var testcases = []struct {
+		name     string
+		action   string
+		title    string
+		hasLabel bool
+
+		added   bool
+		removed bool
+	}{
+		{
+			name:   "regular title",
+			action: "opened",
+			title:  "Fix the thing",
+		},
+		{
+			name:   "WIP prefix",
+			action: "opened",
+			title:  "WIP: fix the thing",
+			added:  true,
+		},
+		{
+			name:   "[WIP] prefix",
+			action: "opened",
+			title:  "[WIP] fix the thing",
+			added:  true,
+		},
+		{
+			name:   "lowercase wip",
+			action: "reopened",
+			title:  "wip fix the thing",
+			added:  true,
+		},
+		{
+			name:   "WIP as part of a word",
+			action: "opened",
+			title:  "WIPE the disk",
+		},
+		{
+			name:     "title edited to drop WIP",
+			action:   "edited",
+			title:    "Fix the thing",
+			hasLabel: true,
+			removed:  true,
+		},
+		{
+			name:     "title still WIP",
+			action:   "edited",
+			title:    "[WIP] fix the thing",
+			hasLabel: true,
+		},
+		{
+			name:   "ignore other actions",
+			action: "synchronize",
+			title:  "WIP fix the thing",
+		},
+	}
+	for _, tc := range testcases {
+		fc := &fakegithub.FakeClient{}
+		if tc.hasLabel {
+			fc.LabelsAdded = []string{"org/repo#1:" + wipLabel}
+		}
+		pre := github.PullRequestEvent{
+			Action: tc.action,
+			Number: 1,
+			PullRequest: github.PullRequest{
+				Number: 1,
+				Title:  tc.title,
+				Base: github.PullRequestBranch{
+					Repo: github.Repo{
+						Owner: github.User{Login: "org"},
+						Name:  "repo",
+					},
+				},
+			},
+		}
+		if err := handlePR(fc, logrus.WithField("plugin", pluginName), pre); err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			continue
+		}
+		var added bool
+		if tc.hasLabel {
+			added = len(fc.LabelsAdded) > 1
+		} else {
+			added = len(fc.LabelsAdded) == 1 && fc.LabelsAdded[0] == "org/repo#1:"+wipLabel
+		}
+		if added != tc.added {
+			t.Errorf("For case %s, expected label added: %t, got %v", tc.name, tc.added, fc.LabelsAdded)
+		}
+		if removed := len(fc.LabelsRemoved) == 1 && fc.LabelsRemoved[0] == "org/repo#1:"+wipLabel; removed != tc.removed {
+			t.Errorf("For case %s, expected label removed: %t, got %v", tc.name, tc.removed, fc.LabelsRemoved)
+		}
+	}
+}
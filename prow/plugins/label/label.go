@@ -23,6 +23,7 @@ import (
 
 	"github.com/Sirupsen/logrus"
 
+	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/plugins"
 )
@@ -42,11 +43,9 @@ type assignEvent struct {
 }
 
 var (
-	labelRegex              = regexp.MustCompile(`(?m)^/(area|priority|kind|sig)\s*(.*)$`)
-	removeLabelRegex        = regexp.MustCompile(`(?m)^/remove-(area|priority|kind|sig)\s*(.*)$`)
-	sigMatcher              = regexp.MustCompile(`(?m)@kubernetes/sig-([\w-]*)-(misc|test-failures|bugs|feature-requests|proposals|pr-reviews|api-reviews)`)
 	chatBack                = "Reiterating the mentions to trigger a notification: \n%v"
 	nonExistentLabelOnIssue = "Those labels are not set on the issue: `%v`"
+	notAllowedLabels        = "you are not allowed to change the following labels: `%v`"
 	kindMap                 = map[string]string{
 		"bugs":             "kind/bug",
 		"feature-requests": "kind/feature",
@@ -55,6 +54,25 @@ var (
 	}
 )
 
+// defaultConfig is used on repos without a label config. It accepts the
+// Kubernetes label prefixes from anyone and understands Kubernetes sig team
+// mentions.
+var defaultConfig = func() *config.Label {
+	l := &config.Label{
+		Prefixes: []config.LabelPrefix{
+			{Name: "area"},
+			{Name: "priority"},
+			{Name: "kind"},
+			{Name: "sig"},
+		},
+		SigMentionPattern: `(?m)@kubernetes/sig-([\w-]*)-(misc|test-failures|bugs|feature-requests|proposals|pr-reviews|api-reviews)`,
+	}
+	if err := l.SetRegexes(); err != nil {
+		panic(err)
+	}
+	return l
+}()
+
 func init() {
 	plugins.RegisterIssueCommentHandler(pluginName, handleIssueComment)
 	plugins.RegisterIssueHandler(pluginName, handleIssue)
@@ -67,6 +85,7 @@ type githubClient interface {
 	AddLabel(owner, repo string, number int, label string) error
 	RemoveLabel(owner, repo string, number int, label string) error
 	GetRepoLabels(owner, repo string) ([]github.Label, error)
+	GetFile(org, repo, filepath, commit string) ([]byte, error)
 	BotName() string
 }
 
@@ -86,7 +105,7 @@ func handleIssueComment(pc plugins.PluginClient, ic github.IssueCommentEvent) er
 		issue:   ic.Issue,
 		comment: ic.Comment,
 	}
-	return handle(pc.GitHubClient, pc.Logger, ae, pc.SlackClient)
+	return handle(pc.GitHubClient, pc.Logger, labelConfig(pc.Config, ae.org, ae.repo), ae, pc.SlackClient)
 }
 
 func handleIssue(pc plugins.PluginClient, i github.IssueEvent) error {
@@ -100,7 +119,7 @@ func handleIssue(pc plugins.PluginClient, i github.IssueEvent) error {
 		number: i.Issue.Number,
 		issue:  i.Issue,
 	}
-	return handle(pc.GitHubClient, pc.Logger, ae, pc.SlackClient)
+	return handle(pc.GitHubClient, pc.Logger, labelConfig(pc.Config, ae.org, ae.repo), ae, pc.SlackClient)
 }
 
 func handlePullRequest(pc plugins.PluginClient, pr github.PullRequestEvent) error {
@@ -113,7 +132,90 @@ func handlePullRequest(pc plugins.PluginClient, pr github.PullRequestEvent) erro
 		url:    pr.PullRequest.HTMLURL,
 		number: pr.Number,
 	}
-	return handle(pc.GitHubClient, pc.Logger, ae, pc.SlackClient)
+	return handle(pc.GitHubClient, pc.Logger, labelConfig(pc.Config, ae.org, ae.repo), ae, pc.SlackClient)
+}
+
+// labelConfig returns the label config that applies to the repo, or the
+// default config if none does. Config for the repo takes precedence over
+// config for its org.
+func labelConfig(c *config.Config, org, repo string) *config.Label {
+	orgConfig := defaultConfig
+	fullName := fmt.Sprintf("%s/%s", org, repo)
+	for i := range c.Labels {
+		for _, r := range c.Labels[i].Repos {
+			if r == fullName {
+				return &c.Labels[i]
+			} else if r == org && orgConfig == defaultConfig {
+				orgConfig = &c.Labels[i]
+			}
+		}
+	}
+	return orgConfig
+}
+
+func findAll(re *regexp.Regexp, body string) [][]string {
+	if re == nil {
+		return nil
+	}
+	return re.FindAllStringSubmatch(body, -1)
+}
+
+// permissions lazily decides whether the commenter may change labels.
+type permissions struct {
+	gc    githubClient
+	log   *logrus.Entry
+	cfg   *config.Label
+	ae    assignEvent
+	owner *bool
+}
+
+// canChange returns whether or not the commenter may add or remove the label.
+func (p *permissions) canChange(label string) bool {
+	prefix := strings.SplitN(label, "/", 2)[0]
+	for _, lp := range p.cfg.Prefixes {
+		if strings.ToLower(lp.Name) != prefix || len(lp.Users) == 0 {
+			continue
+		}
+		allowed := false
+		for _, u := range lp.Users {
+			if strings.EqualFold(u, p.ae.login) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	for _, rl := range p.cfg.RestrictedLabels {
+		if strings.ToLower(rl) == label {
+			return p.isOwner()
+		}
+	}
+	return true
+}
+
+// isOwner returns whether or not the commenter is an org member or listed in
+// the root OWNERS file of the repo.
+func (p *permissions) isOwner() bool {
+	if p.owner != nil {
+		return *p.owner
+	}
+	owner := false
+	if member, err := p.gc.IsMember(p.ae.org, p.ae.login); err != nil {
+		p.log.WithError(err).Errorf("Failed IsMember(%s, %s)", p.ae.org, p.ae.login)
+	} else if member {
+		owner = true
+	}
+	if !owner {
+		if o, err := plugins.RootOwners(p.gc, p.ae.org, p.ae.repo); err != nil {
+			p.log.WithError(err).Warningf("Could not read OWNERS file of %s/%s.", p.ae.org, p.ae.repo)
+		} else {
+			owner = o.IsOwner(p.ae.login)
+		}
+	}
+	p.owner = &owner
+	return owner
 }
 
 // Get Lables from Regexp matches
@@ -127,10 +229,10 @@ func getLabelsFromREMatches(matches [][]string) (labels []string) {
 	return
 }
 
-func (ae assignEvent) getRepeats(sigMatches [][]string, existingLabels map[string]string) (toRepeat []string) {
+func (ae assignEvent) getRepeats(cfg *config.Label, sigMatches [][]string, existingLabels map[string]string) (toRepeat []string) {
 	toRepeat = []string{}
 	for _, sigMatch := range sigMatches {
-		sigLabel := strings.ToLower("sig" + "/" + strings.TrimSpace(sigMatch[1]))
+		sigLabel := cfg.SigLabel(sigMatch[1])
 
 		if _, ok := existingLabels[sigLabel]; ok {
 			toRepeat = append(toRepeat, sigMatch[0])
@@ -139,15 +241,15 @@ func (ae assignEvent) getRepeats(sigMatches [][]string, existingLabels map[strin
 	return
 }
 
-func handle(gc githubClient, log *logrus.Entry, ae assignEvent, sc slackClient) error {
+func handle(gc githubClient, log *logrus.Entry, cfg *config.Label, ae assignEvent, sc slackClient) error {
 	// only parse newly created comments/issues/PRs and if non bot author
 	if ae.login == gc.BotName() || !(ae.action == "created" || ae.action == "opened") {
 		return nil
 	}
 
-	labelMatches := labelRegex.FindAllStringSubmatch(ae.body, -1)
-	removeLabelMatches := removeLabelRegex.FindAllStringSubmatch(ae.body, -1)
-	sigMatches := sigMatcher.FindAllStringSubmatch(ae.body, -1)
+	labelMatches := findAll(cfg.LabelRegexp(), ae.body)
+	removeLabelMatches := findAll(cfg.RemoveLabelRegexp(), ae.body)
+	sigMatches := findAll(cfg.SigMentionRegexp(), ae.body)
 	if len(labelMatches) == 0 && len(sigMatches) == 0 && len(removeLabelMatches) == 0 {
 		return nil
 	}
@@ -164,9 +266,11 @@ func handle(gc githubClient, log *logrus.Entry, ae assignEvent, sc slackClient)
 	var (
 		nonexistent         []string
 		noSuchLabelsOnIssue []string
+		notAllowed          []string
 		labelsToAdd         []string
 		labelsToRemove      []string
 	)
+	perms := &permissions{gc: gc, log: log, cfg: cfg, ae: ae}
 
 	// Get labels to add and labels to remove from regexp matches
 	labelsToAdd = getLabelsFromREMatches(labelMatches)
@@ -183,6 +287,11 @@ func handle(gc githubClient, log *logrus.Entry, ae assignEvent, sc slackClient)
 			continue
 		}
 
+		if !perms.canChange(labelToAdd) {
+			notAllowed = append(notAllowed, labelToAdd)
+			continue
+		}
+
 		if err := gc.AddLabel(ae.org, ae.repo, ae.number, existingLabels[labelToAdd]); err != nil {
 			log.WithError(err).Errorf("Github failed to add the following label: %s", labelToAdd)
 		}
@@ -200,14 +309,22 @@ func handle(gc githubClient, log *logrus.Entry, ae assignEvent, sc slackClient)
 			continue
 		}
 
+		if !perms.canChange(labelToRemove) {
+			notAllowed = append(notAllowed, labelToRemove)
+			continue
+		}
+
 		if err := gc.RemoveLabel(ae.org, ae.repo, ae.number, labelToRemove); err != nil {
 			log.WithError(err).Errorf("Github failed to remove the following label: %s", labelToRemove)
 		}
 	}
 
 	for _, sigMatch := range sigMatches {
-		sigLabel := strings.ToLower("sig" + "/" + strings.TrimSpace(sigMatch[1]))
-		kind := sigMatch[2]
+		sigLabel := cfg.SigLabel(sigMatch[1])
+		var kind string
+		if len(sigMatch) > 2 {
+			kind = sigMatch[2]
+		}
 		if ae.issue.HasLabel(sigLabel) {
 			continue
 		}
@@ -215,11 +332,19 @@ func handle(gc githubClient, log *logrus.Entry, ae assignEvent, sc slackClient)
 			nonexistent = append(nonexistent, sigLabel)
 			continue
 		}
+		if !perms.canChange(sigLabel) {
+			notAllowed = append(notAllowed, sigLabel)
+			continue
+		}
 		if err := gc.AddLabel(ae.org, ae.repo, ae.number, sigLabel); err != nil {
 			log.WithError(err).Errorf("Github failed to add the following label: %s", sigLabel)
 		}
 
 		if kindLabel, ok := kindMap[kind]; ok {
+			if !perms.canChange(kindLabel) {
+				notAllowed = append(notAllowed, kindLabel)
+				continue
+			}
 			if err := gc.AddLabel(ae.org, ae.repo, ae.number, kindLabel); err != nil {
 				log.WithError(err).Errorf("Github failed to add the following label: %s", kindLabel)
 			}
@@ -233,7 +358,7 @@ func handle(gc githubClient, log *logrus.Entry, ae assignEvent, sc slackClient)
 		if err != nil {
 			log.WithError(err).Errorf("Github error occurred when checking if the user: %s is a member of org: %s.", ae.login, ae.org)
 		}
-		toRepeat = ae.getRepeats(sigMatches, existingLabels)
+		toRepeat = ae.getRepeats(cfg, sigMatches, existingLabels)
 	}
 
 	if len(toRepeat) > 0 {
@@ -258,6 +383,13 @@ func handle(gc githubClient, log *logrus.Entry, ae assignEvent, sc slackClient)
 		log.Infof("Nonexistent labels: %v", nonexistent)
 	}
 
+	if len(notAllowed) > 0 {
+		msg := fmt.Sprintf(notAllowedLabels, strings.Join(notAllowed, ", "))
+		if err := gc.CreateComment(ae.org, ae.repo, ae.number, plugins.FormatResponseRaw(ae.body, ae.url, ae.login, msg)); err != nil {
+			log.WithError(err).Errorf("Could not create comment \"%s\".", msg)
+		}
+	}
+
 	// Tried to remove Labels that were not present on the Issue
 	if len(noSuchLabelsOnIssue) > 0 {
 		msg := fmt.Sprintf(nonExistentLabelOnIssue, strings.Join(noSuchLabelsOnIssue, ", "))
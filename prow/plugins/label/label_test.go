@@ -22,6 +22,8 @@ import (
 	"testing"
 
 	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/github/fakegithub"
 	"k8s.io/test-infra/prow/slack/fakeslack"
@@ -539,7 +541,7 @@ func TestLabel(t *testing.T) {
 			fakeSlackClient := &fakeslack.FakeClient{
 				SentMessages: make(map[string][]string),
 			}
-			if err := handle(fakeClient, logrus.WithField("plugin", pluginName), ae, fakeSlackClient); err != nil {
+			if err := handle(fakeClient, logrus.WithField("plugin", pluginName), defaultConfig, ae, fakeSlackClient); err != nil {
 				t.Errorf("For case %s, didn't expect error from label test: %v", tc.name, err)
 				return
 			}
@@ -671,7 +673,7 @@ func TestRepeat(t *testing.T) {
 			member, _ := fakeClient.IsMember(ae.org, ae.login)
 			toRepeat := []string{}
 			if !member {
-				toRepeat = ae.getRepeats(sigMatcher.FindAllStringSubmatch(tc.body, -1), m)
+				toRepeat = ae.getRepeats(defaultConfig, defaultConfig.SigMentionRegexp().FindAllStringSubmatch(tc.body, -1), m)
 			}
 
 			sort.Strings(toRepeat)
@@ -754,7 +756,7 @@ func TestSlackMessages(t *testing.T) {
 				SentMessages: make(map[string][]string),
 			}
 
-			if err := handle(fakeClient, logrus.WithField("plugin", pluginName), ae, fakeSlackClient); err != nil {
+			if err := handle(fakeClient, logrus.WithField("plugin", pluginName), defaultConfig, ae, fakeSlackClient); err != nil {
 				t.Fatalf("For case %s, didn't expect error from label test: %v", tc.name, err)
 			}
 			if len(tc.expectedMessages) != len(fakeSlackClient.SentMessages) {
@@ -772,3 +774,134 @@ func TestSlackMessages(t *testing.T) {
 		}
 	}
 }
+
+func TestConfiguredLabels(t *testing.T) {
+	cfg := &config.Label{
+		Prefixes: []config.LabelPrefix{
+			// A short prefix listed first mustn't swallow longer ones.
+			{Name: "t"},
+			{Name: "triage"},
+			{Name: "team", Users: []string{"lead"}},
+		},
+		RestrictedLabels:  []string{"triage/critical", "group/security"},
+		SigMentionPattern: `(?m)@myorg/([\w-]+)-team`,
+		SigLabelPrefix:    "group",
+	}
+	if err := cfg.SetRegexes(); err != nil {
+		t.Fatalf("Could not set regexes: %v", err)
+	}
+	repoLabels := []string{"triage/needs-info", "triage/critical", "team/infra", "group/storage", "group/security", "area/foo"}
+
+	var testcases = []struct {
+		name          string
+		body          string
+		commenter     string
+		expectedAdded []string
+		shouldComment bool
+	}{
+		{
+			name:          "configured prefix",
+			body:          "/triage needs-info",
+			commenter:     nonOrgMember,
+			expectedAdded: formatLabels("triage/needs-info"),
+		},
+		{
+			name:      "unconfigured prefix is ignored",
+			body:      "/area foo",
+			commenter: nonOrgMember,
+		},
+		{
+			name:          "prefix limited to users",
+			body:          "/team infra",
+			commenter:     nonOrgMember,
+			shouldComment: true,
+		},
+		{
+			name:          "prefix limited to users, allowed user",
+			body:          "/team infra",
+			commenter:     "lead",
+			expectedAdded: formatLabels("team/infra"),
+		},
+		{
+			name:          "restricted label by non-member",
+			body:          "/triage critical",
+			commenter:     nonOrgMember,
+			shouldComment: true,
+		},
+		{
+			name:          "restricted label by org member",
+			body:          "/triage critical",
+			commenter:     orgMember,
+			expectedAdded: formatLabels("triage/critical"),
+		},
+		{
+			name:          "restricted label by OWNERS approver",
+			body:          "/triage critical",
+			commenter:     "Approver",
+			expectedAdded: formatLabels("triage/critical"),
+		},
+		{
+			name:          "custom sig mention",
+			body:          "@myorg/storage-team",
+			commenter:     orgMember,
+			expectedAdded: formatLabels("group/storage"),
+		},
+		{
+			name:          "restricted sig mention by non-member",
+			body:          "@myorg/security-team",
+			commenter:     nonOrgMember,
+			shouldComment: true,
+		},
+		{
+			name:          "restricted sig mention by org member",
+			body:          "@myorg/security-team",
+			commenter:     orgMember,
+			expectedAdded: formatLabels("group/security"),
+		},
+	}
+	for _, tc := range testcases {
+		fakeClient, ae := getFakeRepoIssueComment(tc.body, tc.commenter, repoLabels, nil)
+		fakeClient.RemoteFiles = map[string]map[string]string{
+			"OWNERS": {"master": "approvers:\n- approver\n"},
+		}
+		if err := handle(fakeClient, logrus.WithField("plugin", pluginName), cfg, ae, &fakeslack.FakeClient{SentMessages: make(map[string][]string)}); err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			continue
+		}
+		if len(fakeClient.LabelsAdded) != len(tc.expectedAdded) {
+			t.Errorf("For case %s, expected labels %v to be added, got %v", tc.name, tc.expectedAdded, fakeClient.LabelsAdded)
+		} else {
+			for i := range tc.expectedAdded {
+				if fakeClient.LabelsAdded[i] != tc.expectedAdded[i] {
+					t.Errorf("For case %s, expected labels %v to be added, got %v", tc.name, tc.expectedAdded, fakeClient.LabelsAdded)
+					break
+				}
+			}
+		}
+		if commented := len(fakeClient.IssueComments[prNumber]) > 0; commented != tc.shouldComment {
+			t.Errorf("For case %s, expected comment: %t, got comments %v", tc.name, tc.shouldComment, fakeClient.IssueComments[prNumber])
+		}
+	}
+}
+
+func TestLabelConfig(t *testing.T) {
+	c := &config.Config{
+		Labels: []config.Label{
+			{Repos: []string{"org1"}},
+			{Repos: []string{"org2/repo"}},
+			{Repos: []string{"org1/special"}},
+		},
+	}
+	if lc := labelConfig(c, "org1", "any"); lc != &c.Labels[0] {
+		t.Errorf("Expected org config for org1/any, got %v", lc)
+	}
+	if lc := labelConfig(c, "org1", "special"); lc != &c.Labels[2] {
+		t.Errorf("Expected repo config to override org config for org1/special, got %v", lc)
+	}
+	if lc := labelConfig(c, "org2", "repo"); lc != &c.Labels[1] {
+		t.Errorf("Expected repo config for org2/repo, got %v", lc)
+	}
+	if lc := labelConfig(c, "org2", "other"); lc != defaultConfig {
+		t.Errorf("Expected default config for org2/other, got %v", lc)
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lifecycle contains a plugin which lets anyone mark issues and PRs
+// with /lifecycle frozen, stale or rotten, and undo that with
+// /remove-lifecycle. The sweeper command applies the same labels to inactive
+// issues and PRs automatically.
+package lifecycle
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+const pluginName = "lifecycle"
+
+// The lifecycle labels. An issue or PR has at most one of them.
+const (
+	FrozenLabel = "lifecycle/frozen"
+	StaleLabel  = "lifecycle/stale"
+	RottenLabel = "lifecycle/rotten"
+)
+
+var lifecycleRe = regexp.MustCompile(`(?mi)^/(remove-)?lifecycle (frozen|stale|rotten)\s*$`)
+
+func init() {
+	plugins.RegisterIssueCommentHandler(pluginName, handleIssueComment)
+}
+
+type githubClient interface {
+	AddLabel(owner, repo string, number int, label string) error
+	RemoveLabel(owner, repo string, number int, label string) error
+}
+
+func handleIssueComment(pc plugins.PluginClient, ic github.IssueCommentEvent) error {
+	return handle(pc.GitHubClient, pc.Logger, ic)
+}
+
+// handle applies the last lifecycle command in the comment. Adding a
+// lifecycle label removes the other ones.
+func handle(gc githubClient, log *logrus.Entry, ic github.IssueCommentEvent) error {
+	if ic.Action != "created" || ic.Issue.State != "open" {
+		return nil
+	}
+	matches := lifecycleRe.FindAllStringSubmatch(ic.Comment.Body, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	last := matches[len(matches)-1]
+	remove := last[1] != ""
+	label := "lifecycle/" + strings.ToLower(last[2])
+
+	org := ic.Repo.Owner.Login
+	repo := ic.Repo.Name
+	number := ic.Issue.Number
+	if remove {
+		if !ic.Issue.HasLabel(label) {
+			return nil
+		}
+		log.Infof("Removing %s label.", label)
+		return gc.RemoveLabel(org, repo, number, label)
+	}
+
+	for _, l := range []string{FrozenLabel, StaleLabel, RottenLabel} {
+		if l != label && ic.Issue.HasLabel(l) {
+			log.Infof("Removing %s label.", l)
+			if err := gc.RemoveLabel(org, repo, number, l); err != nil {
+				return err
+			}
+		}
+	}
+	if ic.Issue.HasLabel(label) {
+		return nil
+	}
+	log.Infof("Adding %s label.", label)
+	return gc.AddLabel(org, repo, number, label)
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lifecycle
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+)
+
+func TestLifecycleComment(t *testing.T) {
+	var testcases = []struct {
+		name    string
+		body    string
+		state   string
+		labels  []string
+		added   []string
+		removed []string
+	}{
+		{
+			name: "unrelated comment",
+			body: "this is stale",
+		},
+		{
+			name:  "add frozen",
+			body:  "/lifecycle frozen",
+			added: []string{"org/repo#1:lifecycle/frozen"},
+		},
+		{
+			name:    "freezing a stale issue removes stale",
+			body:    "/lifecycle Frozen",
+			labels:  []string{"lifecycle/stale"},
+			added:   []string{"org/repo#1:lifecycle/frozen"},
+			removed: []string{"org/repo#1:lifecycle/stale"},
+		},
+		{
+			name:   "already rotten",
+			body:   "/lifecycle rotten",
+			labels: []string{"lifecycle/rotten"},
+		},
+		{
+			name:    "remove stale",
+			body:    "/remove-lifecycle stale",
+			labels:  []string{"lifecycle/stale"},
+			removed: []string{"org/repo#1:lifecycle/stale"},
+		},
+		{
+			name: "remove missing label",
+			body: "/remove-lifecycle rotten",
+		},
+		{
+			name:  "last command wins",
+			body:  "/lifecycle stale\n/lifecycle frozen",
+			added: []string{"org/repo#1:lifecycle/frozen"},
+		},
+		{
+			name:  "closed issues are ignored",
+			body:  "/lifecycle frozen",
+			state: "closed",
+		},
+		{
+			name: "unknown lifecycle",
+			body: "/lifecycle dead",
+		},
+	}
+	for _, tc := range testcases {
+		fc := &fakegithub.FakeClient{}
+		ic := github.IssueCommentEvent{
+			Action:  "created",
+			Repo:    github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+			Issue:   github.Issue{Number: 1, State: "open"},
+			Comment: github.IssueComment{Body: tc.body},
+		}
+		if tc.state != "" {
+			ic.Issue.State = tc.state
+		}
+		for _, l := range tc.labels {
+			ic.Issue.Labels = append(ic.Issue.Labels, github.Label{Name: l})
+		}
+		if err := handle(fc, logrus.WithField("plugin", pluginName), ic); err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(fc.LabelsAdded, tc.added) {
+			t.Errorf("For case %s, expected labels %v to be added, got %v", tc.name, tc.added, fc.LabelsAdded)
+		}
+		if !reflect.DeepEqual(fc.LabelsRemoved, tc.removed) {
+			t.Errorf("For case %s, expected labels %v to be removed, got %v", tc.name, tc.removed, fc.LabelsRemoved)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package needsrebase labels PRs that can no longer be merged cleanly into
+// their base branch. PRs are re-checked whenever their base branch is pushed
+// to, whenever they are updated, and once an hour in case we missed an event.
+package needsrebase
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+const (
+	pluginName       = "needs-rebase"
+	needsRebaseLabel = "needs-rebase"
+
+	// GitHub computes mergeability asynchronously, so we may have to ask
+	// for it a few times before we get an answer.
+	maxMergeableTries = 5
+	// maxConcurrentChecks bounds how many PRs a push re-checks at once.
+	maxConcurrentChecks = 5
+)
+
+var (
+	needsRebaseRe = regexp.MustCompile(`(?m)^@\S+ PR needs rebase`)
+
+	sleep              = time.Sleep
+	mergeableRetryWait = 10 * time.Second
+	// background runs the re-checks that follow a push. Tests run them
+	// synchronously.
+	background = func(f func()) { go f() }
+
+	// rechecking holds the org/repo:branch of every push re-check in
+	// progress, so that a burst of pushes doesn't start one each.
+	rechecking = map[string]bool{}
+	recheckMu  sync.Mutex
+)
+
+func init() {
+	plugins.RegisterPushEventHandler(pluginName, handlePushEvent)
+	plugins.RegisterPullRequestHandler(pluginName, handlePullRequestEvent)
+	plugins.RegisterPeriodicHandler(pluginName, time.Hour, handlePeriodic)
+}
+
+type githubClient interface {
+	AddLabel(owner, repo string, number int, label string) error
+	RemoveLabel(owner, repo string, number int, label string) error
+	GetIssueLabels(org, repo string, number int) ([]github.Label, error)
+	CreateComment(owner, repo string, number int, comment string) error
+	ListIssueComments(owner, repo string, number int) ([]github.IssueComment, error)
+	DeleteComment(owner, repo string, ID int) error
+	GetPullRequest(owner, repo string, number int) (*github.PullRequest, error)
+	FindIssues(query, sort string, asc bool) ([]github.Issue, error)
+	BotName() string
+}
+
+func handlePushEvent(pc plugins.PluginClient, pe github.PushEvent) error {
+	return handlePush(pc.GitHubClient, pc.Logger, pe)
+}
+
+func handlePullRequestEvent(pc plugins.PluginClient, pre github.PullRequestEvent) error {
+	return handlePR(pc.GitHubClient, pc.Logger, pre)
+}
+
+func handlePeriodic(pc plugins.PluginClient, repos []string) error {
+	return handleAll(pc.GitHubClient, pc.Logger, repos)
+}
+
+// handlePush re-checks every open PR against the branch that was pushed to.
+// GitHub takes a while to recompute mergeability after a push, so the checks
+// run in the background rather than holding up the handler.
+func handlePush(ghc githubClient, log *logrus.Entry, pe github.PushEvent) error {
+	// Ignore tags and branch deletions.
+	if !strings.HasPrefix(pe.Ref, "refs/heads/") || pe.After == "0000000000000000000000000000000000000000" {
+		return nil
+	}
+	branch := strings.TrimPrefix(pe.Ref, "refs/heads/")
+	key := pe.Repo.FullName + ":" + branch
+	recheckMu.Lock()
+	if rechecking[key] {
+		recheckMu.Unlock()
+		log.Infof("Already re-checking open PRs against %s.", branch)
+		return nil
+	}
+	rechecking[key] = true
+	recheckMu.Unlock()
+	done := func() {
+		recheckMu.Lock()
+		delete(rechecking, key)
+		recheckMu.Unlock()
+	}
+
+	query := fmt.Sprintf("is:pr is:open repo:%s base:%q", pe.Repo.FullName, branch)
+	issues, err := ghc.FindIssues(query, "", false)
+	if err != nil {
+		done()
+		return err
+	}
+	log.Infof("Checking %d open PRs against %s.", len(issues), branch)
+	background(func() {
+		defer done()
+		if err := checkIssues(ghc, log, pe.Repo.Owner.Name, pe.Repo.Name, issues); err != nil {
+			log.WithError(err).Errorf("Error re-checking PRs against %s.", branch)
+		}
+	})
+	return nil
+}
+
+// handlePR checks a single PR when it is opened or its head changes.
+func handlePR(ghc githubClient, log *logrus.Entry, pre github.PullRequestEvent) error {
+	if pre.Action != "opened" && pre.Action != "reopened" && pre.Action != "synchronize" {
+		return nil
+	}
+	org := pre.PullRequest.Base.Repo.Owner.Login
+	repo := pre.PullRequest.Base.Repo.Name
+	labels, err := ghc.GetIssueLabels(org, repo, pre.Number)
+	if err != nil {
+		return err
+	}
+	return takeAction(ghc, log, org, repo, pre.Number, pre.PullRequest.User.Login, hasLabel(labels))
+}
+
+// handleAll checks every open PR in the given orgs and repos.
+func handleAll(ghc githubClient, log *logrus.Entry, repos []string) error {
+	var errs []error
+	for _, r := range repos {
+		scope := "org"
+		if strings.Contains(r, "/") {
+			scope = "repo"
+		}
+		issues, err := ghc.FindIssues(fmt.Sprintf("is:pr is:open %s:%s", scope, r), "", false)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("searching %s: %v", r, err))
+			continue
+		}
+		log.Infof("Checking %d open PRs in %s.", len(issues), r)
+		for _, i := range issues {
+			org, repo, err := i.OrgRepo()
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if err := takeAction(ghc, log, org, repo, i.Number, i.User.Login, i.HasLabel(needsRebaseLabel)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("encountered %d errors checking PRs: %v", len(errs), errs)
+	}
+	return nil
+}
+
+// checkIssues checks the PRs of a repo, at most maxConcurrentChecks at once.
+func checkIssues(ghc githubClient, log *logrus.Entry, org, repo string, issues []github.Issue) error {
+	var (
+		errs []error
+		lock sync.Mutex
+		wg   sync.WaitGroup
+	)
+	sem := make(chan struct{}, maxConcurrentChecks)
+	for _, i := range issues {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i github.Issue) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := takeAction(ghc, log, org, repo, i.Number, i.User.Login, i.HasLabel(needsRebaseLabel)); err != nil {
+				lock.Lock()
+				errs = append(errs, err)
+				lock.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return fmt.Errorf("encountered %d errors checking PRs: %v", len(errs), errs)
+	}
+	return nil
+}
+
+// takeAction adds or removes the needs-rebase label so that it matches the
+// mergeability of the PR.
+func takeAction(ghc githubClient, log *logrus.Entry, org, repo string, number int, author string, hasLabel bool) error {
+	mergeable, err := isMergeable(ghc, org, repo, number)
+	if err != nil {
+		return err
+	}
+	if mergeable == nil {
+		log.Infof("GitHub has not yet computed mergeability of %s/%s#%d, skipping.", org, repo, number)
+		return nil
+	}
+	if *mergeable && hasLabel {
+		log.Infof("Removing %s label from %s/%s#%d.", needsRebaseLabel, org, repo, number)
+		if err := ghc.RemoveLabel(org, repo, number, needsRebaseLabel); err != nil {
+			return err
+		}
+		return deleteStaleComments(ghc, org, repo, number)
+	} else if !*mergeable && !hasLabel {
+		log.Infof("Adding %s label to %s/%s#%d.", needsRebaseLabel, org, repo, number)
+		if err := ghc.AddLabel(org, repo, number, needsRebaseLabel); err != nil {
+			return err
+		}
+		return ghc.CreateComment(org, repo, number, needsRebaseMessage(author))
+	}
+	return nil
+}
+
+// isMergeable returns the mergeable field of the PR, asking again a few times
+// if GitHub has not finished computing it. It returns nil if GitHub never
+// gives us an answer.
+func isMergeable(ghc githubClient, org, repo string, number int) (*bool, error) {
+	for try := 0; try < maxMergeableTries; try++ {
+		pr, err := ghc.GetPullRequest(org, repo, number)
+		if err != nil {
+			return nil, err
+		}
+		if pr.Merged {
+			return nil, nil
+		}
+		if pr.Mergeable != nil {
+			return pr.Mergeable, nil
+		}
+		if try+1 < maxMergeableTries {
+			sleep(mergeableRetryWait)
+		}
+	}
+	return nil, nil
+}
+
+// deleteStaleComments removes the comments we left when adding the label.
+func deleteStaleComments(ghc githubClient, org, repo string, number int) error {
+	comments, err := ghc.ListIssueComments(org, repo, number)
+	if err != nil {
+		return err
+	}
+	botName := ghc.BotName()
+	for _, c := range comments {
+		if c.User.Login != botName || !needsRebaseRe.MatchString(c.Body) {
+			continue
+		}
+		if err := ghc.DeleteComment(org, repo, c.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func needsRebaseMessage(author string) string {
+	return fmt.Sprintf(`@%s PR needs rebase.
+
+This PR can no longer be merged cleanly into its base branch. Please rebase it onto the latest base branch and push again. I will remove the `+"`%s`"+` label once GitHub reports that the PR is mergeable.
+
+<details>
+
+%s
+</details>
+`, author, needsRebaseLabel, plugins.AboutThisBot)
+}
+
+func hasLabel(labels []github.Label) bool {
+	for _, l := range labels {
+		if strings.ToLower(l.Name) == needsRebaseLabel {
+			return true
+		}
+	}
+	return false
+}
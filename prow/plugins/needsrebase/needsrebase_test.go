@@ -0,0 +1,208 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package needsrebase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+)
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestHandlePush(t *testing.T) {
+	sleep = func(time.Duration) {}
+	background = func(f func()) { f() }
+	defer func() {
+		sleep = time.Sleep
+		background = func(f func()) { go f() }
+	}()
+
+	var testcases = []struct {
+		name      string
+		ref       string
+		mergeable *bool
+		hasLabel  bool
+
+		added   bool
+		removed bool
+	}{
+		{
+			name:      "unmergeable PR gets label",
+			ref:       "refs/heads/master",
+			mergeable: boolPtr(false),
+			added:     true,
+		},
+		{
+			name:      "unmergeable PR already labelled",
+			ref:       "refs/heads/master",
+			mergeable: boolPtr(false),
+			hasLabel:  true,
+		},
+		{
+			name:      "mergeable PR loses label",
+			ref:       "refs/heads/master",
+			mergeable: boolPtr(true),
+			hasLabel:  true,
+			removed:   true,
+		},
+		{
+			name:      "mergeable PR without label",
+			ref:       "refs/heads/master",
+			mergeable: boolPtr(true),
+		},
+		{
+			name: "unknown mergeability is skipped",
+			ref:  "refs/heads/master",
+		},
+		{
+			name:      "tags are ignored",
+			ref:       "refs/tags/v1.0",
+			mergeable: boolPtr(false),
+		},
+	}
+	for _, tc := range testcases {
+		issue := github.Issue{
+			Number:      5,
+			User:        github.User{Login: "author"},
+			PullRequest: &struct{}{},
+		}
+		if tc.hasLabel {
+			issue.Labels = []github.Label{{Name: needsRebaseLabel}}
+		}
+		fc := &fakegithub.FakeClient{
+			Issues: []github.Issue{issue},
+			IssueComments: map[int][]github.IssueComment{
+				5: {
+					{ID: 1, Body: "@author PR needs rebase.", User: github.User{Login: "k8s-ci-robot"}},
+					{ID: 2, Body: "@author PR needs rebase.", User: github.User{Login: "someone"}},
+				},
+			},
+			PullRequests: map[int]*github.PullRequest{
+				5: {Number: 5, Mergeable: tc.mergeable},
+			},
+		}
+		pe := github.PushEvent{
+			Ref:   tc.ref,
+			After: "abcdef",
+			Repo: github.Repo{
+				Owner:    github.User{Name: "org"},
+				Name:     "repo",
+				FullName: "org/repo",
+			},
+		}
+		if err := handlePush(fc, logrus.WithField("plugin", pluginName), pe); err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			continue
+		}
+		if added := len(fc.LabelsAdded) == 1; added != tc.added {
+			t.Errorf("For case %s, expected label added: %t, got labels added: %v", tc.name, tc.added, fc.LabelsAdded)
+		}
+		if tc.added && len(fc.IssueComments[5]) != 3 {
+			t.Errorf("For case %s, expected a comment explaining the label.", tc.name)
+		}
+		if removed := len(fc.LabelsRemoved) == 1; removed != tc.removed {
+			t.Errorf("For case %s, expected label removed: %t, got labels removed: %v", tc.name, tc.removed, fc.LabelsRemoved)
+		}
+		if tc.removed {
+			if len(fc.IssueComments[5]) != 1 || fc.IssueComments[5][0].ID != 2 {
+				t.Errorf("For case %s, expected only the bot's comment to be deleted, got %v", tc.name, fc.IssueComments[5])
+			}
+		}
+	}
+}
+
+func TestHandleAll(t *testing.T) {
+	sleep = func(time.Duration) {}
+	defer func() { sleep = time.Sleep }()
+
+	fc := &fakegithub.FakeClient{
+		Issues: []github.Issue{
+			{
+				Number:      1,
+				HTMLURL:     "https://github.com/org/repo/pull/1",
+				PullRequest: &struct{}{},
+			},
+			{
+				Number:      2,
+				HTMLURL:     "https://github.com/org/repo/pull/2",
+				PullRequest: &struct{}{},
+				Labels:      []github.Label{{Name: needsRebaseLabel}},
+			},
+		},
+		IssueComments: map[int][]github.IssueComment{},
+		PullRequests: map[int]*github.PullRequest{
+			1: {Number: 1, Mergeable: boolPtr(false)},
+			2: {Number: 2, Mergeable: boolPtr(true)},
+		},
+	}
+	if err := handleAll(fc, logrus.WithField("plugin", pluginName), []string{"org/repo"}); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if len(fc.LabelsAdded) != 1 || fc.LabelsAdded[0] != "org/repo#1:"+needsRebaseLabel {
+		t.Errorf("Expected label added to #1, got %v", fc.LabelsAdded)
+	}
+	if len(fc.LabelsRemoved) != 1 || fc.LabelsRemoved[0] != "org/repo#2:"+needsRebaseLabel {
+		t.Errorf("Expected label removed from #2, got %v", fc.LabelsRemoved)
+	}
+}
+
+func TestHandlePushInProgress(t *testing.T) {
+	var queued []func()
+	background = func(f func()) { queued = append(queued, f) }
+	defer func() { background = func(f func()) { go f() } }()
+
+	fc := &fakegithub.FakeClient{
+		Issues:        []github.Issue{{Number: 5, PullRequest: &struct{}{}}},
+		IssueComments: map[int][]github.IssueComment{},
+		PullRequests:  map[int]*github.PullRequest{5: {Number: 5, Mergeable: boolPtr(false)}},
+	}
+	pe := github.PushEvent{
+		Ref:   "refs/heads/master",
+		After: "abcdef",
+		Repo: github.Repo{
+			Owner:    github.User{Name: "org"},
+			Name:     "repo",
+			FullName: "org/repo",
+		},
+	}
+	log := logrus.WithField("plugin", pluginName)
+	for i := 0; i < 2; i++ {
+		if err := handlePush(fc, log, pe); err != nil {
+			t.Fatalf("Didn't expect error: %v", err)
+		}
+	}
+	if len(queued) != 1 {
+		t.Fatalf("Expected one re-check while the first is in progress, got %d", len(queued))
+	}
+	queued[0]()
+	if len(fc.LabelsAdded) != 1 {
+		t.Errorf("Expected the label to be added once, got %v", fc.LabelsAdded)
+	}
+	if err := handlePush(fc, log, pe); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if len(queued) != 2 {
+		t.Errorf("Expected another re-check once the first finished, got %d", len(queued))
+	}
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// OwnersFilename is the name of the file that lists who may approve and
+// review changes to a directory.
+const OwnersFilename = "OWNERS"
+
+// Owners is the content of an OWNERS file.
+type Owners struct {
+	Approvers []string `json:"approvers,omitempty"`
+	Reviewers []string `json:"reviewers,omitempty"`
+}
+
+// ParseOwners parses the content of an OWNERS file.
+func ParseOwners(b []byte) (*Owners, error) {
+	o := &Owners{}
+	if err := yaml.Unmarshal(b, o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// IsApprover returns whether or not the user is listed as an approver.
+// GitHub logins are case-insensitive.
+func (o *Owners) IsApprover(login string) bool {
+	return containsLogin(o.Approvers, login)
+}
+
+// IsOwner returns whether or not the user is listed as an approver or a
+// reviewer.
+func (o *Owners) IsOwner(login string) bool {
+	return containsLogin(o.Approvers, login) || containsLogin(o.Reviewers, login)
+}
+
+func containsLogin(logins []string, login string) bool {
+	for _, l := range logins {
+		if strings.EqualFold(l, login) {
+			return true
+		}
+	}
+	return false
+}
+
+type fileGetter interface {
+	GetFile(org, repo, filepath, commit string) ([]byte, error)
+}
+
+// RootOwners fetches and parses the OWNERS file at the root of the repo's
+// default branch.
+func RootOwners(gc fileGetter, org, repo string) (*Owners, error) {
+	b, err := gc.GetFile(org, repo, OwnersFilename, "")
+	if err != nil {
+		return nil, err
+	}
+	return ParseOwners(b)
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"testing"
+)
+
+func TestParseOwners(t *testing.T) {
+	o, err := ParseOwners([]byte("approvers:\n- Alice\nreviewers:\n- bob\n"))
+	if err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	var testcases = []struct {
+		login    string
+		approver bool
+		owner    bool
+	}{
+		{login: "alice", approver: true, owner: true},
+		{login: "ALICE", approver: true, owner: true},
+		{login: "bob", owner: true},
+		{login: "carol"},
+	}
+	for _, tc := range testcases {
+		if a := o.IsApprover(tc.login); a != tc.approver {
+			t.Errorf("For %s, expected IsApprover %t, got %t", tc.login, tc.approver, a)
+		}
+		if ow := o.IsOwner(tc.login); ow != tc.owner {
+			t.Errorf("For %s, expected IsOwner %t, got %t", tc.login, tc.owner, ow)
+		}
+	}
+	if _, err := ParseOwners([]byte("approvers: [")); err == nil {
+		t.Error("Expected error parsing invalid OWNERS file.")
+	}
+}
@@ -19,6 +19,7 @@ package plugins
 import (
 	"fmt"
 	"io/ioutil"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -42,6 +43,7 @@ var (
 	reviewEventHandlers        = map[string]ReviewEventHandler{}
 	reviewCommentEventHandlers = map[string]ReviewCommentEventHandler{}
 	statusEventHandlers        = map[string]StatusEventHandler{}
+	periodicHandlers           = map[string]Periodic{}
 )
 
 type IssueHandler func(PluginClient, github.IssueEvent) error
@@ -93,6 +95,32 @@ func RegisterReviewCommentEventHandler(name string, fn ReviewCommentEventHandler
 	reviewCommentEventHandlers[name] = fn
 }
 
+// PeriodicHandler is called on a timer with the list of orgs and repos (as
+// "org" or "org/repo") on which the plugin is enabled. Use it to reconcile
+// state that may have been missed due to dropped webhooks.
+type PeriodicHandler func(PluginClient, []string) error
+
+// Periodic is a PeriodicHandler along with how often it should be called.
+type Periodic struct {
+	Interval time.Duration
+	Handler  PeriodicHandler
+}
+
+func RegisterPeriodicHandler(name string, interval time.Duration, fn PeriodicHandler) {
+	allPlugins[name] = struct{}{}
+	periodicHandlers[name] = Periodic{Interval: interval, Handler: fn}
+}
+
+// PeriodicHandlers returns a map of plugin names to all registered periodic
+// handlers. Use PluginAgent.EnabledRepos to find where each one is enabled.
+func PeriodicHandlers() map[string]Periodic {
+	hs := map[string]Periodic{}
+	for p, h := range periodicHandlers {
+		hs[p] = h
+	}
+	return hs
+}
+
 // PluginClient may be used concurrently, so each entry must be thread-safe.
 type PluginClient struct {
 	GitHubClient *github.Client
@@ -106,23 +134,123 @@ type PluginClient struct {
 type PluginAgent struct {
 	PluginClient
 
+	// ShadowLog, if set, records the mutating calls that plugins in shadow
+	// mode would have made.
+	ShadowLog *ShadowLog
+	// Validate, if set, can reject a plugin config before it is loaded,
+	// such as one that enables plugins this deployment can't run.
+	Validate func(Configuration) error
+
 	mut sync.Mutex
 	// Repo (eg "k/k") -> list of handler names.
 	ps map[string][]string
+	// Names of plugins in shadow mode.
+	shadow map[string]bool
+	// Repo (eg "k/k") or org -> external plugins enabled on it.
+	external map[string][]ExternalPlugin
+}
+
+// Configuration is the content of the plugin config file.
+type Configuration struct {
+	// Plugins maps orgs and repos (as "org" or "org/repo") to the list of
+	// plugins enabled on them.
+	Plugins map[string][]string `json:"plugins,omitempty"`
+	// Shadow lists plugins that receive real events but don't mutate
+	// anything. Their mutating calls are logged instead.
+	Shadow []string `json:"shadow,omitempty"`
+	// ExternalPlugins maps orgs and repos to plugins that run outside of
+	// hook and receive their events over HTTP.
+	ExternalPlugins map[string][]ExternalPlugin `json:"external_plugins,omitempty"`
 }
 
 // Load attempts to load config from the path. It returns an error if either
-// the file can't be read or it contains an unknown plugin.
+// the file can't be read or it contains an unknown plugin. Files that are
+// just a map from repos to plugins, without any of the top-level keys of
+// Configuration, are still understood.
 func (pa *PluginAgent) Load(path string) error {
 	b, err := ioutil.ReadFile(path)
 	if err != nil {
 		return err
 	}
-	np := map[string][]string{}
-	if err := yaml.Unmarshal(b, &np); err != nil {
+	var keys map[string]interface{}
+	if err := yaml.Unmarshal(b, &keys); err != nil {
+		return err
+	}
+	c := Configuration{}
+	if isLegacyConfig(keys) {
+		np := map[string][]string{}
+		if err := yaml.Unmarshal(b, &np); err != nil {
+			return err
+		}
+		c.Plugins = np
+	} else if err := yaml.Unmarshal(b, &c); err != nil {
+		return err
+	}
+	// Validate everything before changing anything, so that a bad config
+	// never leaves part of it live.
+	if err := validatePlugins(c.Plugins); err != nil {
+		return err
+	}
+	shadow, err := validateShadow(c.Shadow)
+	if err != nil {
+		return err
+	}
+	if err := validateExternal(c.ExternalPlugins); err != nil {
+		return err
+	}
+	if pa.Validate != nil {
+		if err := pa.Validate(c); err != nil {
+			return err
+		}
+	}
+	pa.mut.Lock()
+	defer pa.mut.Unlock()
+	pa.ps = c.Plugins
+	pa.shadow = shadow
+	pa.external = c.ExternalPlugins
+	return nil
+}
+
+// isLegacyConfig returns whether or not the top-level keys of the plugin
+// config file are repos rather than the fields of Configuration.
+func isLegacyConfig(keys map[string]interface{}) bool {
+	for _, k := range []string{"plugins", "shadow", "external_plugins"} {
+		if _, ok := keys[k]; ok {
+			return false
+		}
+	}
+	return true
+}
+
+// SetShadow sets the plugins that run in shadow mode. It will return error if
+// there are unknown plugins.
+func (pa *PluginAgent) SetShadow(names []string) error {
+	shadow, err := validateShadow(names)
+	if err != nil {
 		return err
 	}
-	return pa.Set(np)
+	pa.mut.Lock()
+	defer pa.mut.Unlock()
+	pa.shadow = shadow
+	return nil
+}
+
+func validateShadow(names []string) (map[string]bool, error) {
+	shadow := map[string]bool{}
+	for _, p := range names {
+		if _, ok := allPlugins[p]; !ok {
+			return nil, fmt.Errorf("unknown shadow plugin: %s", p)
+		}
+		shadow[p] = true
+	}
+	return shadow, nil
+}
+
+// IsShadow returns whether or not the plugin runs in shadow mode.
+func (pa *PluginAgent) IsShadow(plugin string) bool {
+	pa.mut.Lock()
+	defer pa.mut.Unlock()
+	return pa.shadow[plugin]
 }
 
 // Set attempts to set the plugins that are enabled on repos. The input is a
@@ -131,6 +259,16 @@ func (pa *PluginAgent) Load(path string) error {
 // all repos in the org. It will return error if there are unknown or duplicated
 // plugins.
 func (pa *PluginAgent) Set(np map[string][]string) error {
+	if err := validatePlugins(np); err != nil {
+		return err
+	}
+	pa.mut.Lock()
+	defer pa.mut.Unlock()
+	pa.ps = np
+	return nil
+}
+
+func validatePlugins(np map[string][]string) error {
 	// Check that there are no plugins that we don't know about.
 	for _, v := range np {
 		for _, p := range v {
@@ -152,9 +290,6 @@ func (pa *PluginAgent) Set(np map[string][]string) error {
 			}
 		}
 	}
-	pa.mut.Lock()
-	defer pa.mut.Unlock()
-	pa.ps = np
 	return nil
 }
 
@@ -279,6 +414,34 @@ func (pa *PluginAgent) PushEventHandlers(owner, repo string) map[string]PushEven
 	return hs
 }
 
+// EnabledRepos returns the orgs and repos (as "org" or "org/repo") on which
+// the plugin is enabled.
+func (pa *PluginAgent) EnabledRepos(plugin string) []string {
+	pa.mut.Lock()
+	defer pa.mut.Unlock()
+	return enabledRepos(pa.ps, plugin)
+}
+
+// EnabledRepos returns the orgs and repos (as "org" or "org/repo") on which
+// the config enables the plugin.
+func (c Configuration) EnabledRepos(plugin string) []string {
+	return enabledRepos(c.Plugins, plugin)
+}
+
+func enabledRepos(np map[string][]string, plugin string) []string {
+	var repos []string
+	for repo, ps := range np {
+		for _, p := range ps {
+			if p == plugin {
+				repos = append(repos, repo)
+				break
+			}
+		}
+	}
+	sort.Strings(repos)
+	return repos
+}
+
 // getPlugins returns a list of plugins that are enabled on a given (org, repository).
 func (pa *PluginAgent) getPlugins(owner, repo string) []string {
 	var plugins []string
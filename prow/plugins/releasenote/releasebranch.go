@@ -0,0 +1,158 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package releasenote
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// releaseBranchContext is the status context that blocks PRs against release
+// branches until they have a milestone and a release note.
+const releaseBranchContext = "release-branch"
+
+var (
+	noteBlockRe = regexp.MustCompile("(?s)```release-note\\s*(.*?)\\s*```")
+	noneNoteRe  = regexp.MustCompile(`(?i)^(none|n/?a)?$`)
+	actionRe    = regexp.MustCompile(`(?i)action required`)
+)
+
+func handlePullRequest(pc plugins.PluginClient, pr github.PullRequestEvent) error {
+	return handlePR(pc.GitHubClient, pc.Logger, pr)
+}
+
+func handleIssue(pc plugins.PluginClient, ie github.IssueEvent) error {
+	return handleMilestone(pc.GitHubClient, pc.Logger, ie)
+}
+
+func isReleaseBranch(ref string) bool {
+	return strings.HasPrefix(ref, "release-")
+}
+
+// handlePR keeps the labels of PRs against release branches in sync with the
+// release note in their body, and updates their status. Label changes only
+// update the status, so that /release-note-none and labels set by hand stick
+// until the body changes.
+func handlePR(gc githubClient, log *logrus.Entry, pre github.PullRequestEvent) error {
+	switch pre.Action {
+	case "opened", "reopened", "edited", "synchronize", "labeled", "unlabeled":
+	default:
+		return nil
+	}
+	pr := pre.PullRequest
+	if !isReleaseBranch(pr.Base.Ref) {
+		return nil
+	}
+	labels, err := gc.GetIssueLabels(pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, pr.Number)
+	if err != nil {
+		return err
+	}
+	syncLabels := pre.Action != "labeled" && pre.Action != "unlabeled"
+	return enforce(gc, log, pr, labels, syncLabels)
+}
+
+// handleMilestone updates the status of PRs against release branches when
+// they are added to or removed from a milestone.
+func handleMilestone(gc githubClient, log *logrus.Entry, ie github.IssueEvent) error {
+	if !ie.Issue.IsPullRequest() || (ie.Action != "milestoned" && ie.Action != "demilestoned") {
+		return nil
+	}
+	pr, err := gc.GetPullRequest(ie.Repo.Owner.Login, ie.Repo.Name, ie.Issue.Number)
+	if err != nil {
+		return err
+	}
+	if !isReleaseBranch(pr.Base.Ref) {
+		return nil
+	}
+	return enforce(gc, log, *pr, ie.Issue.Labels, false)
+}
+
+// noteLabel returns the label for the release note block in the PR body, if
+// there is one.
+func noteLabel(body string) (string, bool) {
+	m := noteBlockRe.FindStringSubmatch(body)
+	if m == nil {
+		return "", false
+	}
+	note := strings.TrimSpace(m[1])
+	switch {
+	case noneNoteRe.MatchString(note):
+		return releaseNoteNone, true
+	case actionRe.MatchString(note):
+		return releaseNoteActionRequired, true
+	}
+	return releaseNote, true
+}
+
+// enforce sets the release note label from the PR body if syncLabels is true,
+// and sets the release-branch status to failure unless the PR has both a
+// milestone and a release note.
+func enforce(gc githubClient, log *logrus.Entry, pr github.PullRequest, labels []github.Label, syncLabels bool) error {
+	org := pr.Base.Repo.Owner.Login
+	repo := pr.Base.Repo.Name
+	has := map[string]bool{}
+	for _, l := range labels {
+		has[l.Name] = true
+	}
+
+	if nl, ok := noteLabel(pr.Body); ok && syncLabels {
+		var errs []error
+		if !has[nl] {
+			log.Infof("Adding %s label.", nl)
+			if err := gc.AddLabel(org, repo, pr.Number, nl); err != nil {
+				errs = append(errs, err)
+			}
+			has[nl] = true
+		}
+		for _, l := range allRNLabels {
+			if l != nl && has[l] {
+				log.Infof("Removing %s label.", l)
+				if err := gc.RemoveLabel(org, repo, pr.Number, l); err != nil {
+					errs = append(errs, err)
+				}
+				has[l] = false
+			}
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("encountered %d errors setting labels: %v", len(errs), errs)
+		}
+	}
+
+	var missing []string
+	if pr.Milestone == nil {
+		missing = append(missing, "a milestone")
+	}
+	if !has[releaseNote] && !has[releaseNoteNone] && !has[releaseNoteActionRequired] {
+		missing = append(missing, "a release note")
+	}
+	status := github.Status{
+		State:       github.StatusSuccess,
+		Context:     releaseBranchContext,
+		Description: "Has a milestone and a release note.",
+	}
+	if len(missing) > 0 {
+		status.State = github.StatusFailure
+		status.Description = fmt.Sprintf("PRs against %s need %s.", pr.Base.Ref, strings.Join(missing, " and "))
+	}
+	return gc.CreateStatus(org, repo, pr.Head.SHA, status)
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package releasenote
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+)
+
+func TestNoteLabel(t *testing.T) {
+	var testcases = []struct {
+		body  string
+		label string
+		ok    bool
+	}{
+		{body: "Fixes a bug.", ok: false},
+		{body: "```release-note\nFixed the frobber.\n```", label: releaseNote, ok: true},
+		{body: "Stuff\n```release-note\nNONE\n```\nMore stuff", label: releaseNoteNone, ok: true},
+		{body: "```release-note\n```", label: releaseNoteNone, ok: true},
+		{body: "```release-note\nN/A\n```", label: releaseNoteNone, ok: true},
+		{body: "```release-note\nAction required: rename your flags.\n```", label: releaseNoteActionRequired, ok: true},
+	}
+	for _, tc := range testcases {
+		label, ok := noteLabel(tc.body)
+		if label != tc.label || ok != tc.ok {
+			t.Errorf("For body %q, expected %q, %t, got %q, %t", tc.body, tc.label, tc.ok, label, ok)
+		}
+	}
+}
+
+func TestReleaseBranchPR(t *testing.T) {
+	var testcases = []struct {
+		name      string
+		action    string
+		base      string
+		body      string
+		milestone bool
+		labels    []string
+
+		added   []string
+		removed []string
+		state   string
+	}{
+		{
+			name:   "not a release branch",
+			action: "opened",
+			base:   "master",
+			body:   "```release-note\nFixed it.\n```",
+		},
+		{
+			name:   "unhandled action",
+			action: "closed",
+			base:   "release-1.7",
+		},
+		{
+			name:   "nothing set",
+			action: "opened",
+			base:   "release-1.7",
+			state:  github.StatusFailure,
+		},
+		{
+			name:      "note in body and milestone",
+			action:    "opened",
+			base:      "release-1.7",
+			body:      "```release-note\nFixed it.\n```",
+			milestone: true,
+			labels:    []string{releaseNoteLabelNeeded},
+			added:     []string{"org/repo#3:" + releaseNote},
+			removed:   []string{"org/repo#3:" + releaseNoteLabelNeeded},
+			state:     github.StatusSuccess,
+		},
+		{
+			name:    "edited note without milestone",
+			action:  "edited",
+			base:    "release-1.7",
+			body:    "```release-note\nNONE\n```",
+			labels:  []string{releaseNote},
+			added:   []string{"org/repo#3:" + releaseNoteNone},
+			removed: []string{"org/repo#3:" + releaseNote},
+			state:   github.StatusFailure,
+		},
+		{
+			name:      "label set by comment",
+			action:    "labeled",
+			base:      "release-1.7",
+			milestone: true,
+			labels:    []string{releaseNoteNone},
+			state:     github.StatusSuccess,
+		},
+		{
+			name:      "label set by comment disagrees with body",
+			action:    "labeled",
+			base:      "release-1.7",
+			body:      "```release-note\nFixed it.\n```",
+			milestone: true,
+			labels:    []string{releaseNoteNone},
+			state:     github.StatusSuccess,
+		},
+	}
+	for _, tc := range testcases {
+		fc := &fakegithub.FakeClient{}
+		for _, l := range tc.labels {
+			fc.LabelsAdded = append(fc.LabelsAdded, "org/repo#3:"+l)
+		}
+		existing := len(fc.LabelsAdded)
+		pre := github.PullRequestEvent{
+			Action: tc.action,
+			Number: 3,
+			PullRequest: github.PullRequest{
+				Number: 3,
+				Body:   tc.body,
+				Base: github.PullRequestBranch{
+					Ref:  tc.base,
+					Repo: github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+				},
+				Head: github.PullRequestBranch{SHA: "abc"},
+			},
+		}
+		if tc.milestone {
+			pre.PullRequest.Milestone = &github.Milestone{Title: "v1.7"}
+		}
+		if err := handlePR(fc, logrus.WithField("plugin", pluginName), pre); err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			continue
+		}
+		var added []string
+		added = append(added, fc.LabelsAdded[existing:]...)
+		if !reflect.DeepEqual(added, tc.added) {
+			t.Errorf("For case %s, expected labels %v to be added, got %v", tc.name, tc.added, added)
+		}
+		if !reflect.DeepEqual(fc.LabelsRemoved, tc.removed) {
+			t.Errorf("For case %s, expected labels %v to be removed, got %v", tc.name, tc.removed, fc.LabelsRemoved)
+		}
+		statuses := fc.CreatedStatuses["abc"]
+		if tc.state == "" {
+			if len(statuses) != 0 {
+				t.Errorf("For case %s, expected no status, got %v", tc.name, statuses)
+			}
+			continue
+		}
+		if len(statuses) != 1 {
+			t.Errorf("For case %s, expected one status, got %v", tc.name, statuses)
+		} else if statuses[0].State != tc.state || statuses[0].Context != releaseBranchContext {
+			t.Errorf("For case %s, expected %s status, got %+v", tc.name, tc.state, statuses[0])
+		}
+	}
+}
+
+func TestReleaseBranchMilestone(t *testing.T) {
+	pr := &github.PullRequest{
+		Number:    3,
+		Milestone: &github.Milestone{Title: "v1.7"},
+		Base: github.PullRequestBranch{
+			Ref:  "release-1.7",
+			Repo: github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+		},
+		Head: github.PullRequestBranch{SHA: "abc"},
+	}
+	fc := &fakegithub.FakeClient{PullRequests: map[int]*github.PullRequest{3: pr}}
+	ie := github.IssueEvent{
+		Action: "milestoned",
+		Repo:   github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+		Issue: github.Issue{
+			Number:      3,
+			Labels:      []github.Label{{Name: releaseNote}},
+			PullRequest: &struct{}{},
+		},
+	}
+	if err := handleMilestone(fc, logrus.WithField("plugin", pluginName), ie); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if statuses := fc.CreatedStatuses["abc"]; len(statuses) != 1 || statuses[0].State != github.StatusSuccess {
+		t.Errorf("Expected a success status, got %v", statuses)
+	}
+
+	ie.Issue.PullRequest = nil
+	fc.CreatedStatuses = nil
+	if err := handleMilestone(fc, logrus.WithField("plugin", pluginName), ie); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if len(fc.CreatedStatuses) != 0 {
+		t.Errorf("Expected issues to be ignored, got %v", fc.CreatedStatuses)
+	}
+}
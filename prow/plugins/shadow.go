@@ -0,0 +1,128 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/slack"
+)
+
+// ShadowCall is a mutating call that a plugin in shadow mode would have made.
+type ShadowCall struct {
+	Time   time.Time `json:"time"`
+	Plugin string    `json:"plugin"`
+	// Client is "github", "kube" or "git".
+	Client string      `json:"client"`
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Body   interface{} `json:"body,omitempty"`
+}
+
+// ShadowLog keeps the most recent calls made by plugins in shadow mode.
+type ShadowLog struct {
+	mut   sync.Mutex
+	max   int
+	calls []ShadowCall
+}
+
+// NewShadowLog returns a ShadowLog that keeps the last max calls.
+func NewShadowLog(max int) *ShadowLog {
+	return &ShadowLog{max: max}
+}
+
+// Record adds the call to the log, forgetting the oldest call if it is full.
+func (sl *ShadowLog) Record(c ShadowCall) {
+	sl.mut.Lock()
+	defer sl.mut.Unlock()
+	sl.calls = append(sl.calls, c)
+	if len(sl.calls) > sl.max {
+		sl.calls = sl.calls[len(sl.calls)-sl.max:]
+	}
+}
+
+// Calls returns the recorded calls, oldest first. If plugin is not empty,
+// only that plugin's calls are returned.
+func (sl *ShadowLog) Calls(plugin string) []ShadowCall {
+	sl.mut.Lock()
+	defer sl.mut.Unlock()
+	res := []ShadowCall{}
+	for _, c := range sl.calls {
+		if plugin == "" || c.Plugin == plugin {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// ServeHTTP serves the recorded calls as JSON. Use the plugin query parameter
+// to see only one plugin's calls.
+func (sl *ShadowLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, err := json.Marshal(sl.Calls(r.URL.Query().Get("plugin")))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+// ShadowClient returns a copy of pc for the plugin if it runs in shadow mode,
+// and pc itself otherwise. In the copy, the GitHub and kube clients log and
+// record their mutating calls rather than making them, the git client records
+// pushes instead of pushing, and the Slack client is fake.
+func (pa *PluginAgent) ShadowClient(plugin string, pc PluginClient) PluginClient {
+	if !pa.IsShadow(plugin) {
+		return pc
+	}
+	record := func(client string) func(method, path string, body interface{}) {
+		return func(method, path string, body interface{}) {
+			pc.Logger.WithFields(logrus.Fields{
+				"shadow": true,
+				"client": client,
+				"method": method,
+				"path":   path,
+			}).Info("Skipped mutating call in shadow mode.")
+			if pa.ShadowLog != nil {
+				pa.ShadowLog.Record(ShadowCall{
+					Time:   time.Now(),
+					Plugin: plugin,
+					Client: client,
+					Method: method,
+					Path:   path,
+					Body:   body,
+				})
+			}
+		}
+	}
+	if pc.GitHubClient != nil {
+		pc.GitHubClient = pc.GitHubClient.DryRunCopy(record("github"))
+	}
+	if pc.KubeClient != nil {
+		pc.KubeClient = pc.KubeClient.DryRunCopy(record("kube"))
+	}
+	if pc.GitClient != nil {
+		pc.GitClient = pc.GitClient.DryRunCopy(record("git"))
+	}
+	pc.SlackClient = slack.NewFakeClient()
+	return pc
+}
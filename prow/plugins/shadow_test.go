@@ -0,0 +1,292 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/git/localgit"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/kube"
+)
+
+func TestLoad(t *testing.T) {
+	allPlugins["shadow-test-a"] = struct{}{}
+	allPlugins["shadow-test-b"] = struct{}{}
+	defer delete(allPlugins, "shadow-test-a")
+	defer delete(allPlugins, "shadow-test-b")
+
+	var testcases = []struct {
+		name    string
+		file    string
+		plugins map[string][]string
+		shadow  map[string]bool
+		err     bool
+	}{
+		{
+			name: "legacy format",
+			file: `
+org:
+- shadow-test-a
+org/repo:
+- shadow-test-b
+`,
+			plugins: map[string][]string{
+				"org":      {"shadow-test-a"},
+				"org/repo": {"shadow-test-b"},
+			},
+			shadow: map[string]bool{},
+		},
+		{
+			name: "plugins and shadow",
+			file: `
+plugins:
+  org:
+  - shadow-test-a
+  - shadow-test-b
+shadow:
+- shadow-test-b
+`,
+			plugins: map[string][]string{
+				"org": {"shadow-test-a", "shadow-test-b"},
+			},
+			shadow: map[string]bool{"shadow-test-b": true},
+		},
+		{
+			name: "only external plugins",
+			file: `
+external_plugins:
+  org:
+  - name: ext
+    endpoint: http://ext
+`,
+			plugins: nil,
+			shadow:  map[string]bool{},
+		},
+		{
+			name: "only shadow plugins",
+			file: `
+shadow:
+- shadow-test-a
+`,
+			plugins: nil,
+			shadow:  map[string]bool{"shadow-test-a": true},
+		},
+		{
+			name: "malformed new format",
+			file: `
+plugins:
+  org: shadow-test-a
+`,
+			err: true,
+		},
+		{
+			name: "unknown shadow plugin",
+			file: `
+plugins:
+  org:
+  - shadow-test-a
+shadow:
+- not-a-plugin
+`,
+			err: true,
+		},
+		{
+			name: "unknown plugin",
+			file: `
+plugins:
+  org:
+  - not-a-plugin
+`,
+			err: true,
+		},
+		{
+			name: "unknown plugin with valid shadow and external plugins",
+			file: `
+plugins:
+  org:
+  - not-a-plugin
+shadow:
+- shadow-test-a
+external_plugins:
+  org:
+  - name: ext
+    endpoint: http://ext
+`,
+			err: true,
+		},
+	}
+	dir, err := ioutil.TempDir("", "plugins")
+	if err != nil {
+		t.Fatalf("Could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, tc := range testcases {
+		path := filepath.Join(dir, "plugins.yaml")
+		if err := ioutil.WriteFile(path, []byte(tc.file), 0644); err != nil {
+			t.Fatalf("Could not write plugin config: %v", err)
+		}
+		pa := PluginAgent{}
+		err := pa.Load(path)
+		if tc.err {
+			if err == nil {
+				t.Errorf("For case %s, expected an error.", tc.name)
+			}
+			if pa.ps != nil || pa.shadow != nil || pa.external != nil {
+				t.Errorf("For case %s, expected no config to be set on error.", tc.name)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(pa.ps, tc.plugins) {
+			t.Errorf("For case %s, expected plugins %v, got %v", tc.name, tc.plugins, pa.ps)
+		}
+		if !reflect.DeepEqual(pa.shadow, tc.shadow) {
+			t.Errorf("For case %s, expected shadow plugins %v, got %v", tc.name, tc.shadow, pa.shadow)
+		}
+	}
+}
+
+func TestLoadValidate(t *testing.T) {
+	allPlugins["shadow-test-a"] = struct{}{}
+	defer delete(allPlugins, "shadow-test-a")
+
+	dir, err := ioutil.TempDir("", "plugins")
+	if err != nil {
+		t.Fatalf("Could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "plugins.yaml")
+	if err := ioutil.WriteFile(path, []byte("plugins:\n  org/repo:\n  - shadow-test-a\n"), 0644); err != nil {
+		t.Fatalf("Could not write plugin config: %v", err)
+	}
+	pa := PluginAgent{
+		Validate: func(c Configuration) error {
+			if repos := c.EnabledRepos("shadow-test-a"); len(repos) > 0 {
+				return fmt.Errorf("shadow-test-a is enabled on %v", repos)
+			}
+			return nil
+		},
+	}
+	if err := pa.Load(path); err == nil {
+		t.Error("Expected the config to be rejected.")
+	}
+	if pa.ps != nil {
+		t.Errorf("Expected no plugins to be set, got %v", pa.ps)
+	}
+}
+
+func TestShadowClient(t *testing.T) {
+	allPlugins["shadow-test"] = struct{}{}
+	defer delete(allPlugins, "shadow-test")
+
+	pa := &PluginAgent{ShadowLog: NewShadowLog(10)}
+	if err := pa.SetShadow([]string{"shadow-test"}); err != nil {
+		t.Fatalf("Could not set shadow plugins: %v", err)
+	}
+	lg, gc, err := localgit.New()
+	if err != nil {
+		t.Fatalf("Making localgit: %v", err)
+	}
+	defer func() {
+		if err := lg.Clean(); err != nil {
+			t.Errorf("Cleaning up localgit: %v", err)
+		}
+		if err := gc.Clean(); err != nil {
+			t.Errorf("Cleaning up client: %v", err)
+		}
+	}()
+	if err := lg.MakeFakeRepo("org", "repo"); err != nil {
+		t.Fatalf("Making fake repo: %v", err)
+	}
+	gc.SetCredentials("bot", "")
+	pc := PluginClient{
+		GitHubClient: github.NewFakeClient("bot"),
+		KubeClient:   kube.NewFakeClient(),
+		GitClient:    gc,
+		Logger:       logrus.NewEntry(logrus.New()),
+	}
+
+	if npc := pa.ShadowClient("other", pc); npc.GitHubClient != pc.GitHubClient || npc.KubeClient != pc.KubeClient {
+		t.Error("Plugins that aren't in shadow mode should get the real clients.")
+	}
+	spc := pa.ShadowClient("shadow-test", pc)
+	if err := spc.GitHubClient.CreateComment("org", "repo", 5, "hello"); err != nil {
+		t.Fatalf("Didn't expect error creating comment: %v", err)
+	}
+	if _, err := spc.KubeClient.CreateProwJob(kube.ProwJob{}); err != nil {
+		t.Fatalf("Didn't expect error creating prowjob: %v", err)
+	}
+	if err := pc.GitHubClient.CreateComment("org", "repo", 5, "not recorded"); err != nil {
+		t.Fatalf("Didn't expect error creating comment: %v", err)
+	}
+	// The bot has no fork, so a real push would fail.
+	r, err := spc.GitClient.Clone("org/repo")
+	if err != nil {
+		t.Fatalf("Cloning: %v", err)
+	}
+	defer r.Clean()
+	if err := r.Push("branch"); err != nil {
+		t.Fatalf("Didn't expect error pushing: %v", err)
+	}
+
+	calls := pa.ShadowLog.Calls("shadow-test")
+	if len(calls) != 3 {
+		t.Fatalf("Expected 3 recorded calls, got %+v", calls)
+	}
+	if calls[0].Client != "github" || calls[0].Method != "POST" || calls[0].Path != "/repos/org/repo/issues/5/comments" {
+		t.Errorf("Wrong GitHub call recorded: %+v", calls[0])
+	}
+	if calls[1].Client != "kube" || calls[1].Method != "POST" {
+		t.Errorf("Wrong kube call recorded: %+v", calls[1])
+	}
+	if calls[2].Client != "git" || calls[2].Method != "push" || calls[2].Path != "bot/repo" || calls[2].Body != "branch" {
+		t.Errorf("Wrong git call recorded: %+v", calls[2])
+	}
+}
+
+func TestShadowLog(t *testing.T) {
+	sl := NewShadowLog(2)
+	sl.Record(ShadowCall{Plugin: "a", Path: "/1"})
+	sl.Record(ShadowCall{Plugin: "b", Path: "/2"})
+	sl.Record(ShadowCall{Plugin: "a", Path: "/3"})
+
+	if calls := sl.Calls(""); len(calls) != 2 || calls[0].Path != "/2" || calls[1].Path != "/3" {
+		t.Errorf("Expected only the last two calls, got %+v", calls)
+	}
+
+	rr := httptest.NewRecorder()
+	sl.ServeHTTP(rr, httptest.NewRequest("GET", "/debug/shadow?plugin=a", nil))
+	var calls []ShadowCall
+	if err := json.Unmarshal(rr.Body.Bytes(), &calls); err != nil {
+		t.Fatalf("Could not unmarshal response: %v", err)
+	}
+	if len(calls) != 1 || calls[0].Path != "/3" {
+		t.Errorf("Expected only plugin a's last call, got %+v", calls)
+	}
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package size contains a plugin which labels PRs with size/XS through
+// size/XXL according to how many lines they change. Files listed in the
+// repo's .generated_files don't count.
+package size
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+const (
+	pluginName  = "size"
+	labelPrefix = "size/"
+
+	// generatedFilesFile is read from the root of the PR's base commit.
+	generatedFilesFile = ".generated_files"
+)
+
+func init() {
+	plugins.RegisterPullRequestHandler(pluginName, handlePullRequest)
+}
+
+type githubClient interface {
+	AddLabel(owner, repo string, number int, label string) error
+	RemoveLabel(owner, repo string, number int, label string) error
+	GetIssueLabels(org, repo string, number int) ([]github.Label, error)
+	GetFile(org, repo, filepath, commit string) ([]byte, error)
+	GetPullRequestChanges(org, repo string, number int) ([]github.PullRequestChange, error)
+}
+
+func handlePullRequest(pc plugins.PluginClient, pe github.PullRequestEvent) error {
+	return handlePR(pc.GitHubClient, pc.Logger, pe)
+}
+
+func handlePR(gc githubClient, log *logrus.Entry, pe github.PullRequestEvent) error {
+	if pe.Action != "opened" && pe.Action != "reopened" && pe.Action != "synchronize" {
+		return nil
+	}
+	org := pe.PullRequest.Base.Repo.Owner.Login
+	repo := pe.PullRequest.Base.Repo.Name
+	number := pe.PullRequest.Number
+
+	gf, err := loadGeneratedFiles(gc, org, repo, pe.PullRequest.Base.SHA)
+	if err != nil {
+		log.WithError(err).Infof("No %s, counting every file.", generatedFilesFile)
+		gf = &genfiles{}
+	}
+	changes, err := gc.GetPullRequestChanges(org, repo, number)
+	if err != nil {
+		return err
+	}
+	lines := 0
+	for _, c := range changes {
+		if gf.Match(c.Filename) {
+			continue
+		}
+		lines += c.Additions + c.Deletions
+	}
+	want := labelPrefix + bucket(lines)
+
+	labels, err := gc.GetIssueLabels(org, repo, number)
+	if err != nil {
+		return err
+	}
+	has := false
+	for _, l := range labels {
+		if l.Name == want {
+			has = true
+		} else if strings.HasPrefix(l.Name, labelPrefix) {
+			log.Infof("Removing %s label.", l.Name)
+			if err := gc.RemoveLabel(org, repo, number, l.Name); err != nil {
+				return err
+			}
+		}
+	}
+	if has {
+		return nil
+	}
+	log.Infof("Adding %s label for %d changed lines.", want, lines)
+	return gc.AddLabel(org, repo, number, want)
+}
+
+// sizes are upper bounds on the changed lines for each size. Anything bigger
+// is XXL.
+var sizes = []struct {
+	upper int
+	name  string
+}{
+	{10, "XS"},
+	{30, "S"},
+	{100, "M"},
+	{500, "L"},
+	{1000, "XL"},
+}
+
+func bucket(lines int) string {
+	for _, s := range sizes {
+		if lines < s.upper {
+			return s.name
+		}
+	}
+	return "XXL"
+}
+
+// genfiles says which files are generated. See loadGeneratedFiles for the
+// format.
+type genfiles struct {
+	paths        map[string]bool
+	pathPrefixes []string
+	filePrefixes []string
+	fileNames    map[string]bool
+	patterns     []string
+}
+
+// Match returns whether or not the file is generated.
+func (g *genfiles) Match(file string) bool {
+	if g.paths[file] {
+		return true
+	}
+	for _, p := range g.pathPrefixes {
+		if strings.HasPrefix(file, p) {
+			return true
+		}
+	}
+	name := path.Base(file)
+	if g.fileNames[name] {
+		return true
+	}
+	for _, p := range g.filePrefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	for _, p := range g.patterns {
+		if ok, _ := path.Match(p, file); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// loadGeneratedFiles reads the .generated_files at the commit. Each line is
+// a key and a value:
+//
+//	path <path>              a generated file
+//	path-prefix <prefix>     generated files are under it (also "prefix")
+//	file-prefix <prefix>     generated file names start with it
+//	file-name <name>         generated files are called this
+//	pattern <glob>           generated file paths match the glob
+//	paths-from-repo <file>   the file lists generated paths, one per line
+//
+// Empty lines and lines starting with # are ignored.
+func loadGeneratedFiles(gc githubClient, org, repo, commit string) (*genfiles, error) {
+	b, err := gc.GetFile(org, repo, generatedFilesFile, commit)
+	if err != nil {
+		return nil, err
+	}
+	g := &genfiles{
+		paths:     map[string]bool{},
+		fileNames: map[string]bool{},
+	}
+	for i, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid line %d in %s: %q", i+1, generatedFilesFile, line)
+		}
+		switch key, val := fields[0], fields[1]; key {
+		case "path":
+			g.paths[val] = true
+		case "prefix", "path-prefix":
+			g.pathPrefixes = append(g.pathPrefixes, val)
+		case "file-prefix":
+			g.filePrefixes = append(g.filePrefixes, val)
+		case "file-name":
+			g.fileNames[val] = true
+		case "pattern":
+			if _, err := path.Match(val, ""); err != nil {
+				return nil, fmt.Errorf("invalid pattern on line %d in %s: %v", i+1, generatedFilesFile, err)
+			}
+			g.patterns = append(g.patterns, val)
+		case "paths-from-repo":
+			pb, err := gc.GetFile(org, repo, val, commit)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range strings.Split(string(pb), "\n") {
+				if p = strings.TrimSpace(p); p != "" {
+					g.paths[p] = true
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unknown key %q on line %d in %s", key, i+1, generatedFilesFile)
+		}
+	}
+	return g, nil
+}
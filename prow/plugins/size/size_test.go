@@ -0,0 +1,178 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package size
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+)
+
+func TestBucket(t *testing.T) {
+	var testcases = []struct {
+		lines int
+		size  string
+	}{
+		{0, "XS"},
+		{9, "XS"},
+		{10, "S"},
+		{99, "M"},
+		{100, "L"},
+		{999, "XL"},
+		{1000, "XXL"},
+	}
+	for _, tc := range testcases {
+		if s := bucket(tc.lines); s != tc.size {
+			t.Errorf("For %d lines, expected %s, got %s", tc.lines, tc.size, s)
+		}
+	}
+}
+
+func TestGeneratedFiles(t *testing.T) {
+	fc := &fakegithub.FakeClient{
+		RemoteFiles: map[string]map[string]string{
+			".generated_files": {"base": `# Generated code.
+path api/openapi-spec/swagger.json
+prefix vendor/
+file-prefix zz_generated.
+file-name types.generated.go
+pattern docs/*/*.md
+paths-from-repo hack/generated-docs.txt
+`},
+			"hack/generated-docs.txt": {"base": "docs/man/kubectl.1\n\n"},
+		},
+	}
+	g, err := loadGeneratedFiles(fc, "org", "repo", "base")
+	if err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	var testcases = []struct {
+		file      string
+		generated bool
+	}{
+		{"api/openapi-spec/swagger.json", true},
+		{"vendor/github.com/foo/foo.go", true},
+		{"pkg/api/zz_generated.deepcopy.go", true},
+		{"pkg/api/types.generated.go", true},
+		{"docs/user-guide/kubectl.md", true},
+		{"docs/man/kubectl.1", true},
+		{"docs/README.md", false},
+		{"pkg/api/types.go", false},
+	}
+	for _, tc := range testcases {
+		if g.Match(tc.file) != tc.generated {
+			t.Errorf("For %s, expected generated: %t", tc.file, tc.generated)
+		}
+	}
+
+	fc.RemoteFiles[".generated_files"]["base"] = "unknown-key value\n"
+	if _, err := loadGeneratedFiles(fc, "org", "repo", "base"); err == nil {
+		t.Error("Expected an error for an unknown key.")
+	}
+}
+
+func TestHandlePR(t *testing.T) {
+	var testcases = []struct {
+		name    string
+		action  string
+		changes []github.PullRequestChange
+		labels  []string
+
+		added   []string
+		removed []string
+	}{
+		{
+			name:    "ignored action",
+			action:  "closed",
+			changes: []github.PullRequestChange{{Filename: "foo.go", Additions: 5}},
+		},
+		{
+			name:    "new small PR",
+			action:  "opened",
+			changes: []github.PullRequestChange{{Filename: "foo.go", Additions: 5, Deletions: 10}},
+			added:   []string{"size/S"},
+		},
+		{
+			name:   "generated files don't count",
+			action: "synchronize",
+			changes: []github.PullRequestChange{
+				{Filename: "foo.go", Additions: 5},
+				{Filename: "vendor/bar.go", Additions: 5000},
+			},
+			labels:  []string{"size/XXL", "lgtm"},
+			added:   []string{"size/XS"},
+			removed: []string{"size/XXL"},
+		},
+		{
+			name:    "size unchanged",
+			action:  "synchronize",
+			changes: []github.PullRequestChange{{Filename: "foo.go", Additions: 50}},
+			labels:  []string{"size/M"},
+		},
+		{
+			name:    "extra size labels are removed",
+			action:  "reopened",
+			changes: []github.PullRequestChange{{Filename: "foo.go", Additions: 50}},
+			labels:  []string{"size/M", "size/S"},
+			removed: []string{"size/S"},
+		},
+	}
+	for _, tc := range testcases {
+		fc := &fakegithub.FakeClient{
+			PullRequestChanges: map[int][]github.PullRequestChange{3: tc.changes},
+			RemoteFiles: map[string]map[string]string{
+				".generated_files": {"base": "prefix vendor/\n"},
+			},
+		}
+		for _, l := range tc.labels {
+			fc.LabelsAdded = append(fc.LabelsAdded, "org/repo#3:"+l)
+		}
+		existing := len(fc.LabelsAdded)
+		pe := github.PullRequestEvent{
+			Action: tc.action,
+			Number: 3,
+			PullRequest: github.PullRequest{
+				Number: 3,
+				Base: github.PullRequestBranch{
+					SHA:  "base",
+					Repo: github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+				},
+			},
+		}
+		if err := handlePR(fc, logrus.WithField("plugin", pluginName), pe); err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			continue
+		}
+		var added, removed []string
+		for _, l := range fc.LabelsAdded[existing:] {
+			added = append(added, l[len("org/repo#3:"):])
+		}
+		for _, l := range fc.LabelsRemoved {
+			removed = append(removed, l[len("org/repo#3:"):])
+		}
+		if !reflect.DeepEqual(added, tc.added) {
+			t.Errorf("For case %s, expected labels %v to be added, got %v", tc.name, tc.added, added)
+		}
+		if !reflect.DeepEqual(removed, tc.removed) {
+			t.Errorf("For case %s, expected labels %v to be removed, got %v", tc.name, tc.removed, removed)
+		}
+	}
+}
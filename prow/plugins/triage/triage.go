@@ -0,0 +1,192 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package triage contains a plugin which makes sure that new issues get
+// categorized. Issues without a label for one of the configured prefixes get
+// a needs-<prefix> placeholder, such as needs-sig, which goes away once the
+// label plugin adds a real label with that prefix. Issues that are added to a
+// milestone while they still need labels get a reminder, or are taken back out
+// of the milestone if the repo enforces it.
+package triage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+const pluginName = "triage"
+
+// needsPrefix prefixes the placeholder labels, such as needs-sig.
+const needsPrefix = "needs-"
+
+// defaultConfig is used on repos without triage config.
+var defaultConfig = &config.Triage{
+	Prefixes:         []string{"sig", "kind", "priority"},
+	EnforceMilestone: true,
+}
+
+func init() {
+	plugins.RegisterIssueHandler(pluginName, handleIssue)
+}
+
+type githubClient interface {
+	AddLabel(owner, repo string, number int, label string) error
+	RemoveLabel(owner, repo string, number int, label string) error
+	CreateComment(owner, repo string, number int, comment string) error
+	ClearMilestone(org, repo string, number int) error
+}
+
+func handleIssue(pc plugins.PluginClient, ie github.IssueEvent) error {
+	cfg := triageConfig(pc.Config, ie.Repo.Owner.Login, ie.Repo.Name)
+	return handle(pc.GitHubClient, pc.Logger, cfg, ie)
+}
+
+func triageConfig(c *config.Config, org, repo string) *config.Triage {
+	fullName := fmt.Sprintf("%s/%s", org, repo)
+	for i := range c.Triage {
+		for _, r := range c.Triage[i].Repos {
+			if r == org || r == fullName {
+				return &c.Triage[i]
+			}
+		}
+	}
+	return defaultConfig
+}
+
+func handle(gc githubClient, log *logrus.Entry, cfg *config.Triage, ie github.IssueEvent) error {
+	if ie.Issue.IsPullRequest() || ie.Issue.State != "open" {
+		return nil
+	}
+	switch ie.Action {
+	case "opened", "reopened":
+		if err := syncPlaceholders(gc, log, cfg, ie); err != nil {
+			return err
+		}
+		if ie.Action == "opened" && cfg.Checklist {
+			if missing := missingPrefixes(cfg, ie.Issue.Labels); len(missing) > 0 {
+				log.Info("Commenting triage checklist.")
+				return gc.CreateComment(ie.Repo.Owner.Login, ie.Repo.Name, ie.Issue.Number, checklist(ie.Issue.User.Login, missing))
+			}
+		}
+	case "labeled", "unlabeled":
+		// Our own placeholders don't change what's missing.
+		if strings.HasPrefix(ie.Label.Name, needsPrefix) {
+			return nil
+		}
+		return syncPlaceholders(gc, log, cfg, ie)
+	case "milestoned":
+		missing := missingPrefixes(cfg, ie.Issue.Labels)
+		if len(missing) == 0 {
+			return nil
+		}
+		if cfg.EnforceMilestone {
+			log.Info("Removing issue that needs labels from its milestone.")
+			if err := gc.ClearMilestone(ie.Repo.Owner.Login, ie.Repo.Name, ie.Issue.Number); err != nil {
+				return err
+			}
+		} else {
+			log.Info("Commenting that milestoned issue needs labels.")
+		}
+		return gc.CreateComment(ie.Repo.Owner.Login, ie.Repo.Name, ie.Issue.Number, milestoneReminder(ie.Issue.Milestone, missing, cfg.EnforceMilestone))
+	}
+	return nil
+}
+
+// missingPrefixes returns the configured prefixes that none of the labels
+// have.
+func missingPrefixes(cfg *config.Triage, labels []github.Label) []string {
+	var missing []string
+	for _, p := range cfg.Prefixes {
+		found := false
+		for _, l := range labels {
+			if strings.HasPrefix(l.Name, p+"/") {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
+
+// syncPlaceholders adds the placeholder labels for missing prefixes and
+// removes the rest.
+func syncPlaceholders(gc githubClient, log *logrus.Entry, cfg *config.Triage, ie github.IssueEvent) error {
+	org := ie.Repo.Owner.Login
+	repo := ie.Repo.Name
+	number := ie.Issue.Number
+	missing := map[string]bool{}
+	for _, p := range missingPrefixes(cfg, ie.Issue.Labels) {
+		missing[p] = true
+	}
+	var errs []error
+	for _, p := range cfg.Prefixes {
+		placeholder := needsPrefix + p
+		has := ie.Issue.HasLabel(placeholder)
+		if missing[p] && !has {
+			log.Infof("Adding %s label.", placeholder)
+			if err := gc.AddLabel(org, repo, number, placeholder); err != nil {
+				errs = append(errs, err)
+			}
+		} else if !missing[p] && has {
+			log.Infof("Removing %s label.", placeholder)
+			if err := gc.RemoveLabel(org, repo, number, placeholder); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("errors updating triage labels: %v", errs)
+	}
+	return nil
+}
+
+func checklist(author string, missing []string) string {
+	var items []string
+	for _, p := range missing {
+		items = append(items, fmt.Sprintf("- [ ] `/%s <name>` to add a %s/ label", p, p))
+	}
+	return fmt.Sprintf(`@%s: Thanks for the issue! To help route it to the right people, please add these labels with comments like the following:
+
+%s
+
+%s`, author, strings.Join(items, "\n"), plugins.AboutThisBot)
+}
+
+func milestoneReminder(m *github.Milestone, missing []string, removed bool) string {
+	var cmds []string
+	for _, p := range missing {
+		cmds = append(cmds, fmt.Sprintf("`/%s`", p))
+	}
+	milestone := "a milestone"
+	if m != nil {
+		milestone = fmt.Sprintf("the %s milestone", m.Title)
+	}
+	if removed {
+		return fmt.Sprintf("I removed this issue from %s because issues need %s labels before they are milestoned. Please add them with %s, then add the milestone again.\n\n%s",
+			milestone, strings.Join(missing, ", "), strings.Join(cmds, ", "), plugins.AboutThisBot)
+	}
+	return fmt.Sprintf("This issue was added to %s, but issues need %s labels before they are milestoned. Please add them with %s.\n\n%s",
+		milestone, strings.Join(missing, ", "), strings.Join(cmds, ", "), plugins.AboutThisBot)
+}
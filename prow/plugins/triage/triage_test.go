@@ -0,0 +1,208 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package triage
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+)
+
+func TestHandle(t *testing.T) {
+	var testcases = []struct {
+		name        string
+		action      string
+		pr          bool
+		labels      []string
+		eventLabel  string
+		milestone   *github.Milestone
+		checklist   bool
+		enforce     bool
+		added       []string
+		removed     []string
+		comment     bool
+		commentHave []string
+		cleared     bool
+	}{
+		{
+			name:   "new issue gets all placeholders",
+			action: "opened",
+			added:  []string{"needs-kind", "needs-priority", "needs-sig"},
+		},
+		{
+			name:   "new pull requests are ignored",
+			action: "opened",
+			pr:     true,
+		},
+		{
+			name:   "new issue with a sig label",
+			action: "opened",
+			labels: []string{"sig/node"},
+			added:  []string{"needs-kind", "needs-priority"},
+		},
+		{
+			name:        "checklist lists the missing labels",
+			action:      "opened",
+			labels:      []string{"kind/bug"},
+			checklist:   true,
+			added:       []string{"needs-priority", "needs-sig"},
+			comment:     true,
+			commentHave: []string{"/sig <name>", "/priority <name>"},
+		},
+		{
+			name:      "no checklist when nothing is missing",
+			action:    "opened",
+			labels:    []string{"kind/bug", "sig/node", "priority/backlog"},
+			checklist: true,
+		},
+		{
+			name:       "real label replaces placeholder",
+			action:     "labeled",
+			labels:     []string{"needs-kind", "needs-sig", "sig/node"},
+			eventLabel: "sig/node",
+			added:      []string{"needs-priority"},
+			removed:    []string{"needs-sig"},
+		},
+		{
+			name:       "removing the real label brings the placeholder back",
+			action:     "unlabeled",
+			labels:     []string{"needs-kind", "needs-priority"},
+			eventLabel: "sig/node",
+			added:      []string{"needs-sig"},
+		},
+		{
+			name:       "placeholder changes are ignored",
+			action:     "labeled",
+			labels:     []string{"needs-kind"},
+			eventLabel: "needs-kind",
+		},
+		{
+			name:        "milestoned without labels",
+			action:      "milestoned",
+			labels:      []string{"sig/node", "needs-kind", "needs-priority"},
+			milestone:   &github.Milestone{Title: "v1.8"},
+			comment:     true,
+			commentHave: []string{"v1.8", "kind, priority", "`/kind`, `/priority`"},
+		},
+		{
+			name:      "milestoned with labels",
+			action:    "milestoned",
+			labels:    []string{"sig/node", "kind/bug", "priority/backlog"},
+			milestone: &github.Milestone{Title: "v1.8"},
+		},
+		{
+			name:        "milestoned without labels when enforced",
+			action:      "milestoned",
+			labels:      []string{"sig/node", "needs-kind", "needs-priority"},
+			milestone:   &github.Milestone{Title: "v1.8"},
+			enforce:     true,
+			comment:     true,
+			commentHave: []string{"I removed this issue from the v1.8 milestone", "`/kind`, `/priority`"},
+			cleared:     true,
+		},
+		{
+			name:      "milestoned with labels when enforced",
+			action:    "milestoned",
+			labels:    []string{"sig/node", "kind/bug", "priority/backlog"},
+			milestone: &github.Milestone{Title: "v1.8"},
+			enforce:   true,
+		},
+	}
+	for _, tc := range testcases {
+		fc := &fakegithub.FakeClient{IssueComments: map[int][]github.IssueComment{}}
+		ie := github.IssueEvent{
+			Action: tc.action,
+			Repo:   github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+			Issue: github.Issue{
+				Number:    5,
+				State:     "open",
+				User:      github.User{Login: "author"},
+				Milestone: tc.milestone,
+			},
+			Label: github.Label{Name: tc.eventLabel},
+		}
+		if tc.pr {
+			ie.Issue.PullRequest = &struct{}{}
+		}
+		for _, l := range tc.labels {
+			ie.Issue.Labels = append(ie.Issue.Labels, github.Label{Name: l})
+		}
+		cfg := &config.Triage{
+			Prefixes:         defaultConfig.Prefixes,
+			Checklist:        tc.checklist,
+			EnforceMilestone: tc.enforce,
+		}
+		if err := handle(fc, logrus.WithField("plugin", pluginName), cfg, ie); err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			continue
+		}
+		if added := trimLabels(fc.LabelsAdded); !reflect.DeepEqual(added, tc.added) {
+			t.Errorf("For case %s, expected labels %v to be added, got %v", tc.name, tc.added, added)
+		}
+		if removed := trimLabels(fc.LabelsRemoved); !reflect.DeepEqual(removed, tc.removed) {
+			t.Errorf("For case %s, expected labels %v to be removed, got %v", tc.name, tc.removed, removed)
+		}
+		if cleared := len(fc.MilestonesCleared) == 1; cleared != tc.cleared {
+			t.Errorf("For case %s, expected milestone cleared: %t, got %v", tc.name, tc.cleared, fc.MilestonesCleared)
+		}
+		comments := fc.IssueComments[5]
+		if tc.comment != (len(comments) == 1) {
+			t.Errorf("For case %s, expected comment: %t, got comments %v", tc.name, tc.comment, comments)
+			continue
+		}
+		for _, want := range tc.commentHave {
+			if !strings.Contains(comments[0].Body, want) {
+				t.Errorf("For case %s, expected comment to contain %q, got %s", tc.name, want, comments[0].Body)
+			}
+		}
+	}
+}
+
+func TestTriageConfig(t *testing.T) {
+	c := &config.Config{
+		Triage: []config.Triage{
+			{Repos: []string{"org"}, Prefixes: []string{"sig"}},
+			{Repos: []string{"other/repo"}, Prefixes: []string{"kind"}},
+		},
+	}
+	if cfg := triageConfig(c, "org", "repo"); !reflect.DeepEqual(cfg.Prefixes, []string{"sig"}) {
+		t.Errorf("Expected org config, got %+v", cfg)
+	}
+	if cfg := triageConfig(c, "other", "repo"); !reflect.DeepEqual(cfg.Prefixes, []string{"kind"}) {
+		t.Errorf("Expected repo config, got %+v", cfg)
+	}
+	if cfg := triageConfig(c, "other", "else"); cfg != defaultConfig {
+		t.Errorf("Expected default config, got %+v", cfg)
+	}
+}
+
+// trimLabels turns "org/repo#5:label" into "label" and sorts the result.
+func trimLabels(labels []string) []string {
+	var res []string
+	for _, l := range labels {
+		res = append(res, l[strings.Index(l, ":")+1:])
+	}
+	sort.Strings(res)
+	return res
+}
@@ -49,13 +49,12 @@ func handleIC(c client, ic github.IssueCommentEvent) error {
 	if commentAuthor == c.GitHubClient.BotName() {
 		return nil
 	}
-	var trustedOrg string
-	if tr := triggerConfig(c.Config, org, repo); tr != nil && tr.TrustedOrg == "" {
-		c.Logger.Info("Ignoring PR Event, no TrustedOrg set in config.")
+	tr := triggerConfig(c.Config, org, repo)
+	if tr != nil && !tr.TrustsAnyone() {
+		c.Logger.Info("Ignoring PR Event, no trusted users set in config.")
 		return nil
-	} else if tr != nil {
-		trustedOrg = tr.TrustedOrg
 	}
+	trust := newTrustChecker(c, tr, org, repo)
 
 	if okToTest.MatchString(ic.Comment.Body) && ic.Issue.HasLabel(needsOkToTest) {
 		if err := c.GitHubClient.RemoveLabel(ic.Repo.Owner.Login, ic.Repo.Name, ic.Issue.Number, needsOkToTest); err != nil {
@@ -64,8 +63,9 @@ func handleIC(c client, ic github.IssueCommentEvent) error {
 	}
 	// Which jobs does the comment want us to run?
 	shouldRetestFailed := retest.MatchString(ic.Comment.Body)
-	requestedJobs := c.Config.MatchingPresubmits(ic.Repo.FullName, ic.Comment.Body, okToTest)
-	if !shouldRetestFailed && len(requestedJobs) == 0 {
+	shouldSkip := skipRe.MatchString(ic.Comment.Body)
+	requestedJobs, unknownJobs, listJobs := requestedPresubmits(c.Config, ic.Repo.FullName, ic.Comment.Body)
+	if !shouldRetestFailed && !shouldSkip && !listJobs && len(requestedJobs) == 0 && len(unknownJobs) == 0 {
 		return nil
 	}
 
@@ -74,6 +74,17 @@ func handleIC(c client, ic github.IssueCommentEvent) error {
 		return err
 	}
 
+	// Anyone may ask which jobs there are.
+	if listJobs {
+		resp := listJobsMessage(c.Config.RepoPresubmits(ic.Repo.FullName), pr.Base.Ref)
+		if err := c.GitHubClient.CreateComment(org, repo, number, plugins.FormatICResponse(ic.Comment, resp)); err != nil {
+			return err
+		}
+		if !shouldRetestFailed && !shouldSkip && len(requestedJobs) == 0 && len(unknownJobs) == 0 {
+			return nil
+		}
+	}
+
 	if shouldRetestFailed {
 		combinedStatus, err := c.GitHubClient.GetCombinedStatus(org, repo, pr.Head.SHA)
 		if err != nil {
@@ -93,21 +104,46 @@ func handleIC(c client, ic github.IssueCommentEvent) error {
 	}
 
 	// Skip untrusted users.
-	orgMember, err := c.GitHubClient.IsMember(trustedOrg, commentAuthor)
+	trustedCommenter, err := trust.trustedUser(commentAuthor)
 	if err != nil {
 		return err
-	} else if !orgMember {
-		trusted, err := trustedPullRequest(c.GitHubClient, *pr, trustedOrg)
+	} else if !trustedCommenter {
+		trusted, err := trustedPullRequest(c.GitHubClient, *pr, trust)
 		if err != nil {
 			return err
 		}
 		if !trusted {
-			resp := fmt.Sprintf("you can't request testing unless you are a [%s](https://github.com/orgs/%s/people) member", trustedOrg, trustedOrg)
+			resp := fmt.Sprintf("you can't request testing unless you are a %s", trust.description())
 			c.Logger.Infof("Commenting \"%s\".", resp)
 			return c.GitHubClient.CreateComment(org, repo, number, plugins.FormatICResponse(ic.Comment, resp))
 		}
 	}
 
+	if len(unknownJobs) > 0 {
+		resp := unknownJobsMessage(unknownJobs, c.Config.RepoPresubmits(ic.Repo.FullName))
+		c.Logger.Infof("Commenting \"%s\".", resp)
+		if err := c.GitHubClient.CreateComment(org, repo, number, plugins.FormatICResponse(ic.Comment, resp)); err != nil {
+			return err
+		}
+	}
+
+	if shouldSkip {
+		// Trusted PRs let anyone test them, but skipping sets statuses, so
+		// that takes a trusted commenter.
+		if !trustedCommenter {
+			resp := fmt.Sprintf("you can't skip jobs unless you are a %s", trust.description())
+			c.Logger.Infof("Commenting \"%s\".", resp)
+			if err := c.GitHubClient.CreateComment(org, repo, number, plugins.FormatICResponse(ic.Comment, resp)); err != nil {
+				return err
+			}
+		} else if err := skipOptional(c, *pr); err != nil {
+			return err
+		}
+	}
+	if len(requestedJobs) == 0 {
+		return nil
+	}
+
 	ref, err := c.GitHubClient.GetRef(org, repo, "heads/"+pr.Base.Ref)
 	if err != nil {
 		return err
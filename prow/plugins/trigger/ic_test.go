@@ -17,6 +17,7 @@ limitations under the License.
 package trigger
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/Sirupsen/logrus"
@@ -29,6 +30,7 @@ import (
 
 type fkc struct {
 	started []string
+	jobs    []kube.ProwJob
 }
 
 func (c *fkc) CreateProwJob(pj kube.ProwJob) (kube.ProwJob, error) {
@@ -36,6 +38,10 @@ func (c *fkc) CreateProwJob(pj kube.ProwJob) (kube.ProwJob, error) {
 	return pj, nil
 }
 
+func (c *fkc) ListProwJobs(map[string]string) ([]kube.ProwJob, error) {
+	return c.jobs, nil
+}
+
 func TestHandleIssueComment(t *testing.T) {
 	var testcases = []struct {
 		Author        string
@@ -46,6 +52,7 @@ func TestHandleIssueComment(t *testing.T) {
 		ShouldBuild   bool
 		HasOkToTest   bool
 		StartsExactly string
+		Comment       string
 	}{
 		// Not a PR.
 		{
@@ -147,6 +154,21 @@ func TestHandleIssueComment(t *testing.T) {
 			ShouldBuild:   true,
 			StartsExactly: "pull-jib",
 		},
+		// Unknown job names are only explained to trusted users.
+		{
+			Author:  "t",
+			Body:    "/test jub",
+			State:   "open",
+			IsPR:    true,
+			Comment: "there is no job named `jub`",
+		},
+		{
+			Author:  "u",
+			Body:    "/test jub",
+			State:   "open",
+			IsPR:    true,
+			Comment: "you can't request testing",
+		},
 	}
 	for _, tc := range testcases {
 		if tc.Branch == "" {
@@ -240,5 +262,10 @@ func TestHandleIssueComment(t *testing.T) {
 		if tc.StartsExactly != "" && (len(kc.started) != 1 || kc.started[0] != tc.StartsExactly) {
 			t.Errorf("Didn't build expected context %v, instead built %v", tc.StartsExactly, kc.started)
 		}
+		if tc.Comment != "" {
+			if comments := g.IssueComments[0]; len(comments) != 1 || !strings.Contains(comments[0].Body, tc.Comment) {
+				t.Errorf("Expected one comment containing %q, got %+v", tc.Comment, comments)
+			}
+		}
 	}
 }
@@ -0,0 +1,224 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+)
+
+var (
+	testRe = regexp.MustCompile(`(?m)^/test[ \t]+(.+)$`)
+	skipRe = regexp.MustCompile(`(?m)^/skip\s*$`)
+)
+
+// maxSuggestions is how many job names we suggest for a name we don't know.
+const maxSuggestions = 3
+
+// testArguments returns the arguments of every /test command in the body.
+// Arguments are separated by spaces or commas.
+func testArguments(body string) []string {
+	var args []string
+	for _, m := range testRe.FindAllStringSubmatch(body, -1) {
+		args = append(args, strings.FieldsFunc(m[1], func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\r'
+		})...)
+	}
+	return args
+}
+
+// requestedPresubmits returns the jobs requested by the comment, either
+// through /test <name> or through their trigger, and any names that it asked
+// for that we don't know. It also returns whether or not the comment asks for
+// the list of jobs with /test ?.
+func requestedPresubmits(c *config.Config, fullRepoName, body string) (jobs []config.Presubmit, unknown []string, list bool) {
+	var names []string
+	for _, arg := range testArguments(body) {
+		if arg == "?" {
+			list = true
+		} else {
+			names = append(names, arg)
+		}
+	}
+	named, unknown := c.NamedPresubmits(fullRepoName, names)
+	seen := map[string]bool{}
+	for _, job := range append(c.MatchingPresubmits(fullRepoName, body, okToTest), named...) {
+		if !seen[job.Name] {
+			seen[job.Name] = true
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, unknown, list
+}
+
+// unknownJobsMessage explains that we don't know the names, and suggests
+// similar job names.
+func unknownJobsMessage(unknown []string, jobs []config.Presubmit) string {
+	var names []string
+	for _, job := range jobs {
+		names = append(names, job.Name)
+	}
+	var lines []string
+	for _, name := range unknown {
+		line := fmt.Sprintf("there is no job named `%s` for this repo.", name)
+		if s := suggestions(name, names); len(s) > 0 {
+			line += fmt.Sprintf(" Did you mean `%s`?", strings.Join(s, "`, `"))
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, "Say `/test ?` to list the jobs that you can run.")
+	return strings.Join(lines, "\n")
+}
+
+// suggestions returns up to maxSuggestions names that look like name, closest
+// first. Names that contain name qualify, as do the names that are closest to
+// it if they are within a few edits.
+func suggestions(name string, names []string) []string {
+	type candidate struct {
+		name     string
+		distance int
+		contains bool
+	}
+	var candidates []candidate
+	best := -1
+	for _, n := range names {
+		lower := strings.ToLower(n)
+		d := levenshtein(strings.ToLower(name), lower)
+		candidates = append(candidates, candidate{name: n, distance: d, contains: strings.Contains(lower, strings.ToLower(name))})
+		if best == -1 || d < best {
+			best = d
+		}
+	}
+	maxDistance := min(len(name)/5+1, best+1)
+	var res []candidate
+	for _, c := range candidates {
+		if c.contains || c.distance <= maxDistance {
+			res = append(res, c)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].distance < res[j].distance
+	})
+	var suggested []string
+	for i := 0; i < len(res) && i < maxSuggestions; i++ {
+		suggested = append(suggested, res[i].name)
+	}
+	return suggested
+}
+
+// levenshtein returns the edit distance between the strings.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min(first int, rest ...int) int {
+	m := first
+	for _, n := range rest {
+		if n < m {
+			m = n
+		}
+	}
+	return m
+}
+
+// listJobsMessage lists the jobs that can run against the branch.
+func listJobsMessage(jobs []config.Presubmit, branch string) string {
+	var lines []string
+	for _, job := range jobs {
+		if !job.RunsAgainstBranch(branch) {
+			continue
+		}
+		kind := "optional"
+		if job.AlwaysRun {
+			kind = "required"
+		} else if job.RunIfChanged != "" {
+			kind = "runs if files matching `" + job.RunIfChanged + "` change"
+		}
+		lines = append(lines, fmt.Sprintf("* `/test %s` (%s)", job.Name, kind))
+	}
+	if len(lines) == 0 {
+		return fmt.Sprintf("there are no jobs for the %s branch.", branch)
+	}
+	return fmt.Sprintf("these jobs can run on this PR:\n\n%s\n\nSay `/test all` to run the required jobs, or `/skip` to mark the optional jobs that haven't run as skipped.", strings.Join(lines, "\n"))
+}
+
+// skipOptional marks the contexts of optional jobs that haven't reported yet
+// as skipped, so that they don't block merging. Jobs that will run because
+// the PR changes their files are not optional.
+func skipOptional(c client, pr github.PullRequest) error {
+	org := pr.Base.Repo.Owner.Login
+	repo := pr.Base.Repo.Name
+	combinedStatus, err := c.GitHubClient.GetCombinedStatus(org, repo, pr.Head.SHA)
+	if err != nil {
+		return err
+	}
+	reported := map[string]bool{}
+	for _, status := range combinedStatus.Statuses {
+		reported[status.Context] = true
+	}
+	var changes []string // lazily initialized
+	for _, job := range c.Config.Presubmits[pr.Base.Repo.FullName] {
+		if job.AlwaysRun || job.SkipReport || reported[job.Context] {
+			continue
+		}
+		if job.RunIfChanged != "" && job.RunsAgainstBranch(pr.Base.Ref) {
+			if changes == nil {
+				changesFull, err := c.GitHubClient.GetPullRequestChanges(org, repo, pr.Number)
+				if err != nil {
+					return err
+				}
+				changes = []string{}
+				for _, change := range changesFull {
+					changes = append(changes, change.Filename)
+				}
+			}
+			if job.RunsAgainstChanges(changes) {
+				continue
+			}
+		}
+		c.Logger.Infof("Skipping %s.", job.Context)
+		if err := c.GitHubClient.CreateStatus(org, repo, pr.Head.SHA, github.Status{
+			State:       github.StatusSuccess,
+			Context:     job.Context,
+			Description: "Skipped",
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
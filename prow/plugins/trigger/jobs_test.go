@@ -0,0 +1,201 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+)
+
+func TestTestArguments(t *testing.T) {
+	var testcases = []struct {
+		body string
+		args []string
+	}{
+		{body: "/test"},
+		{body: "/test/e2e is broken"},
+		{body: "/test\npull-a"},
+		{body: "/test all", args: []string{"all"}},
+		{body: "/test pull-a, pull-b\r", args: []string{"pull-a", "pull-b"}},
+		{body: "lgtm\n/test ?\n/test  pull-c", args: []string{"?", "pull-c"}},
+	}
+	for _, tc := range testcases {
+		if args := testArguments(tc.body); !reflect.DeepEqual(args, tc.args) {
+			t.Errorf("For body %q, expected %v, got %v", tc.body, tc.args, args)
+		}
+	}
+}
+
+func TestSuggestions(t *testing.T) {
+	names := []string{"pull-kubernetes-e2e-gce", "pull-kubernetes-e2e-gke", "pull-kubernetes-unit", "pull-kubernetes-verify"}
+	var testcases = []struct {
+		name     string
+		expected []string
+	}{
+		{name: "pull-kubernetes-e2e-gcp", expected: []string{"pull-kubernetes-e2e-gce", "pull-kubernetes-e2e-gke"}},
+		{name: "pull-kubernetes-unti", expected: []string{"pull-kubernetes-unit"}},
+		{name: "verify", expected: []string{"pull-kubernetes-verify"}},
+		{name: "Verify", expected: []string{"pull-kubernetes-verify"}},
+		{name: "something-else"},
+	}
+	for _, tc := range testcases {
+		if s := suggestions(tc.name, names); !reflect.DeepEqual(s, tc.expected) {
+			t.Errorf("For %s, expected suggestions %v, got %v", tc.name, tc.expected, s)
+		}
+	}
+}
+
+func TestJobCommands(t *testing.T) {
+	var testcases = []struct {
+		name     string
+		author   string
+		prAuthor string
+		body     string
+		changes  []string
+
+		started  []string
+		skipped  []string
+		comments []string
+	}{
+		{
+			name:    "test by name",
+			author:  "t",
+			body:    "/test pull-unit",
+			started: []string{"unit"},
+		},
+		{
+			name:     "unknown name gets suggestions",
+			author:   "t",
+			body:     "/test pull-unti",
+			comments: []string{"there is no job named `pull-unti` for this repo. Did you mean `pull-unit`?"},
+		},
+		{
+			name:     "list jobs",
+			author:   "u",
+			body:     "/test ?",
+			comments: []string{"* `/test pull-unit` (required)", "* `/test pull-lint` (optional)", "* `/test pull-docs` (runs if files matching `^docs/` change)"},
+		},
+		{
+			name:    "skip optional jobs",
+			author:  "t",
+			body:    "/skip",
+			changes: []string{"main.go"},
+			skipped: []string{"lint", "docs"},
+		},
+		{
+			name:    "skip doesn't skip jobs for changed files",
+			author:  "t",
+			body:    "/skip",
+			changes: []string{"docs/README.md"},
+			skipped: []string{"lint"},
+		},
+		{
+			name:     "untrusted users can't skip",
+			author:   "u",
+			body:     "/skip",
+			comments: []string{"you can't request testing"},
+		},
+		{
+			name:     "untrusted users can't skip on trusted PRs",
+			author:   "u",
+			prAuthor: "t",
+			body:     "/skip",
+			comments: []string{"you can't skip jobs"},
+		},
+	}
+	for _, tc := range testcases {
+		var changes []github.PullRequestChange
+		for _, f := range tc.changes {
+			changes = append(changes, github.PullRequestChange{Filename: f})
+		}
+		g := &fakegithub.FakeClient{
+			IssueComments:      map[int][]github.IssueComment{},
+			OrgMembers:         []string{"t"},
+			PullRequestChanges: map[int][]github.PullRequestChange{0: changes},
+			PullRequests: map[int]*github.PullRequest{
+				0: {
+					User: github.User{Login: tc.prAuthor},
+					Head: github.PullRequestBranch{SHA: "cafe"},
+					Base: github.PullRequestBranch{
+						Ref:  "master",
+						Repo: github.Repo{Name: "repo", FullName: "org/repo"},
+					},
+				},
+			},
+			CombinedStatuses: map[string]*github.CombinedStatus{
+				"cafe": {Statuses: []github.Status{{State: "pending", Context: "unit"}}},
+			},
+		}
+		kc := &fkc{}
+		c := client{
+			GitHubClient: g,
+			KubeClient:   kc,
+			Config:       &config.Config{},
+			Logger:       logrus.WithField("plugin", pluginName),
+		}
+		if err := c.Config.SetPresubmits(map[string][]config.Presubmit{
+			"org/repo": {
+				{Name: "pull-unit", AlwaysRun: true, Context: "unit", Trigger: `(?m)^/test( all| pull-unit),?(\s+|$)`},
+				{Name: "pull-lint", Context: "lint", Trigger: `(?m)^/test pull-lint,?(\s+|$)`},
+				{Name: "pull-docs", Context: "docs", RunIfChanged: `^docs/`, Trigger: `(?m)^/test pull-docs,?(\s+|$)`},
+			},
+		}); err != nil {
+			t.Fatalf("Setting presubmits: %v", err)
+		}
+		event := github.IssueCommentEvent{
+			Action:  "created",
+			Repo:    github.Repo{Name: "repo", FullName: "org/repo"},
+			Comment: github.IssueComment{Body: tc.body, User: github.User{Login: tc.author}},
+			Issue:   github.Issue{PullRequest: &struct{}{}, State: "open"},
+		}
+		if err := handleIC(c, event); err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(kc.started, tc.started) {
+			t.Errorf("For case %s, expected %v to start, got %v", tc.name, tc.started, kc.started)
+		}
+		var skipped []string
+		for _, s := range g.CreatedStatuses["cafe"] {
+			if s.Description == "Skipped" {
+				skipped = append(skipped, s.Context)
+			}
+		}
+		if !reflect.DeepEqual(skipped, tc.skipped) {
+			t.Errorf("For case %s, expected %v to be skipped, got %v", tc.name, tc.skipped, skipped)
+		}
+		var bodies []string
+		for _, ic := range g.IssueComments[0] {
+			bodies = append(bodies, ic.Body)
+		}
+		for _, expected := range tc.comments {
+			if !strings.Contains(strings.Join(bodies, "\n"), expected) {
+				t.Errorf("For case %s, expected a comment containing %q, got %v", tc.name, expected, bodies)
+			}
+		}
+		if len(tc.comments) == 0 && len(bodies) > 0 {
+			t.Errorf("For case %s, expected no comments, got %v", tc.name, bodies)
+		}
+	}
+}
@@ -33,35 +33,34 @@ func handlePR(c client, pr github.PullRequestEvent) error {
 	org := pr.PullRequest.Base.Repo.Owner.Login
 	repo := pr.PullRequest.Base.Repo.Name
 	author := pr.PullRequest.User.Login
-	var trustedOrg string
-	if tr := triggerConfig(c.Config, org, repo); tr != nil && tr.TrustedOrg == "" {
-		c.Logger.Info("Ignoring PR Event, no TrustedOrg set in config.")
+	tr := triggerConfig(c.Config, org, repo)
+	if tr != nil && !tr.TrustsAnyone() {
+		c.Logger.Info("Ignoring PR Event, no trusted users set in config.")
 		return nil
-	} else if tr != nil {
-		trustedOrg = tr.TrustedOrg
 	}
+	trust := newTrustChecker(c, tr, org, repo)
 	switch pr.Action {
 	case "opened":
-		// When a PR is opened, if the author is in the org then build it.
+		// When a PR is opened, if the author is trusted then build it.
 		// Otherwise, ask for "ok to test". There's no need to look for previous
 		// "ok to test" comments since the PR was just opened!
-		member, err := c.GitHubClient.IsMember(trustedOrg, author)
+		trusted, err := trust.trustedUser(author)
 		if err != nil {
 			return fmt.Errorf("could not check membership: %s", err)
-		} else if member {
+		} else if trusted {
 			c.Logger.Info("Starting all jobs for new PR.")
 			return buildAll(c, pr.PullRequest)
 		} else {
 			c.Logger.Infof("Welcome message to PR author %q.", author)
-			if err := welcomeMsg(c.GitHubClient, pr.PullRequest, trustedOrg); err != nil {
+			if err := welcomeMsg(c.GitHubClient, pr.PullRequest, trust); err != nil {
 				return fmt.Errorf("could not welcome non-org member %q: %v", author, err)
 			}
 		}
 	case "reopened", "synchronize":
-		// When a PR is updated, check that the user is in the org or that an org
-		// member has said "ok to test" before building. There's no need to ask
+		// When a PR is updated, check that the user is trusted or that a trusted
+		// user has said "ok to test" before building. There's no need to ask
 		// for "ok to test" because we do that once when the PR is created.
-		trusted, err := trustedPullRequest(c.GitHubClient, pr.PullRequest, trustedOrg)
+		trusted, err := trustedPullRequest(c.GitHubClient, pr.PullRequest, trust)
 		if err != nil {
 			return fmt.Errorf("could not validate PR: %s", err)
 		} else if trusted {
@@ -71,7 +70,7 @@ func handlePR(c client, pr github.PullRequestEvent) error {
 	case "labeled":
 		// When a PR is LGTMd, if it is untrusted then build it once.
 		if pr.Label.Name == lgtmLabel {
-			trusted, err := trustedPullRequest(c.GitHubClient, pr.PullRequest, trustedOrg)
+			trusted, err := trustedPullRequest(c.GitHubClient, pr.PullRequest, trust)
 			if err != nil {
 				return fmt.Errorf("could not validate PR: %s", err)
 			} else if !trusted {
@@ -83,10 +82,10 @@ func handlePR(c client, pr github.PullRequestEvent) error {
 	return nil
 }
 
-func welcomeMsg(ghc githubClient, pr github.PullRequest, trustedOrg string) error {
+func welcomeMsg(ghc githubClient, pr github.PullRequest, trust *trustChecker) error {
 	commentTemplate := `Hi @%s. Thanks for your PR.
 
-I'm waiting for a [%s](https://github.com/orgs/%s/people) member to verify that this patch is reasonable to test. If it is, they should reply with ` + "`/ok-to-test`" + ` on its own line. Until that is done, I will not automatically test new commits in this PR, but the usual testing commands by org members will still work. Regular contributors should join the org to skip this step.
+I'm waiting for a %s to verify that this patch is reasonable to test. If it is, they should reply with ` + "`/ok-to-test`" + ` on its own line. Until that is done, I will not automatically test new commits in this PR, but the usual testing commands by org members will still work. Regular contributors should join the org to skip this step.
 
 I understand the commands that are listed [here](https://github.com/kubernetes/test-infra/blob/master/commands.md).
 
@@ -95,7 +94,7 @@ I understand the commands that are listed [here](https://github.com/kubernetes/t
 %s
 </details>
 `
-	comment := fmt.Sprintf(commentTemplate, pr.User.Login, trustedOrg, trustedOrg, plugins.AboutThisBot)
+	comment := fmt.Sprintf(commentTemplate, pr.User.Login, trust.description(), plugins.AboutThisBot)
 
 	owner := pr.Base.Repo.Owner.Login
 	name := pr.Base.Repo.Name
@@ -108,15 +107,15 @@ I understand the commands that are listed [here](https://github.com/kubernetes/t
 }
 
 // trustedPullRequest returns whether or not the given PR should be tested.
-// It first checks if the author is in the org, then looks for "ok to test
-// comments by org members.
-func trustedPullRequest(ghc githubClient, pr github.PullRequest, trustedOrg string) (bool, error) {
+// It first checks if the author is trusted, then looks for "ok to test"
+// comments by trusted users.
+func trustedPullRequest(ghc githubClient, pr github.PullRequest, trust *trustChecker) (bool, error) {
 	author := pr.User.Login
-	// First check if the author is a member of the org.
-	orgMember, err := ghc.IsMember(trustedOrg, author)
+	// First check if the author is trusted.
+	trusted, err := trust.trustedUser(author)
 	if err != nil {
 		return false, err
-	} else if orgMember {
+	} else if trusted {
 		return true, nil
 	}
 	// Next look for "ok to test" comments on the PR.
@@ -138,11 +137,11 @@ func trustedPullRequest(ghc githubClient, pr github.PullRequest, trustedOrg stri
 		if !okToTest.MatchString(comment.Body) {
 			continue
 		}
-		// Ensure that the commenter is in the org.
-		commentAuthorMember, err := ghc.IsMember(trustedOrg, commentAuthor)
+		// Ensure that the commenter is trusted.
+		commentAuthorTrusted, err := trust.trustedUser(commentAuthor)
 		if err != nil {
 			return false, err
-		} else if commentAuthorMember {
+		} else if commentAuthorTrusted {
 			return true, nil
 		}
 	}
@@ -19,6 +19,7 @@ package trigger
 import (
 	"testing"
 
+	"k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/github/fakegithub"
 )
@@ -168,7 +169,7 @@ func TestTrusted(t *testing.T) {
 				0: tc.Comments,
 			},
 		}
-		trusted, err := trustedPullRequest(g, tc.PR, "kubernetes")
+		trusted, err := trustedPullRequest(g, tc.PR, newTrustChecker(client{GitHubClient: g}, &config.Trigger{TrustedOrg: "kubernetes"}, "kubernetes", "kubernetes"))
 		if err != nil {
 			t.Fatalf("Didn't expect error: %s", err)
 		}
@@ -0,0 +1,225 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/kube"
+	"k8s.io/test-infra/prow/plank"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// staleCheckInterval is how often we look for PRs with the lgtm label whose
+// presubmit results are stale.
+const staleCheckInterval = 30 * time.Minute
+
+func handlePeriodic(pc plugins.PluginClient, repos []string) error {
+	return handleStale(getClient(pc), repos, time.Now())
+}
+
+// handleStale re-runs the stale presubmits of open PRs with the lgtm label in
+// the repos, which are either of the form org/repo or just org. Only repos
+// with stale_results in their trigger config are considered.
+func handleStale(c client, repos []string, now time.Time) error {
+	var pjs []kube.ProwJob // lazily initialized
+	var errs []error
+	for _, r := range repos {
+		if !staleResultsConfigured(c.Config, r) {
+			continue
+		}
+		scope := "org"
+		if strings.Contains(r, "/") {
+			scope = "repo"
+		}
+		issues, err := c.GitHubClient.FindIssues(fmt.Sprintf("is:pr is:open label:%s %s:%s", lgtmLabel, scope, r), "", false)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("searching %s: %v", r, err))
+			continue
+		}
+		for _, i := range issues {
+			org, repo, err := i.OrgRepo()
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			tr := triggerConfig(c.Config, org, repo)
+			if tr == nil || tr.StaleResults == nil {
+				continue
+			}
+			if pjs == nil {
+				if pjs, err = c.KubeClient.ListProwJobs(nil); err != nil {
+					return fmt.Errorf("listing prowjobs: %v", err)
+				}
+			}
+			if err := rerunStale(c, tr.StaleResults, org, repo, i.Number, pjs, now); err != nil {
+				errs = append(errs, fmt.Errorf("%s/%s#%d: %v", org, repo, i.Number, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("errors re-running stale presubmits: %v", errs)
+	}
+	return nil
+}
+
+// staleResultsConfigured returns whether or not any trigger config with
+// stale_results applies to some repo covered by r, which is either of the form
+// org/repo or just org. It spares us searching repos that don't need it.
+func staleResultsConfigured(c *config.Config, r string) bool {
+	org := strings.Split(r, "/")[0]
+	for _, tr := range c.Triggers {
+		if tr.StaleResults == nil {
+			continue
+		}
+		for _, repo := range tr.Repos {
+			if repo == r || repo == org || strings.HasPrefix(repo, r+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rerunStale starts the always-run presubmits of the PR whose latest results
+// at its head SHA are stale, testing them against the current base branch.
+func rerunStale(c client, sr *config.StaleResults, org, repo string, number int, pjs []kube.ProwJob, now time.Time) error {
+	pr, err := c.GitHubClient.GetPullRequest(org, repo, number)
+	if err != nil {
+		return err
+	}
+	baseSHA, err := c.GitHubClient.GetRef(org, repo, "heads/"+pr.Base.Ref)
+	if err != nil {
+		return err
+	}
+	var reported map[string]github.Status // lazily initialized
+	distances := map[string]int{}
+	// distance returns how many commits the base branch is ahead of oldBase.
+	distance := func(oldBase string) (int, error) {
+		if _, ok := distances[oldBase]; !ok {
+			cmp, err := c.GitHubClient.CompareCommits(org, repo, oldBase, baseSHA)
+			if err != nil {
+				return 0, err
+			}
+			distances[oldBase] = cmp.AheadBy
+		}
+		return distances[oldBase], nil
+	}
+	var mergeBase *string // lazily initialized
+	for _, job := range c.Config.Presubmits[pr.Base.Repo.FullName] {
+		if !job.AlwaysRun || !job.RunsAgainstBranch(pr.Base.Ref) {
+			continue
+		}
+		latest := latestProwJob(pjs, org, repo, job.Name, *pr)
+		var stale bool
+		if latest == nil {
+			// Sinker garbage-collects old prowjobs, so fall back on when the
+			// job last reported a status and on where the PR branched off.
+			// If we can't tell either, leave the job alone.
+			if reported == nil {
+				combinedStatus, err := c.GitHubClient.GetCombinedStatus(org, repo, pr.Head.SHA)
+				if err != nil {
+					return err
+				}
+				reported = map[string]github.Status{}
+				for _, status := range combinedStatus.Statuses {
+					reported[status.Context] = status
+				}
+			}
+			status, ok := reported[job.Context]
+			if !ok {
+				continue
+			}
+			if sr.MaxAge > 0 && !status.UpdatedAt.IsZero() && now.Sub(status.UpdatedAt) > sr.MaxAge {
+				stale = true
+			} else if sr.MaxBaseDistance > 0 {
+				if mergeBase == nil {
+					cmp, err := c.GitHubClient.CompareCommits(org, repo, baseSHA, pr.Head.SHA)
+					if err != nil {
+						return err
+					}
+					mergeBase = &cmp.MergeBaseCommit.SHA
+				}
+				if *mergeBase != "" && *mergeBase != baseSHA {
+					d, err := distance(*mergeBase)
+					if err != nil {
+						return err
+					}
+					stale = d > sr.MaxBaseDistance
+				}
+			}
+		} else if !latest.Complete() {
+			continue
+		} else if sr.MaxAge > 0 && now.Sub(latest.Status.StartTime) > sr.MaxAge {
+			stale = true
+		} else if sr.MaxBaseDistance > 0 && latest.Spec.Refs.BaseSHA != baseSHA {
+			d, err := distance(latest.Spec.Refs.BaseSHA)
+			if err != nil {
+				return err
+			}
+			stale = d > sr.MaxBaseDistance
+		}
+		if !stale {
+			continue
+		}
+		c.Logger.Infof("Re-running stale %s on %s/%s#%d.", job.Name, org, repo, number)
+		kr := kube.Refs{
+			Org:     org,
+			Repo:    repo,
+			BaseRef: pr.Base.Ref,
+			BaseSHA: baseSHA,
+			Pulls: []kube.Pull{
+				{
+					Number: number,
+					Author: pr.User.Login,
+					SHA:    pr.Head.SHA,
+				},
+			},
+		}
+		if _, err := c.KubeClient.CreateProwJob(plank.NewProwJob(plank.PresubmitSpec(job, kr))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// latestProwJob returns the most recently started presubmit prowjob for the
+// job at the PR's head SHA, or nil if there is none.
+func latestProwJob(pjs []kube.ProwJob, org, repo, job string, pr github.PullRequest) *kube.ProwJob {
+	var latest *kube.ProwJob
+	for i := range pjs {
+		pj := &pjs[i]
+		if pj.Spec.Type != kube.PresubmitJob || pj.Spec.Job != job {
+			continue
+		}
+		refs := pj.Spec.Refs
+		if refs.Org != org || refs.Repo != repo || len(refs.Pulls) != 1 {
+			continue
+		}
+		if refs.Pulls[0].Number != pr.Number || refs.Pulls[0].SHA != pr.Head.SHA {
+			continue
+		}
+		if latest == nil || pj.Status.StartTime.After(latest.Status.StartTime) {
+			latest = pj
+		}
+	}
+	return latest
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/kube"
+)
+
+func TestHandleStale(t *testing.T) {
+	now := time.Now()
+	prowJob := func(sha, baseSHA string, started time.Time, complete bool) kube.ProwJob {
+		pj := kube.ProwJob{
+			Spec: kube.ProwJobSpec{
+				Type: kube.PresubmitJob,
+				Job:  "pull-unit",
+				Refs: kube.Refs{
+					Org:     "org",
+					Repo:    "repo",
+					BaseSHA: baseSHA,
+					Pulls:   []kube.Pull{{Number: 1, SHA: sha}},
+				},
+			},
+			Status: kube.ProwJobStatus{StartTime: started},
+		}
+		if complete {
+			pj.Status.CompletionTime = started.Add(time.Minute)
+		}
+		return pj
+	}
+	var testcases = []struct {
+		name     string
+		stale    *config.StaleResults
+		jobs     []kube.ProwJob
+		statuses []github.Status
+		// mergeBase is where the PR branched off the base branch.
+		mergeBase string
+
+		rerun bool
+	}{
+		{
+			name:  "no stale results config",
+			jobs:  []kube.ProwJob{prowJob("head", "abcde", now.Add(-48*time.Hour), true)},
+			stale: nil,
+		},
+		{
+			name:  "fresh result",
+			stale: &config.StaleResults{MaxAge: 24 * time.Hour, MaxBaseDistance: 10},
+			jobs:  []kube.ProwJob{prowJob("head", "abcde", now.Add(-time.Hour), true)},
+		},
+		{
+			name:  "old result",
+			stale: &config.StaleResults{MaxAge: 24 * time.Hour},
+			jobs:  []kube.ProwJob{prowJob("head", "abcde", now.Add(-48*time.Hour), true)},
+			rerun: true,
+		},
+		{
+			name:  "newer result for the same SHA is fresh",
+			stale: &config.StaleResults{MaxAge: 24 * time.Hour},
+			jobs: []kube.ProwJob{
+				prowJob("head", "abcde", now.Add(-48*time.Hour), true),
+				prowJob("head", "abcde", now.Add(-time.Hour), true),
+			},
+		},
+		{
+			name:  "result for an old head SHA is ignored",
+			stale: &config.StaleResults{MaxAge: 24 * time.Hour},
+			jobs: []kube.ProwJob{
+				prowJob("old", "abcde", now.Add(-48*time.Hour), true),
+				prowJob("head", "abcde", now.Add(-time.Hour), true),
+			},
+		},
+		{
+			name:  "old result still running",
+			stale: &config.StaleResults{MaxAge: 24 * time.Hour},
+			jobs:  []kube.ProwJob{prowJob("head", "abcde", now.Add(-48*time.Hour), false)},
+		},
+		{
+			name:  "base moved too far",
+			stale: &config.StaleResults{MaxBaseDistance: 10},
+			jobs:  []kube.ProwJob{prowJob("head", "far", now.Add(-time.Hour), true)},
+			rerun: true,
+		},
+		{
+			name:  "base moved a little",
+			stale: &config.StaleResults{MaxBaseDistance: 10},
+			jobs:  []kube.ProwJob{prowJob("head", "near", now.Add(-time.Hour), true)},
+		},
+		{
+			name:     "garbage-collected old result",
+			stale:    &config.StaleResults{MaxAge: 24 * time.Hour},
+			statuses: []github.Status{{State: github.StatusSuccess, Context: "unit", UpdatedAt: now.Add(-48 * time.Hour)}},
+			rerun:    true,
+		},
+		{
+			name:     "garbage-collected fresh result",
+			stale:    &config.StaleResults{MaxAge: 24 * time.Hour},
+			statuses: []github.Status{{State: github.StatusSuccess, Context: "unit", UpdatedAt: now.Add(-time.Hour)}},
+		},
+		{
+			name:     "garbage-collected result of unknown age",
+			stale:    &config.StaleResults{MaxAge: 24 * time.Hour},
+			statuses: []github.Status{{State: github.StatusSuccess, Context: "unit"}},
+		},
+		{
+			name:      "garbage-collected result, branched off far behind",
+			stale:     &config.StaleResults{MaxBaseDistance: 10},
+			statuses:  []github.Status{{State: github.StatusSuccess, Context: "unit"}},
+			mergeBase: "far",
+			rerun:     true,
+		},
+		{
+			name:      "garbage-collected result, branched off a little behind",
+			stale:     &config.StaleResults{MaxBaseDistance: 10},
+			statuses:  []github.Status{{State: github.StatusSuccess, Context: "unit"}},
+			mergeBase: "near",
+		},
+		{
+			name:  "never ran",
+			stale: &config.StaleResults{MaxAge: 24 * time.Hour},
+		},
+	}
+	for _, tc := range testcases {
+		g := &fakegithub.FakeClient{
+			Issues: []github.Issue{
+				{
+					Number:      1,
+					HTMLURL:     "https://github.com/org/repo/pull/1",
+					PullRequest: &struct{}{},
+					Labels:      []github.Label{{Name: lgtmLabel}},
+				},
+			},
+			PullRequests: map[int]*github.PullRequest{
+				1: {
+					Number: 1,
+					Head:   github.PullRequestBranch{SHA: "head"},
+					Base: github.PullRequestBranch{
+						Ref:  "master",
+						Repo: github.Repo{Name: "repo", FullName: "org/repo"},
+					},
+				},
+			},
+			CombinedStatuses: map[string]*github.CombinedStatus{
+				"head": {Statuses: tc.statuses},
+			},
+			Comparisons: map[string]*github.CommitComparison{
+				"far...abcde":  {Status: "ahead", AheadBy: 11},
+				"near...abcde": {Status: "ahead", AheadBy: 3},
+				"abcde...head": {Status: "diverged", MergeBaseCommit: github.RepositoryCommit{SHA: tc.mergeBase}},
+			},
+		}
+		kc := &fkc{jobs: tc.jobs}
+		c := client{
+			GitHubClient: g,
+			KubeClient:   kc,
+			Config: &config.Config{
+				Triggers: []config.Trigger{{Repos: []string{"org"}, TrustedOrg: "org", StaleResults: tc.stale}},
+			},
+			Logger: logrus.WithField("plugin", pluginName),
+		}
+		if err := c.Config.SetPresubmits(map[string][]config.Presubmit{
+			"org/repo": {
+				{Name: "pull-unit", AlwaysRun: true, Context: "unit"},
+				{Name: "pull-e2e", Context: "e2e"},
+			},
+		}); err != nil {
+			t.Fatalf("Setting presubmits: %v", err)
+		}
+		if err := handleStale(c, []string{"org"}, now); err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+			continue
+		}
+		var expected []string
+		if tc.rerun {
+			expected = []string{"unit"}
+		}
+		if !reflect.DeepEqual(kc.started, expected) {
+			t.Errorf("For case %s, expected %v to start, got %v", tc.name, expected, kc.started)
+		}
+	}
+}
+
+func TestStaleResultsConfigured(t *testing.T) {
+	c := &config.Config{
+		Triggers: []config.Trigger{
+			{Repos: []string{"a", "b/x"}, StaleResults: &config.StaleResults{MaxAge: time.Hour}},
+			{Repos: []string{"c"}},
+		},
+	}
+	for r, expected := range map[string]bool{"a": true, "a/x": true, "b": true, "b/x": true, "b/y": false, "c": false, "c/x": false} {
+		if configured := staleResultsConfigured(c, r); configured != expected {
+			t.Errorf("For %s, expected %t, got %t", r, expected, configured)
+		}
+	}
+}
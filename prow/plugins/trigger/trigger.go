@@ -36,12 +36,15 @@ func init() {
 	plugins.RegisterIssueCommentHandler(pluginName, handleIssueComment)
 	plugins.RegisterPullRequestHandler(pluginName, handlePullRequest)
 	plugins.RegisterPushEventHandler(pluginName, handlePush)
+	plugins.RegisterPeriodicHandler(pluginName, staleCheckInterval, handlePeriodic)
 }
 
 type githubClient interface {
 	AddLabel(org, repo string, number int, label string) error
 	BotName() string
 	IsMember(org, user string) (bool, error)
+	IsCollaborator(org, repo, user string) (bool, error)
+	GetFile(org, repo, filepath, commit string) ([]byte, error)
 	GetPullRequest(org, repo string, number int) (*github.PullRequest, error)
 	GetRef(org, repo, ref string) (string, error)
 	CreateComment(owner, repo string, number int, comment string) error
@@ -50,10 +53,13 @@ type githubClient interface {
 	GetCombinedStatus(org, repo, ref string) (*github.CombinedStatus, error)
 	GetPullRequestChanges(org, repo string, number int) ([]github.PullRequestChange, error)
 	RemoveLabel(org, repo string, number int, label string) error
+	FindIssues(query, sort string, asc bool) ([]github.Issue, error)
+	CompareCommits(org, repo, base, head string) (*github.CommitComparison, error)
 }
 
 type kubeClient interface {
 	CreateProwJob(kube.ProwJob) (kube.ProwJob, error)
+	ListProwJobs(labels map[string]string) ([]kube.ProwJob, error)
 }
 
 type client struct {
@@ -61,8 +67,14 @@ type client struct {
 	KubeClient   kubeClient
 	Config       *config.Config
 	Logger       *logrus.Entry
+	// Cache remembers who is trusted. It may be nil.
+	Cache *membershipCache
 }
 
+// trustCache is shared by all events so that we don't ask GitHub about the
+// same users over and over.
+var trustCache = newMembershipCache(membershipCacheTTL)
+
 func triggerConfig(c *config.Config, org, repo string) *config.Trigger {
 	for _, tr := range c.Triggers {
 		for _, r := range tr.Repos {
@@ -80,6 +92,7 @@ func getClient(pc plugins.PluginClient) client {
 		Config:       pc.Config,
 		KubeClient:   pc.KubeClient,
 		Logger:       pc.Logger,
+		Cache:        trustCache,
 	}
 }
 
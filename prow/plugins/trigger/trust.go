@@ -0,0 +1,213 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/plugins"
+)
+
+// membershipCacheTTL is how long we remember whether or not a user is
+// trusted before asking GitHub again.
+const membershipCacheTTL = 10 * time.Minute
+
+// membershipCache remembers the results of trust lookups to spare API tokens.
+type membershipCache struct {
+	sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	trusted bool
+	expiry  time.Time
+}
+
+func newMembershipCache(ttl time.Duration) *membershipCache {
+	return &membershipCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[string]cacheEntry{},
+	}
+}
+
+// lookup returns the cached answer for key, or calls fn and caches its answer.
+// A nil cache always calls fn.
+func (mc *membershipCache) lookup(key string, fn func() (bool, error)) (bool, error) {
+	if mc == nil {
+		return fn()
+	}
+	mc.Lock()
+	e, ok := mc.entries[key]
+	mc.Unlock()
+	if ok && mc.now().Before(e.expiry) {
+		return e.trusted, nil
+	}
+	trusted, err := fn()
+	if err != nil {
+		return false, err
+	}
+	mc.Lock()
+	defer mc.Unlock()
+	mc.entries[key] = cacheEntry{trusted: trusted, expiry: mc.now().Add(mc.ttl)}
+	// Forget expired entries so that the cache doesn't grow forever.
+	for k, e := range mc.entries {
+		if !mc.now().Before(e.expiry) {
+			delete(mc.entries, k)
+		}
+	}
+	return trusted, nil
+}
+
+// trustChecker decides whether or not users are trusted to test PRs on a
+// repo.
+type trustChecker struct {
+	ghc   githubClient
+	cache *membershipCache
+	log   *logrus.Entry
+
+	org  string
+	repo string
+
+	orgs          []string
+	collaborators bool
+	files         []string
+}
+
+// newTrustChecker returns a trustChecker for the repo. Without trigger config,
+// members of the repo's org are trusted.
+func newTrustChecker(c client, tr *config.Trigger, org, repo string) *trustChecker {
+	t := &trustChecker{
+		ghc:   c.GitHubClient,
+		cache: c.Cache,
+		log:   c.Logger,
+		org:   org,
+		repo:  repo,
+		orgs:  []string{org},
+	}
+	if tr != nil {
+		t.orgs = tr.Orgs()
+		t.collaborators = tr.TrustCollaborators
+		t.files = tr.TrustedUsersFiles
+	}
+	return t
+}
+
+// trustedUser returns whether or not the user is a member of a trusted org, a
+// collaborator of the repo if collaborators are trusted, or listed in one of
+// the trusted users files.
+func (t *trustChecker) trustedUser(user string) (bool, error) {
+	for _, org := range t.orgs {
+		member, err := t.cache.lookup(fmt.Sprintf("member:%s:%s", org, user), func() (bool, error) {
+			return t.ghc.IsMember(org, user)
+		})
+		if err != nil {
+			return false, err
+		} else if member {
+			return true, nil
+		}
+	}
+	if t.collaborators {
+		collaborator, err := t.cache.lookup(fmt.Sprintf("collaborator:%s/%s:%s", t.org, t.repo, user), func() (bool, error) {
+			return t.ghc.IsCollaborator(t.org, t.repo, user)
+		})
+		if err != nil {
+			return false, err
+		} else if collaborator {
+			return true, nil
+		}
+	}
+	for _, file := range t.files {
+		listed, err := t.cache.lookup(fmt.Sprintf("file:%s/%s/%s:%s", t.org, t.repo, file, user), func() (bool, error) {
+			return t.listedIn(file, user)
+		})
+		if err != nil {
+			// A missing or broken file shouldn't stop trusted orgs from
+			// working, so just log it.
+			t.log.WithError(err).Warningf("Could not read %s in %s/%s.", file, t.org, t.repo)
+			continue
+		} else if listed {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// listedIn returns whether or not the user is listed in the file at the root
+// of the repo.
+func (t *trustChecker) listedIn(file, user string) (bool, error) {
+	b, err := t.ghc.GetFile(t.org, t.repo, file, "")
+	if err != nil {
+		return false, err
+	}
+	if path.Base(file) == plugins.OwnersFilename {
+		o, err := plugins.ParseOwners(b)
+		if err != nil {
+			return false, err
+		}
+		return o.IsOwner(user), nil
+	}
+	for _, login := range parseUsersFile(b) {
+		if strings.EqualFold(login, user) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// parseUsersFile returns the logins in a file that lists one per line.
+// Blank lines and lines starting with # are skipped, and a leading @ is
+// dropped.
+func parseUsersFile(b []byte) []string {
+	var logins []string
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		logins = append(logins, strings.TrimPrefix(strings.Fields(line)[0], "@"))
+	}
+	return logins
+}
+
+// description describes the trusted users for comments, such as
+// "[kubernetes](https://github.com/orgs/kubernetes/people) member".
+func (t *trustChecker) description() string {
+	var parts []string
+	for _, org := range t.orgs {
+		parts = append(parts, fmt.Sprintf("[%s](https://github.com/orgs/%s/people) member", org, org))
+	}
+	if t.collaborators {
+		parts = append(parts, "collaborator of this repo")
+	}
+	for _, file := range t.files {
+		parts = append(parts, fmt.Sprintf("user listed in %s", file))
+	}
+	return strings.Join(parts, " or ")
+}
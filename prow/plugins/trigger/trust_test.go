@@ -0,0 +1,160 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/config"
+	"k8s.io/test-infra/prow/github/fakegithub"
+)
+
+// countingClient counts the membership lookups that reach GitHub.
+type countingClient struct {
+	*fakegithub.FakeClient
+	calls int
+}
+
+func (c *countingClient) IsMember(org, user string) (bool, error) {
+	c.calls++
+	return c.FakeClient.IsMember(org, user)
+}
+
+func TestTrustedUser(t *testing.T) {
+	var testcases = []struct {
+		name    string
+		trigger *config.Trigger
+		user    string
+		trusted bool
+	}{
+		{
+			name:    "no config trusts the repo's org",
+			user:    "member",
+			trusted: true,
+		},
+		{
+			name:    "member of the trusted org",
+			trigger: &config.Trigger{TrustedOrg: "org"},
+			user:    "member",
+			trusted: true,
+		},
+		{
+			name:    "stranger",
+			trigger: &config.Trigger{TrustedOrg: "org", TrustCollaborators: true, TrustedUsersFiles: []string{"OWNERS", "SECURITY_CONTACTS"}},
+			user:    "stranger",
+		},
+		{
+			name:    "collaborator without trusting collaborators",
+			trigger: &config.Trigger{TrustedOrg: "org"},
+			user:    "collaborator",
+		},
+		{
+			name:    "collaborator",
+			trigger: &config.Trigger{TrustCollaborators: true},
+			user:    "collaborator",
+			trusted: true,
+		},
+		{
+			name:    "reviewer in OWNERS",
+			trigger: &config.Trigger{TrustedUsersFiles: []string{"OWNERS"}},
+			user:    "Reviewer",
+			trusted: true,
+		},
+		{
+			name:    "listed in SECURITY_CONTACTS",
+			trigger: &config.Trigger{TrustedUsersFiles: []string{"SECURITY_CONTACTS"}},
+			user:    "security",
+			trusted: true,
+		},
+		{
+			name:    "missing file is skipped",
+			trigger: &config.Trigger{TrustedUsersFiles: []string{"MISSING", "SECURITY_CONTACTS"}},
+			user:    "security",
+			trusted: true,
+		},
+	}
+	for _, tc := range testcases {
+		g := &fakegithub.FakeClient{
+			OrgMembers:    []string{"member"},
+			Collaborators: []string{"collaborator"},
+			RemoteFiles: map[string]map[string]string{
+				"OWNERS":            {"master": "approvers:\n- approver\nreviewers:\n- reviewer\n"},
+				"SECURITY_CONTACTS": {"master": "# Report security issues to:\n\n@security\n"},
+			},
+		}
+		c := client{GitHubClient: g, Logger: logrus.WithField("plugin", pluginName)}
+		trusted, err := newTrustChecker(c, tc.trigger, "org", "repo").trustedUser(tc.user)
+		if err != nil {
+			t.Errorf("For case %s, didn't expect error: %v", tc.name, err)
+		} else if trusted != tc.trusted {
+			t.Errorf("For case %s, expected trusted: %t, got %t", tc.name, tc.trusted, trusted)
+		}
+	}
+}
+
+func TestMembershipCache(t *testing.T) {
+	g := &countingClient{FakeClient: &fakegithub.FakeClient{OrgMembers: []string{"member"}}}
+	mc := newMembershipCache(time.Minute)
+	now := time.Now()
+	mc.now = func() time.Time { return now }
+	c := client{GitHubClient: g, Cache: mc, Logger: logrus.WithField("plugin", pluginName)}
+	trust := newTrustChecker(c, &config.Trigger{TrustedOrgs: []string{"a", "b"}}, "org", "repo")
+
+	for i := 0; i < 3; i++ {
+		if trusted, err := trust.trustedUser("member"); err != nil || !trusted {
+			t.Fatalf("Expected member to be trusted, got %t, %v", trusted, err)
+		}
+	}
+	if g.calls != 1 {
+		t.Errorf("Expected 1 lookup for a cached member, got %d", g.calls)
+	}
+	if _, err := trust.trustedUser("stranger"); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if g.calls != 3 {
+		t.Errorf("Expected a lookup in each org for a stranger, got %d lookups", g.calls)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := trust.trustedUser("member"); err != nil {
+		t.Fatalf("Didn't expect error: %v", err)
+	}
+	if g.calls != 4 {
+		t.Errorf("Expected another lookup once the cache expired, got %d lookups", g.calls)
+	}
+}
+
+func TestParseUsersFile(t *testing.T) {
+	b := []byte("# Security contacts\n\nalice\n@bob  # on call\n   \n")
+	if logins, expected := parseUsersFile(b), []string{"alice", "bob"}; !reflect.DeepEqual(logins, expected) {
+		t.Errorf("Expected %v, got %v", expected, logins)
+	}
+}
+
+func TestTrustDescription(t *testing.T) {
+	trust := newTrustChecker(client{}, &config.Trigger{TrustedOrg: "kubernetes"}, "kubernetes", "test-infra")
+	if d, expected := trust.description(), "[kubernetes](https://github.com/orgs/kubernetes/people) member"; d != expected {
+		t.Errorf("Expected %q, got %q", expected, d)
+	}
+	trust = newTrustChecker(client{}, &config.Trigger{TrustedOrgs: []string{"a"}, TrustCollaborators: true, TrustedUsersFiles: []string{"OWNERS"}}, "a", "b")
+	if d, expected := trust.description(), "[a](https://github.com/orgs/a/people) member or collaborator of this repo or user listed in OWNERS"; d != expected {
+		t.Errorf("Expected %q, got %q", expected, d)
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package splice describes the merge pool that cmd/splice batches PRs from,
+// so that deck can show it.
+package splice
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/test-infra/prow/kube"
+)
+
+// BatchBaseRef is the only branch that splice batches PRs against.
+const BatchBaseRef = "master"
+
+// What splice is doing with a pool.
+const (
+	// Idle means that there is nothing to batch.
+	Idle = "idle"
+	// Testing means that the batch's jobs are running.
+	Testing = "testing"
+	// CoolingDown means that splice is giving the submit queue time to merge
+	// the last batch before starting another.
+	CoolingDown = "cooling_down"
+)
+
+// QueuedPR is a PR in the submit queue.
+type QueuedPR struct {
+	Number  int
+	BaseRef string
+}
+
+// PR is a queued PR and where it stands in the pool.
+type PR struct {
+	Number  int    `json:"number"`
+	BaseRef string `json:"base_ref"`
+	// InBatch is true if the PR is in the pool's batch.
+	InBatch bool `json:"in_batch"`
+	// Reason says why the PR isn't in the batch. It only covers batching:
+	// PRs that the submit queue holds back aren't in its queue at all.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Pool is splice's view of a repo's merge queue.
+type Pool struct {
+	Org  string `json:"org"`
+	Repo string `json:"repo"`
+	// PRs are the queued PRs, in queue order.
+	PRs []PR `json:"prs"`
+	// Batch is what splice tests together. It has no pulls if there is no
+	// batch.
+	Batch kube.Refs `json:"batch"`
+	// State is one of Idle, Testing or CoolingDown.
+	State string `json:"state"`
+	// Error is set if splice couldn't check the queue last time it tried.
+	Error string `json:"error,omitempty"`
+	// Updated is when splice last looked at the queue.
+	Updated time.Time `json:"updated"`
+}
+
+// Batchable returns the numbers of the queued PRs that splice may batch, in
+// queue order. A PR without a base ref is against master.
+func Batchable(queue []QueuedPR) []int {
+	ret := []int{}
+	for _, pr := range queue {
+		if pr.BaseRef == "" || pr.BaseRef == BatchBaseRef {
+			ret = append(ret, pr.Number)
+		}
+	}
+	return ret
+}
+
+// NewPool picks the batch from the queue. mergeable are the batchable PRs
+// that merge onto the base branch and the PRs ahead of them without
+// conflicts, in queue order. There is no batch unless at least two PRs are
+// mergeable, since the submit queue tests single PRs itself.
+func NewPool(org, repo string, queue []QueuedPR, mergeable []int, maxBatchSize int) Pool {
+	pool := Pool{
+		Org:     org,
+		Repo:    repo,
+		PRs:     []PR{},
+		State:   Idle,
+		Updated: time.Now(),
+	}
+	batch := mergeable
+	if len(batch) <= 1 {
+		batch = nil
+	} else if len(batch) > maxBatchSize {
+		batch = batch[:maxBatchSize]
+	}
+	inBatch := make(map[int]bool)
+	for _, n := range batch {
+		inBatch[n] = true
+		pool.Batch.Pulls = append(pool.Batch.Pulls, kube.Pull{Number: n})
+	}
+	isMergeable := make(map[int]bool)
+	for _, n := range mergeable {
+		isMergeable[n] = true
+	}
+	for _, q := range queue {
+		pr := PR{Number: q.Number, BaseRef: q.BaseRef, InBatch: inBatch[q.Number]}
+		switch {
+		case pr.InBatch:
+		case q.BaseRef != "" && q.BaseRef != BatchBaseRef:
+			pr.Reason = fmt.Sprintf("Only PRs against %s are batched.", BatchBaseRef)
+		case !isMergeable[q.Number]:
+			pr.Reason = "Conflicts with the base branch or the PRs ahead of it."
+		case len(batch) == 0:
+			pr.Reason = "Only mergeable PR, so the submit queue tests it alone."
+		default:
+			pr.Reason = fmt.Sprintf("Batch is full at %d PRs.", maxBatchSize)
+		}
+		pool.PRs = append(pool.PRs, pr)
+	}
+	return pool
+}
+
+// BatchPRs returns the numbers of the PRs in the batch.
+func (p Pool) BatchPRs() []int {
+	var prs []int
+	for _, pull := range p.Batch.Pulls {
+		prs = append(prs, pull.Number)
+	}
+	return prs
+}
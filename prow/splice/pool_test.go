@@ -0,0 +1,106 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package splice
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBatchable(t *testing.T) {
+	queue := []QueuedPR{
+		{Number: 3},
+		{Number: 4, BaseRef: "master"},
+		{Number: 1},
+		{Number: 5, BaseRef: "release-1.5"},
+	}
+	if b := Batchable(queue); !reflect.DeepEqual(b, []int{3, 4, 1}) {
+		t.Errorf("Expected [3 4 1], got %v", b)
+	}
+}
+
+func TestNewPool(t *testing.T) {
+	queue := []QueuedPR{
+		{Number: 3},
+		{Number: 4, BaseRef: "master"},
+		{Number: 1},
+		{Number: 5, BaseRef: "release-1.5"},
+		{Number: 2},
+	}
+	var testcases = []struct {
+		name      string
+		mergeable []int
+		maxBatch  int
+		batch     []int
+		reasons   map[int]string
+	}{
+		{
+			name:      "conflict and other branch",
+			mergeable: []int{3, 1, 2},
+			maxBatch:  5,
+			batch:     []int{3, 1, 2},
+			reasons: map[int]string{
+				4: "Conflicts with the base branch or the PRs ahead of it.",
+				5: "Only PRs against master are batched.",
+			},
+		},
+		{
+			name:      "full batch",
+			mergeable: []int{3, 4, 1, 2},
+			maxBatch:  2,
+			batch:     []int{3, 4},
+			reasons: map[int]string{
+				1: "Batch is full at 2 PRs.",
+				2: "Batch is full at 2 PRs.",
+				5: "Only PRs against master are batched.",
+			},
+		},
+		{
+			name:      "single mergeable PR",
+			mergeable: []int{4},
+			maxBatch:  5,
+			reasons: map[int]string{
+				3: "Conflicts with the base branch or the PRs ahead of it.",
+				4: "Only mergeable PR, so the submit queue tests it alone.",
+				1: "Conflicts with the base branch or the PRs ahead of it.",
+				5: "Only PRs against master are batched.",
+				2: "Conflicts with the base branch or the PRs ahead of it.",
+			},
+		},
+	}
+	for _, tc := range testcases {
+		pool := NewPool("org", "repo", queue, tc.mergeable, tc.maxBatch)
+		if b := pool.BatchPRs(); !reflect.DeepEqual(b, tc.batch) {
+			t.Errorf("For case %s, expected batch %v, got %v", tc.name, tc.batch, b)
+		}
+		if len(pool.PRs) != len(queue) {
+			t.Errorf("For case %s, expected %d PRs, got %d", tc.name, len(queue), len(pool.PRs))
+			continue
+		}
+		for i, pr := range pool.PRs {
+			if pr.Number != queue[i].Number {
+				t.Errorf("For case %s, expected PR %d at %d, got %d", tc.name, queue[i].Number, i, pr.Number)
+			}
+			if pr.Reason != tc.reasons[pr.Number] {
+				t.Errorf("For case %s, expected reason %q for #%d, got %q", tc.name, tc.reasons[pr.Number], pr.Number, pr.Reason)
+			}
+			if pr.InBatch != (pr.Reason == "") {
+				t.Errorf("For case %s, #%d is in the batch: %t, but has reason %q", tc.name, pr.Number, pr.InBatch, pr.Reason)
+			}
+		}
+	}
+}